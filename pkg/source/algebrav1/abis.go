@@ -11,6 +11,8 @@ var (
 	algebraV1DirFeePoolABI                abi.ABI
 	algebraV1DataStorageOperatorAPI       abi.ABI
 	algebraV1DirFeeDataStorageOperatorAPI abi.ABI
+	algebraV1FactoryABI                   abi.ABI
+	tickLensABI                           abi.ABI
 	erc20ABI                              abi.ABI
 )
 
@@ -23,6 +25,8 @@ func init() {
 		{&algebraV1DirFeePoolABI, algebraV1DirFeePoolJson},
 		{&algebraV1DataStorageOperatorAPI, algebraV1DataStorageOperatorJson},
 		{&algebraV1DirFeeDataStorageOperatorAPI, algebraV1DirFeeDataStorageOperatorJson},
+		{&algebraV1FactoryABI, algebraV1FactoryJson},
+		{&tickLensABI, tickLensJson},
 		{&erc20ABI, erc20Json},
 	}
 
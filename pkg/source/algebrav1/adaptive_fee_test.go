@@ -0,0 +1,37 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFee(t *testing.T) {
+	// AdaptiveFee.getFee of the default configuration at averages where its sigmoids are exact: α/2 at x = β,
+	// 0 or α once |x - β| >= 6γ
+	testcases := []struct {
+		volatility         int64
+		volumePerLiquidity int64
+		expectedFee        uint16
+	}{
+		// baseFee + sigmoidVolume(0, 0)
+		{0, 0, 100},
+		// 100 + sigmoidVolume(2900/2 + 0, 0) = 100 + 1450/2
+		{360, 0, 825},
+		// 100 + sigmoidVolume(2900 + 12000/2, 0) = 100 + 8900/2
+		{60000, 0, 4550},
+		{60000, 60, 9000},
+		// capped by baseFee + alpha1 + alpha2
+		{111000, 60, 15000},
+		{1e12, 1e12, 15000},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			fee := getFee(big.NewInt(tc.volatility), big.NewInt(tc.volumePerLiquidity), &defaultFeeConfig)
+			assert.Equal(t, tc.expectedFee, fee)
+		})
+	}
+}
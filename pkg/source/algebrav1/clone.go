@@ -0,0 +1,42 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// clone returns a copy of the simulator that can be updated independently of p.
+// UpdateBalance replaces the state instead of mutating it, so the state values are shared until then.
+func (p *PoolSimulator) clone() *PoolSimulator {
+	cloned := *p
+	cloned.Info.Reserves = append([]*big.Int(nil), p.Info.Reserves...)
+	return &cloned
+}
+
+// CloneState returns a deep copy of the mutable state of the simulator, e.g. for a route to apply its swaps to its own
+// copy of a shared pool. The ticks and the timepoints are shared until they are replaced, InsertTick, RemoveTick and
+// UpdateBalance copy them on write. The tracked fee growth outside of the ticks is never changed.
+func (p *PoolSimulator) CloneState() pool.IPoolSimulator {
+	cloned := p.clone()
+	for i, reserve := range p.Info.Reserves {
+		cloned.Info.Reserves[i] = new(big.Int).Set(reserve)
+	}
+	cloned.liquidity = new(big.Int).Set(p.liquidity)
+	cloned.globalState.Price = new(big.Int).Set(p.globalState.Price)
+	if p.globalState.Tick != nil {
+		cloned.globalState.Tick = new(big.Int).Set(p.globalState.Tick)
+	}
+	if p.volumePerLiquidityInBlock != nil {
+		cloned.volumePerLiquidityInBlock = new(big.Int).Set(p.volumePerLiquidityInBlock)
+	}
+	cloned.totalFeeGrowth = FeeGrowth{
+		Token0: new(big.Int).Set(p.totalFeeGrowth.Token0),
+		Token1: new(big.Int).Set(p.totalFeeGrowth.Token1),
+	}
+	cloned.crossedFeeGrowthOutside = make(map[int]FeeGrowth, len(p.crossedFeeGrowthOutside))
+	for tick, feeGrowth := range p.crossedFeeGrowthOutside {
+		cloned.crossedFeeGrowthOutside[tick] = feeGrowth
+	}
+	return cloned
+}
@@ -0,0 +1,137 @@
+package algebrav1
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// amountOut returns the output of swapping the whole amountIn, the pools that can only fill a part of it fail with
+// ErrNotEnoughLiquidity so that they aren't compared with the ones swapping all of it
+func (p *PoolSimulator) amountOut(amountIn *big.Int, tokenIn, tokenOut string) (*big.Int, error) {
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: amountIn}, tokenOut)
+	if err != nil {
+		return nil, err
+	}
+	if amountInSwapped(res, amountIn).Cmp(amountIn) < 0 {
+		return nil, ErrNotEnoughLiquidity
+	}
+	return res.TokenAmountOut.Amount, nil
+}
+
+// amountInSwapped returns the part of amountIn swapped by res, amountIn less the RemainingTokenAmountIn of a partial
+// fill
+func amountInSwapped(res *pool.CalcAmountOutResult, amountIn *big.Int) *big.Int {
+	if res.RemainingTokenAmountIn == nil || res.RemainingTokenAmountIn.Amount == nil {
+		return amountIn
+	}
+	return new(big.Int).Sub(amountIn, res.RemainingTokenAmountIn.Amount)
+}
+
+// CompareOutputTo compares the output of p and other for the same input:
+// 1 if p gives more output, -1 if other gives more, 0 if they are equal
+func (p *PoolSimulator) CompareOutputTo(other *PoolSimulator, amountIn *big.Int, tokenIn, tokenOut string) (int, error) {
+	amountOut, err := p.amountOut(amountIn, tokenIn, tokenOut)
+	if err != nil {
+		return 0, err
+	}
+	otherAmountOut, err := other.amountOut(amountIn, tokenIn, tokenOut)
+	if err != nil {
+		return 0, err
+	}
+	return amountOut.Cmp(otherAmountOut), nil
+}
+
+// CompareFee compares the effective fees of p and other like big.Int.Cmp: -1 if p is cheaper, 1 if other is cheaper.
+// The fee of a pool is the mean of its fees of both directions, less the off-chain adjustment. The fees of the
+// adaptive fee pools are the ones at the current volatility, computed by the tracker.
+func (p *PoolSimulator) CompareFee(other *PoolSimulator) int {
+	fee, otherFee := p.effectiveFee(), other.effectiveFee()
+	switch {
+	case fee < otherFee:
+		return -1
+	case fee > otherFee:
+		return 1
+	}
+	return 0
+}
+
+// effectiveFee returns the sum of the fees of both directions less their adjustment, in hundredths of a bip
+func (p *PoolSimulator) effectiveFee() int {
+	fee := 0
+	for _, directionFee := range []uint16{p.globalState.FeeZto, p.globalState.FeeOtz} {
+		fee += int(directionFee) - p.effectiveFeeAdjustmentBpsOf(directionFee)*100
+	}
+	return fee
+}
+
+// BestPoolForAmount returns the pool of the list that gives the most output for amountIn,
+// the pools are expected to have the same token pair. Pools that can't quote the swap are skipped. Ties are broken
+// by address then exchange, so that the result doesn't depend on the order of the list.
+func BestPoolForAmount(pools []*PoolSimulator, amountIn *big.Int, tokenIn, tokenOut string) (*PoolSimulator, error) {
+	switch len(pools) {
+	case 0:
+		return nil, ErrNoPools
+	case 1:
+		return pools[0], nil
+	}
+
+	var (
+		best          *PoolSimulator
+		bestAmountOut *big.Int
+		lastErr       error
+	)
+	for _, p := range pools {
+		amountOut, err := p.amountOut(amountIn, tokenIn, tokenOut)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if bestAmountOut == nil {
+			best, bestAmountOut = p, amountOut
+			continue
+		}
+		if c := amountOut.Cmp(bestAmountOut); c > 0 || c == 0 && pool.ComparePools(p, best) < 0 {
+			best, bestAmountOut = p, amountOut
+		}
+	}
+
+	if best == nil {
+		return nil, lastErr
+	}
+	return best, nil
+}
+
+// OutputDeltaForFeeChange returns how much the output of swapping amountIn of tokenIn would change if the fee of the
+// direction were newFee (in hundredths of a bip, like feeZto and feeOtz): negative if the output decreases.
+// The pool isn't changed.
+func (p *PoolSimulator) OutputDeltaForFeeChange(tokenIn string, amountIn *big.Int, newFee *big.Int) (*big.Int, error) {
+	tokenInIndex := p.GetTokenIndex(tokenIn)
+	if tokenInIndex < 0 {
+		return nil, ErrInvalidToken
+	}
+	// the fees are stored as uint16
+	if newFee == nil || !newFee.IsUint64() || newFee.Uint64() > math.MaxUint16 {
+		return nil, ErrInvalidFee
+	}
+	tokenOut := p.Info.Tokens[1-tokenInIndex]
+
+	amountOut, err := p.amountOut(amountIn, tokenIn, tokenOut)
+	if err != nil {
+		return nil, err
+	}
+
+	withNewFee := p.clone()
+	if tokenInIndex == 0 {
+		withNewFee.globalState.FeeZto = uint16(newFee.Uint64())
+	} else {
+		withNewFee.globalState.FeeOtz = uint16(newFee.Uint64())
+	}
+	newAmountOut, err := withNewFee.amountOut(amountIn, tokenIn, tokenOut)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAmountOut.Sub(newAmountOut, amountOut), nil
+}
@@ -0,0 +1,187 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newComparePool(t *testing.T, feeZto, feeOtz uint16) *PoolSimulator {
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange: "",
+		Type:     "",
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra: fmt.Sprintf(`{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":%v,"feeOtz":%v,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+			feeZto, feeOtz),
+	}, 1001)
+	require.Nil(t, err)
+	return p
+}
+
+func TestPoolSimulator_CompareOutputTo(t *testing.T) {
+	// same liquidity, ordered by fee
+	cheap, expensive := newComparePool(t, 100, 100), newComparePool(t, 3000, 500)
+
+	testcases := []struct {
+		a, b     *PoolSimulator
+		tokenIn  string
+		amountIn int64
+		tokenOut string
+		expected int
+	}{
+		{cheap, expensive, "A", 1000, "B", 1},
+		{expensive, cheap, "A", 1000, "B", -1},
+		{cheap, cheap, "A", 1000, "B", 0},
+		{expensive, cheap, "B", 100000000000000000, "A", -1},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			res, err := tc.a.CompareOutputTo(tc.b, big.NewInt(tc.amountIn), tc.tokenIn, tc.tokenOut)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+
+	_, err := cheap.CompareOutputTo(expensive, big.NewInt(0), "A", "B")
+	assert.ErrorContains(t, err, ErrZeroAmountIn.Error())
+}
+
+func TestBestPoolForAmount(t *testing.T) {
+	cheap, medium, expensive := newComparePool(t, 100, 3000), newComparePool(t, 500, 500), newComparePool(t, 3000, 100)
+
+	testcases := []struct {
+		pools    []*PoolSimulator
+		tokenIn  string
+		amountIn int64
+		tokenOut string
+		expected *PoolSimulator
+	}{
+		{[]*PoolSimulator{expensive, cheap, medium}, "A", 1000, "B", cheap},
+		{[]*PoolSimulator{cheap, medium, expensive}, "B", 100000000000000000, "A", expensive},
+		{[]*PoolSimulator{medium}, "A", 1000, "B", medium},
+		// can't quote the swap, but there is no need to
+		{[]*PoolSimulator{medium}, "A", 0, "B", medium},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			best, err := BestPoolForAmount(tc.pools, big.NewInt(tc.amountIn), tc.tokenIn, tc.tokenOut)
+			require.Nil(t, err)
+			assert.Same(t, tc.expected, best)
+		})
+	}
+
+	_, err := BestPoolForAmount(nil, big.NewInt(1000), "A", "B")
+	assert.ErrorIs(t, err, ErrNoPools)
+	_, err = BestPoolForAmount([]*PoolSimulator{cheap, expensive}, big.NewInt(0), "A", "B")
+	assert.ErrorContains(t, err, ErrZeroAmountIn.Error())
+}
+
+func TestPoolSimulator_OutputDeltaForFeeChange(t *testing.T) {
+	p := newComparePool(t, 500, 3000)
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn int64
+		tokenOut string
+		newFee   uint16
+		// the pool charging the fees the output is compared with
+		expected *PoolSimulator
+	}{
+		{"A", 1000000, "B", 3000, newComparePool(t, 3000, 3000)},
+		{"A", 1000000, "B", 100, newComparePool(t, 100, 3000)},
+		{"A", 1000000, "B", 500, p},
+		{"B", 100000000000000000, "A", 500, newComparePool(t, 500, 500)},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			current, err := p.amountOut(big.NewInt(tc.amountIn), tc.tokenIn, tc.tokenOut)
+			require.Nil(t, err)
+			expected, err := tc.expected.amountOut(big.NewInt(tc.amountIn), tc.tokenIn, tc.tokenOut)
+			require.Nil(t, err)
+
+			delta, err := p.OutputDeltaForFeeChange(tc.tokenIn, big.NewInt(tc.amountIn), big.NewInt(int64(tc.newFee)))
+			require.Nil(t, err)
+			assert.Equal(t, new(big.Int).Sub(expected, current), delta)
+
+			// the pool keeps its fees
+			after, err := p.amountOut(big.NewInt(tc.amountIn), tc.tokenIn, tc.tokenOut)
+			require.Nil(t, err)
+			assert.Equal(t, current, after)
+		})
+	}
+
+	delta, err := p.OutputDeltaForFeeChange("A", big.NewInt(1000000), big.NewInt(3000))
+	require.Nil(t, err)
+	assert.Equal(t, -1, delta.Sign())
+
+	_, err = p.OutputDeltaForFeeChange("C", big.NewInt(1000000), big.NewInt(3000))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = p.OutputDeltaForFeeChange("A", big.NewInt(1000000), big.NewInt(1e6))
+	assert.ErrorIs(t, err, ErrInvalidFee)
+	_, err = p.OutputDeltaForFeeChange("A", big.NewInt(1000000), big.NewInt(-1))
+	assert.ErrorIs(t, err, ErrInvalidFee)
+	_, err = p.OutputDeltaForFeeChange("A", big.NewInt(0), big.NewInt(3000))
+	assert.ErrorContains(t, err, ErrZeroAmountIn.Error())
+}
+
+func TestBestPoolForAmount_Deterministic(t *testing.T) {
+	// equally good pools
+	var pools []*PoolSimulator
+	for _, address := range []string{"0xc", "0xa", "0xb", "0xa"} {
+		p := newComparePool(t, 500, 500)
+		p.Info.Address = address
+		p.Info.Exchange = "algebra-v1"
+		pools = append(pools, p)
+	}
+	pools[3].Info.Exchange = "zyberswap-v3"
+
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 50; i++ {
+		rng.Shuffle(len(pools), func(i, j int) { pools[i], pools[j] = pools[j], pools[i] })
+		best, err := BestPoolForAmount(pools, big.NewInt(1000), "A", "B")
+		require.Nil(t, err)
+		assert.Equal(t, "0xa", best.GetAddress())
+		assert.Equal(t, "algebra-v1", best.GetExchange())
+	}
+}
+
+func TestPoolSimulator_CompareFee(t *testing.T) {
+	// 0.2% rebate, so a 0.3% pool charges 0.1%
+	rebated := newComparePool(t, 3000, 3000)
+	rebated.effectiveFeeAdjustmentBps = 20
+
+	testcases := []struct {
+		a, b     *PoolSimulator
+		expected int
+	}{
+		{newComparePool(t, 100, 100), newComparePool(t, 500, 500), -1},
+		{newComparePool(t, 3000, 3000), newComparePool(t, 500, 500), 1},
+		{newComparePool(t, 500, 500), newComparePool(t, 500, 500), 0},
+		// the mean of the directions
+		{newComparePool(t, 100, 900), newComparePool(t, 500, 500), 0},
+		{newComparePool(t, 100, 1000), newComparePool(t, 500, 500), 1},
+		{rebated, newComparePool(t, 1000, 1000), 0},
+		{rebated, newComparePool(t, 500, 500), 1},
+		{rebated, newComparePool(t, 3000, 3000), -1},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.a.CompareFee(tc.b))
+			assert.Equal(t, -tc.expected, tc.b.CompareFee(tc.a))
+		})
+	}
+
+	// without the rebate
+	rebated.SetStrictMode(true)
+	assert.Equal(t, 1, rebated.CompareFee(newComparePool(t, 1000, 1000)))
+}
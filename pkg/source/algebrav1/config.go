@@ -6,4 +6,32 @@ type Config struct {
 	AllowSubgraphError bool   `json:"allowSubgraphError"`
 	SkipFeeCalculating bool   `json:"skipFeeCalculating"` // do not pre-calculate fee at tracker, use last block's fee instead
 	UseDirectionalFee  bool   `json:"useDirectionalFee"`  // for Camelot and similar dexes
+
+	// off-chain fee rebates (incentive programs) by pool address, written to the pool's StaticExtra.
+	// Only used for ranking, never for the swap math
+	EffectiveFeeAdjustmentBps map[string]int `json:"effectiveFeeAdjustmentBps"`
+
+	// drift in percent between the balances of a pool and the amounts locked by its tracked liquidity,
+	// beyond which the ticks of the pool are read again from TickLens. 0 to disable
+	ReserveDriftThreshold float64 `json:"reserveDriftThreshold"`
+
+	// number of updates in a row a field of the pool state whose call failed is taken from the previous state, beyond
+	// which the pool is disabled if the field is liquidity or globalState. 0 to fail the update on any failed call
+	MaxStaleFieldReuses int `json:"maxStaleFieldReuses"`
+
+	// MIN_TICK/MAX_TICK of the deployment on this chain, if they aren't the ones of Uniswap V3
+	TickBounds *TickBounds `json:"tickBounds"`
+
+	// for dexes without a subgraph: pools are discovered from factory logs and ticks are read from TickLens
+	RPCOnly           bool   `json:"rpcOnly"`
+	FactoryAddress    string `json:"factoryAddress"`
+	TickLensAddress   string `json:"tickLensAddress"`
+	StartBlock        uint64 `json:"startBlock"`        // factory deployment block, to start scanning logs from
+	BlockRange        uint64 `json:"blockRange"`        // max number of blocks to scan per round
+	MaxLogsPerRequest int    `json:"maxLogsPerRequest"` // the block range will be shrunk until a request returns at most this many logs
+}
+
+// IsRPCOnly returns true if the dex is configured without a subgraph, so everything must be fetched from RPC
+func (c *Config) IsRPCOnly() bool {
+	return c.RPCOnly
 }
@@ -0,0 +1,48 @@
+package algebrav1
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool/conformance"
+)
+
+func TestPoolSimulator_Conformance(t *testing.T) {
+	factory := func(entityPool entity.Pool) (pool.IPoolSimulator, error) {
+		return NewPoolSimulator(entityPool, 1001)
+	}
+	swaps := []conformance.Swap{
+		{TokenIn: "A", AmountIn: big.NewInt(1000), TokenOut: "B"},
+		{TokenIn: "A", AmountIn: big.NewInt(1000000), TokenOut: "B"},
+		{TokenIn: "B", AmountIn: big.NewInt(1000000000000000), TokenOut: "A"},
+	}
+
+	conformance.Run(t, factory, []conformance.Fixture{
+		{
+			Name: "static fee",
+			Pool: entity.Pool{
+				Address:  "0xpool",
+				Exchange: "quickswap-v3",
+				Type:     "algebra-v1",
+				Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+				Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+				Extra:    `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":500,"feeOtz":500,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+			},
+			Swaps: swaps,
+		},
+		{
+			Name: "directional fee and community fee",
+			Pool: entity.Pool{
+				Address:  "0xpool",
+				Exchange: "camelot-v3",
+				Type:     "algebra-v1",
+				Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+				Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+				Extra:    `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":100,"feeOtz":3000,"timepoint_index":65,"community_fee_token0":100,"community_fee_token1":150,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+			},
+			Swaps: swaps,
+		},
+	}, conformance.Options{})
+}
@@ -26,18 +26,43 @@ const (
 	methodGetTimepoints          = "timepoints"
 	methodGetTickSpacing         = "tickSpacing"
 	erc20MethodBalanceOf         = "balanceOf"
+	erc20MethodDecimals          = "decimals"
+	erc20MethodSymbol            = "symbol"
+	erc20MethodName              = "name"
+
+	tickLensMethodGetPopulatedTicksInWord = "getPopulatedTicksInWord"
+	factoryEventPool                      = "Pool"
+
+	defaultBlockRange        = uint64(5000)
+	defaultMaxLogsPerRequest = 1000
+	multicallBatchSize       = 500
+	maxWordSize              = 256
 
 	maxSwapLoop         = 1000000
 	maxBinarySearchLoop = 1000
 
+	// gas of crossing an initialized tick, on top of the base gas of the swap
+	defaultCrossInitTickGas = 24000
+
 	timepointPageSize = uint16(300)
 
+	// IsSymmetric swaps symmetryProbeBps of the token0 reserve and back, and tolerates a loss of
+	// symmetryToleranceBps on top of the fees for the rounding
+	symmetryProbeBps     = 100
+	symmetryToleranceBps = 10
+
+	// AveragePriceImpact quotes at most averagePriceImpactConcurrency samples at once
+	averagePriceImpactConcurrency = 8
+
 	WINDOW        = 86400 // 1 day in seconds
 	UINT16_MODULO = 65536
 )
 
 var (
 	COMMUNITY_FEE_DENOMINATOR = big.NewInt(1000)
+	// maximum meaningful ratio of volume to liquidity, see calculateVolumePerLiquidity
+	MAX_VOLUME_PER_LIQUIDITY = new(big.Int).Lsh(big.NewInt(100000), 64)
+	twoPow192                = new(big.Int).Lsh(big.NewInt(1), 192)
 
 	slot3 = common.BigToHash(big.NewInt(3))
 )
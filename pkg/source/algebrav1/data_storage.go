@@ -5,7 +5,7 @@ import (
 	"math/big"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 // port https://github.com/cryptoalgebra/AlgebraV1/blob/dfebf532a27803dafcbf2ba49724740bd6220505/src/core/contracts/libraries/DataStorage.sol
@@ -480,3 +480,103 @@ func (ts *TimepointStorage) _getNewFee(
 		_feeConf,
 	), nil
 }
+
+// getTwapTick returns the time-weighted average tick of the last WINDOW before the last timepoint, or since the oldest
+// timepoint if they don't cover the window, rounded to negative infinity like the OracleLibrary of Uniswap V3.
+// Returns false if there are no 2 timepoints to average between.
+func getTwapTick(timepoints map[uint16]Timepoint, index uint16) (int24, bool) {
+	ts := TimepointStorage{
+		data:    timepoints,
+		updates: map[uint16]Timepoint{},
+	}
+	last := ts.Get(index)
+	var oldestIndex uint16
+	oldest := ts.Get(0)
+	if nxt := ts.Get(index + 1); nxt.Initialized {
+		oldest, oldestIndex = nxt, index+1
+	}
+	if !last.Initialized || !oldest.Initialized {
+		return 0, false
+	}
+
+	time := last.BlockTimestamp
+	secondsAgo := uint32(WINDOW)
+	if !lteConsideringOverflow(oldest.BlockTimestamp, time-WINDOW, time) {
+		secondsAgo = time - oldest.BlockTimestamp
+	}
+	if secondsAgo == 0 {
+		return 0, false
+	}
+
+	// the target is before the last timepoint, the current tick and liquidity aren't used
+	start, err := ts.getSingleTimepoint(time, secondsAgo, 0, index, oldestIndex, bignumber.ZeroBI)
+	if err != nil || !start.Initialized {
+		return 0, false
+	}
+	delta := last.TickCumulative - start.TickCumulative
+	twapTick := delta / int64(secondsAgo)
+	if delta < 0 && delta%int64(secondsAgo) != 0 {
+		twapTick--
+	}
+	return int24(twapTick), true
+}
+
+// getNewFees writes the timepoint of blockTimestamp, as the pool does before a swap in _writeTimepoint, and returns the
+// adaptive fees of both directions after it. A single fee pool has the same configuration for both directions.
+func getNewFees(
+	timepoints map[uint16]Timepoint,
+	state GlobalState,
+	liquidity *big.Int,
+	blockTimestamp uint32,
+	volumePerLiquidityInBlock *big.Int,
+	feeConfZto *FeeConfiguration,
+	feeConfOtz *FeeConfiguration,
+) (feeZto uint16, feeOtz uint16, err error) {
+	ts := TimepointStorage{
+		data:    timepoints,
+		updates: map[uint16]Timepoint{},
+	}
+	currentTick := int24(state.Tick.Int64())
+	newTimepointIndex, err := ts.write(state.TimepointIndex, blockTimestamp, currentTick, liquidity, volumePerLiquidityInBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	feeZto, err = ts._getNewFee(blockTimestamp, currentTick, newTimepointIndex, liquidity, feeConfZto)
+	if err != nil {
+		return 0, 0, err
+	}
+	if feeConfOtz == feeConfZto {
+		return feeZto, feeZto, nil
+	}
+	feeOtz, err = ts._getNewFee(blockTimestamp, currentTick, newTimepointIndex, liquidity, feeConfOtz)
+	if err != nil {
+		return 0, 0, err
+	}
+	return feeZto, feeOtz, nil
+}
+
+// calculateVolumePerLiquidity returns the volume per liquidity of a swap, as added to the volume of the block:
+// sqrt(|amount0|) * sqrt(|amount1|) / liquidity in Q64, capped at MAX_VOLUME_PER_LIQUIDITY
+// https://github.com/cryptoalgebra/AlgebraV1/blob/dfebf532a27803dafcbf2ba49724740bd6220505/src/core/contracts/DataStorageOperator.sol#L122
+func calculateVolumePerLiquidity(liquidity, amount0, amount1 *big.Int) *big.Int {
+	volume := new(big.Int).Mul(
+		new(big.Int).Sqrt(new(big.Int).Abs(amount0)),
+		new(big.Int).Sqrt(new(big.Int).Abs(amount1)),
+	)
+	l := liquidity
+	if l.Sign() <= 0 {
+		l = bignumber.One
+	}
+
+	var volumeShifted *big.Int
+	if volume.Cmp(twoPow192) >= 0 {
+		volumeShifted = new(big.Int).Div(maxUint256, l)
+	} else {
+		volumeShifted = new(big.Int).Div(new(big.Int).Lsh(volume, 64), l)
+	}
+	if volumeShifted.Cmp(MAX_VOLUME_PER_LIQUIDITY) >= 0 {
+		return new(big.Int).Set(MAX_VOLUME_PER_LIQUIDITY)
+	}
+	return volumeShifted
+}
@@ -0,0 +1,183 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// the default configuration of the Algebra V1 data storage operator
+var defaultFeeConfig = FeeConfiguration{
+	Alpha1:      2900,
+	Alpha2:      12000,
+	Beta1:       360,
+	Beta2:       60000,
+	Gamma1:      59,
+	Gamma2:      8500,
+	VolumeBeta:  0,
+	VolumeGamma: 10,
+	BaseFee:     100,
+}
+
+// newTimepoints returns the timepoints written by a swap every minute from start, at the ticks, and the state after them
+func newTimepoints(t *testing.T, start uint32, ticks []int24, liquidity *big.Int) (map[uint16]Timepoint, GlobalState) {
+	ts := TimepointStorage{data: map[uint16]Timepoint{}, updates: map[uint16]Timepoint{}}
+	ts.Set(0, Timepoint{
+		Initialized:                   true,
+		BlockTimestamp:                start,
+		SecondsPerLiquidityCumulative: new(big.Int),
+		VolatilityCumulative:          new(big.Int),
+		VolumePerLiquidityCumulative:  new(big.Int),
+	})
+
+	var index uint16
+	for i, tick := range ticks {
+		var err error
+		index, err = ts.write(index, start+uint32(i+1)*60, tick, liquidity, big.NewInt(0))
+		require.Nil(t, err)
+	}
+	lastTick := int24(0)
+	if len(ticks) > 0 {
+		lastTick = ticks[len(ticks)-1]
+	}
+	return ts.updates, GlobalState{Tick: big.NewInt(int64(lastTick)), TimepointIndex: index}
+}
+
+func TestGetNewFees(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	start := uint32(1700000000)
+	feeConfOtz := defaultFeeConfig
+	feeConfOtz.BaseFee = 500
+
+	testcases := []struct {
+		ticks []int24
+		// fee of the default configuration, feeZto
+		expectedBaseFee bool
+	}{
+		// a single timepoint, no volatility yet
+		{nil, true},
+		// the price doesn't move
+		{[]int24{0, 0, 0, 0, 0, 0}, true},
+		// the price swings by 2000 ticks every minute
+		{[]int24{1000, -1000, 1000, -1000, 1000, -1000}, false},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			timepoints, state := newTimepoints(t, start, tc.ticks, liquidity)
+			blockTimestamp := start + uint32(len(tc.ticks)+1)*60
+
+			feeZto, feeOtz, err := getNewFees(timepoints, state, liquidity, blockTimestamp, big.NewInt(0),
+				&defaultFeeConfig, &feeConfOtz)
+			require.Nil(t, err)
+			if tc.expectedBaseFee {
+				assert.Equal(t, defaultFeeConfig.BaseFee, feeZto)
+				assert.Equal(t, feeConfOtz.BaseFee, feeOtz)
+			} else {
+				assert.Greater(t, feeZto, defaultFeeConfig.BaseFee)
+				assert.LessOrEqual(t, feeZto, defaultFeeConfig.BaseFee+defaultFeeConfig.Alpha1+defaultFeeConfig.Alpha2)
+				// same volatility, the directions only differ by the base fee
+				assert.Equal(t, feeConfOtz.BaseFee-defaultFeeConfig.BaseFee, feeOtz-feeZto)
+			}
+
+			// a single fee pool
+			single, same, err := getNewFees(timepoints, state, liquidity, blockTimestamp, big.NewInt(0),
+				&defaultFeeConfig, &defaultFeeConfig)
+			require.Nil(t, err)
+			assert.Equal(t, feeZto, single)
+			assert.Equal(t, single, same)
+		})
+	}
+}
+
+func TestGetNewFees_Quote(t *testing.T) {
+	// the quotes with the fee computed from a volatile history of timepoints, and without history
+	liquidity := big.NewInt(1e18)
+	start := uint32(1700000000)
+	blockTimestamp := start + 5*60
+	quote := func(ticks []int24) *big.Int {
+		timepoints, state := newTimepoints(t, start, ticks, liquidity)
+		feeZto, feeOtz, err := getNewFees(timepoints, state, liquidity, blockTimestamp, big.NewInt(0),
+			&defaultFeeConfig, &defaultFeeConfig)
+		require.Nil(t, err)
+		res, err := newComparePool(t, feeZto, feeOtz).CalcAmountOut(
+			pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}, "B")
+		require.Nil(t, err)
+		return res.TokenAmountOut.Amount
+	}
+
+	withoutHistory, volatile := quote(nil), quote([]int24{1000, -1000, 1000, 0})
+	assert.Equal(t, -1, volatile.Cmp(withoutHistory))
+	assert.Equal(t, withoutHistory, quote([]int24{0, 0, 0, 0}))
+}
+
+func TestGetTwapTick(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	start := uint32(1700000000)
+
+	// a day and an hour of timepoints, the first hour out of the window
+	dayAndHour := make([]int24, 0, 1500)
+	for i := 0; i < 1500; i++ {
+		tick := int24(100)
+		if i < 60 {
+			tick = -5000
+		}
+		dayAndHour = append(dayAndHour, tick)
+	}
+
+	testcases := []struct {
+		ticks            []int24
+		expectedTwapTick int24
+		expectedOk       bool
+	}{
+		// a single timepoint, nothing to average
+		{nil, 0, false},
+		{[]int24{1000, 1000, -500}, 500, true},
+		{[]int24{-1000, -1000, 500}, -500, true},
+		// -1/3 is rounded down
+		{[]int24{-1, 0, 0}, -1, true},
+		{dayAndHour, 100, true},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			timepoints, state := newTimepoints(t, start, tc.ticks, liquidity)
+			twapTick, ok := getTwapTick(timepoints, state.TimepointIndex)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedTwapTick, twapTick)
+		})
+	}
+
+	_, ok := getTwapTick(nil, 0)
+	assert.False(t, ok)
+}
+
+func TestCalculateVolumePerLiquidity(t *testing.T) {
+	testcases := []struct {
+		liquidity, amount0, amount1 *big.Int
+		expected                    *big.Int
+	}{
+		// sqrt(4e18) * sqrt(1e18) / 1e18 = 2, in Q64
+		{big.NewInt(1e18), big.NewInt(4e18), big.NewInt(-1e18), new(big.Int).Lsh(big.NewInt(2), 64)},
+		{big.NewInt(1e18), big.NewInt(-4e18), big.NewInt(1e18), new(big.Int).Lsh(big.NewInt(2), 64)},
+		// the square roots are rounded down
+		{big.NewInt(1), big.NewInt(8), big.NewInt(3), new(big.Int).Lsh(big.NewInt(2), 64)},
+		{big.NewInt(1e18), big.NewInt(0), big.NewInt(1e18), big.NewInt(0)},
+		// capped
+		{big.NewInt(1), big.NewInt(1e18), big.NewInt(1e18), MAX_VOLUME_PER_LIQUIDITY},
+		{big.NewInt(0), big.NewInt(1e6), big.NewInt(1e6), MAX_VOLUME_PER_LIQUIDITY},
+		{big.NewInt(1e18), new(big.Int).Lsh(big.NewInt(1), 200), new(big.Int).Lsh(big.NewInt(1), 200),
+			MAX_VOLUME_PER_LIQUIDITY},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.expected, calculateVolumePerLiquidity(tc.liquidity, tc.amount0, tc.amount1))
+		})
+	}
+}
@@ -2,6 +2,8 @@ package algebrav1
 
 import (
 	"errors"
+	"fmt"
+	"math/big"
 )
 
 var (
@@ -9,10 +11,43 @@ var (
 	ErrMaxBinarySearchLoop = errors.New("max binary search loop reached")
 	ErrStaleTimepoints     = errors.New("getting stale timepoint data")
 	ErrTickNil             = errors.New("tick is nil")
-	ErrTicksEmpty          = errors.New("ticks list is empty")
+	ErrNoLiquidity         = errors.New("pool has no initialized ticks")
 	ErrInvalidToken        = errors.New("invalid token info")
 	ErrZeroAmountIn        = errors.New("amountIn is 0")
 	ErrZeroAmountOut       = errors.New("amountOut is 0")
+	ErrAmountOutTooLarge   = errors.New("amountOut exceeds the liquidity of the initialized ticks")
+	ErrNotEnoughLiquidity  = errors.New("amountIn exceeds the liquidity of the initialized ticks")
 	ErrSPL                 = errors.New("invalid sqrt price limit")
 	ErrPoolLocked          = errors.New("pool is locked")
+	ErrInvalidLiquidity    = errors.New("invalid liquidity")
+	ErrInvalidTickRange    = errors.New("invalid tick range")
+	ErrInvalidTickBounds   = errors.New("invalid tick bounds")
+	ErrInvalidSnapshot     = errors.New("invalid snapshot")
+	ErrNoPools             = errors.New("no pools")
+	ErrInvalidSampleCount  = errors.New("invalid sample count")
+	ErrInvalidFee          = errors.New("invalid fee")
+	ErrInvalidExtra        = errors.New("invalid extra")
+	ErrInvalidTick         = errors.New("invalid tick")
+	ErrTickAlreadyExists   = errors.New("tick is already initialized")
+	ErrTickNotInitialized  = errors.New("tick is not initialized")
+	ErrInvalidFeeRevenue   = errors.New("invalid fee revenue")
+	ErrLiquidityOverflow   = errors.New("liquidity overflows uint128")
+	ErrAmountOverflow      = errors.New("amount overflows int256")
+	ErrMulDivOverflow      = errors.New("mulDiv overflows uint256")
+	ErrFieldCallFailed     = errors.New("failed to read a field of the pool state")
 )
+
+// NotEnoughLiquidityError is the error of a swap of Token stopping at the last initialized tick before swapping
+// RemainingAmountIn, it unwraps to ErrNotEnoughLiquidity
+type NotEnoughLiquidityError struct {
+	Token             string
+	RemainingAmountIn *big.Int
+}
+
+func (e *NotEnoughLiquidityError) Error() string {
+	return fmt.Sprintf("%s: %s of %s left", ErrNotEnoughLiquidity, e.RemainingAmountIn, e.Token)
+}
+
+func (e *NotEnoughLiquidityError) Unwrap() error {
+	return ErrNotEnoughLiquidity
+}
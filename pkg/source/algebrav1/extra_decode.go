@@ -0,0 +1,280 @@
+package algebrav1
+
+import (
+	"encoding/json"
+	"math/bits"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/jsonscan"
+)
+
+// decodeExtra decodes the Extra written by the tracker with a scanner, falling back to encoding/json for the inputs
+// the scanner doesn't read (unknown or differently cased keys, quoted numbers, tickFeeGrowthOutside, timepoints...), so both
+// accept exactly the same inputs with the same result.
+// A null value leaves the field zero, like encoding/json does with a zero Extra.
+func decodeExtra(data []byte) (Extra, error) {
+	var extra Extra
+	s := jsonscan.New(data)
+	scanExtra(s, &extra)
+	s.End()
+	if !s.Failed() {
+		return extra, nil
+	}
+
+	extra = Extra{}
+	err := json.Unmarshal(data, &extra)
+	return extra, err
+}
+
+func scanExtra(s *jsonscan.Scanner, extra *Extra) {
+	if s.Null() {
+		return
+	}
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "liquidity":
+			s.Seen(&seen, 0)
+			extra.Liquidity = s.BigInt()
+		case "globalState":
+			s.Seen(&seen, 1)
+			scanGlobalState(s, &extra.GlobalState)
+		case "ticks":
+			s.Seen(&seen, 2)
+			extra.Ticks = scanTicks(s)
+		case "tickSpacing":
+			s.Seen(&seen, 3)
+			if !s.Null() {
+				extra.TickSpacing = int24(s.Int(32))
+			}
+		case "feeConfigZto":
+			s.Seen(&seen, 4)
+			extra.FeeConfigZto = scanFeeConfiguration(s)
+		case "feeConfigOtz":
+			s.Seen(&seen, 5)
+			extra.FeeConfigOtz = scanFeeConfiguration(s)
+		case "totalFeeGrowth":
+			s.Seen(&seen, 6)
+			if !s.Null() {
+				extra.TotalFeeGrowth = &FeeGrowth{}
+				scanFeeGrowth(s, extra.TotalFeeGrowth)
+			}
+		case "reserveDrift":
+			s.Seen(&seen, 7)
+			if !s.Null() {
+				extra.ReserveDrift = s.Float64()
+			}
+		case "tickBounds":
+			s.Seen(&seen, 8)
+			if !s.Null() {
+				extra.TickBounds = &TickBounds{}
+				scanTickBounds(s, extra.TickBounds)
+			}
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
+
+func scanGlobalState(s *jsonscan.Scanner, state *GlobalState) {
+	if s.Null() {
+		return
+	}
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "price":
+			s.Seen(&seen, 0)
+			state.Price = s.BigInt()
+		case "tick":
+			s.Seen(&seen, 1)
+			state.Tick = s.BigInt()
+		case "feeZto":
+			s.Seen(&seen, 2)
+			scanUint16(s, &state.FeeZto)
+		case "feeOtz":
+			s.Seen(&seen, 3)
+			scanUint16(s, &state.FeeOtz)
+		case "timepoint_index":
+			s.Seen(&seen, 4)
+			scanUint16(s, &state.TimepointIndex)
+		case "community_fee_token0":
+			s.Seen(&seen, 5)
+			scanUint16(s, &state.CommunityFeeToken0)
+		case "community_fee_token1":
+			s.Seen(&seen, 6)
+			scanUint16(s, &state.CommunityFeeToken1)
+		case "unlocked":
+			s.Seen(&seen, 7)
+			if !s.Null() {
+				state.Unlocked = s.Bool()
+			}
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
+
+// scanTicks reads the ticks, whose fields have no json tag
+func scanTicks(s *jsonscan.Scanner) []v3Entities.Tick {
+	if s.Null() {
+		return nil
+	}
+	ticks := make([]v3Entities.Tick, 0)
+	s.ArrayStart()
+	for i := 0; s.NextElem(i); i++ {
+		var tick v3Entities.Tick
+		if !s.Null() {
+			scanTick(s, &tick)
+		}
+		ticks = append(ticks, tick)
+	}
+	return ticks
+}
+
+func scanTick(s *jsonscan.Scanner, tick *v3Entities.Tick) {
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "Index":
+			s.Seen(&seen, 0)
+			if !s.Null() {
+				tick.Index = int(s.Int(bits.UintSize))
+			}
+		case "LiquidityGross":
+			s.Seen(&seen, 1)
+			tick.LiquidityGross = s.BigInt()
+		case "LiquidityNet":
+			s.Seen(&seen, 2)
+			tick.LiquidityNet = s.BigInt()
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
+
+func scanFeeConfiguration(s *jsonscan.Scanner) *FeeConfiguration {
+	if s.Null() {
+		return nil
+	}
+	var config FeeConfiguration
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return &config
+		}
+		switch string(key) {
+		case "alpha1":
+			s.Seen(&seen, 0)
+			scanUint16(s, &config.Alpha1)
+		case "alpha2":
+			s.Seen(&seen, 1)
+			scanUint16(s, &config.Alpha2)
+		case "beta1":
+			s.Seen(&seen, 2)
+			scanUint32(s, &config.Beta1)
+		case "beta2":
+			s.Seen(&seen, 3)
+			scanUint32(s, &config.Beta2)
+		case "gamma1":
+			s.Seen(&seen, 4)
+			scanUint16(s, &config.Gamma1)
+		case "gamma2":
+			s.Seen(&seen, 5)
+			scanUint16(s, &config.Gamma2)
+		case "volumeBeta":
+			s.Seen(&seen, 6)
+			scanUint32(s, &config.VolumeBeta)
+		case "volumeGamma":
+			s.Seen(&seen, 7)
+			scanUint16(s, &config.VolumeGamma)
+		case "baseFee":
+			s.Seen(&seen, 8)
+			scanUint16(s, &config.BaseFee)
+		default:
+			s.Fail()
+			return nil
+		}
+	}
+}
+
+func scanFeeGrowth(s *jsonscan.Scanner, feeGrowth *FeeGrowth) {
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "token0":
+			s.Seen(&seen, 0)
+			feeGrowth.Token0 = s.BigInt()
+		case "token1":
+			s.Seen(&seen, 1)
+			feeGrowth.Token1 = s.BigInt()
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
+
+func scanTickBounds(s *jsonscan.Scanner, bounds *TickBounds) {
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "minTick":
+			s.Seen(&seen, 0)
+			if !s.Null() {
+				bounds.MinTick = int(s.Int(bits.UintSize))
+			}
+		case "maxTick":
+			s.Seen(&seen, 1)
+			if !s.Null() {
+				bounds.MaxTick = int(s.Int(bits.UintSize))
+			}
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
+
+func scanUint16(s *jsonscan.Scanner, v *uint16) {
+	if !s.Null() {
+		*v = uint16(s.Uint(16))
+	}
+}
+
+func scanUint32(s *jsonscan.Scanner, v *uint32) {
+	if !s.Null() {
+		*v = uint32(s.Uint(32))
+	}
+}
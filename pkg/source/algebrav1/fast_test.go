@@ -0,0 +1,176 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the golden vectors of the CalcAmountOut tests, quoted with the fast path
+func TestPoolSimulator_CalcAmountOutWithParams(t *testing.T) {
+	type testcase struct {
+		in                string
+		inAmount          string
+		out               string
+		expectedOutAmount string
+	}
+	corpus := []struct {
+		reserves  entity.PoolReserves
+		extra     string
+		testcases []testcase
+	}{
+		{
+			// https://polygonscan.com/address/0xd372b5067fe9cbac932af47406fdb9c64666295b#readContract
+			entity.PoolReserves{"723924", "36031866872048609640"},
+			`{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":2985,"feeOtz":2985,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+			[]testcase{
+				{"A", "10", "B", "12418116005823"},
+				{"B", "100000000000000000", "A", "70148"},
+				// exactly to the price of the tick 279120 and one less, see TestPoolSimulator_CalcAmountOut_TickBoundary
+				{"A", "51573", "B", "69459855477488322"},
+				{"A", "51572", "B", "69459773825031087"},
+			},
+		},
+		{
+			// https://bscscan.com/address/0x0137a5ba1dfa5d6d9a5896251f3d06b2e6669c3a#readContract
+			entity.PoolReserves{"4972738711862929441043", "1959593146565760679885786"},
+			`{"liquidity":98714460437307995596273,"globalState":{"price":1572768200222810245774927517376,"tick":59768,"feeZto":11076,"feeOtz":11076,"timepoint_index":45,"community_fee_token0":1000,"community_fee_token1":1000,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":98714460437307995596273,"LiquidityNet":98714460437307995596273},{"Index":887220,"LiquidityGross":98714460437307995596273,"LiquidityNet":-98714460437307995596273}],"tickSpacing":60}`,
+			[]testcase{
+				{"A", "10", "B", "3546"},
+				{"A", "100", "B", "38618"},
+				{"A", "1000", "B", "389338"},
+				{"B", "100000000000000000", "A", "250953133732636"},
+			},
+		},
+		{
+			// https://ftmscan.com/address/0x2fbb6b6c054ef35f20c91fd29d6579cb3c642195#code
+			entity.PoolReserves{"21265875874493991905878", "10344609910613908943698"},
+			`{"liquidity":299344339249801237803452,"globalState":{"price":50556054571765543459252266509,"tick":-8986,"feeZto":7550,"feeOtz":7550,"timepoint_index":4,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-23040,"LiquidityGross":18101291400643986804037,"LiquidityNet":18101291400643986804037},{"Index":-9495,"LiquidityGross":281243047849157250999415,"LiquidityNet":281243047849157250999415},{"Index":-8940,"LiquidityGross":281243047849157250999415,"LiquidityNet":-281243047849157250999415},{"Index":16080,"LiquidityGross":18101291400643986804037,"LiquidityNet":-18101291400643986804037}],"tickSpacing":5}`,
+			[]testcase{
+				{"A", "10", "B", "3"},
+				{"A", "10000000000", "B", "4041064818"},
+				{"B", "10000000000", "A", "24373699676"},
+			},
+		},
+		{
+			// https://arbiscan.io/address/0x2f0bcb4a8bd714953eefd5339326ee0ff62c5b62#readContract
+			entity.PoolReserves{"723924", "36031866872048609640"},
+			`{"liquidity":954140562773509808028,"globalState":{"price":84125210470736011805469300802,"tick":1199,"feeZto":100,"feeOtz":3000,"timepoint_index":104,"community_fee_token0":150,"community_fee_token1":150,"unlocked":true},"ticks":[{"Index":480,"LiquidityGross":954140562773509808028,"LiquidityNet":954140562773509808028},{"Index":1200,"LiquidityGross":954140562773509808028,"LiquidityNet":-954140562773509808028}],"tickSpacing":60}`,
+			[]testcase{
+				{"A", "10000000000", "B", "11273265321"},
+				{"B", "10000000000", "A", "8843048322"},
+			},
+		},
+	}
+
+	for poolIdx, c := range corpus {
+		p, err := NewPoolSimulator(entity.Pool{
+			Reserves: c.reserves,
+			Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+			Extra:    c.extra,
+		}, 1001)
+		require.Nil(t, err)
+
+		for idx, tc := range c.testcases {
+			t.Run(fmt.Sprintf("test %d %d", poolIdx, idx), func(t *testing.T) {
+				in := pool.TokenAmount{Token: tc.in, Amount: bignumber.NewBig10(tc.inAmount)}
+				out, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+					TokenAmountIn: in,
+					TokenOut:      tc.out,
+					Precision:     pool.PrecisionFast,
+				})
+				require.Nil(t, err)
+				assert.Equal(t, tc.out, out.TokenAmountOut.Token)
+
+				// |fast - exact| <= max(exact * bound, 1)
+				expected := bignumber.NewBig10(tc.expectedOutAmount)
+				diff := new(big.Float).SetInt(new(big.Int).Abs(new(big.Int).Sub(out.TokenAmountOut.Amount, expected)))
+				bound := new(big.Float).Mul(new(big.Float).SetInt(expected), big.NewFloat(pool.FastPrecisionMaxRelativeError))
+				if bound.Cmp(big.NewFloat(1)) < 0 {
+					bound = big.NewFloat(1)
+				}
+				assert.True(t, diff.Cmp(bound) <= 0, "fast %v, exact %v", out.TokenAmountOut.Amount, expected)
+
+				exact, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+					TokenAmountIn: in,
+					TokenOut:      tc.out,
+					Precision:     pool.PrecisionExact,
+				})
+				require.Nil(t, err)
+				assert.Equal(t, expected, exact.TokenAmountOut.Amount)
+
+				// the fee of the swap, in the token in, within a few wei of the exact one
+				assert.Equal(t, tc.in, out.Fee.Token)
+				require.NotNil(t, out.Fee.Amount)
+				assert.InDelta(t, bignumber.ToFloat64(exact.Fee.Amount), bignumber.ToFloat64(out.Fee.Amount), 2)
+			})
+		}
+	}
+}
+
+func TestPoolSimulator_calcAmountOutFast_CrossTick(t *testing.T) {
+	p, err := NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":2985,"feeOtz":2985,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+
+	_, _, ok := p.calcAmountOutFast(true, bignumber.NewBig10("10"))
+	assert.True(t, ok)
+
+	// crosses the tick 279120, fallback to the exact path which gives the same result
+	in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("1000000000000000000")}
+	_, _, ok = p.calcAmountOutFast(true, in.Amount)
+	assert.False(t, ok)
+
+	fast, err := p.CalcAmountOutWithParams(pool.CalcAmountOutParams{
+		TokenAmountIn: in,
+		TokenOut:      "B",
+		Precision:     pool.PrecisionFast,
+	})
+	require.Nil(t, err)
+	exact, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, exact.TokenAmountOut.Amount, fast.TokenAmountOut.Amount)
+	assert.NotNil(t, fast.SwapInfo)
+}
+
+func TestPoolSimulator_CalcAmountOutWithParams_ForUpdateBalance(t *testing.T) {
+	p := newComparePool(t, 2985, 2985)
+	in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("10")}
+
+	fast, err := p.CalcAmountOutWithParams(pool.CalcAmountOutParams{
+		TokenAmountIn: in,
+		TokenOut:      "B",
+		Precision:     pool.PrecisionFast,
+	})
+	require.Nil(t, err)
+	assert.Nil(t, fast.SwapInfo)
+
+	// the exact path, whose result can be applied
+	res, err := p.CalcAmountOutWithParams(pool.CalcAmountOutParams{
+		TokenAmountIn:    in,
+		TokenOut:         "B",
+		Precision:        pool.PrecisionFast,
+		ForUpdateBalance: true,
+	})
+	require.Nil(t, err)
+	exact, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, exact, res)
+
+	p.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  in,
+		TokenAmountOut: *res.TokenAmountOut,
+		Fee:            *res.Fee,
+		SwapInfo:       res.SwapInfo,
+	})
+	assert.Equal(t, "723934", p.Info.Reserves[0].String())
+}
@@ -0,0 +1,73 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/blockchain-toolkit/integer"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// fee growth accumulators are uint256 in the contract and are allowed to overflow
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(bignumber.One, 256), bignumber.One)
+
+func addUint256(a, b *big.Int) *big.Int {
+	return new(big.Int).And(new(big.Int).Add(a, b), maxUint256)
+}
+
+func subUint256(a, b *big.Int) *big.Int {
+	return new(big.Int).And(new(big.Int).Sub(a, b), maxUint256)
+}
+
+// getFeeGrowthOutside returns the outer fee growth of a tick, zero if it has never been tracked nor crossed
+func (p *PoolSimulator) getFeeGrowthOutside(tick int) FeeGrowth {
+	if feeGrowth, ok := p.crossedFeeGrowthOutside[tick]; ok {
+		return feeGrowth
+	}
+	if feeGrowth, ok := p.tickFeeGrowthOutside[tick]; ok {
+		return feeGrowth
+	}
+	return FeeGrowth{Token0: integer.Zero(), Token1: integer.Zero()}
+}
+
+// FeeGrowthGlobal returns the total fee growth of both tokens
+func (p *PoolSimulator) FeeGrowthGlobal() FeeGrowth {
+	return FeeGrowth{
+		Token0: new(big.Int).Set(p.totalFeeGrowth.Token0),
+		Token1: new(big.Int).Set(p.totalFeeGrowth.Token1),
+	}
+}
+
+// FeeGrowthOutside returns the fee growth on the other side of the tick (relative to the current tick)
+func (p *PoolSimulator) FeeGrowthOutside(tick int) FeeGrowth {
+	feeGrowth := p.getFeeGrowthOutside(tick)
+	return FeeGrowth{
+		Token0: new(big.Int).Set(feeGrowth.Token0),
+		Token1: new(big.Int).Set(feeGrowth.Token1),
+	}
+}
+
+// FeeGrowthInside returns the fee growth inside [tickLower, tickUpper), same as the contract's _getInnerFeeGrowth
+func (p *PoolSimulator) FeeGrowthInside(tickLower, tickUpper int) (FeeGrowth, error) {
+	if tickLower >= tickUpper {
+		return FeeGrowth{}, ErrInvalidTickRange
+	}
+
+	currentTick := p.PriceTick()
+	lower, upper := p.getFeeGrowthOutside(tickLower), p.getFeeGrowthOutside(tickUpper)
+
+	inner := func(total, lowerOuter, upperOuter *big.Int) *big.Int {
+		if currentTick < tickUpper {
+			if currentTick >= tickLower {
+				return subUint256(subUint256(total, lowerOuter), upperOuter)
+			}
+			return subUint256(lowerOuter, upperOuter)
+		}
+		return subUint256(upperOuter, lowerOuter)
+	}
+
+	return FeeGrowth{
+		Token0: inner(p.totalFeeGrowth.Token0, lower.Token0, upper.Token0),
+		Token1: inner(p.totalFeeGrowth.Token1, lower.Token1, upper.Token1),
+	}, nil
+}
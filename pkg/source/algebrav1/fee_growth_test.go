@@ -0,0 +1,101 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSimulator_FeeGrowth(t *testing.T) {
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange: "",
+		Type:     "",
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":2985,"feeOtz":2985,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+
+	// the fee of token0 grows with the volume, token1 is never sold
+	prevFeeGrowth := p.FeeGrowthGlobal()
+	assert.Equal(t, 0, prevFeeGrowth.Token0.Sign())
+	for idx, inAmount := range []string{"10", "100", "1000", "100000", "1000000"} {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10(inAmount)}
+			out, err := p.CalcAmountOut(in, "B")
+			require.Nil(t, err)
+			p.UpdateBalance(pool.UpdateBalanceParams{
+				TokenAmountIn:  in,
+				TokenAmountOut: *out.TokenAmountOut,
+				Fee:            *out.Fee,
+				SwapInfo:       out.SwapInfo,
+			})
+
+			feeGrowth := p.FeeGrowthGlobal()
+			assert.Equal(t, 1, feeGrowth.Token0.Cmp(prevFeeGrowth.Token0))
+			assert.Equal(t, 0, feeGrowth.Token1.Cmp(prevFeeGrowth.Token1))
+			prevFeeGrowth = feeGrowth
+		})
+	}
+
+	// a big swap crosses initialized ticks, their outer fee growth is flipped
+	in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("1000000000000000000")}
+	out, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	si := out.SwapInfo.(StateUpdate)
+	require.NotEmpty(t, si.TickFeeGrowthOutside)
+	for tick, feeGrowth := range si.TickFeeGrowthOutside {
+		assert.Equal(t, 1, si.TotalFeeGrowth.Token0.Cmp(feeGrowth.Token0))
+
+		// the simulator must not be touched before UpdateBalance
+		assert.Equal(t, big.NewInt(0), p.FeeGrowthOutside(tick).Token0)
+	}
+
+	p.UpdateBalance(pool.UpdateBalanceParams{SwapInfo: out.SwapInfo})
+	for tick, feeGrowth := range si.TickFeeGrowthOutside {
+		assert.Equal(t, feeGrowth.Token0, p.FeeGrowthOutside(tick).Token0)
+	}
+
+	_, err = p.FeeGrowthInside(279120, 273540)
+	assert.ErrorIs(t, err, ErrInvalidTickRange)
+}
+
+func TestPoolSimulator_FeeGrowthOutside_Overlay(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	tracked := map[int]FeeGrowth{
+		-887220: {Token0: big.NewInt(1), Token1: big.NewInt(2)},
+		285480:  {Token0: big.NewInt(3), Token1: big.NewInt(4)},
+	}
+	p.tickFeeGrowthOutside = tracked
+	cloned := p.CloneState().(*PoolSimulator)
+
+	// crosses the tick 279120 only
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}
+	out, err := cloned.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	si := out.SwapInfo.(StateUpdate)
+	require.Len(t, si.TickFeeGrowthOutside, 1)
+	cloned.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  in,
+		TokenAmountOut: *out.TokenAmountOut,
+		Fee:            *out.Fee,
+		SwapInfo:       out.SwapInfo,
+	})
+
+	// the tracked fee growth is shared and untouched, the overlay holds the crossed tick
+	assert.Len(t, tracked, 2)
+	assert.Equal(t, fmt.Sprintf("%p", tracked), fmt.Sprintf("%p", cloned.tickFeeGrowthOutside))
+	assert.Equal(t, si.TickFeeGrowthOutside, cloned.crossedFeeGrowthOutside)
+	assert.Equal(t, si.TickFeeGrowthOutside[279120], cloned.FeeGrowthOutside(279120))
+	assert.Equal(t, tracked[285480], cloned.FeeGrowthOutside(285480))
+
+	// the source pool doesn't see the swap of its clone
+	assert.Empty(t, p.crossedFeeGrowthOutside)
+	assert.Equal(t, big.NewInt(0), p.FeeGrowthOutside(279120).Token0)
+}
@@ -1,10 +1,13 @@
 package algebrav1
 
 import (
+	"math"
 	"math/big"
 
 	"github.com/KyberNetwork/blockchain-toolkit/integer"
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/daoleno/uniswapv3-sdk/constants"
 	"github.com/daoleno/uniswapv3-sdk/utils"
 )
@@ -17,8 +20,9 @@ type SwapCalculationCache struct {
 	// computedLatestTimepoint       bool     //  if we have already fetched _tickCumulative_ and _secondPerLiquidity_ from the DataOperator
 	amountRequiredInitial *big.Int // The initial value of the exact input\output amount
 	amountCalculated      *big.Int // The additive amount of total output\input calculated trough the swap
-	// totalFeeGrowth                *big.Int // The initial totalFeeGrowth + the fee growth during a swap
-	// totalFeeGrowthB               *big.Int
+	feeAmount             *big.Int // The total fee paid in the input token, community fee included
+	totalFeeGrowth        *big.Int // The initial totalFeeGrowth + the fee growth during a swap
+	totalFeeGrowthB       *big.Int
 	// incentiveStatus               IAlgebraVirtualPool.Status // If there is an active incentive at the moment
 	exactInput bool   // Whether the exact input or output is specified
 	fee        uint16 // The current dynamic fee
@@ -42,43 +46,78 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 	amountRequired *big.Int,
 	limitSqrtPrice *big.Int,
 ) (error, *big.Int, *big.Int, *StateUpdate) {
+	err, amount0, amount1, _, nextState := p._calculateSwap(zeroToOne, amountRequired, limitSqrtPrice)
+	return err, amount0, amount1, nextState
+}
+
+// _calculateSwap is _calculateSwapAndLock also returning the swap fee, in the input token and community fee included.
+// A negative amountRequired is an exact output swap.
+func (p *PoolSimulator) _calculateSwap(
+	zeroToOne bool,
+	amountRequired *big.Int,
+	limitSqrtPrice *big.Int,
+) (error, *big.Int, *big.Int, *big.Int, *StateUpdate) {
 	var cache SwapCalculationCache
 	var err error
 
+	if p.zeroLiquidity {
+		return ErrNoLiquidity, nil, nil, nil, nil
+	}
+
 	nextState := &StateUpdate{}
+	budget := pool.GetComputeBudget()
 
 	// load from one storage slot
 	currentPrice := p.globalState.Price
-	currentTick := int(p.globalState.Tick.Int64())
+	currentTick := p.PriceTick()
+	if currentTick == math.MinInt32 {
+		return ErrTickNil, nil, nil, nil, nil
+	}
 	cache.amountCalculated = integer.Zero()
+	cache.feeAmount = integer.Zero()
 	_communityFeeToken0 := p.globalState.CommunityFeeToken0
 	_communityFeeToken1 := p.globalState.CommunityFeeToken1
 
+	// e.g. the last initialized tick is out of range, see getSqrtPriceLimit
+	if limitSqrtPrice == nil {
+		return ErrSPL, nil, nil, nil, nil
+	}
+
 	cmp := amountRequired.Cmp(integer.Zero())
 	if cmp == 0 {
-		return ErrZeroAmountIn, nil, nil, nil
+		return ErrZeroAmountIn, nil, nil, nil, nil
+	}
+
+	if !isInt256(amountRequired) {
+		return ErrAmountOverflow, nil, nil, nil, nil
 	}
 
 	cache.amountRequiredInitial, cache.exactInput = amountRequired, cmp > 0
 
+	nextState.TickFeeGrowthOutside = map[int]FeeGrowth{}
+
 	currentLiquidity := p.liquidity
 
 	if zeroToOne {
-		if limitSqrtPrice.Cmp(currentPrice) >= 0 || limitSqrtPrice.Cmp(utils.MinSqrtRatio) <= 0 {
-			return ErrSPL, nil, nil, nil
+		if limitSqrtPrice.Cmp(currentPrice) >= 0 || limitSqrtPrice.Cmp(p.minSqrtRatio) <= 0 {
+			return ErrSPL, nil, nil, nil, nil
 		}
 		cache.communityFee = big.NewInt(int64(_communityFeeToken0))
+		cache.totalFeeGrowth = p.totalFeeGrowth.Token0
+		cache.totalFeeGrowthB = p.totalFeeGrowth.Token1
 	} else {
-		if limitSqrtPrice.Cmp(currentPrice) <= 0 || limitSqrtPrice.Cmp(utils.MaxSqrtRatio) >= 0 {
-			return ErrSPL, nil, nil, nil
+		if limitSqrtPrice.Cmp(currentPrice) <= 0 || limitSqrtPrice.Cmp(p.maxSqrtRatio) >= 0 {
+			return ErrSPL, nil, nil, nil, nil
 		}
 		cache.communityFee = big.NewInt(int64(_communityFeeToken1))
+		cache.totalFeeGrowth = p.totalFeeGrowth.Token1
+		cache.totalFeeGrowthB = p.totalFeeGrowth.Token0
 	}
 
 	// don't need to care about activeIncentive
 
-	// use pre-calculated fee instead of calculating from timepoints
-	// see tracker code for more details
+	// use the fee computed by the tracker, or at the simulation timestamp, instead of writing a timepoint
+	// see SetSimulationTimestamp and the tracker code for more details
 	if zeroToOne {
 		cache.fee = p.globalState.FeeZto
 	} else {
@@ -94,12 +133,12 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 
 		step.nextTick, step.initialized, err = p.ticks.NextInitializedTickWithinOneWord(currentTick, zeroToOne, p.tickSpacing)
 		if err != nil {
-			return err, nil, nil, nil
+			return err, nil, nil, nil, nil
 		}
 
 		step.nextTickPrice, err = utils.GetSqrtRatioAtTick(step.nextTick)
 		if err != nil {
-			return err, nil, nil, nil
+			return err, nil, nil, nil, nil
 		}
 
 		// calculate the amounts needed to move the price to the next target if it is possible or as much as possible
@@ -116,7 +155,10 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 			constants.FeeAmount(cache.fee),
 		)
 		if err != nil {
-			return err, nil, nil, nil
+			return err, nil, nil, nil, nil
+		}
+		if !isUint256(step.input) || !isUint256(step.output) || !isUint256(step.feeAmount) {
+			return ErrMulDivOverflow, nil, nil, nil, nil
 		}
 
 		if cache.exactInput {
@@ -129,6 +171,8 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 			) // increase calculated input amount
 		}
 
+		cache.feeAmount = new(big.Int).Add(cache.feeAmount, step.feeAmount)
+
 		if cache.communityFee.Cmp(integer.Zero()) > 0 {
 			delta := new(big.Int).Div(
 				new(big.Int).Mul(step.feeAmount, cache.communityFee),
@@ -137,7 +181,15 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 			step.feeAmount = new(big.Int).Sub(step.feeAmount, delta)
 		}
 
-		if currentPrice == step.nextTickPrice {
+		if currentLiquidity.Sign() > 0 {
+			cache.totalFeeGrowth = addUint256(cache.totalFeeGrowth,
+				new(big.Int).Div(new(big.Int).Mul(step.feeAmount, bignumber.TwoPow128), currentLiquidity),
+			)
+		}
+
+		// compared by value as in the contract: a price landing exactly on the tick crosses it, even if it isn't the
+		// target of the step (limit price on the tick)
+		if currentPrice.Cmp(step.nextTickPrice) == 0 {
 			// if the reached tick is initialized then we need to cross it
 			if step.initialized {
 				// once at a swap we have to get the last timepoint of the observation
@@ -146,9 +198,27 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 				// every tick cross is needed to be duplicated in a virtual pool
 				// don't need to do this here
 
+				// flip the fee growth outside of the crossed tick
+				// (written to the state update, the tick list is kept untouched)
+				var feeGrowth0, feeGrowth1 *big.Int
+				if zeroToOne {
+					feeGrowth0, feeGrowth1 = cache.totalFeeGrowth, cache.totalFeeGrowthB
+				} else {
+					feeGrowth0, feeGrowth1 = cache.totalFeeGrowthB, cache.totalFeeGrowth
+				}
+				outside := p.getFeeGrowthOutside(step.nextTick)
+				nextState.TickFeeGrowthOutside[step.nextTick] = FeeGrowth{
+					Token0: subUint256(feeGrowth0, outside.Token0),
+					Token1: subUint256(feeGrowth1, outside.Token1),
+				}
+				nextState.CrossedTicks++
+				if budget.TickCrossingsExceeded(nextState.CrossedTicks) {
+					return pool.ErrComputeBudgetExceeded, nil, nil, nil, nil
+				}
+
 				nextTickData, err := p.ticks.GetTick(step.nextTick)
 				if err != nil {
-					return err, nil, nil, nil
+					return err, nil, nil, nil, nil
 				}
 				var liquidityDelta *big.Int
 				if zeroToOne {
@@ -158,17 +228,20 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 				}
 
 				currentLiquidity = utils.AddDelta(currentLiquidity, liquidityDelta)
+				if !isUint128(currentLiquidity) {
+					return ErrLiquidityOverflow, nil, nil, nil, nil
+				}
 			}
 			if zeroToOne {
 				currentTick = step.nextTick - 1
 			} else {
 				currentTick = step.nextTick
 			}
-		} else if currentPrice != step.stepSqrtPrice {
+		} else if currentPrice.Cmp(step.stepSqrtPrice) != 0 {
 			// if the price has changed but hasn't reached the target
 			currentTick, err = utils.GetTickAtSqrtRatio(currentPrice)
 			if err != nil {
-				return err, nil, nil, nil
+				return err, nil, nil, nil, nil
 			}
 			break // since the price hasn't reached the target, amountRequired should be 0
 		}
@@ -179,6 +252,10 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 		}
 	}
 
+	if !isInt256(cache.amountCalculated) {
+		return ErrAmountOverflow, nil, nil, nil, nil
+	}
+
 	var amount0, amount1 *big.Int
 	// the amount to provide could be less then initially specified (e.g. reached limit)
 	if zeroToOne == cache.exactInput {
@@ -195,10 +272,17 @@ func (p *PoolSimulator) _calculateSwapAndLock(
 		FeeOtz:             p.globalState.FeeOtz,
 		TimepointIndex:     p.globalState.TimepointIndex,
 		CommunityFeeToken0: p.globalState.CommunityFeeToken0,
-		CommunityFeeToken1: p.globalState.CommunityFeeToken0,
+		CommunityFeeToken1: p.globalState.CommunityFeeToken1,
+		Unlocked:           p.globalState.Unlocked,
 	}
 
 	nextState.Liquidity = currentLiquidity
 
-	return nil, amount0, amount1, nextState
+	if zeroToOne {
+		nextState.TotalFeeGrowth = FeeGrowth{Token0: cache.totalFeeGrowth, Token1: cache.totalFeeGrowthB}
+	} else {
+		nextState.TotalFeeGrowth = FeeGrowth{Token0: cache.totalFeeGrowthB, Token1: cache.totalFeeGrowth}
+	}
+
+	return nil, amount0, amount1, cache.feeAmount, nextState
 }
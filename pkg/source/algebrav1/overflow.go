@@ -0,0 +1,27 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// the big.Int math never overflows, so the bounds of the contract types are checked explicitly where the contract
+// would revert: the liquidity is a uint128, the amounts of the swaps are int256 and the results of mulDiv uint256
+var (
+	maxUint128 = new(big.Int).Sub(new(big.Int).Lsh(bignumber.One, 128), bignumber.One)
+	maxInt256  = new(big.Int).Sub(new(big.Int).Lsh(bignumber.One, 255), bignumber.One)
+	minInt256  = new(big.Int).Neg(new(big.Int).Lsh(bignumber.One, 255))
+)
+
+func isUint128(n *big.Int) bool {
+	return n.Sign() >= 0 && n.Cmp(maxUint128) <= 0
+}
+
+func isInt256(n *big.Int) bool {
+	return n.Cmp(minInt256) >= 0 && n.Cmp(maxInt256) <= 0
+}
+
+func isUint256(n *big.Int) bool {
+	return n.Sign() >= 0 && n.Cmp(maxUint256) <= 0
+}
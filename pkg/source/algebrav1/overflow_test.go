@@ -0,0 +1,54 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// newLiquidityPool is the pool of newComparePool with the given liquidity at the current tick
+func newLiquidityPool(liquidity *big.Int) (*PoolSimulator, error) {
+	return NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra: fmt.Sprintf(`{"liquidity":%v,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":500,"feeOtz":500,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+			liquidity),
+	}, 1001)
+}
+
+func TestPoolSimulator_ExtremeLiquidity(t *testing.T) {
+	_, err := newLiquidityPool(new(big.Int).Add(maxUint128, bignumber.One))
+	assert.ErrorIs(t, err, ErrInvalidLiquidity)
+
+	p, err := newLiquidityPool(maxUint128)
+	require.Nil(t, err)
+
+	// within the current tick range the rate is the spot price minus the fee, nothing wraps around
+	for _, amountIn := range []string{"1000000000000", "1000000000000000000000000"} {
+		res, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10(amountIn)}, "B")
+		require.Nil(t, err)
+		spotPrice, err := p.SpotPrice("A", "B")
+		require.Nil(t, err)
+		expected, _ := new(big.Float).Mul(spotPrice, new(big.Float).SetInt(bignumber.NewBig10(amountIn))).Float64()
+		actual, _ := new(big.Float).SetInt(res.TokenAmountOut.Amount).Float64()
+		assert.InEpsilon(t, expected, actual, 1e-6, amountIn)
+	}
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: bignumber.NewBig10("1000000000000000000000000000000")}, "A")
+	require.Nil(t, err)
+	assert.Positive(t, res.TokenAmountOut.Amount.Sign())
+
+	// crossing the tick 279120 down adds its liquidity, above uint128 where the contract would revert
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("100000000000000000000000000000000")}, "B")
+	assert.ErrorIs(t, err, ErrLiquidityOverflow)
+
+	// the amounts are int256 in the contract
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: new(big.Int).Lsh(bignumber.One, 255)}, "B")
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
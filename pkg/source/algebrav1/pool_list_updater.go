@@ -8,12 +8,12 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/KyberNetwork/logger"
 	"github.com/machinebox/graphql"
 
 	"github.com/KyberNetwork/blockchain-toolkit/integer"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	graphqlPkg "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/graphql"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolsListUpdater struct {
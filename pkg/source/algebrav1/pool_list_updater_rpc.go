@@ -0,0 +1,386 @@
+package algebrav1
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/ethrpc"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+// LogFilterer is the subset of ethclient.Client needed to scan factory logs
+type LogFilterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// RPCPoolsListUpdater discovers pools from the factory's Pool events instead of a subgraph,
+// used for new forks that don't have a subgraph yet
+type RPCPoolsListUpdater struct {
+	config       *Config
+	ethrpcClient *ethrpc.Client
+	logFilterer  LogFilterer
+}
+
+func NewRPCPoolsListUpdater(
+	cfg *Config,
+	ethrpcClient *ethrpc.Client,
+	logFilterer LogFilterer,
+) *RPCPoolsListUpdater {
+	return &RPCPoolsListUpdater{
+		config:       cfg,
+		ethrpcClient: ethrpcClient,
+		logFilterer:  logFilterer,
+	}
+}
+
+func (d *RPCPoolsListUpdater) GetNewPools(ctx context.Context, metadataBytes []byte) ([]entity.Pool, []byte, error) {
+	var metadata RPCMetadata
+	if len(metadataBytes) != 0 {
+		err := json.Unmarshal(metadataBytes, &metadata)
+		if err != nil {
+			return nil, metadataBytes, err
+		}
+	}
+
+	latestBlock, err := d.logFilterer.BlockNumber(ctx)
+	if err != nil {
+		logger.WithFields(logger.Fields{
+			"error": err,
+		}).Errorf("failed to get latest block number")
+		return nil, metadataBytes, err
+	}
+
+	fromBlock := d.config.StartBlock
+	if metadata.LastBlockNumber >= fromBlock {
+		fromBlock = metadata.LastBlockNumber + 1
+	}
+	if fromBlock > latestBlock {
+		// no new block
+		return []entity.Pool{}, metadataBytes, nil
+	}
+
+	blockRange := d.config.BlockRange
+	if blockRange == 0 {
+		blockRange = defaultBlockRange
+	}
+	toBlock := fromBlock + blockRange - 1
+	if toBlock > latestBlock {
+		toBlock = latestBlock
+	}
+
+	logs, toBlock, err := d.getPoolCreatedLogs(ctx, fromBlock, toBlock)
+	if err != nil {
+		return nil, metadataBytes, err
+	}
+
+	pools, err := d.initPools(ctx, logs)
+	if err != nil {
+		return nil, metadataBytes, err
+	}
+
+	newMetadataBytes, err := json.Marshal(RPCMetadata{
+		LastBlockNumber: toBlock,
+	})
+	if err != nil {
+		return nil, metadataBytes, err
+	}
+
+	logger.Infof("got %v %v pools from block %v to %v", len(pools), d.config.DexID, fromBlock, toBlock)
+
+	return pools, newMetadataBytes, nil
+}
+
+// getPoolCreatedLogs fetches the factory's Pool events in [fromBlock, toBlock],
+// halving the range until the response fits into MaxLogsPerRequest.
+// Returns the logs and the last block that has actually been scanned.
+func (d *RPCPoolsListUpdater) getPoolCreatedLogs(ctx context.Context, fromBlock, toBlock uint64) ([]types.Log, uint64, error) {
+	maxLogs := d.config.MaxLogsPerRequest
+	if maxLogs <= 0 {
+		maxLogs = defaultMaxLogsPerRequest
+	}
+
+	for {
+		logs, err := d.logFilterer.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(fromBlock),
+			ToBlock:   new(big.Int).SetUint64(toBlock),
+			Addresses: []common.Address{common.HexToAddress(d.config.FactoryAddress)},
+			Topics:    [][]common.Hash{{algebraV1FactoryABI.Events[factoryEventPool].ID}},
+		})
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"fromBlock": fromBlock,
+				"toBlock":   toBlock,
+				"error":     err,
+			}).Errorf("failed to filter factory logs")
+			return nil, 0, err
+		}
+
+		// a single block can't be split any further, so accept it as is
+		if len(logs) <= maxLogs || fromBlock == toBlock {
+			return logs, toBlock, nil
+		}
+
+		toBlock = fromBlock + (toBlock-fromBlock)/2
+	}
+}
+
+// poolCreated is a pool of a factory log
+type poolCreated struct {
+	address string
+	token0  string
+	token1  string
+}
+
+func (d *RPCPoolsListUpdater) initPools(ctx context.Context, logs []types.Log) ([]entity.Pool, error) {
+	createdPools := make([]poolCreated, 0, len(logs))
+	tokens := make(map[string]*entity.PoolToken)
+	for _, l := range logs {
+		if l.Removed || len(l.Topics) < 3 {
+			continue
+		}
+
+		values, err := algebraV1FactoryABI.Unpack(factoryEventPool, l.Data)
+		if err != nil || len(values) != 1 {
+			logger.WithFields(logger.Fields{
+				"txHash": l.TxHash.Hex(),
+				"error":  err,
+			}).Errorf("failed to unpack factory log")
+			continue
+		}
+		poolAddress, ok := values[0].(common.Address)
+		if !ok {
+			continue
+		}
+
+		created := poolCreated{
+			address: strings.ToLower(poolAddress.Hex()),
+			token0:  strings.ToLower(common.BytesToAddress(l.Topics[1].Bytes()).Hex()),
+			token1:  strings.ToLower(common.BytesToAddress(l.Topics[2].Bytes()).Hex()),
+		}
+		createdPools = append(createdPools, created)
+		tokens[created.token0] = &entity.PoolToken{Address: created.token0}
+		tokens[created.token1] = &entity.PoolToken{Address: created.token1}
+	}
+
+	if len(createdPools) == 0 {
+		return []entity.Pool{}, nil
+	}
+
+	if err := d.fetchTokensInfo(ctx, tokens); err != nil {
+		return nil, err
+	}
+
+	states, err := d.fetchPoolStates(ctx, createdPools)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make([]entity.Pool, 0, len(createdPools))
+	for i, p := range createdPools {
+		pool, err := d.newPool(p.address, *tokens[p.token0], *tokens[p.token1], states[i])
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
+}
+
+// poolState is the initial state of a listed pool. Its fields are nil if their call failed, e.g. for a pool that
+// isn't initialized yet, the tracker fetches them again.
+type poolState struct {
+	liquidity *big.Int
+	state     *GlobalState
+	reserve0  *big.Int
+	reserve1  *big.Int
+}
+
+// newPool is the listed pool of address, with the initial state if its liquidity and globalState could be read
+func (d *RPCPoolsListUpdater) newPool(address string, token0, token1 entity.PoolToken, state poolState) (entity.Pool, error) {
+	reserves := entity.PoolReserves{zeroString, zeroString}
+	if state.reserve0 != nil {
+		reserves[0] = state.reserve0.String()
+	}
+	if state.reserve1 != nil {
+		reserves[1] = state.reserve1.String()
+	}
+
+	var extra string
+	if state.liquidity != nil && state.state != nil {
+		extraBytes, err := json.Marshal(Extra{
+			Liquidity:   state.liquidity,
+			GlobalState: *state.state,
+			TickBounds:  d.config.TickBounds,
+		})
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"poolAddress": address,
+				"error":       err,
+			}).Errorf("failed to marshal extra data")
+			return entity.Pool{}, err
+		}
+		extra = string(extraBytes)
+	}
+
+	return entity.Pool{
+		Address:      address,
+		ReserveUsd:   0,
+		AmplifiedTvl: 0,
+		Exchange:     d.config.DexID,
+		Type:         DexTypeAlgebraV1,
+		Timestamp:    time.Now().Unix(),
+		Reserves:     reserves,
+		Tokens:       []*entity.PoolToken{&token0, &token1},
+		Extra:        extra,
+	}, nil
+}
+
+// fetchPoolStates reads the liquidity, the globalState and the balances of each pool in the same multicall batch,
+// so that they are of the same block
+func (d *RPCPoolsListUpdater) fetchPoolStates(ctx context.Context, createdPools []poolCreated) ([]poolState, error) {
+	states := make([]poolState, len(createdPools))
+
+	for start := 0; start < len(createdPools); start += multicallBatchSize {
+		end := start + multicallBatchSize
+		if end > len(createdPools) {
+			end = len(createdPools)
+		}
+		chunk := createdPools[start:end]
+		results := make([]FetchRPCResult, len(chunk))
+		rpcStates := make([]interface{}, len(chunk))
+
+		rpcRequest := d.ethrpcClient.NewRequest()
+		rpcRequest.SetContext(ctx)
+		for i, p := range chunk {
+			rpcRequest.AddCall(&ethrpc.Call{
+				ABI:    algebraV1PoolABI,
+				Target: p.address,
+				Method: methodGetLiquidity,
+				Params: nil,
+			}, []interface{}{&results[i].liquidity})
+			rpcStates[i] = addGlobalStateCall(rpcRequest, p.address, d.config.UseDirectionalFee)
+			rpcRequest.AddCall(&ethrpc.Call{
+				ABI:    erc20ABI,
+				Target: p.token0,
+				Method: erc20MethodBalanceOf,
+				Params: []interface{}{common.HexToAddress(p.address)},
+			}, []interface{}{&results[i].reserve0}).AddCall(&ethrpc.Call{
+				ABI:    erc20ABI,
+				Target: p.token1,
+				Method: erc20MethodBalanceOf,
+				Params: []interface{}{common.HexToAddress(p.address)},
+			}, []interface{}{&results[i].reserve1})
+		}
+
+		resp, err := rpcRequest.TryAggregate()
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"error": err,
+			}).Errorf("failed to fetch pools state")
+			return nil, err
+		}
+
+		for i := range chunk {
+			state := &states[start+i]
+			if resp.Result[4*i] {
+				state.liquidity = results[i].liquidity
+			}
+			if resp.Result[4*i+1] {
+				globalState := toGlobalState(rpcStates[i])
+				state.state = &globalState
+			}
+			if resp.Result[4*i+2] {
+				state.reserve0 = results[i].reserve0
+			}
+			if resp.Result[4*i+3] {
+				state.reserve1 = results[i].reserve1
+			}
+		}
+	}
+
+	return states, nil
+}
+
+// fetchTokensInfo fills decimals, symbol and name of the tokens.
+// Non-standard tokens (bytes32 symbol...) are tolerated and keep the default values.
+func (d *RPCPoolsListUpdater) fetchTokensInfo(ctx context.Context, tokens map[string]*entity.PoolToken) error {
+	type tokenInfo struct {
+		decimals uint8
+		symbol   string
+		name     string
+	}
+
+	addresses := make([]string, 0, len(tokens))
+	for address := range tokens {
+		addresses = append(addresses, address)
+	}
+	// same multicall batches whatever the map order
+	sort.Strings(addresses)
+
+	for start := 0; start < len(addresses); start += multicallBatchSize {
+		end := start + multicallBatchSize
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunk := addresses[start:end]
+		infos := make([]tokenInfo, len(chunk))
+
+		rpcRequest := d.ethrpcClient.NewRequest()
+		rpcRequest.SetContext(ctx)
+		for i, address := range chunk {
+			rpcRequest.AddCall(&ethrpc.Call{
+				ABI:    erc20ABI,
+				Target: address,
+				Method: erc20MethodDecimals,
+				Params: nil,
+			}, []interface{}{&infos[i].decimals}).AddCall(&ethrpc.Call{
+				ABI:    erc20ABI,
+				Target: address,
+				Method: erc20MethodSymbol,
+				Params: nil,
+			}, []interface{}{&infos[i].symbol}).AddCall(&ethrpc.Call{
+				ABI:    erc20ABI,
+				Target: address,
+				Method: erc20MethodName,
+				Params: nil,
+			}, []interface{}{&infos[i].name})
+		}
+
+		resp, err := rpcRequest.TryAggregate()
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"error": err,
+			}).Errorf("failed to fetch tokens info")
+			return err
+		}
+
+		for i, address := range chunk {
+			token := tokens[address]
+			token.Weight = defaultTokenWeight
+			token.Swappable = true
+			token.Decimals = defaultTokenDecimals
+			if resp.Result[3*i] {
+				token.Decimals = infos[i].decimals
+			}
+			if resp.Result[3*i+1] {
+				token.Symbol = infos[i].symbol
+			}
+			if resp.Result[3*i+2] {
+				token.Name = infos[i].name
+			}
+		}
+	}
+
+	return nil
+}
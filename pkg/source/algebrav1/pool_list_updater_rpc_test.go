@@ -0,0 +1,121 @@
+package algebrav1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// return one log per block in the queried range
+type mockLogFilterer struct {
+	latestBlock uint64
+	queries     []ethereum.FilterQuery
+}
+
+func (m *mockLogFilterer) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	m.queries = append(m.queries, q)
+	var logs []types.Log
+	for b := q.FromBlock.Uint64(); b <= q.ToBlock.Uint64(); b++ {
+		logs = append(logs, types.Log{BlockNumber: b})
+	}
+	return logs, nil
+}
+
+func (m *mockLogFilterer) BlockNumber(context.Context) (uint64, error) {
+	return m.latestBlock, nil
+}
+
+func TestRPCPoolsListUpdater_getPoolCreatedLogs(t *testing.T) {
+	filterer := &mockLogFilterer{latestBlock: 1000}
+	d := NewRPCPoolsListUpdater(&Config{MaxLogsPerRequest: 10}, nil, filterer)
+
+	logs, toBlock, err := d.getPoolCreatedLogs(context.Background(), 100, 199)
+	require.Nil(t, err)
+
+	// 100 blocks -> 50 -> 25 -> 13 -> 7
+	assert.Equal(t, uint64(106), toBlock)
+	assert.Len(t, logs, 7)
+	assert.Len(t, filterer.queries, 5)
+}
+
+func TestRPCPoolsListUpdater_GetNewPools_Cursor(t *testing.T) {
+	// no pool created in the whole range
+	filterer := &emptyLogFilterer{mockLogFilterer: &mockLogFilterer{latestBlock: 150}}
+	d := NewRPCPoolsListUpdater(&Config{StartBlock: 100, BlockRange: 20}, nil, filterer)
+
+	var metadata []byte
+	for _, expected := range []string{`{"lastBlockNumber":119}`, `{"lastBlockNumber":139}`, `{"lastBlockNumber":150}`, `{"lastBlockNumber":150}`} {
+		pools, newMetadata, err := d.GetNewPools(context.Background(), metadata)
+		require.Nil(t, err)
+		assert.Empty(t, pools)
+		assert.Equal(t, expected, string(newMetadata))
+		metadata = newMetadata
+	}
+}
+
+type emptyLogFilterer struct {
+	*mockLogFilterer
+}
+
+func (m *emptyLogFilterer) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	m.queries = append(m.queries, q)
+	return nil, nil
+}
+
+func TestRPCPoolsListUpdater_newPool(t *testing.T) {
+	d := NewRPCPoolsListUpdater(&Config{DexID: "algebra-fork", RPCOnly: true}, nil, nil)
+	token0 := entity.PoolToken{Address: "0xa", Symbol: "A", Decimals: 6, Weight: defaultTokenWeight, Swappable: true}
+	token1 := entity.PoolToken{Address: "0xb", Symbol: "B", Decimals: 18, Weight: defaultTokenWeight, Swappable: true}
+
+	testcases := []struct {
+		state            poolState
+		expectedReserves entity.PoolReserves
+		expectedExtra    string
+	}{
+		{
+			poolState{
+				liquidity: big.NewInt(2822091172725),
+				state: &GlobalState{
+					Price:    bignumber.NewBig10("93065132232889433968150957834858946"),
+					Tick:     big.NewInt(279543),
+					FeeZto:   2985,
+					FeeOtz:   2985,
+					Unlocked: true,
+				},
+				reserve0: big.NewInt(723924),
+				reserve1: bignumber.NewBig10("36031866872048609640"),
+			},
+			entity.PoolReserves{"723924", "36031866872048609640"},
+			`{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":2985,"feeOtz":2985,"timepoint_index":0,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":null,"tickSpacing":0}`,
+		},
+		{
+			// the globalState call failed, e.g. the pool isn't initialized
+			poolState{liquidity: big.NewInt(0), reserve0: big.NewInt(0)},
+			entity.PoolReserves{"0", "0"},
+			"",
+		},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p, err := d.newPool("0xpool", token0, token1, tc.state)
+			require.Nil(t, err)
+
+			assert.Equal(t, "0xpool", p.Address)
+			assert.Equal(t, "algebra-fork", p.Exchange)
+			assert.Equal(t, DexTypeAlgebraV1, p.Type)
+			assert.Equal(t, []*entity.PoolToken{&token0, &token1}, p.Tokens)
+			assert.Equal(t, tc.expectedReserves, p.Reserves)
+			assert.Equal(t, tc.expectedExtra, p.Extra)
+		})
+	}
+}
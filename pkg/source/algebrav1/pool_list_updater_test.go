@@ -0,0 +1,97 @@
+package algebrav1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// newSubgraphServer serves the responses recorded in testdata/subgraph, by createdAtTimestamp_gte of the query
+func newSubgraphServer(t *testing.T) (*httptest.Server, *[]string) {
+	createdAtRegexp := regexp.MustCompile(`createdAtTimestamp_gte: (\d+)`)
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		queries = append(queries, req.Query)
+
+		match := createdAtRegexp.FindStringSubmatch(req.Query)
+		if match == nil {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		response, err := os.ReadFile(filepath.Join("testdata", "subgraph", "pools_"+match[1]+".json"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &queries
+}
+
+func TestPoolsListUpdater_DryRun(t *testing.T) {
+	server, queries := newSubgraphServer(t)
+
+	configPath := filepath.Join(t.TempDir(), "quickswap-v3.json")
+	require.Nil(t, os.WriteFile(configPath, []byte(`{"DexID":"quickswap-v3","subgraphAPI":"`+server.URL+`"}`), 0o600))
+
+	report, err := pool.DryRunWithConfigFile(context.Background(), configPath, func(configBytes []byte) (pool.IPoolsListUpdater, error) {
+		var config Config
+		if err := json.Unmarshal(configBytes, &config); err != nil {
+			return nil, err
+		}
+		return NewPoolsListUpdater(&config), nil
+	}, 10)
+	require.Nil(t, err)
+
+	// the last round finds no new pool
+	assert.Equal(t, 3, report.Rounds)
+	assert.Len(t, *queries, 3)
+	assert.Contains(t, (*queries)[1], `id_not_in: ["0x55caabb0d2b704fd0ef8192a7e35d8837e678207","0x0e3eb2c75bd7dd0e12249d96b1321d9570764d77"]`)
+
+	assert.Equal(t, 4, report.PoolsFound)
+	assert.Equal(t, []string{
+		"0x0d500b1d8e8ef31e21c99d1db9a6444d3adf1270",
+		"0x2791bca1f2de4661ed88a30c99a7a9449aa84174",
+		"0x7ceb23fd6bc0add59e62ac25578270cff1b9f619",
+		"0xc2132d05d31c914a87c6611c10748aeb04b58e8f",
+	}, report.Tokens)
+	// the pool of the subgraph without token1
+	assert.Equal(t, []pool.DryRunFailure{
+		{Address: "0x0e3eb2c75bd7dd0e12249d96b1321d9570764d77", Reason: "invalid pool: 1 tokens"},
+	}, report.ValidationFailures)
+	// the extras are written by the tracker
+	assert.Equal(t, 0, report.TotalExtraSize)
+	assert.JSONEq(t, `{"lastCreatedAtTimestamp":1690000200,"lastPoolIds":["0x479e1b71a702a595e19b6d5932cd5c863ab57ee0"]}`, report.Metadata)
+
+	// the production run writes the same pools to its sink
+	var written int
+	sink := pool.PoolSinkFunc(func(_ context.Context, pools []entity.Pool) error {
+		written += len(pools)
+		return nil
+	})
+	rounds, _, err := pool.RunPoolsListUpdater(context.Background(),
+		NewPoolsListUpdater(&Config{DexID: "quickswap-v3", SubgraphAPI: server.URL}), sink, nil, 2)
+	require.Nil(t, err)
+	assert.Equal(t, 2, rounds)
+	assert.Equal(t, 4, written)
+}
@@ -3,7 +3,10 @@ package algebrav1
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
+	"sort"
+	"strconv"
 	"strings"
 
 	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
@@ -13,23 +16,51 @@ import (
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolSimulator struct {
 	pool.Pool
+	decimals    []uint8
 	globalState GlobalState
 	liquidity   *big.Int
-	ticks       *v3Entities.TickListDataProvider
-	gas         int64
+	ticks       *clmath.SparseTickList
+	gas         Gas
 	tickMin     int
 	tickMax     int
-	tickSpacing int
+	tickBounds  TickBounds
+	// sqrt prices at the tick bounds, the price limit of a swap must be strictly within them
+	minSqrtRatio *big.Int
+	maxSqrtRatio *big.Int
+	tickSpacing  int
+	// true if the pool has no initialized ticks with liquidity, the swaps fail with ErrNoLiquidity
+	zeroLiquidity bool
+
+	totalFeeGrowth       FeeGrowth
+	tickFeeGrowthOutside map[int]FeeGrowth // as tracked, never changed so it is shared by the clones
+	// fee growth outside of the ticks crossed by the simulated swaps, over tickFeeGrowthOutside. Copied on write, it
+	// only holds the crossed ticks
+	crossedFeeGrowthOutside map[int]FeeGrowth
+
+	feeConfigZto *FeeConfiguration
+	feeConfigOtz *FeeConfiguration
+	// inputs of the adaptive fees, see SetSimulationTimestamp
+	timepoints                map[uint16]Timepoint
+	volumePerLiquidityInBlock *big.Int
+	trackedFeeZto             uint16 // fees computed by the tracker
+	trackedFeeOtz             uint16
+	feeTimestamp              int64 // block timestamp of the current fees, the one of the tracking or of the simulation
+	trackedTimestamp          int64
+	twapTick                  *int // average tick of the timepoints, nil without them
+
+	effectiveFeeAdjustmentBps int
+	strictMode                bool
 }
 
 func NewPoolSimulator(entityPool entity.Pool, defaultGas int64) (*PoolSimulator, error) {
-	var extra Extra
-	if err := json.Unmarshal([]byte(entityPool.Extra), &extra); err != nil {
+	extra, err := decodeExtra([]byte(entityPool.Extra))
+	if err != nil {
 		return nil, err
 	}
 
@@ -37,33 +68,74 @@ func NewPoolSimulator(entityPool entity.Pool, defaultGas int64) (*PoolSimulator,
 		return nil, ErrTickNil
 	}
 
+	var staticExtra StaticExtra
+	if len(entityPool.StaticExtra) > 0 {
+		if err := json.Unmarshal([]byte(entityPool.StaticExtra), &staticExtra); err != nil {
+			return nil, err
+		}
+	}
+
 	tokens := make([]string, 2)
 	reserves := make([]*big.Int, 2)
+	decimals := make([]uint8, 2)
 	if len(entityPool.Reserves) == 2 && len(entityPool.Tokens) == 2 {
 		tokens[0] = entityPool.Tokens[0].Address
 		reserves[0] = bignumber.NewBig10(entityPool.Reserves[0])
+		decimals[0] = entityPool.Tokens[0].Decimals
 		tokens[1] = entityPool.Tokens[1].Address
 		reserves[1] = bignumber.NewBig10(entityPool.Reserves[1])
+		decimals[1] = entityPool.Tokens[1].Decimals
 	} else {
 		return nil, ErrInvalidToken
 	}
 
-	// if the tick list is empty, the pool should be ignored
-	if len(extra.Ticks) == 0 {
-		return nil, ErrTicksEmpty
-	}
-
 	if !extra.GlobalState.Unlocked {
 		return nil, ErrPoolLocked
 	}
 
-	ticks, err := v3Entities.NewTickListDataProvider(extra.Ticks, int(extra.TickSpacing))
+	if extra.Liquidity == nil || !isUint128(extra.Liquidity) {
+		return nil, ErrInvalidLiquidity
+	}
+
+	ticks, err := clmath.NewSparseTickList(extra.Ticks, int(extra.TickSpacing))
 	if err != nil {
 		return nil, err
 	}
 
-	tickMin := extra.Ticks[0].Index
-	tickMax := extra.Ticks[len(extra.Ticks)-1].Index
+	tickBounds, minSqrtRatio, maxSqrtRatio, err := newTickBounds(extra.TickBounds)
+	if err != nil {
+		return nil, err
+	}
+
+	// a pool without initialized ticks, e.g. before its first mint, is kept but can't swap, see ErrNoLiquidity. So is a
+	// pool whose ticks all have a LiquidityGross of 0, e.g. once all its positions are burnt. A pool without liquidity
+	// at the current price can still swap to its ticks.
+	zeroLiquidity := !hasLiquidityGross(extra.Ticks)
+	var tickMin, tickMax int
+	if !zeroLiquidity {
+		tickMin, tickMax = extra.Ticks[0].Index, extra.Ticks[len(extra.Ticks)-1].Index
+	}
+
+	totalFeeGrowth := FeeGrowth{Token0: integer.Zero(), Token1: integer.Zero()}
+	if extra.TotalFeeGrowth != nil {
+		if extra.TotalFeeGrowth.Token0 != nil {
+			totalFeeGrowth.Token0 = extra.TotalFeeGrowth.Token0
+		}
+		if extra.TotalFeeGrowth.Token1 != nil {
+			totalFeeGrowth.Token1 = extra.TotalFeeGrowth.Token1
+		}
+	}
+
+	tickFeeGrowthOutside := extra.TickFeeGrowthOutside
+	if tickFeeGrowthOutside == nil {
+		tickFeeGrowthOutside = map[int]FeeGrowth{}
+	}
+
+	var twapTick *int
+	if tick, ok := getTwapTick(extra.Timepoints, extra.GlobalState.TimepointIndex); ok {
+		twapTick = new(int)
+		*twapTick = int(tick)
+	}
 
 	var info = pool.PoolInfo{
 		Address:    strings.ToLower(entityPool.Address),
@@ -77,20 +149,80 @@ func NewPoolSimulator(entityPool entity.Pool, defaultGas int64) (*PoolSimulator,
 
 	return &PoolSimulator{
 		Pool:        pool.Pool{Info: info},
+		decimals:    decimals,
 		globalState: extra.GlobalState,
 		liquidity:   extra.Liquidity,
 		ticks:       ticks,
-		gas:         defaultGas,
+		gas:         Gas{Swap: defaultGas, CrossInitTick: defaultCrossInitTickGas},
 		tickMin:     tickMin,
 		tickMax:     tickMax,
+		tickBounds:  tickBounds,
+
+		minSqrtRatio: minSqrtRatio,
+		maxSqrtRatio: maxSqrtRatio,
+
 		tickSpacing: int(extra.TickSpacing),
+
+		zeroLiquidity: zeroLiquidity,
+
+		totalFeeGrowth:       totalFeeGrowth,
+		tickFeeGrowthOutside: tickFeeGrowthOutside,
+
+		feeConfigZto: extra.FeeConfigZto,
+		feeConfigOtz: extra.FeeConfigOtz,
+
+		timepoints:                extra.Timepoints,
+		volumePerLiquidityInBlock: extra.VolumePerLiquidityInBlock,
+		trackedFeeZto:             extra.GlobalState.FeeZto,
+		trackedFeeOtz:             extra.GlobalState.FeeOtz,
+		feeTimestamp:              entityPool.Timestamp,
+		trackedTimestamp:          entityPool.Timestamp,
+		twapTick:                  twapTick,
+
+		effectiveFeeAdjustmentBps: staticExtra.EffectiveFeeAdjustmentBps,
 	}, nil
 }
 
+// hasLiquidityGross returns true if one of the ticks has a non-zero LiquidityGross
+func hasLiquidityGross(ticks []v3Entities.Tick) bool {
+	for _, tick := range ticks {
+		if tick.LiquidityGross != nil && tick.LiquidityGross.Sign() != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newTickBounds returns the tick bounds of the pool, the ones of Uniswap V3 if bounds is nil, and their sqrt prices.
+// Custom bounds must be within the ones of Uniswap V3 since the tick math is the same.
+func newTickBounds(bounds *TickBounds) (TickBounds, *big.Int, *big.Int, error) {
+	if bounds == nil {
+		return TickBounds{MinTick: v3Utils.MinTick, MaxTick: v3Utils.MaxTick}, v3Utils.MinSqrtRatio, v3Utils.MaxSqrtRatio, nil
+	}
+	if bounds.MinTick >= bounds.MaxTick || bounds.MinTick < v3Utils.MinTick || bounds.MaxTick > v3Utils.MaxTick {
+		return TickBounds{}, nil, nil, ErrInvalidTickBounds
+	}
+
+	minSqrtRatio, err := v3Utils.GetSqrtRatioAtTick(bounds.MinTick)
+	if err != nil {
+		return TickBounds{}, nil, nil, err
+	}
+	maxSqrtRatio, err := v3Utils.GetSqrtRatioAtTick(bounds.MaxTick)
+	if err != nil {
+		return TickBounds{}, nil, nil, err
+	}
+	return *bounds, minSqrtRatio, maxSqrtRatio, nil
+}
+
 /**
- * getSqrtPriceLimit get the price limit of pool based on the initialized ticks that this pool has
+ * getSqrtPriceLimit get the price limit of pool based on the initialized ticks that this pool has,
+ * nil if it has none or if the last initialized tick is out of the tick range of Uniswap V3
  */
 func (p *PoolSimulator) getSqrtPriceLimit(zeroForOne bool) *big.Int {
+	if p.zeroLiquidity {
+		return nil
+	}
+
 	var tickLimit int
 	if zeroForOne {
 		tickLimit = p.tickMin
@@ -99,6 +231,9 @@ func (p *PoolSimulator) getSqrtPriceLimit(zeroForOne bool) *big.Int {
 	}
 
 	sqrtPriceX96Limit, err := v3Utils.GetSqrtRatioAtTick(tickLimit)
+	if err != nil {
+		return nil
+	}
 
 	if zeroForOne {
 		sqrtPriceX96Limit = new(big.Int).Add(sqrtPriceX96Limit, integer.One()) // = (sqrtPrice at minTick) + 1
@@ -106,16 +241,45 @@ func (p *PoolSimulator) getSqrtPriceLimit(zeroForOne bool) *big.Int {
 		sqrtPriceX96Limit = new(big.Int).Sub(sqrtPriceX96Limit, integer.One()) // = (sqrtPrice at maxTick) - 1
 	}
 
-	if err != nil {
+	return sqrtPriceX96Limit
+}
+
+// GetEffectiveSqrtPriceLimitX96 returns the sqrtPriceLimitX96 of the swaps of tokenIn: just within the sqrt price
+// of the last initialized tick in the direction of the swap. Returns nil if tokenIn isn't a token of the pool.
+func (p *PoolSimulator) GetEffectiveSqrtPriceLimitX96(tokenIn string) *big.Int {
+	tokenInIndex := p.GetTokenIndex(tokenIn)
+	if tokenInIndex < 0 {
 		return nil
 	}
-
-	return sqrtPriceX96Limit
+	return p.getSqrtPriceLimit(tokenInIndex == 0)
 }
 
+// CalcAmountOut quotes the swap of exactly tokenAmountIn. The swap stops at the last initialized tick, it fails with
+// a NotEnoughLiquidityError if it can't swap the whole tokenAmountIn then, see CalcAmountOutWithLimit for partial fills.
 func (p *PoolSimulator) CalcAmountOut(
 	tokenAmountIn pool.TokenAmount,
 	tokenOut string,
+) (*pool.CalcAmountOutResult, error) {
+	return p.calcAmountOut(tokenAmountIn, tokenOut, false, nil)
+}
+
+// CalcAmountOutWithLimit is CalcAmountOut stopping the swap once the price reaches sqrtPriceLimitX96, like the
+// sqrtPriceLimitX96 of the contract. Unlike CalcAmountOut, the swap is partially filled: the part of tokenAmountIn not
+// swapped is RemainingTokenAmountIn of the result. A nil limit is the last initialized tick, and a limit beyond it is
+// clamped to it. Returns ErrSPL if the limit isn't strictly on the side of the current price the swap moves to.
+func (p *PoolSimulator) CalcAmountOutWithLimit(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+	sqrtPriceLimitX96 *big.Int,
+) (*pool.CalcAmountOutResult, error) {
+	return p.calcAmountOut(tokenAmountIn, tokenOut, true, sqrtPriceLimitX96)
+}
+
+func (p *PoolSimulator) calcAmountOut(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+	partialFill bool,
+	sqrtPriceLimitX96 *big.Int,
 ) (*pool.CalcAmountOutResult, error) {
 	var tokenInIndex = p.GetTokenIndex(tokenAmountIn.Token)
 	var tokenOutIndex = p.GetTokenIndex(tokenOut)
@@ -129,17 +293,32 @@ func (p *PoolSimulator) CalcAmountOut(
 		}
 
 		priceLimit := p.getSqrtPriceLimit(zeroForOne)
-		err, amount0, amount1, stateUpdate := p._calculateSwapAndLock(zeroForOne, tokenAmountIn.Amount, priceLimit)
+		if sqrtPriceLimitX96 != nil && priceLimit != nil {
+			// the closest to the current price of the two limits
+			if zeroForOne == (sqrtPriceLimitX96.Cmp(priceLimit) > 0) {
+				priceLimit = sqrtPriceLimitX96
+			}
+		}
+		err, amount0, amount1, fee, stateUpdate := p._calculateSwap(zeroForOne, tokenAmountIn.Amount, priceLimit)
 		if err != nil {
-			return &pool.CalcAmountOutResult{}, fmt.Errorf("can not GetOutputAmount, err: %+v", err)
+			return &pool.CalcAmountOutResult{}, fmt.Errorf("can not GetOutputAmount, err: %w", err)
 		}
 
-		var amountOut *big.Int
+		var amountIn, amountOut *big.Int
 		if zeroForOne {
-			amountOut = new(big.Int).Neg(amount1)
+			amountIn, amountOut = amount0, new(big.Int).Neg(amount1)
 		} else {
-			amountOut = new(big.Int).Neg(amount0)
+			amountIn, amountOut = amount1, new(big.Int).Neg(amount0)
+		}
+		// the swap reached the price limit before consuming the whole amount in
+		remainingAmountIn := new(big.Int).Sub(tokenAmountIn.Amount, amountIn)
+		if remainingAmountIn.Sign() > 0 && !partialFill {
+			return &pool.CalcAmountOutResult{}, &NotEnoughLiquidityError{
+				Token:             tokenAmountIn.Token,
+				RemainingAmountIn: remainingAmountIn,
+			}
 		}
+		stateUpdate.AmountIn = amountIn
 
 		if amountOut.Cmp(integer.Zero()) > 0 {
 			return &pool.CalcAmountOutResult{
@@ -147,11 +326,15 @@ func (p *PoolSimulator) CalcAmountOut(
 					Token:  tokenOut,
 					Amount: amountOut,
 				},
+				RemainingTokenAmountIn: &pool.TokenAmount{
+					Token:  tokenAmountIn.Token,
+					Amount: remainingAmountIn,
+				},
 				Fee: &pool.TokenAmount{
 					Token:  tokenAmountIn.Token,
-					Amount: nil,
+					Amount: fee,
 				},
-				Gas:      p.gas,
+				Gas:      p.swapGas(stateUpdate),
 				SwapInfo: *stateUpdate,
 			}, nil
 		}
@@ -162,16 +345,499 @@ func (p *PoolSimulator) CalcAmountOut(
 	return &pool.CalcAmountOutResult{}, fmt.Errorf("tokenInIndex %v or tokenOutIndex %v is not correct", tokenInIndex, tokenOutIndex)
 }
 
+// swapGas returns the gas of a swap, the base cost plus the cost of crossing each initialized tick
+func (p *PoolSimulator) swapGas(stateUpdate *StateUpdate) int64 {
+	return p.gas.Swap + int64(stateUpdate.CrossedTicks)*p.gas.CrossInitTick
+}
+
+// CalcAmountOutWithParams is CalcAmountOut with an optional fast path, see pool.PrecisionFast.
+// The fast path only handles swaps that stay within the current initialized tick range,
+// other swaps are quoted exactly.
+func (p *PoolSimulator) CalcAmountOutWithParams(params pool.CalcAmountOutParams) (*pool.CalcAmountOutResult, error) {
+	tokenAmountIn, tokenOut := params.TokenAmountIn, params.TokenOut
+	var tokenInIndex = p.GetTokenIndex(tokenAmountIn.Token)
+	var tokenOutIndex = p.GetTokenIndex(tokenOut)
+	if params.IsExact() || tokenInIndex < 0 || tokenOutIndex < 0 {
+		return p.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+
+	zeroForOne := tokenInIndex == 0
+	amountOut, fee, ok := p.calcAmountOutFast(zeroForOne, tokenAmountIn.Amount)
+	if !ok {
+		return p.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+	if amountOut.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, ErrZeroAmountOut
+	}
+
+	return &pool.CalcAmountOutResult{
+		TokenAmountOut: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: amountOut,
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenAmountIn.Token,
+			Amount: fee,
+		},
+		Gas: p.gas.Swap,
+	}, nil
+}
+
+// CalcAmountOutWithLiquidity is CalcAmountOut as if the active liquidity at the current tick was liquidityOverride,
+// the liquidity of the other tick ranges still follows from the liquidityNet of the crossed ticks.
+// The pool isn't changed and the SwapInfo of the result describes the overridden pool, so it must not be passed to
+// the UpdateBalance of p.
+func (p *PoolSimulator) CalcAmountOutWithLiquidity(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+	liquidityOverride *big.Int,
+) (*pool.CalcAmountOutResult, error) {
+	if liquidityOverride == nil || liquidityOverride.Sign() < 0 {
+		return &pool.CalcAmountOutResult{}, ErrInvalidLiquidity
+	}
+
+	overridden := p.clone()
+	overridden.liquidity = liquidityOverride
+	return overridden.CalcAmountOut(tokenAmountIn, tokenOut)
+}
+
+// CalcAmountIn quotes the swap of tokenIn for exactly tokenAmountOut, the way the contract does for a negative
+// amountRequired. The fee is taken in tokenIn. Returns ErrAmountOutTooLarge if tokenAmountOut is above the reserve of
+// the pool, or if the swap would stop at the last initialized tick before getting the whole amount out, instead of
+// quoting a partial fill, see CalcAmountInWithPartialFill.
+func (p *PoolSimulator) CalcAmountIn(
+	tokenAmountOut pool.TokenAmount,
+	tokenIn string,
+) (*pool.CalcAmountInResult, error) {
+	return p.calcAmountIn(tokenAmountOut, tokenIn, false)
+}
+
+// CalcAmountInWithPartialFill is CalcAmountIn quoting the max amount out the pool can provide if it is less than
+// tokenAmountOut, so that the router can get the rest elsewhere: the result is the amount in of that max amount out and
+// RemainingTokenAmountOut the part of tokenAmountOut not provided.
+func (p *PoolSimulator) CalcAmountInWithPartialFill(
+	tokenAmountOut pool.TokenAmount,
+	tokenIn string,
+) (*pool.CalcAmountInResult, error) {
+	return p.calcAmountIn(tokenAmountOut, tokenIn, true)
+}
+
+func (p *PoolSimulator) calcAmountIn(
+	tokenAmountOut pool.TokenAmount,
+	tokenIn string,
+	partialFill bool,
+) (*pool.CalcAmountInResult, error) {
+	var tokenInIndex = p.GetTokenIndex(tokenIn)
+	var tokenOutIndex = p.GetTokenIndex(tokenAmountOut.Token)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return &pool.CalcAmountInResult{}, fmt.Errorf("tokenInIndex %v or tokenOutIndex %v is not correct", tokenInIndex, tokenOutIndex)
+	}
+	if tokenAmountOut.Amount == nil || tokenAmountOut.Amount.Sign() <= 0 {
+		return &pool.CalcAmountInResult{}, ErrZeroAmountOut
+	}
+	if p.zeroLiquidity {
+		return &pool.CalcAmountInResult{}, ErrNoLiquidity
+	}
+	// no need to run the swap loop for more than the balance of the pool
+	if !partialFill && tokenOutIndex < len(p.Info.Reserves) && p.Info.Reserves[tokenOutIndex] != nil &&
+		tokenAmountOut.Amount.Cmp(p.Info.Reserves[tokenOutIndex]) > 0 {
+		return &pool.CalcAmountInResult{}, ErrAmountOutTooLarge
+	}
+
+	zeroForOne := tokenInIndex == 0
+	priceLimit := p.getSqrtPriceLimit(zeroForOne)
+	amountRequired := new(big.Int).Neg(tokenAmountOut.Amount)
+	err, amount0, amount1, fee, stateUpdate := p._calculateSwap(zeroForOne, amountRequired, priceLimit)
+	if err != nil {
+		return &pool.CalcAmountInResult{}, fmt.Errorf("can not GetInputAmount, err: %w", err)
+	}
+
+	var amountIn, amountOut *big.Int
+	if zeroForOne {
+		amountIn, amountOut = amount0, new(big.Int).Neg(amount1)
+	} else {
+		amountIn, amountOut = amount1, new(big.Int).Neg(amount0)
+	}
+	if amountOut.Cmp(tokenAmountOut.Amount) < 0 && !partialFill {
+		return &pool.CalcAmountInResult{}, ErrAmountOutTooLarge
+	}
+	if amountIn.Sign() <= 0 || amountOut.Sign() <= 0 {
+		return &pool.CalcAmountInResult{}, ErrZeroAmountIn
+	}
+	stateUpdate.AmountIn = amountIn
+
+	return &pool.CalcAmountInResult{
+		TokenAmountIn: &pool.TokenAmount{
+			Token:  tokenIn,
+			Amount: amountIn,
+		},
+		RemainingTokenAmountOut: &pool.TokenAmount{
+			Token:  tokenAmountOut.Token,
+			Amount: new(big.Int).Sub(tokenAmountOut.Amount, amountOut),
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenIn,
+			Amount: fee,
+		},
+		Gas:      p.swapGas(stateUpdate),
+		SwapInfo: *stateUpdate,
+	}, nil
+}
+
 func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 	si, ok := params.SwapInfo.(StateUpdate)
 	if !ok {
 		logger.Warnf("failed to UpdateBalance for Algebra %v %v pool, wrong swapInfo type", p.Info.Address, p.Info.Exchange)
 		return
 	}
+	// the timepoint of the block is written at the state before the swap
+	p.writeSwapTimepoint()
+	timepointIndex := p.globalState.TimepointIndex
+
 	p.liquidity = new(big.Int).Set(si.Liquidity)
 	p.globalState = si.GlobalState
+	p.globalState.TimepointIndex = timepointIndex
+	p.totalFeeGrowth = si.TotalFeeGrowth
+
+	// copy on write, the overlay might be shared with a clone
+	crossedFeeGrowthOutside := make(map[int]FeeGrowth, len(p.crossedFeeGrowthOutside)+len(si.TickFeeGrowthOutside))
+	for tick, feeGrowth := range p.crossedFeeGrowthOutside {
+		crossedFeeGrowthOutside[tick] = feeGrowth
+	}
+	for tick, feeGrowth := range si.TickFeeGrowthOutside {
+		crossedFeeGrowthOutside[tick] = feeGrowth
+	}
+	p.crossedFeeGrowthOutside = crossedFeeGrowthOutside
+
+	// the balances of the pool, the community fee sent to the vault isn't deducted. The amount in of a partial fill
+	// is the part swapped, i.e. the amount in less RemainingTokenAmountIn
+	amountIn := params.TokenAmountIn.Amount
+	if si.AmountIn != nil {
+		amountIn = si.AmountIn
+	}
+	for i, token := range p.Info.Tokens {
+		if token == params.TokenAmountIn.Token {
+			p.Info.Reserves[i] = new(big.Int).Add(p.Info.Reserves[i], amountIn)
+		}
+		if token == params.TokenAmountOut.Token {
+			p.Info.Reserves[i] = new(big.Int).Sub(p.Info.Reserves[i], params.TokenAmountOut.Amount)
+		}
+	}
+	p.addSwapVolume(p.liquidity, amountIn, params.TokenAmountOut.Amount)
+}
+
+// SetStrictMode disables the off-chain fee adjustment, so that the ranking only relies on on-chain data
+func (p *PoolSimulator) SetStrictMode(strict bool) {
+	p.strictMode = strict
+}
+
+// Capabilities reports the features of the simulator. Swaps stop at the last initialized tick, so CalcAmountOut fails
+// for an amount in above the liquidity, and CalcAmountIn for an amount out above it. The quotes don't expire:
+// SetSimulationTimestamp only moves the clock of the adaptive fees.
+func (p *PoolSimulator) Capabilities() pool.Capabilities {
+	return pool.Capabilities{
+		CalcAmountIn:  true,
+		GasEstimation: true,
+		PartialFill:   false,
+		Clone:         true,
+		FastPrecision: true,
+		Expiry:        false,
+	}
+}
+
+// effectiveFeeAdjustmentBpsOf returns the fee adjustment of a direction, clamped to [0, swap fee]
+func (p *PoolSimulator) effectiveFeeAdjustmentBpsOf(fee uint16) int {
+	if p.strictMode || p.effectiveFeeAdjustmentBps <= 0 {
+		return 0
+	}
+	// fee is in hundredths of a bip
+	if maxBps := int(fee) / 100; p.effectiveFeeAdjustmentBps > maxBps {
+		return maxBps
+	}
+	return p.effectiveFeeAdjustmentBps
 }
 
+// GetMetaInfo returns the fee breakdown of the direction, the router may use the adjustment as a ranking hint, what
+// the calldata of the swap needs: the pool, the price limit of the simulated swaps and the current tick, the state the
+// quotes are computed against, and the TWAP tick of the data storage to cross-check the current tick against
 func (p *PoolSimulator) GetMetaInfo(tokenIn string, tokenOut string) interface{} {
-	return nil
+	zeroForOne := p.GetTokenIndex(tokenIn) == 0
+	fee := p.globalState.FeeOtz
+	if zeroForOne {
+		fee = p.globalState.FeeZto
+	}
+
+	return Meta{
+		PoolAddress: p.Info.Address,
+		Fee: FeeBreakdown{
+			SwapFee:                   fee,
+			EffectiveFeeAdjustmentBps: p.effectiveFeeAdjustmentBpsOf(fee),
+		},
+		PriceLimit:  p.GetEffectiveSqrtPriceLimitX96(tokenIn),
+		CurrentTick: p.PriceTick(),
+		ZeroForOne:  zeroForOne,
+		TickSpacing: p.tickSpacing,
+
+		BlockTimestamp: p.feeTimestamp,
+		TimepointIndex: p.globalState.TimepointIndex,
+		TwapTick:       p.twapTick,
+	}
+}
+
+// GetTokenPair returns the tokens of the pool in canonical order, token0 being the lexicographically smaller one
+func (p *PoolSimulator) GetTokenPair() (token0, token1 string) {
+	token0, token1 = p.Info.Tokens[0], p.Info.Tokens[1]
+	if token1 < token0 {
+		return token1, token0
+	}
+	return token0, token1
+}
+
+// GetTokenPairReversed returns the tokens of GetTokenPair the other way round, i.e. the tokens out and in of the
+// swaps from token0 to token1
+func (p *PoolSimulator) GetTokenPairReversed() (tokenOut, tokenIn string) {
+	tokenIn, tokenOut = p.GetTokenPair()
+	return tokenOut, tokenIn
+}
+
+// RatioToken0ToToken1 returns reserve0 / reserve1, nil if reserve1 is 0. The reserves of a concentrated liquidity pool
+// aren't its real balances, so the ratio is only a heuristic of the imbalance, e.g. to prefer the balanced pools for
+// the swaps between stablecoins.
+func (p *PoolSimulator) RatioToken0ToToken1() *big.Float {
+	if len(p.Info.Reserves) < 2 || p.Info.Reserves[0] == nil || p.Info.Reserves[1] == nil || p.Info.Reserves[1].Sign() == 0 {
+		return nil
+	}
+	return new(big.Float).Quo(new(big.Float).SetInt(p.Info.Reserves[0]), new(big.Float).SetInt(p.Info.Reserves[1]))
+}
+
+// FeeTier returns a human-readable fee tier for display, e.g. "0.3%".
+// Adaptive fee pools return the range of the fee, e.g. "dynamic (0.01%-3%)",
+// pools with a different static fee per direction return "zeroForOne/oneForZero", e.g. "0.01%/0.3%".
+func (p *PoolSimulator) FeeTier() string {
+	minFee, maxFee, dynamic := uint32(math.MaxUint32), uint32(0), false
+	for _, feeConfig := range []*FeeConfiguration{p.feeConfigZto, p.feeConfigOtz} {
+		if feeConfig == nil || feeConfig.Alpha1 == 0 && feeConfig.Alpha2 == 0 {
+			continue
+		}
+		dynamic = true
+		if baseFee := uint32(feeConfig.BaseFee); baseFee < minFee {
+			minFee = baseFee
+		}
+		if fee := uint32(feeConfig.BaseFee) + uint32(feeConfig.Alpha1) + uint32(feeConfig.Alpha2); fee > maxFee {
+			maxFee = fee
+		}
+	}
+
+	if dynamic {
+		return fmt.Sprintf("dynamic (%s-%s)", formatFee(minFee), formatFee(maxFee))
+	}
+	if p.globalState.FeeZto != p.globalState.FeeOtz {
+		return fmt.Sprintf("%s/%s", formatFee(uint32(p.globalState.FeeZto)), formatFee(uint32(p.globalState.FeeOtz)))
+	}
+	return formatFee(uint32(p.globalState.FeeZto))
+}
+
+// SwapFeeFor returns the fee charged for swapping amountIn of tokenIn at the current fee of the direction,
+// feeZto for zeroForOne and feeOtz otherwise, without simulating the swap.
+// It's the fee of the swap step as computed by the pool: amountIn - amountIn * (1e6 - fee) / 1e6, rounded down.
+// A swap crossing initialized ticks rounds the fee of each step up, so it may be charged a few wei more.
+func (p *PoolSimulator) SwapFeeFor(tokenIn string, amountIn *big.Int) (*big.Int, error) {
+	tokenInIndex := p.GetTokenIndex(tokenIn)
+	if tokenInIndex < 0 {
+		return nil, ErrInvalidToken
+	}
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return nil, ErrZeroAmountIn
+	}
+
+	fee := p.globalState.FeeOtz
+	if tokenInIndex == 0 {
+		fee = p.globalState.FeeZto
+	}
+
+	amountInLessFee := new(big.Int).Div(
+		new(big.Int).Mul(amountIn, big.NewInt(int64(1e6-int(fee)))),
+		big.NewInt(1e6),
+	)
+	return amountInLessFee.Sub(amountIn, amountInLessFee), nil
+}
+
+// VolumeForFeeRevenue is the inverse of SwapFeeFor: it returns the smallest amount of tokenIn whose swap is charged
+// at least targetFee at the current fee of the direction. Returns ErrInvalidFee if the fee of the direction is 0.
+func (p *PoolSimulator) VolumeForFeeRevenue(tokenIn string, targetFee *big.Int) (*big.Int, error) {
+	tokenInIndex := p.GetTokenIndex(tokenIn)
+	if tokenInIndex < 0 {
+		return nil, ErrInvalidToken
+	}
+	if targetFee == nil || targetFee.Sign() <= 0 {
+		return nil, ErrInvalidFeeRevenue
+	}
+
+	fee := p.globalState.FeeOtz
+	if tokenInIndex == 0 {
+		fee = p.globalState.FeeZto
+	}
+	if fee == 0 {
+		return nil, ErrInvalidFee
+	}
+
+	// the fee of amountIn is ceil(amountIn * fee / 1e6), so amountIn = floor((targetFee - 1) * 1e6 / fee) + 1
+	amountIn := new(big.Int).Sub(targetFee, integer.One())
+	amountIn.Mul(amountIn, big.NewInt(1e6))
+	amountIn.Div(amountIn, big.NewInt(int64(fee)))
+	return amountIn.Add(amountIn, integer.One()), nil
+}
+
+// PriceTick returns the current tick of the pool, math.MinInt32 if it isn't known
+func (p *PoolSimulator) PriceTick() int {
+	if p.globalState.Tick == nil {
+		return math.MinInt32
+	}
+	return int(p.globalState.Tick.Int64())
+}
+
+// DistanceToPriceBoundary returns the number of initialized ticks that a swap in the given direction can still cross,
+// 0 if the pool is at the boundary of its liquidity. Going down (zeroForOne) crosses the ticks <= the current tick.
+func (p *PoolSimulator) DistanceToPriceBoundary(zeroForOne bool) int {
+	tick := p.PriceTick()
+	if tick == math.MinInt32 {
+		return 0
+	}
+	below := p.ticks.CountAtOrBelow(tick)
+	if zeroForOne {
+		return below
+	}
+	return p.ticks.Len() - below
+}
+
+// IsOutOfRange returns true if tick is outside of the initialized tick range [tickMin, tickMax] of the pool, e.g. to
+// validate the boundaries of a position
+func (p *PoolSimulator) IsOutOfRange(tick int) bool {
+	return tick < p.tickMin || tick > p.tickMax
+}
+
+// HasActiveTickAbove returns true if there is an initialized tick strictly above tick
+func (p *PoolSimulator) HasActiveTickAbove(tick int) bool {
+	_, ok := p.ticks.FirstAbove(tick)
+	return ok
+}
+
+// HasActiveTickBelow returns true if there is an initialized tick strictly below tick
+func (p *PoolSimulator) HasActiveTickBelow(tick int) bool {
+	_, ok := p.ticks.LastAtOrBelow(tick - 1)
+	return ok
+}
+
+// TicksBetween returns the sorted indexes of the initialized ticks strictly between lower and upper
+func (p *PoolSimulator) TicksBetween(lower, upper int) []int {
+	if lower >= upper {
+		return nil
+	}
+	var ticks []int
+	p.ticks.Ascend(lower+1, func(tick v3Entities.Tick) bool {
+		if tick.Index >= upper {
+			return false
+		}
+		ticks = append(ticks, tick.Index)
+		return true
+	})
+	return ticks
+}
+
+// NewTickListDataProvider returns a tick list of ticks with the tick spacing of the pool, e.g. to rebuild the ticks
+// after applying mint and burn events. The ticks must be sorted, see v3Entities.ValidateList.
+func (p *PoolSimulator) NewTickListDataProvider(ticks []v3Entities.Tick) (*v3Entities.TickListDataProvider, error) {
+	return v3Entities.NewTickListDataProvider(ticks, p.tickSpacing)
+}
+
+// DeltaTick returns the net number of initialized ticks that swapping amountIn of tokenIn would cross, negative if
+// the price goes down (zeroForOne). The crossings are the main part of the gas of a swap. The swap is computed without
+// building the result nor changing the pool.
+func (p *PoolSimulator) DeltaTick(amountIn *big.Int, tokenIn string) (int, error) {
+	tokenInIndex := p.GetTokenIndex(tokenIn)
+	if tokenInIndex < 0 {
+		return 0, ErrInvalidToken
+	}
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return 0, ErrZeroAmountIn
+	}
+
+	zeroForOne := tokenInIndex == 0
+	err, _, _, stateUpdate := p._calculateSwapAndLock(zeroForOne, amountIn, p.getSqrtPriceLimit(zeroForOne))
+	if err != nil {
+		return 0, err
+	}
+
+	crossed := stateUpdate.CrossedTicks
+	if zeroForOne {
+		return -crossed, nil
+	}
+	return crossed, nil
+}
+
+// CrossedTicksInSwap returns the initialized ticks that swapping amountIn of tokenIn for tokenOut would cross, in the
+// order they would be crossed. Like DeltaTick, the swap is computed without changing the pool.
+func (p *PoolSimulator) CrossedTicksInSwap(amountIn *big.Int, tokenIn, tokenOut string) ([]int, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenIn), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return nil, ErrInvalidToken
+	}
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return nil, ErrZeroAmountIn
+	}
+
+	zeroForOne := tokenInIndex == 0
+	err, _, _, stateUpdate := p._calculateSwapAndLock(zeroForOne, amountIn, p.getSqrtPriceLimit(zeroForOne))
+	if err != nil {
+		return nil, err
+	}
+
+	// the ticks crossed are the ones whose fee growth outside is flipped, the price moves in one direction
+	ticks := make([]int, 0, len(stateUpdate.TickFeeGrowthOutside))
+	for tick := range stateUpdate.TickFeeGrowthOutside {
+		ticks = append(ticks, tick)
+	}
+	if zeroForOne {
+		sort.Sort(sort.Reverse(sort.IntSlice(ticks)))
+	} else {
+		sort.Ints(ticks)
+	}
+	return ticks, nil
+}
+
+// FormattedState returns the current state of the pool for diagnostics and debugging, ready to be marshaled to JSON.
+// The big numbers are decimal strings so that they aren't rounded by JSON parsers, the fee is the one of FeeTier.
+func (p *PoolSimulator) FormattedState() map[string]interface{} {
+	reserves := make([]string, len(p.Info.Reserves))
+	for i, reserve := range p.Info.Reserves {
+		reserves[i] = bigIntString(reserve)
+	}
+
+	return map[string]interface{}{
+		"address":      p.Info.Address,
+		"exchange":     p.Info.Exchange,
+		"tokens":       append([]string(nil), p.Info.Tokens...),
+		"reserves":     reserves,
+		"currentTick":  bigIntString(p.globalState.Tick),
+		"sqrtPriceX96": bigIntString(p.globalState.Price),
+		"liquidity":    bigIntString(p.liquidity),
+		"fee":          p.FeeTier(),
+		"tickCount":    p.ticks.Len(),
+		"tickSpacing":  p.tickSpacing,
+		"tickMin":      p.tickMin,
+		"tickMax":      p.tickMax,
+	}
+}
+
+// bigIntString formats n in base 10, "0" if it's nil
+func bigIntString(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
+
+// formatFee formats a fee in hundredths of a bip (1e-6) as a percentage
+func formatFee(fee uint32) string {
+	return strconv.FormatFloat(float64(fee)/1e4, 'f', -1, 64) + "%"
 }
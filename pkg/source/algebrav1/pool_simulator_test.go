@@ -1,14 +1,21 @@
 package algebrav1
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
 	"github.com/KyberNetwork/logger"
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -123,6 +130,11 @@ func TestPoolSimulator_CalcAmountOut_SPL(t *testing.T) {
 			assert.Contains(t, err.Error(), ErrSPL.Error())
 		})
 	}
+
+	// no liquidity at the current price, but the swaps of B cross the ticks above it
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: big.NewInt(1e15)}, "A")
+	require.Nil(t, err)
+	assert.Positive(t, res.TokenAmountOut.Amount.Sign())
 }
 
 func TestPoolSimulator_CalcAmountOut_CommFee(t *testing.T) {
@@ -272,3 +284,1300 @@ func TestPoolSimulator_UpdateBalance_DirFee(t *testing.T) {
 		})
 	}
 }
+
+func TestPoolSimulator_UpdateBalance_Sequential(t *testing.T) {
+	ticks := []v3Entities.Tick{
+		{Index: -887220, LiquidityGross: big.NewInt(2822091172725), LiquidityNet: big.NewInt(2822091172725)},
+		{Index: 273540, LiquidityGross: big.NewInt(116315447200034), LiquidityNet: big.NewInt(116315447200034)},
+		{Index: 279120, LiquidityGross: big.NewInt(116315447200034), LiquidityNet: big.NewInt(-116315447200034)},
+		{Index: 285480, LiquidityGross: big.NewInt(2822091172725), LiquidityNet: big.NewInt(-2822091172725)},
+	}
+	newPool := func(reserves entity.PoolReserves, extra Extra) *PoolSimulator {
+		extra.Ticks, extra.TickSpacing = ticks, 60
+		extraBytes, err := json.Marshal(extra)
+		require.Nil(t, err)
+		p, err := NewPoolSimulator(entity.Pool{
+			Reserves: reserves,
+			Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+			Extra:    string(extraBytes),
+		}, 1001)
+		require.Nil(t, err)
+		return p
+	}
+	initialState := GlobalState{
+		Price:              bignumber.NewBig10("93065132232889433968150957834858946"),
+		Tick:               big.NewInt(279543),
+		FeeZto:             500,
+		FeeOtz:             3000,
+		TimepointIndex:     65,
+		CommunityFeeToken0: 100,
+		CommunityFeeToken1: 200,
+		Unlocked:           true,
+	}
+
+	testcases := []struct {
+		first, second pool.TokenAmount
+	}{
+		// same direction, the second swap crosses a tick
+		{pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}, pool.TokenAmount{Token: "A", Amount: big.NewInt(1e8)}},
+		{pool.TokenAmount{Token: "B", Amount: big.NewInt(1e16)}, pool.TokenAmount{Token: "B", Amount: big.NewInt(1e17)}},
+		// opposite directions, back over the crossed tick
+		{pool.TokenAmount{Token: "A", Amount: big.NewInt(1e8)}, pool.TokenAmount{Token: "B", Amount: big.NewInt(1e18)}},
+		{pool.TokenAmount{Token: "B", Amount: big.NewInt(1e17)}, pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p := newPool(entity.PoolReserves{"723924", "36031866872048609640"},
+				Extra{Liquidity: big.NewInt(2822091172725), GlobalState: initialState})
+			tokenOut := func(tokenIn string) string {
+				if tokenIn == "A" {
+					return "B"
+				}
+				return "A"
+			}
+
+			first, err := p.CalcAmountOut(tc.first, tokenOut(tc.first.Token))
+			require.Nil(t, err)
+			p.UpdateBalance(pool.UpdateBalanceParams{
+				TokenAmountIn:  tc.first,
+				TokenAmountOut: *first.TokenAmountOut,
+				Fee:            *first.Fee,
+				SwapInfo:       first.SwapInfo,
+			})
+
+			// the pool as read from the chain after the first swap
+			si := first.SwapInfo.(StateUpdate)
+			assert.Equal(t, initialState.CommunityFeeToken1, si.GlobalState.CommunityFeeToken1)
+			reserves := entity.PoolReserves{p.Info.Reserves[0].String(), p.Info.Reserves[1].String()}
+			expected := newPool(reserves, Extra{
+				Liquidity:            si.Liquidity,
+				GlobalState:          si.GlobalState,
+				TotalFeeGrowth:       &si.TotalFeeGrowth,
+				TickFeeGrowthOutside: si.TickFeeGrowthOutside,
+			})
+			assert.Equal(t, expected.globalState, p.globalState)
+			assert.Equal(t, expected.liquidity, p.liquidity)
+
+			in := bignumber.NewBig10(reserves[p.GetTokenIndex(tc.first.Token)])
+			out := bignumber.NewBig10(reserves[p.GetTokenIndex(tokenOut(tc.first.Token))])
+			if tc.first.Token == "A" {
+				assert.Equal(t, new(big.Int).Add(big.NewInt(723924), tc.first.Amount), in)
+				assert.Equal(t, new(big.Int).Sub(bignumber.NewBig10("36031866872048609640"), first.TokenAmountOut.Amount), out)
+			} else {
+				assert.Equal(t, new(big.Int).Sub(big.NewInt(723924), first.TokenAmountOut.Amount), out)
+			}
+
+			second, err := p.CalcAmountOut(tc.second, tokenOut(tc.second.Token))
+			require.Nil(t, err)
+			expectedSecond, err := expected.CalcAmountOut(tc.second, tokenOut(tc.second.Token))
+			require.Nil(t, err)
+			assert.Equal(t, expectedSecond.TokenAmountOut, second.TokenAmountOut)
+			assert.Equal(t, expectedSecond.Fee, second.Fee)
+			assert.Equal(t, expectedSecond.SwapInfo, second.SwapInfo)
+		})
+	}
+}
+
+func TestPoolSimulator_UpdateBalance_WrongSwapInfo(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}
+	expected, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+
+	for _, swapInfo := range []interface{}{nil, &StateUpdate{}, "swap"} {
+		p.UpdateBalance(pool.UpdateBalanceParams{
+			TokenAmountIn:  in,
+			TokenAmountOut: *expected.TokenAmountOut,
+			SwapInfo:       swapInfo,
+		})
+	}
+
+	// the state is left untouched
+	assert.Equal(t, "2822091172725", p.liquidity.String())
+	assert.Equal(t, "723924", p.Info.Reserves[0].String())
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, expected.TokenAmountOut, res.TokenAmountOut)
+}
+
+func TestPoolSimulator_FeeTier(t *testing.T) {
+	testcases := []struct {
+		feeZto       uint16
+		feeOtz       uint16
+		feeConfigZto *FeeConfiguration
+		feeConfigOtz *FeeConfiguration
+		expected     string
+	}{
+		{500, 500, nil, nil, "0.05%"},
+		{3000, 3000, nil, nil, "0.3%"},
+		{10000, 10000, nil, nil, "1%"},
+		{2985, 2985, nil, nil, "0.2985%"},
+		{100, 3000, nil, nil, "0.01%/0.3%"},
+		// static fee config
+		{500, 500, &FeeConfiguration{BaseFee: 500}, &FeeConfiguration{BaseFee: 500}, "0.05%"},
+		{2985, 2985, &FeeConfiguration{Alpha1: 2900, Alpha2: 12000, BaseFee: 100}, &FeeConfiguration{Alpha1: 2900, Alpha2: 12000, BaseFee: 100}, "dynamic (0.01%-1.5%)"},
+		{100, 3000, &FeeConfiguration{Alpha1: 3000, Alpha2: 15000, BaseFee: 100}, &FeeConfiguration{Alpha1: 2000, Alpha2: 10000, BaseFee: 500}, "dynamic (0.01%-1.81%)"},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p := &PoolSimulator{
+				globalState:  GlobalState{FeeZto: tc.feeZto, FeeOtz: tc.feeOtz},
+				feeConfigZto: tc.feeConfigZto,
+				feeConfigOtz: tc.feeConfigOtz,
+			}
+			assert.Equal(t, tc.expected, p.FeeTier())
+		})
+	}
+}
+
+func TestPoolSimulator_SwapFeeFor(t *testing.T) {
+	p := &PoolSimulator{
+		Pool:        pool.Pool{Info: pool.PoolInfo{Tokens: []string{"A", "B"}}},
+		globalState: GlobalState{FeeZto: 100, FeeOtz: 3000},
+	}
+
+	testcases := []struct {
+		tokenIn     string
+		amountIn    *big.Int
+		expectedFee *big.Int
+		expectedErr error
+	}{
+		{"A", big.NewInt(1000000), big.NewInt(100), nil},
+		{"B", big.NewInt(1000000), big.NewInt(3000), nil},
+		// rounded in favor of the pool
+		{"A", big.NewInt(1), big.NewInt(1), nil},
+		{"B", big.NewInt(12345), big.NewInt(38), nil},
+		{"B", bignumber.NewBig10("1000000000000000000000"), bignumber.NewBig10("3000000000000000000"), nil},
+		{"C", big.NewInt(1000000), nil, ErrInvalidToken},
+		{"A", big.NewInt(0), nil, ErrZeroAmountIn},
+		{"A", nil, nil, ErrZeroAmountIn},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			fee, err := p.SwapFeeFor(tc.tokenIn, tc.amountIn)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedFee, fee)
+		})
+	}
+}
+
+func TestPoolSimulator_VolumeForFeeRevenue(t *testing.T) {
+	p := &PoolSimulator{
+		Pool:        pool.Pool{Info: pool.PoolInfo{Tokens: []string{"A", "B"}}},
+		globalState: GlobalState{FeeZto: 100, FeeOtz: 3000},
+	}
+
+	testcases := []struct {
+		tokenIn        string
+		targetFee      *big.Int
+		expectedVolume *big.Int
+		expectedErr    error
+	}{
+		{"A", big.NewInt(100), big.NewInt(990001), nil},
+		{"B", big.NewInt(3000), big.NewInt(999667), nil},
+		{"A", big.NewInt(1), big.NewInt(1), nil},
+		{"B", big.NewInt(38), big.NewInt(12334), nil},
+		{"B", bignumber.NewBig10("3000000000000000000"), bignumber.NewBig10("999999999999999999667"), nil},
+		{"C", big.NewInt(100), nil, ErrInvalidToken},
+		{"A", big.NewInt(0), nil, ErrInvalidFeeRevenue},
+		{"A", nil, nil, ErrInvalidFeeRevenue},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			volume, err := p.VolumeForFeeRevenue(tc.tokenIn, tc.targetFee)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedVolume, volume)
+
+			// smallest volume charged the target fee
+			fee, err := p.SwapFeeFor(tc.tokenIn, volume)
+			require.Nil(t, err)
+			assert.True(t, fee.Cmp(tc.targetFee) >= 0)
+			if volume.Cmp(big.NewInt(1)) > 0 {
+				fee, err = p.SwapFeeFor(tc.tokenIn, new(big.Int).Sub(volume, big.NewInt(1)))
+				require.Nil(t, err)
+				assert.True(t, fee.Cmp(tc.targetFee) < 0)
+			}
+		})
+	}
+
+	p.globalState.FeeZto = 0
+	_, err := p.VolumeForFeeRevenue("A", big.NewInt(100))
+	assert.ErrorIs(t, err, ErrInvalidFee)
+}
+
+func TestPoolSimulator_DistanceToPriceBoundary(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480
+	p := newComparePool(t, 100, 100)
+	assert.Equal(t, 3, p.DistanceToPriceBoundary(true))
+	assert.Equal(t, 1, p.DistanceToPriceBoundary(false))
+
+	testcases := []struct {
+		tick         int64
+		expectedDown int
+		expectedUp   int
+	}{
+		// a tick at the current tick is crossed when the price goes down
+		{279120, 3, 1},
+		{279119, 2, 2},
+		{285480, 4, 0},
+		{887000, 4, 0},
+		{-887220, 1, 3},
+		{-887221, 0, 4},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p.globalState.Tick = big.NewInt(tc.tick)
+			assert.Equal(t, tc.expectedDown, p.DistanceToPriceBoundary(true))
+			assert.Equal(t, tc.expectedUp, p.DistanceToPriceBoundary(false))
+		})
+	}
+}
+
+func TestPoolSimulator_PriceTick(t *testing.T) {
+	p := newComparePool(t, 100, 100)
+	assert.Equal(t, 279543, p.PriceTick())
+
+	p.globalState.Tick = nil
+	assert.Equal(t, math.MinInt32, p.PriceTick())
+	assert.Equal(t, 0, p.DistanceToPriceBoundary(true))
+	_, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}, "B")
+	assert.ErrorIs(t, err, ErrTickNil)
+}
+
+func TestPoolSimulator_GetEffectiveSqrtPriceLimitX96(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480
+	p := newComparePool(t, 100, 100)
+
+	sqrtPriceAtMinTick, err := v3Utils.GetSqrtRatioAtTick(-887220)
+	require.Nil(t, err)
+	sqrtPriceAtMaxTick, err := v3Utils.GetSqrtRatioAtTick(285480)
+	require.Nil(t, err)
+
+	assert.Equal(t, new(big.Int).Add(sqrtPriceAtMinTick, big.NewInt(1)), p.GetEffectiveSqrtPriceLimitX96("A"))
+	assert.Equal(t, new(big.Int).Sub(sqrtPriceAtMaxTick, big.NewInt(1)), p.GetEffectiveSqrtPriceLimitX96("B"))
+	assert.Nil(t, p.GetEffectiveSqrtPriceLimitX96("C"))
+}
+
+func TestNewPoolSimulator_TickBounds(t *testing.T) {
+	// a deployment with MIN_TICK/MAX_TICK = -/+443636, full range positions are at -/+443580
+	newPool := func(tickBounds string) (*PoolSimulator, error) {
+		return NewPoolSimulator(entity.Pool{
+			Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+			Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+			Extra: fmt.Sprintf(`{"liquidity":954140562773509808028,"globalState":{"price":84125210470736011805469300802,"tick":1199,"feeZto":100,"feeOtz":3000,"timepoint_index":104,"community_fee_token0":150,"community_fee_token1":150,"unlocked":true},"ticks":[{"Index":-443580,"LiquidityGross":954140562773509808028,"LiquidityNet":954140562773509808028},{"Index":443580,"LiquidityGross":954140562773509808028,"LiquidityNet":-954140562773509808028}],"tickSpacing":60%s}`,
+				tickBounds),
+		}, 1001)
+	}
+
+	custom, err := newPool(`,"tickBounds":{"minTick":-443636,"maxTick":443636}`)
+	require.Nil(t, err)
+	standard, err := newPool("")
+	require.Nil(t, err)
+
+	// same swap math within the bounds
+	for _, tc := range [][2]string{{"A", "B"}, {"B", "A"}} {
+		in := pool.TokenAmount{Token: tc[0], Amount: big.NewInt(10000000000)}
+		out, err := custom.CalcAmountOut(in, tc[1])
+		require.Nil(t, err)
+		expected, err := standard.CalcAmountOut(in, tc[1])
+		require.Nil(t, err)
+		assert.Equal(t, expected.TokenAmountOut, out.TokenAmountOut)
+	}
+
+	// the price limit is clamped to the bounds of the deployment
+	minSqrtRatio, err := v3Utils.GetSqrtRatioAtTick(-443636)
+	require.Nil(t, err)
+	err, _, _, _ = custom._calculateSwapAndLock(true, big.NewInt(10000000000), minSqrtRatio)
+	assert.ErrorIs(t, err, ErrSPL)
+	err, _, _, _ = standard._calculateSwapAndLock(true, big.NewInt(10000000000), minSqrtRatio)
+	assert.Nil(t, err)
+
+	// so are the positions
+	_, _, err = custom.AmountsForCurrentPosition(big.NewInt(1e18), -443700, 443700)
+	assert.ErrorIs(t, err, ErrInvalidTickRange)
+	_, _, err = standard.AmountsForCurrentPosition(big.NewInt(1e18), -443700, 443700)
+	assert.Nil(t, err)
+
+	for _, tickBounds := range []string{
+		`,"tickBounds":{"minTick":0,"maxTick":0}`,
+		`,"tickBounds":{"minTick":-900000,"maxTick":443636}`,
+	} {
+		_, err = newPool(tickBounds)
+		assert.ErrorIs(t, err, ErrInvalidTickBounds)
+	}
+}
+
+func TestPoolSimulator_EffectiveFeeAdjustment(t *testing.T) {
+	newPool := func(staticExtra string) *PoolSimulator {
+		p, err := NewPoolSimulator(entity.Pool{
+			Exchange:    "",
+			Type:        "",
+			Reserves:    entity.PoolReserves{"723924", "36031866872048609640"},
+			Tokens:      []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+			Extra:       `{"liquidity":954140562773509808028,"globalState":{"price":84125210470736011805469300802,"tick":1199,"feeZto":100,"feeOtz":3000,"timepoint_index":104,"community_fee_token0":150,"community_fee_token1":150,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":954140562773509808028,"LiquidityNet":954140562773509808028},{"Index":887220,"LiquidityGross":954140562773509808028,"LiquidityNet":-954140562773509808028}],"tickSpacing":60}`,
+			StaticExtra: staticExtra,
+		}, 1001)
+		require.Nil(t, err)
+		return p
+	}
+
+	p, noAdjustment := newPool(`{"effectiveFeeAdjustmentBps":5}`), newPool("")
+
+	// clamped to the fee of the direction: 1bps for A->B, 30bps for B->A
+	assert.Equal(t, FeeBreakdown{SwapFee: 100, EffectiveFeeAdjustmentBps: 1}, p.GetMetaInfo("A", "B").(Meta).Fee)
+	assert.Equal(t, FeeBreakdown{SwapFee: 3000, EffectiveFeeAdjustmentBps: 5}, p.GetMetaInfo("B", "A").(Meta).Fee)
+	assert.Equal(t, FeeBreakdown{SwapFee: 3000}, noAdjustment.GetMetaInfo("B", "A").(Meta).Fee)
+
+	// never changes the swap output
+	for _, tc := range [][2]string{{"A", "B"}, {"B", "A"}} {
+		in := pool.TokenAmount{Token: tc[0], Amount: big.NewInt(10000000000)}
+		out, err := p.CalcAmountOut(in, tc[1])
+		require.Nil(t, err)
+		expected, err := noAdjustment.CalcAmountOut(in, tc[1])
+		require.Nil(t, err)
+		assert.Equal(t, expected.TokenAmountOut, out.TokenAmountOut)
+	}
+
+	p.SetStrictMode(true)
+	assert.Equal(t, FeeBreakdown{SwapFee: 3000}, p.GetMetaInfo("B", "A").(Meta).Fee)
+}
+
+func TestPoolSimulator_GetMetaInfo(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	for _, tc := range [][2]string{{"A", "B"}, {"B", "A"}} {
+		meta := p.GetMetaInfo(tc[0], tc[1]).(Meta)
+		assert.Equal(t, 279543, meta.CurrentTick)
+		// the limit of the simulated swaps
+		assert.Equal(t, p.getSqrtPriceLimit(tc[0] == "A"), meta.PriceLimit)
+		assert.Equal(t, tc[0] == "A", meta.ZeroForOne)
+		assert.Equal(t, 60, meta.TickSpacing)
+	}
+
+	in := pool.TokenAmount{Token: "B", Amount: big.NewInt(1e18)}
+	res, err := p.CalcAmountOut(in, "A")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut, SwapInfo: res.SwapInfo})
+	assert.Equal(t, p.PriceTick(), p.GetMetaInfo("A", "B").(Meta).CurrentTick)
+	assert.NotEqual(t, 279543, p.PriceTick())
+}
+
+func TestPoolSimulator_DeltaTick(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480, current tick 279543
+	p := newComparePool(t, 500, 500)
+
+	testcases := []struct {
+		tokenIn     string
+		amountIn    *big.Int
+		expected    int
+		expectedErr error
+	}{
+		{"A", big.NewInt(1000), 0, nil},
+		{"A", big.NewInt(1000000), -1, nil},
+		{"A", big.NewInt(100000000), -2, nil},
+		{"B", big.NewInt(1e15), 0, nil},
+		// the swaps stop at the last initialized tick, see getSqrtPriceLimit
+		{"B", bignumber.NewBig10("10000000000000000000000000"), 0, nil},
+		{"C", big.NewInt(1000), 0, ErrInvalidToken},
+		{"A", big.NewInt(0), 0, ErrZeroAmountIn},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			delta, err := p.DeltaTick(tc.amountIn, tc.tokenIn)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, delta)
+		})
+	}
+	// below 279120 after a swap going down, crossed again going up
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: big.NewInt(1000000)}, "B")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{SwapInfo: res.SwapInfo})
+	delta, err := p.DeltaTick(bignumber.NewBig10("100000000000000000000"), "B")
+	require.Nil(t, err)
+	assert.Equal(t, 1, delta)
+}
+
+func TestPoolSimulator_CrossedTicksInSwap(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480, current tick 279543
+	p := newComparePool(t, 500, 500)
+
+	testcases := []struct {
+		tokenIn, tokenOut string
+		amountIn          *big.Int
+		expected          []int
+		expectedErr       error
+	}{
+		{"A", "B", big.NewInt(1000), []int{}, nil},
+		{"A", "B", big.NewInt(1000000), []int{279120}, nil},
+		{"A", "B", big.NewInt(100000000), []int{279120, 273540}, nil},
+		{"B", "A", big.NewInt(1e15), []int{}, nil},
+		{"C", "B", big.NewInt(1000), nil, ErrInvalidToken},
+		{"A", "A", big.NewInt(1000), nil, ErrInvalidToken},
+		{"A", "B", big.NewInt(0), nil, ErrZeroAmountIn},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			ticks, err := p.CrossedTicksInSwap(tc.amountIn, tc.tokenIn, tc.tokenOut)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, ticks)
+
+			delta, err := p.DeltaTick(tc.amountIn, tc.tokenIn)
+			require.Nil(t, err)
+			assert.Equal(t, len(ticks), int(math.Abs(float64(delta))))
+		})
+	}
+
+	// crossed again going up, in ascending order
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: big.NewInt(100000000)}, "B")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{SwapInfo: res.SwapInfo})
+	price := new(big.Int).Set(p.globalState.Price)
+	ticks, err := p.CrossedTicksInSwap(bignumber.NewBig10("100000000000000000000"), "B", "A")
+	require.Nil(t, err)
+	assert.Equal(t, []int{273540, 279120}, ticks)
+	// the pool isn't changed
+	assert.Equal(t, price, p.globalState.Price)
+}
+
+func TestPoolSimulator_Capabilities(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	capabilities := pool.CapabilitiesOf(p)
+	assert.Equal(t, pool.Capabilities{
+		CalcAmountIn:  true,
+		GasEstimation: true,
+		PartialFill:   false,
+		Clone:         true,
+		FastPrecision: true,
+		Expiry:        false,
+	}, capabilities)
+
+	var iface pool.IPoolSimulator = p
+	_, ok := iface.(pool.IPoolApproximator)
+	assert.Equal(t, capabilities.FastPrecision, ok)
+	// takes the simulation timestamp for the adaptive fees, but the quotes don't expire
+	_, ok = iface.(pool.IPoolExpirable)
+	assert.True(t, ok)
+	_, ok = iface.(pool.IPoolExactOut)
+	assert.Equal(t, capabilities.CalcAmountIn, ok)
+
+	// far more than the reserve of B, the output is still within it
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("1000000000000000000000")}, "B")
+	require.Nil(t, err)
+	assert.True(t, res.TokenAmountOut.Amount.Cmp(p.Info.Reserves[1]) <= 0)
+	assert.Positive(t, res.Gas)
+}
+
+func TestPoolSimulator_CalcAmountOut_PriceLimitReached(t *testing.T) {
+	// a single range of the liquidity around the current tick
+	p, err := NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"1000000000000", "1000000000000"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":1000000000000,"globalState":{"price":79228162514264337593543950336,"tick":0,"feeZto":500,"feeOtz":500,"timepoint_index":0,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-600,"LiquidityGross":1000000000000,"LiquidityNet":1000000000000},{"Index":600,"LiquidityGross":1000000000000,"LiquidityNet":-1000000000000}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		tokenIn   string
+		amountIn  *big.Int
+		tokenOut  string
+		remaining bool
+	}{
+		{"A", big.NewInt(1e9), "B", false},
+		{"A", bignumber.NewBig10("1000000000000000000000"), "B", true},
+		{"B", big.NewInt(1e9), "A", false},
+		{"B", bignumber.NewBig10("1000000000000000000000"), "A", true},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: tc.tokenIn, Amount: tc.amountIn}
+			res, err := p.CalcAmountOutWithLimit(in, tc.tokenOut, nil)
+			require.Nil(t, err)
+			assert.Equal(t, tc.tokenIn, res.RemainingTokenAmountIn.Token)
+			assert.Equal(t, tc.remaining, res.RemainingTokenAmountIn.Amount.Sign() > 0)
+			assert.True(t, res.RemainingTokenAmountIn.Amount.Cmp(tc.amountIn) < 0)
+
+			strict, err := p.CalcAmountOut(in, tc.tokenOut)
+			if tc.remaining {
+				assert.ErrorIs(t, err, ErrNotEnoughLiquidity)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, res.TokenAmountOut, strict.TokenAmountOut)
+		})
+	}
+}
+
+func TestPoolSimulator_CalcAmountOut_NotEnoughLiquidity(t *testing.T) {
+	newPool := func(tickMax int) *PoolSimulator {
+		p, err := NewPoolSimulator(entity.Pool{
+			Reserves: entity.PoolReserves{"29553010880", "29553010880"},
+			Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+			Extra: fmt.Sprintf(`{"liquidity":1000000000000,"globalState":{"price":79228162514264337593543950336,"tick":0,"feeZto":500,"feeOtz":500,"timepoint_index":0,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-600,"LiquidityGross":1000000000000,"LiquidityNet":1000000000000},{"Index":%d,"LiquidityGross":1000000000000,"LiquidityNet":-1000000000000}],"tickSpacing":60}`,
+				tickMax),
+		}, 1001)
+		require.Nil(t, err)
+		return p
+	}
+	// 10x the liquidity in range
+	amountIn := big.NewInt(1e13)
+
+	p := newPool(600)
+	for idx, tc := range [][2]string{{"A", "B"}, {"B", "A"}} {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: tc[0], Amount: amountIn}
+			res, err := p.CalcAmountOutWithLimit(in, tc[1], nil)
+			require.Nil(t, err)
+			assert.True(t, res.TokenAmountOut.Amount.Cmp(p.Info.Reserves[p.GetTokenIndex(tc[1])]) <= 0)
+			consumed := new(big.Int).Sub(amountIn, res.RemainingTokenAmountIn.Amount)
+			assert.Equal(t, 1, consumed.Sign())
+			assert.Equal(t, -1, consumed.Cmp(amountIn))
+
+			_, err = p.CalcAmountOut(in, tc[1])
+			assert.True(t, errors.Is(err, ErrNotEnoughLiquidity))
+			var notEnoughLiquidity *NotEnoughLiquidityError
+			require.True(t, errors.As(err, &notEnoughLiquidity))
+			assert.Equal(t, tc[0], notEnoughLiquidity.Token)
+			assert.Equal(t, res.RemainingTokenAmountIn.Amount, notEnoughLiquidity.RemainingAmountIn)
+		})
+	}
+
+	// the last initialized tick is above the max tick, there is no price limit for the swaps of B
+	p = newPool(887280)
+	assert.Nil(t, p.GetEffectiveSqrtPriceLimitX96("B"))
+	_, err := p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: amountIn}, "A")
+	assert.ErrorIs(t, err, ErrSPL)
+	_, err = p.DeltaTick(amountIn, "B")
+	assert.ErrorIs(t, err, ErrSPL)
+}
+
+func TestPoolSimulator_GetToken(t *testing.T) {
+	var p pool.IPoolSimulator = newComparePool(t, 500, 500)
+	for idx, token := range p.GetTokens() {
+		assert.Equal(t, idx, p.GetTokenIndex(token))
+		actual, err := p.GetToken(idx)
+		require.Nil(t, err)
+		assert.Equal(t, token, actual)
+	}
+	assert.Equal(t, -1, p.GetTokenIndex("C"))
+
+	_, err := p.GetToken(-1)
+	assert.ErrorIs(t, err, pool.ErrInvalidTokenIndex)
+	_, err = p.GetToken(2)
+	assert.ErrorIs(t, err, pool.ErrInvalidTokenIndex)
+}
+
+func TestPoolSimulator_FormattedState(t *testing.T) {
+	p := newComparePool(t, 100, 3000)
+	p.Info.Address = "0xpool"
+	p.Info.Exchange = "quickswap-v3"
+
+	state := p.FormattedState()
+	assert.Equal(t, map[string]interface{}{
+		"address":      "0xpool",
+		"exchange":     "quickswap-v3",
+		"tokens":       []string{"A", "B"},
+		"reserves":     []string{"723924", "36031866872048609640"},
+		"currentTick":  "279543",
+		"sqrtPriceX96": "93065132232889433968150957834858946",
+		"liquidity":    "2822091172725",
+		"fee":          "0.01%/0.3%",
+		"tickCount":    4,
+		"tickSpacing":  60,
+		"tickMin":      -887220,
+		"tickMax":      285480,
+	}, state)
+
+	_, err := json.Marshal(state)
+	require.Nil(t, err)
+
+	// the state follows the swaps, this one crosses the tick 279120
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: big.NewInt(1000000)}, "B")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  pool.TokenAmount{Token: "A", Amount: big.NewInt(1000000)},
+		TokenAmountOut: *res.TokenAmountOut,
+		Fee:            *res.Fee,
+		SwapInfo:       res.SwapInfo,
+	})
+	state = p.FormattedState()
+	assert.Equal(t, p.globalState.Tick.String(), state["currentTick"])
+	assert.NotEqual(t, "279543", state["currentTick"])
+	assert.NotEqual(t, "2822091172725", state["liquidity"])
+}
+
+func TestPoolSimulator_CalcAmountOutWithLiquidity(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	// same pool with twice the active liquidity at the current tick
+	doubled, err := NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":5644182345450,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":500,"feeOtz":500,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn string
+		tokenOut string
+	}{
+		{"A", "1000", "B"},
+		{"A", "100000", "B"},
+		{"A", "100000000", "B"}, // crosses ticks
+		{"B", "1000000000000000", "A"},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: tc.tokenIn, Amount: bignumber.NewBig10(tc.amountIn)}
+
+			// the current liquidity gives the same result as CalcAmountOut
+			expected, err := p.CalcAmountOut(in, tc.tokenOut)
+			require.Nil(t, err)
+			actual, err := p.CalcAmountOutWithLiquidity(in, tc.tokenOut, big.NewInt(2822091172725))
+			require.Nil(t, err)
+			assert.Equal(t, expected.TokenAmountOut.Amount, actual.TokenAmountOut.Amount)
+
+			expected, err = doubled.CalcAmountOut(in, tc.tokenOut)
+			require.Nil(t, err)
+			actual, err = p.CalcAmountOutWithLiquidity(in, tc.tokenOut, big.NewInt(5644182345450))
+			require.Nil(t, err)
+			assert.Equal(t, expected.TokenAmountOut.Amount, actual.TokenAmountOut.Amount)
+		})
+	}
+
+	// the pool isn't changed
+	assert.Equal(t, big.NewInt(2822091172725), p.liquidity)
+
+	_, err = p.CalcAmountOutWithLiquidity(pool.TokenAmount{Token: "A", Amount: big.NewInt(1000)}, "B", nil)
+	assert.ErrorIs(t, err, ErrInvalidLiquidity)
+	_, err = p.CalcAmountOutWithLiquidity(pool.TokenAmount{Token: "A", Amount: big.NewInt(1000)}, "B", big.NewInt(-1))
+	assert.ErrorIs(t, err, ErrInvalidLiquidity)
+}
+
+func TestPoolSimulator_GetTokenPair(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	token0, token1 := p.GetTokenPair()
+	assert.Equal(t, "A", token0)
+	assert.Equal(t, "B", token1)
+	tokenOut, tokenIn := p.GetTokenPairReversed()
+	assert.Equal(t, "B", tokenOut)
+	assert.Equal(t, "A", tokenIn)
+
+	// same order whatever the order of the tokens of the pool
+	p.Info.Tokens = []string{"B", "A"}
+	token0, token1 = p.GetTokenPair()
+	assert.Equal(t, "A", token0)
+	assert.Equal(t, "B", token1)
+	tokenOut, tokenIn = p.GetTokenPairReversed()
+	assert.Equal(t, "B", tokenOut)
+	assert.Equal(t, "A", tokenIn)
+}
+
+func TestPoolSimulator_CalcAmountIn(t *testing.T) {
+	p := newComparePool(t, 500, 3000)
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn string
+		tokenOut string
+	}{
+		{"A", "1000", "B"},
+		{"A", "1000000", "B"},   // crosses a tick
+		{"A", "100000000", "B"}, // crosses two ticks
+		{"B", "1000000000000000", "A"},
+		{"B", "1000000000000000000", "A"},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			out, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: bignumber.NewBig10(tc.amountIn)}, tc.tokenOut)
+			require.Nil(t, err)
+
+			res, err := p.CalcAmountIn(*out.TokenAmountOut, tc.tokenIn)
+			require.Nil(t, err)
+			require.True(t, res.IsValid())
+			assert.Equal(t, tc.tokenIn, res.TokenAmountIn.Token)
+			assert.Equal(t, tc.tokenIn, res.Fee.Token)
+			assert.Positive(t, res.Fee.Amount.Sign())
+			assert.Positive(t, res.Gas)
+
+			// at most the amount in giving that amount out, and enough to get it back
+			assert.True(t, res.TokenAmountIn.Amount.Cmp(bignumber.NewBig10(tc.amountIn)) <= 0)
+			back, err := p.CalcAmountOut(*res.TokenAmountIn, tc.tokenOut)
+			require.Nil(t, err)
+			assert.True(t, back.TokenAmountOut.Amount.Cmp(out.TokenAmountOut.Amount) >= 0)
+
+			// the swap moves the price to the same tick as the exact input one
+			assert.Equal(t, out.SwapInfo.(StateUpdate).GlobalState.Tick, res.SwapInfo.(StateUpdate).GlobalState.Tick)
+		})
+	}
+
+	// the state update can be applied for the next hops
+	res, err := p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(1000000000000000)}, "A")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  *res.TokenAmountIn,
+		TokenAmountOut: pool.TokenAmount{Token: "B", Amount: big.NewInt(1000000000000000)},
+		Fee:            *res.Fee,
+		SwapInfo:       res.SwapInfo,
+	})
+	assert.Equal(t, res.SwapInfo.(StateUpdate).GlobalState.Price, p.globalState.Price)
+
+	// more than the pool can give before the last initialized tick
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: p.Info.Reserves[1]}, "A")
+	assert.ErrorIs(t, err, ErrAmountOutTooLarge)
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "A", Amount: p.Info.Reserves[0]}, "B")
+	assert.ErrorIs(t, err, ErrAmountOutTooLarge)
+
+	// the ticks could provide it but not the balance of the pool
+	p.Info.Reserves[1] = big.NewInt(1000)
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(1001)}, "A")
+	assert.ErrorIs(t, err, ErrAmountOutTooLarge)
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(1000)}, "A")
+	assert.Nil(t, err)
+
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(0)}, "A")
+	assert.ErrorIs(t, err, ErrZeroAmountOut)
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(1000)}, "B")
+	assert.NotNil(t, err)
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(1000)}, "C")
+	assert.NotNil(t, err)
+}
+
+func TestPoolSimulator_RatioToken0ToToken1(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	ratio, _ := p.RatioToken0ToToken1().Float64()
+	assert.InEpsilon(t, 723924/36031866872048609640.0, ratio, 1e-12)
+
+	p.Info.Reserves = []*big.Int{big.NewInt(1000), big.NewInt(4000)}
+	assert.Equal(t, "0.25", p.RatioToken0ToToken1().Text('f', 2))
+
+	p.Info.Reserves = []*big.Int{big.NewInt(1000), big.NewInt(0)}
+	assert.Nil(t, p.RatioToken0ToToken1())
+}
+
+func TestPoolSimulator_CalcAmountInWithPartialFill(t *testing.T) {
+	p := newComparePool(t, 500, 3000)
+
+	// within the liquidity, same as CalcAmountIn with nothing remaining
+	out := pool.TokenAmount{Token: "B", Amount: big.NewInt(1000000000000000)}
+	expected, err := p.CalcAmountIn(out, "A")
+	require.Nil(t, err)
+	actual, err := p.CalcAmountInWithPartialFill(out, "A")
+	require.Nil(t, err)
+	assert.Equal(t, expected.TokenAmountIn, actual.TokenAmountIn)
+	assert.Equal(t, "B", actual.RemainingTokenAmountOut.Token)
+	assert.Zero(t, actual.RemainingTokenAmountOut.Amount.Sign())
+
+	// above the liquidity, the amount in of all the pool can provide
+	out = pool.TokenAmount{Token: "B", Amount: new(big.Int).Mul(p.Info.Reserves[1], big.NewInt(2))}
+	_, err = p.CalcAmountIn(out, "A")
+	assert.ErrorIs(t, err, ErrAmountOutTooLarge)
+	actual, err = p.CalcAmountInWithPartialFill(out, "A")
+	require.Nil(t, err)
+	require.True(t, actual.IsValid())
+	assert.Equal(t, "B", actual.RemainingTokenAmountOut.Token)
+	assert.Positive(t, actual.RemainingTokenAmountOut.Amount.Sign())
+	filled := new(big.Int).Sub(out.Amount, actual.RemainingTokenAmountOut.Amount)
+	assert.True(t, filled.Cmp(p.Info.Reserves[1]) <= 0)
+
+	// the amount in is enough to get the max amount out back
+	back, err := p.CalcAmountOut(*actual.TokenAmountIn, "B")
+	require.Nil(t, err)
+	assert.True(t, back.TokenAmountOut.Amount.Cmp(filled) >= 0)
+}
+
+func TestPoolSimulator_IsOutOfRange(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	testcases := []struct {
+		tick     int
+		expected bool
+	}{
+		{-887221, true},
+		{-887220, false},
+		{279543, false},
+		{285480, false},
+		{285481, true},
+	}
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.expected, p.IsOutOfRange(tc.tick))
+		})
+	}
+}
+
+func TestPoolSimulator_CalcAmountOut_Fee(t *testing.T) {
+	p := newComparePool(t, 500, 3000)
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn string
+		tokenOut string
+		fee      uint16
+	}{
+		{"A", "1000000", "B", 500},
+		{"A", "100000000", "B", 500}, // crosses ticks, the fee of each step
+		{"B", "1000000000000000", "A", 3000},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			amountIn := bignumber.NewBig10(tc.amountIn)
+			res, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: amountIn}, tc.tokenOut)
+			require.Nil(t, err)
+			assert.Equal(t, tc.tokenIn, res.Fee.Token)
+			require.NotNil(t, res.Fee.Amount)
+
+			// amountIn * fee, rounded up at each step
+			expected := new(big.Int).Div(new(big.Int).Mul(amountIn, big.NewInt(int64(tc.fee))), big.NewInt(1000000))
+			assert.True(t, res.Fee.Amount.Cmp(expected) >= 0)
+			assert.True(t, res.Fee.Amount.Cmp(new(big.Int).Add(expected, big.NewInt(10))) <= 0)
+		})
+	}
+
+	// for a small swap, the amount out is the amount in without the fee at the spot price
+	amountIn := big.NewInt(1000000000000000)
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: amountIn}, "A")
+	require.Nil(t, err)
+	spotPrice := p.spotPrice(false)
+	expected, _ := new(big.Float).Mul(spotPrice, new(big.Float).SetInt(new(big.Int).Sub(amountIn, res.Fee.Amount))).Float64()
+	actual, _ := new(big.Float).SetInt(res.TokenAmountOut.Amount).Float64()
+	assert.InEpsilon(t, expected, actual, 1e-3)
+}
+
+func TestPoolSimulator_CalcAmountOut_Gas(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn string
+		tokenOut string
+		crossed  int64
+	}{
+		{"A", "1000", "B", 0},
+		{"A", "1000000", "B", 1},
+		{"A", "100000000", "B", 2},
+		{"B", "1000000000000000", "A", 0},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			res, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: bignumber.NewBig10(tc.amountIn)}, tc.tokenOut)
+			require.Nil(t, err)
+			assert.Equal(t, 1001+tc.crossed*defaultCrossInitTickGas, res.Gas)
+			assert.Equal(t, int(tc.crossed), res.SwapInfo.(StateUpdate).CrossedTicks)
+
+			in, err := p.CalcAmountIn(*res.TokenAmountOut, tc.tokenIn)
+			require.Nil(t, err)
+			assert.Equal(t, res.Gas, in.Gas)
+		})
+	}
+}
+
+func TestPoolSimulator_HasActiveTick(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480
+	p := newComparePool(t, 500, 500)
+	testcases := []struct {
+		tick          int
+		expectedAbove bool
+		expectedBelow bool
+	}{
+		{-887221, true, false},
+		{-887220, true, false},
+		{-887219, true, true},
+		{279543, true, true},
+		{285479, true, true},
+		{285480, false, true},
+		{887272, false, true},
+	}
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.expectedAbove, p.HasActiveTickAbove(tc.tick))
+			assert.Equal(t, tc.expectedBelow, p.HasActiveTickBelow(tc.tick))
+		})
+	}
+}
+
+func TestPoolSimulator_TicksBetween(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480
+	p := newComparePool(t, 500, 500)
+	testcases := []struct {
+		lower, upper int
+		expected     []int
+	}{
+		{-887272, 887272, []int{-887220, 273540, 279120, 285480}},
+		// the bounds are excluded
+		{-887220, 285480, []int{273540, 279120}},
+		{-887221, 285481, []int{-887220, 273540, 279120, 285480}},
+		{273540, 279120, nil},
+		{279000, 280000, []int{279120}},
+		{285480, 887272, nil},
+		{279120, 273540, nil},
+	}
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.expected, p.TicksBetween(tc.lower, tc.upper))
+		})
+	}
+
+	// the result is a copy
+	ticks := p.TicksBetween(-887272, 887272)
+	ticks[0] = 0
+	assert.Equal(t, -887220, p.TicksBetween(-887272, 887272)[0])
+}
+
+func TestPoolSimulator_NewTickListDataProvider(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	liquidity := big.NewInt(1e12)
+
+	ticks, err := p.NewTickListDataProvider([]v3Entities.Tick{
+		{Index: -887220, LiquidityGross: liquidity, LiquidityNet: liquidity},
+		{Index: 279180, LiquidityGross: liquidity, LiquidityNet: new(big.Int).Neg(liquidity)},
+	})
+	require.Nil(t, err)
+	next, initialized, err := ticks.NextInitializedTickWithinOneWord(279543, true, 60)
+	require.Nil(t, err)
+	assert.Equal(t, 279180, next)
+	assert.True(t, initialized)
+
+	// the ticks must be sorted, on the tick spacing of the pool and their liquidity net must sum to 0
+	for idx, invalid := range [][]v3Entities.Tick{
+		{
+			{Index: 279180, LiquidityGross: liquidity, LiquidityNet: new(big.Int).Neg(liquidity)},
+			{Index: -887220, LiquidityGross: liquidity, LiquidityNet: liquidity},
+		},
+		{
+			{Index: -887220, LiquidityGross: liquidity, LiquidityNet: liquidity},
+			{Index: 279190, LiquidityGross: liquidity, LiquidityNet: new(big.Int).Neg(liquidity)},
+		},
+		{
+			{Index: -887220, LiquidityGross: liquidity, LiquidityNet: liquidity},
+		},
+	} {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			_, err := p.NewTickListDataProvider(invalid)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestPoolSimulator_ComputeBudget(t *testing.T) {
+	defer pool.SetComputeBudget(pool.ComputeBudget{})
+	p := newComparePool(t, 500, 500)
+	// crosses 2 ticks
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e8)}
+
+	pool.SetComputeBudget(pool.ComputeBudget{MaxTickCrossings: 1})
+	_, err := p.CalcAmountOut(in, "B")
+	assert.ErrorIs(t, err, pool.ErrComputeBudgetExceeded)
+
+	pool.SetComputeBudget(pool.ComputeBudget{MaxTickCrossings: 2})
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, 2, res.SwapInfo.(StateUpdate).CrossedTicks)
+}
+
+// boundaryAmountIn returns the smallest exact input moving the price from sqrtPrice exactly to the one of tick:
+// the amount of the swap step, plus its fee rounded up like in ComputeSwapStep
+func boundaryAmountIn(t *testing.T, sqrtPrice *big.Int, tick int, liquidity *big.Int, fee uint16) *big.Int {
+	tickPrice, err := v3Utils.GetSqrtRatioAtTick(tick)
+	require.Nil(t, err)
+	var amountIn *big.Int
+	if tickPrice.Cmp(sqrtPrice) < 0 {
+		amountIn = v3Utils.GetAmount0Delta(tickPrice, sqrtPrice, liquidity, true)
+	} else {
+		amountIn = v3Utils.GetAmount1Delta(sqrtPrice, tickPrice, liquidity, true)
+	}
+	feeAmount := v3Utils.MulDivRoundingUp(amountIn, big.NewInt(int64(fee)), big.NewInt(1e6-int64(fee)))
+	return new(big.Int).Add(amountIn, feeAmount)
+}
+
+// An input exactly moving the price to an initialized tick crosses it, like in the contract: the liquidity is the one
+// beyond the tick and, for zeroForOne, the tick is the one below although the price is the one of the tick. One less
+// stops just before the tick.
+func TestPoolSimulator_CalcAmountOut_TickBoundary(t *testing.T) {
+	// the pool of newComparePool with a tick above the current one, the last tick can't be reached
+	p, err := NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":2985,"feeOtz":2985,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":280020,"LiquidityGross":1000000000000,"LiquidityNet":1000000000000},{"Index":285480,"LiquidityGross":3822091172725,"LiquidityNet":-3822091172725}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+	sqrtPrice := bignumber.NewBig10("93065132232889433968150957834858946")
+	liquidity := big.NewInt(2822091172725)
+
+	testcases := []struct {
+		tokenIn, tokenOut string
+		tick              int
+		delta             int64 // added to the amount reaching the tick
+		expectedTick      int
+		expectedLiquidity string
+		expectedCrossed   int
+	}{
+		{"A", "B", 279120, 0, 279119, "119137538372759", 1},
+		{"A", "B", 279120, -1, 279120, "2822091172725", 0},
+		{"B", "A", 280020, 0, 280020, "3822091172725", 1},
+		{"B", "A", 280020, -1, 280019, "2822091172725", 0},
+	}
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			amountIn := boundaryAmountIn(t, sqrtPrice, tc.tick, liquidity, 2985)
+			amountIn.Add(amountIn, big.NewInt(tc.delta))
+			res, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: amountIn}, tc.tokenOut)
+			require.Nil(t, err)
+
+			si := res.SwapInfo.(StateUpdate)
+			tickPrice, err := v3Utils.GetSqrtRatioAtTick(tc.tick)
+			require.Nil(t, err)
+			assert.Equal(t, tc.delta == 0, si.GlobalState.Price.Cmp(tickPrice) == 0)
+			assert.Equal(t, int64(tc.expectedTick), si.GlobalState.Tick.Int64())
+			assert.Equal(t, tc.expectedLiquidity, si.Liquidity.String())
+			assert.Equal(t, tc.expectedCrossed, si.CrossedTicks)
+
+			// the output of the step up to the price reached, rounded down
+			var expectedOut *big.Int
+			if tc.tokenIn == "A" {
+				expectedOut = v3Utils.GetAmount1Delta(si.GlobalState.Price, sqrtPrice, liquidity, false)
+			} else {
+				expectedOut = v3Utils.GetAmount0Delta(sqrtPrice, si.GlobalState.Price, liquidity, false)
+			}
+			assert.Equal(t, expectedOut, res.TokenAmountOut.Amount)
+		})
+	}
+
+	// a limit price on the tick stops the swap on it, the tick is crossed as well
+	tickPrice, err := v3Utils.GetSqrtRatioAtTick(280020)
+	require.Nil(t, err)
+	err, _, _, _, si := p._calculateSwap(false, big.NewInt(1e18), new(big.Int).Set(tickPrice))
+	require.Nil(t, err)
+	assert.Equal(t, tickPrice, si.GlobalState.Price)
+	assert.Equal(t, int64(280020), si.GlobalState.Tick.Int64())
+	assert.Equal(t, "3822091172725", si.Liquidity.String())
+	assert.Equal(t, 1, si.CrossedTicks)
+}
+
+func TestPoolSimulator_CloneState(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}
+	expected, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	liquidity, price, reserves := new(big.Int).Set(p.liquidity), new(big.Int).Set(p.globalState.Price),
+		[]string{p.Info.Reserves[0].String(), p.Info.Reserves[1].String()}
+
+	// the swaps of concurrent routes, each on its own clone
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cloned := p.CloneState()
+			for j := 0; j < 3; j++ {
+				res, err := cloned.CalcAmountOut(in, "B")
+				if !assert.Nil(t, err) {
+					return
+				}
+				cloned.UpdateBalance(pool.UpdateBalanceParams{
+					TokenAmountIn:  in,
+					TokenAmountOut: *res.TokenAmountOut,
+					Fee:            *res.Fee,
+					SwapInfo:       res.SwapInfo,
+				})
+			}
+			// the first swap crossed the tick 279120
+			assert.NotEqual(t, liquidity, cloned.(*PoolSimulator).liquidity)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, liquidity, p.liquidity)
+	assert.Equal(t, price, p.globalState.Price)
+	assert.Equal(t, reserves, []string{p.Info.Reserves[0].String(), p.Info.Reserves[1].String()})
+	assert.Empty(t, p.tickFeeGrowthOutside)
+	assert.Empty(t, p.crossedFeeGrowthOutside)
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, expected, res)
+}
+
+func BenchmarkPoolSimulator_CloneState(b *testing.B) {
+	// 3000 initialized ticks around the current tick 0
+	ticks := make([]string, 0, 3000)
+	for i := 1500; i > 0; i-- {
+		ticks = append(ticks, fmt.Sprintf(`{"Index":%d,"LiquidityGross":1000000,"LiquidityNet":1000000}`, -i*60))
+	}
+	for i := 1; i <= 1500; i++ {
+		ticks = append(ticks, fmt.Sprintf(`{"Index":%d,"LiquidityGross":1000000,"LiquidityNet":-1000000}`, i*60))
+	}
+	entityPool := entity.Pool{
+		Reserves: entity.PoolReserves{"1000000000000", "1000000000000"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra: fmt.Sprintf(`{"liquidity":1500000000,"globalState":{"price":79228162514264337593543950336,"tick":0,"feeZto":500,"feeOtz":500,"timepoint_index":0,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[%s],"tickSpacing":60}`,
+			strings.Join(ticks, ",")),
+	}
+	p, err := NewPoolSimulator(entityPool, 1001)
+	require.Nil(b, err)
+
+	b.Run("NewPoolSimulator", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewPoolSimulator(entityPool, 1001)
+		}
+	})
+	b.Run("CloneState", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = p.CloneState()
+		}
+	})
+}
+
+func TestPoolSimulator_ZeroLiquidity(t *testing.T) {
+	newPool := func(ticks string) *PoolSimulator {
+		p, err := NewPoolSimulator(entity.Pool{
+			Address:  "0xPool",
+			Reserves: entity.PoolReserves{"0", "0"},
+			Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+			Extra:    `{"liquidity":0,"globalState":{"price":79228162514264337593543950336,"tick":0,"feeZto":500,"feeOtz":500,"timepoint_index":0,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":` + ticks + `,"tickSpacing":60}`,
+		}, 1001)
+		require.Nil(t, err)
+		return p
+	}
+
+	// the ticks of the burnt positions, if the tracker keeps them
+	p := newPool(`[{"Index":-600,"LiquidityGross":0,"LiquidityNet":0},{"Index":600,"LiquidityGross":0,"LiquidityNet":0}]`)
+	assert.True(t, p.zeroLiquidity)
+	_, err := p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: big.NewInt(1e9)}, "A")
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+	assert.Nil(t, p.GetEffectiveSqrtPriceLimitX96("A"))
+
+	// the tracker drops the ticks of LiquidityGross 0, none is left before the first mint
+	p = newPool(`[]`)
+	assert.True(t, p.zeroLiquidity)
+	assert.Equal(t, "0xpool", p.GetAddress())
+
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e9)}
+	_, err = p.CalcAmountOut(in, "B")
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+	_, err = p.CalcAmountOutWithParams(pool.CalcAmountOutParams{
+		TokenAmountIn: in,
+		TokenOut:      "B",
+		Precision:     pool.PrecisionFast,
+	})
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+	_, err = p.CalcAmountIn(pool.TokenAmount{Token: "B", Amount: big.NewInt(1e9)}, "A")
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+	_, err = p.DeltaTick(big.NewInt(1e9), "A")
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+
+	meta := p.GetMetaInfo("A", "B").(Meta)
+	assert.Equal(t, "0xpool", meta.PoolAddress)
+	assert.Nil(t, meta.PriceLimit)
+	assert.True(t, meta.ZeroForOne)
+
+	// a mint around the current price
+	liquidity := big.NewInt(1e12)
+	require.Nil(t, p.InsertTick(v3Entities.Tick{Index: -600, LiquidityGross: liquidity, LiquidityNet: liquidity}))
+	require.Nil(t, p.InsertTick(v3Entities.Tick{Index: 600, LiquidityGross: liquidity, LiquidityNet: new(big.Int).Neg(liquidity)}))
+	p.liquidity = liquidity
+	assert.False(t, p.zeroLiquidity)
+
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Positive(t, res.TokenAmountOut.Amount.Sign())
+	assert.NotNil(t, p.GetMetaInfo("A", "B").(Meta).PriceLimit)
+}
+
+func TestPoolSimulator_CalcAmountOutWithLimit(t *testing.T) {
+	p, err := NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"29553010880", "29553010880"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":1000000000000,"globalState":{"price":79228162514264337593543950336,"tick":0,"feeZto":500,"feeOtz":500,"timepoint_index":0,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-600,"LiquidityGross":1000000000000,"LiquidityNet":1000000000000},{"Index":600,"LiquidityGross":1000000000000,"LiquidityNet":-1000000000000}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+	sqrtPriceAt := func(tick int) *big.Int {
+		price, err := v3Utils.GetSqrtRatioAtTick(tick)
+		require.Nil(t, err)
+		return price
+	}
+	amountIn := big.NewInt(1e13)
+
+	testcases := []struct {
+		tokenIn  string
+		tokenOut string
+		limit    *big.Int
+	}{
+		{"A", "B", sqrtPriceAt(-300)},
+		{"B", "A", sqrtPriceAt(300)},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: tc.tokenIn, Amount: amountIn}
+			full, err := p.CalcAmountOutWithLimit(in, tc.tokenOut, nil)
+			require.Nil(t, err)
+
+			// stops at the limit, halfway to the last initialized tick
+			limited, err := p.CalcAmountOutWithLimit(in, tc.tokenOut, tc.limit)
+			require.Nil(t, err)
+			assert.Equal(t, tc.limit, limited.SwapInfo.(StateUpdate).GlobalState.Price)
+			assert.Equal(t, -1, limited.TokenAmountOut.Amount.Cmp(full.TokenAmountOut.Amount))
+			assert.Equal(t, 1, limited.RemainingTokenAmountIn.Amount.Cmp(full.RemainingTokenAmountIn.Amount))
+
+			// the partial fill is the swap of the amount consumed
+			consumed := new(big.Int).Sub(amountIn, limited.RemainingTokenAmountIn.Amount)
+			partial, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: consumed}, tc.tokenOut)
+			require.Nil(t, err)
+			assert.Equal(t, limited.TokenAmountOut, partial.TokenAmountOut)
+
+			// only the amount consumed is added to the reserve
+			updated := p.clone()
+			updated.UpdateBalance(pool.UpdateBalanceParams{
+				TokenAmountIn:  in,
+				TokenAmountOut: *limited.TokenAmountOut,
+				SwapInfo:       limited.SwapInfo,
+			})
+			tokenInIndex := p.GetTokenIndex(tc.tokenIn)
+			assert.Equal(t, new(big.Int).Add(p.Info.Reserves[tokenInIndex], consumed), updated.Info.Reserves[tokenInIndex])
+
+			// beyond the last initialized tick is the same as nil
+			for _, limit := range []*big.Int{nil, v3Utils.MinSqrtRatio, v3Utils.MaxSqrtRatio} {
+				res, err := p.CalcAmountOutWithLimit(in, tc.tokenOut, limit)
+				if err != nil {
+					// MinSqrtRatio is on the wrong side of the current price for B and MaxSqrtRatio for A
+					assert.ErrorIs(t, err, ErrSPL)
+					continue
+				}
+				assert.Equal(t, full.TokenAmountOut, res.TokenAmountOut)
+			}
+		})
+	}
+
+	// on the wrong side of the current price
+	_, err = p.CalcAmountOutWithLimit(pool.TokenAmount{Token: "A", Amount: amountIn}, "B", sqrtPriceAt(300))
+	assert.ErrorIs(t, err, ErrSPL)
+	_, err = p.CalcAmountOutWithLimit(pool.TokenAmount{Token: "B", Amount: amountIn}, "A", sqrtPriceAt(-300))
+	assert.ErrorIs(t, err, ErrSPL)
+}
@@ -7,22 +7,29 @@ import (
 	"time"
 
 	"github.com/KyberNetwork/ethrpc"
-	"github.com/KyberNetwork/logger"
 	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/machinebox/graphql"
+	cmap "github.com/orcaman/concurrent-map"
 	"github.com/sourcegraph/conc/pool"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
 	graphqlPkg "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/graphql"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolTracker struct {
 	config        *Config
 	ethrpcClient  *ethrpc.Client
 	graphqlClient *graphql.Client
+
+	// pools whose ticks must be read from TickLens on the next update, see clmath.ReconcileReserves
+	forceRefresh cmap.ConcurrentMap
+	// number of updates in a row each field of a pool has failed, see reuseStaleFields
+	staleFields cmap.ConcurrentMap
 }
 
 func NewPoolTracker(
@@ -35,6 +42,8 @@ func NewPoolTracker(
 		config:        cfg,
 		ethrpcClient:  ethrpcClient,
 		graphqlClient: graphqlClient,
+		forceRefresh:  cmap.New(),
+		staleFields:   cmap.New(),
 	}, nil
 }
 
@@ -46,8 +55,8 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 		poolTicks []TickResp
 	)
 
-	g := pool.New().WithContext(ctx)
-	g.Go(func(context.Context) error {
+	if d.config.IsRPCOnly() || clmath.TakeForceRefresh(d.forceRefresh, p.Address) {
+		// TickLens needs the tick spacing, so ticks can only be fetched after the rpc data
 		var err error
 		rpcData, err = d.fetchRPCData(ctx, p)
 		if err != nil {
@@ -55,30 +64,52 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 				"poolAddress": p.Address,
 				"error":       err,
 			}).Errorf("failed to fetch data from RPC")
-
+			return entity.Pool{}, err
 		}
 
-		return err
-	})
-	g.Go(func(context.Context) error {
-		var err error
-		poolTicks, err = d.getPoolTicks(ctx, p.Address)
+		poolTicks, err = d.getPoolTicksFromSC(ctx, p.Address, int(rpcData.tickSpacing.Int64()))
 		if err != nil {
 			logger.WithFields(logger.Fields{
 				"poolAddress": p.Address,
 				"error":       err,
-			}).Errorf("failed to query subgraph for pool ticks")
+			}).Errorf("failed to call SC for pool ticks")
+			return entity.Pool{}, err
 		}
+	} else {
+		g := pool.New().WithContext(ctx)
+		g.Go(func(context.Context) error {
+			var err error
+			rpcData, err = d.fetchRPCData(ctx, p)
+			if err != nil {
+				logger.WithFields(logger.Fields{
+					"poolAddress": p.Address,
+					"error":       err,
+				}).Errorf("failed to fetch data from RPC")
 
-		return err
-	})
+			}
 
-	if err := g.Wait(); err != nil {
-		logger.WithFields(logger.Fields{
-			"poolAddress": p.Address,
-			"error":       err,
-		}).Errorf("failed to fetch pool state, pool: %v, err: %v", p.Address, err)
-		return entity.Pool{}, err
+			return err
+		})
+		g.Go(func(context.Context) error {
+			var err error
+			poolTicks, err = d.getPoolTicks(ctx, p.Address)
+			if err != nil {
+				logger.WithFields(logger.Fields{
+					"poolAddress": p.Address,
+					"error":       err,
+				}).Errorf("failed to query subgraph for pool ticks")
+			}
+
+			return err
+		})
+
+		if err := g.Wait(); err != nil {
+			logger.WithFields(logger.Fields{
+				"poolAddress": p.Address,
+				"error":       err,
+			}).Errorf("failed to fetch pool state, pool: %v, err: %v", p.Address, err)
+			return entity.Pool{}, err
+		}
 	}
 
 	ticks := make([]v3Entities.Tick, 0, len(poolTicks))
@@ -100,11 +131,23 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 		ticks = append(ticks, tick)
 	}
 
+	reserveDrift := clmath.ReconcileReserves(d.config.DexID, p.Address, tickLiquidities(ticks), rpcData.state.Price,
+		rpcData.reserve0, rpcData.reserve1, d.config.ReserveDriftThreshold, d.flagForceRefresh)
+
 	extraBytes, err := json.Marshal(Extra{
 		Liquidity:   rpcData.liquidity,
 		GlobalState: rpcData.state,
 		Ticks:       ticks,
 		TickSpacing: int24(rpcData.tickSpacing.Int64()),
+
+		FeeConfigZto: rpcData.feeConfigZto,
+		FeeConfigOtz: rpcData.feeConfigOtz,
+
+		Timepoints:                rpcData.timepoints,
+		VolumePerLiquidityInBlock: rpcData.volumePerLiquidityInBlock,
+
+		ReserveDrift: reserveDrift,
+		TickBounds:   d.config.TickBounds,
 	})
 
 	if err != nil {
@@ -115,6 +158,18 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 		return entity.Pool{}, err
 	}
 
+	if bps, ok := d.config.EffectiveFeeAdjustmentBps[p.Address]; ok {
+		staticExtraBytes, err := json.Marshal(StaticExtra{EffectiveFeeAdjustmentBps: bps})
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"poolAddress": p.Address,
+				"error":       err,
+			}).Errorf("failed to marshal static extra data")
+			return entity.Pool{}, err
+		}
+		p.StaticExtra = string(staticExtraBytes)
+	}
+
 	p.Extra = string(extraBytes)
 	p.Timestamp = time.Now().Unix()
 	p.Reserves = entity.PoolReserves{
@@ -143,25 +198,7 @@ func (d *PoolTracker) fetchRPCData(ctx context.Context, p entity.Pool) (FetchRPC
 		Params: nil,
 	}, []interface{}{&res.liquidity})
 
-	// the globalstate abi are slightly different across versions
-	var rpcState interface{}
-	if d.config.UseDirectionalFee {
-		rpcState = &rpcGlobalStateDirFee{}
-		rpcRequest.AddCall(&ethrpc.Call{
-			ABI:    algebraV1DirFeePoolABI,
-			Target: p.Address,
-			Method: methodGetGlobalState,
-			Params: nil,
-		}, []interface{}{rpcState})
-	} else {
-		rpcState = &rpcGlobalStateSingleFee{}
-		rpcRequest.AddCall(&ethrpc.Call{
-			ABI:    algebraV1PoolABI,
-			Target: p.Address,
-			Method: methodGetGlobalState,
-			Params: nil,
-		}, []interface{}{rpcState})
-	}
+	rpcState := addGlobalStateCall(rpcRequest, p.Address, d.config.UseDirectionalFee)
 
 	rpcRequest.AddCall(&ethrpc.Call{
 		ABI:    algebraV1PoolABI,
@@ -177,6 +214,7 @@ func (d *PoolTracker) fetchRPCData(ctx context.Context, p entity.Pool) (FetchRPC
 		Params: nil,
 	}, []interface{}{&res.tickSpacing})
 
+	fields := []rpcField{fieldLiquidity, fieldGlobalState, fieldDataStorageOperator, fieldTickSpacing}
 	if len(p.Tokens) == 2 {
 		rpcRequest.AddCall(&ethrpc.Call{
 			ABI:    erc20ABI,
@@ -191,9 +229,11 @@ func (d *PoolTracker) fetchRPCData(ctx context.Context, p entity.Pool) (FetchRPC
 			Method: erc20MethodBalanceOf,
 			Params: []interface{}{common.HexToAddress(p.Address)},
 		}, []interface{}{&res.reserve1})
+		fields = append(fields, fieldReserve0, fieldReserve1)
 	}
 
-	_, err := rpcRequest.Aggregate()
+	// each field is a call of its own, a call failing doesn't fail the others
+	resp, err := rpcRequest.TryAggregate()
 	if err != nil {
 		logger.WithFields(logger.Fields{
 			"poolAddress": p.Address,
@@ -202,35 +242,30 @@ func (d *PoolTracker) fetchRPCData(ctx context.Context, p entity.Pool) (FetchRPC
 		return res, err
 	}
 
-	if d.config.UseDirectionalFee {
-		rpcStateRes := rpcState.(*rpcGlobalStateDirFee)
-		res.state = GlobalState{
-			Price:              rpcStateRes.Price,
-			Tick:               rpcStateRes.Tick,
-			FeeZto:             rpcStateRes.FeeZto,
-			FeeOtz:             rpcStateRes.FeeOtz,
-			TimepointIndex:     rpcStateRes.TimepointIndex,
-			CommunityFeeToken0: uint16(rpcStateRes.CommunityFeeToken0),
-			CommunityFeeToken1: uint16(rpcStateRes.CommunityFeeToken1),
-			Unlocked:           rpcStateRes.Unlocked,
-		}
-	} else {
-		// for v1 without directional fee, we'll use Fee for both FeeZto/FeeOtz
-		rpcStateRes := rpcState.(*rpcGlobalStateSingleFee)
-		res.state = GlobalState{
-			Price:              rpcStateRes.Price,
-			Tick:               rpcStateRes.Tick,
-			FeeZto:             rpcStateRes.Fee,
-			FeeOtz:             rpcStateRes.Fee,
-			TimepointIndex:     rpcStateRes.TimepointIndex,
-			CommunityFeeToken0: rpcStateRes.CommunityFeeToken0,
-			CommunityFeeToken1: rpcStateRes.CommunityFeeToken1,
-			Unlocked:           rpcStateRes.Unlocked,
+	var failed []rpcField
+	for i, field := range fields {
+		if i >= len(resp.Result) || !resp.Result[i] {
+			failed = append(failed, field)
 		}
 	}
 
-	if !d.config.SkipFeeCalculating {
-		err = d.approximateFee(ctx, p.Address, dataStorageOperator.Hex(), &res.state, res.liquidity)
+	if len(resp.Result) > 1 && resp.Result[1] {
+		res.state = toGlobalState(rpcState)
+	}
+
+	reused, err := d.reuseStaleFields(p, &res, failed)
+	if err != nil {
+		logger.WithFields(logger.Fields{
+			"poolAddress": p.Address,
+			"fields":      failed,
+			"error":       err,
+		}).Errorf("failed to fetch the fields of the pool state")
+		return res, err
+	}
+
+	// the fee is approximated from a fresh state only, otherwise the fee of the last block is used
+	if !d.config.SkipFeeCalculating && !hasFeeInput(reused) {
+		err = d.approximateFee(ctx, p.Address, dataStorageOperator.Hex(), &res)
 		if err != nil {
 			return res, err
 		}
@@ -245,7 +280,9 @@ func (d *PoolTracker) fetchRPCData(ctx context.Context, p entity.Pool) (FetchRPC
 	return res, err
 }
 
-func (d *PoolTracker) approximateFee(ctx context.Context, poolAddress, dataStorageOperator string, state *GlobalState, currentLiquidity *big.Int) error {
+func (d *PoolTracker) approximateFee(ctx context.Context, poolAddress, dataStorageOperator string, res *FetchRPCResult) error {
+	state, currentLiquidity := &res.state, res.liquidity
+
 	// fee approximation: assume that the swap will be soon after this
 	blockTimestamp := uint32(time.Now().Unix())
 	yesterday := blockTimestamp - WINDOW
@@ -281,38 +318,17 @@ func (d *PoolTracker) approximateFee(ctx context.Context, poolAddress, dataStora
 		return err
 	}
 
-	ts := TimepointStorage{
-		data:    timepoints,
-		updates: map[uint16]Timepoint{},
+	if d.config.UseDirectionalFee {
+		res.feeConfigZto, res.feeConfigOtz = &feeConfZto, &feeConfOtz
+	} else {
+		res.feeConfigZto, res.feeConfigOtz = &feeConf, &feeConf
 	}
-	currentTick := int24(state.Tick.Int64())
-	newTimepointIndex, err := ts.write(
-		state.TimepointIndex,
-		blockTimestamp,
-		currentTick,
-		currentLiquidity,
-		volumePerLiquidityInBlock,
-	)
+	res.timepoints, res.volumePerLiquidityInBlock = timepoints, volumePerLiquidityInBlock
+	state.FeeZto, state.FeeOtz, err = getNewFees(timepoints, *state, currentLiquidity, blockTimestamp,
+		volumePerLiquidityInBlock, res.feeConfigZto, res.feeConfigOtz)
 	if err != nil {
 		return err
 	}
-
-	if d.config.UseDirectionalFee {
-		state.FeeZto, err = ts._getNewFee(blockTimestamp, currentTick, newTimepointIndex, currentLiquidity, &feeConfZto)
-		if err != nil {
-			return err
-		}
-		state.FeeOtz, err = ts._getNewFee(blockTimestamp, currentTick, newTimepointIndex, currentLiquidity, &feeConfOtz)
-		if err != nil {
-			return err
-		}
-	} else {
-		state.FeeZto, err = ts._getNewFee(blockTimestamp, currentTick, newTimepointIndex, currentLiquidity, &feeConf)
-		if err != nil {
-			return err
-		}
-		state.FeeOtz = state.FeeZto
-	}
 	return nil
 }
 
@@ -596,3 +612,70 @@ func (d *PoolTracker) getPoolTicks(ctx context.Context, poolAddress string) ([]T
 
 	return ticks, nil
 }
+
+// addGlobalStateCall adds the globalState call of the pool to the request, the abi is slightly different across
+// versions. The result is converted by toGlobalState once the request is done.
+func addGlobalStateCall(rpcRequest *ethrpc.Request, poolAddress string, useDirectionalFee bool) interface{} {
+	if useDirectionalFee {
+		rpcState := &rpcGlobalStateDirFee{}
+		rpcRequest.AddCall(&ethrpc.Call{
+			ABI:    algebraV1DirFeePoolABI,
+			Target: poolAddress,
+			Method: methodGetGlobalState,
+			Params: nil,
+		}, []interface{}{rpcState})
+		return rpcState
+	}
+
+	rpcState := &rpcGlobalStateSingleFee{}
+	rpcRequest.AddCall(&ethrpc.Call{
+		ABI:    algebraV1PoolABI,
+		Target: poolAddress,
+		Method: methodGetGlobalState,
+		Params: nil,
+	}, []interface{}{rpcState})
+	return rpcState
+}
+
+func toGlobalState(rpcState interface{}) GlobalState {
+	switch rpcStateRes := rpcState.(type) {
+	case *rpcGlobalStateDirFee:
+		return GlobalState{
+			Price:              rpcStateRes.Price,
+			Tick:               rpcStateRes.Tick,
+			FeeZto:             rpcStateRes.FeeZto,
+			FeeOtz:             rpcStateRes.FeeOtz,
+			TimepointIndex:     rpcStateRes.TimepointIndex,
+			CommunityFeeToken0: uint16(rpcStateRes.CommunityFeeToken0),
+			CommunityFeeToken1: uint16(rpcStateRes.CommunityFeeToken1),
+			Unlocked:           rpcStateRes.Unlocked,
+		}
+	case *rpcGlobalStateSingleFee:
+		// for v1 without directional fee, we'll use Fee for both FeeZto/FeeOtz
+		return GlobalState{
+			Price:              rpcStateRes.Price,
+			Tick:               rpcStateRes.Tick,
+			FeeZto:             rpcStateRes.Fee,
+			FeeOtz:             rpcStateRes.Fee,
+			TimepointIndex:     rpcStateRes.TimepointIndex,
+			CommunityFeeToken0: rpcStateRes.CommunityFeeToken0,
+			CommunityFeeToken1: rpcStateRes.CommunityFeeToken1,
+			Unlocked:           rpcStateRes.Unlocked,
+		}
+	default:
+		return GlobalState{}
+	}
+}
+
+// flagForceRefresh flags the pool to read its ticks from TickLens on the next update
+func (d *PoolTracker) flagForceRefresh(poolAddress string) error {
+	return clmath.FlagForceRefresh(d.forceRefresh, poolAddress, d.config.TickLensAddress)
+}
+
+func tickLiquidities(ticks []v3Entities.Tick) []clmath.TickLiquidity {
+	tickLiquidities := make([]clmath.TickLiquidity, 0, len(ticks))
+	for _, tick := range ticks {
+		tickLiquidities = append(tickLiquidities, clmath.TickLiquidity{Index: tick.Index, LiquidityNet: tick.LiquidityNet})
+	}
+	return tickLiquidities
+}
@@ -0,0 +1,138 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// AmountsForCurrentPosition returns the token0/token1 amounts that a position with `positionLiquidity`
+// in [tickLower, tickUpper) represents at the current pool price (rounded down, as when burning the position)
+func (p *PoolSimulator) AmountsForCurrentPosition(positionLiquidity *big.Int, tickLower, tickUpper int) (amount0, amount1 *big.Int, err error) {
+	if positionLiquidity == nil || positionLiquidity.Sign() < 0 {
+		return nil, nil, ErrInvalidLiquidity
+	}
+	if tickLower >= tickUpper || tickLower < p.tickBounds.MinTick || tickUpper > p.tickBounds.MaxTick {
+		return nil, nil, ErrInvalidTickRange
+	}
+
+	sqrtRatioLower, err := v3Utils.GetSqrtRatioAtTick(tickLower)
+	if err != nil {
+		return nil, nil, err
+	}
+	sqrtRatioUpper, err := v3Utils.GetSqrtRatioAtTick(tickUpper)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentTick := p.PriceTick()
+	currentPrice := p.globalState.Price
+
+	if currentTick < tickLower {
+		// position is entirely in token0
+		return v3Utils.GetAmount0Delta(sqrtRatioLower, sqrtRatioUpper, positionLiquidity, false), new(big.Int).Set(bignumber.ZeroBI), nil
+	}
+	if currentTick < tickUpper {
+		return v3Utils.GetAmount0Delta(currentPrice, sqrtRatioUpper, positionLiquidity, false),
+			v3Utils.GetAmount1Delta(sqrtRatioLower, currentPrice, positionLiquidity, false),
+			nil
+	}
+	// position is entirely in token1
+	return new(big.Int).Set(bignumber.ZeroBI), v3Utils.GetAmount1Delta(sqrtRatioLower, sqrtRatioUpper, positionLiquidity, false), nil
+}
+
+// LiquidityTokenAmounts returns the token0/token1 amounts backing the active liquidity at the current price, i.e. the
+// amounts of the liquidity of the range of the current tick, delimited by the initialized ticks around it. Both are 0
+// if the price is out of the initialized ticks.
+func (p *PoolSimulator) LiquidityTokenAmounts() ([]*big.Int, error) {
+	currentTick := p.PriceTick()
+	lower, hasLower := p.ticks.LastAtOrBelow(currentTick)
+	upper, hasUpper := p.ticks.FirstAbove(currentTick)
+	if !hasLower || !hasUpper {
+		return []*big.Int{new(big.Int), new(big.Int)}, nil
+	}
+
+	amount0, amount1, err := p.AmountsForCurrentPosition(p.liquidity, lower.Index, upper.Index)
+	if err != nil {
+		return nil, err
+	}
+	return []*big.Int{amount0, amount1}, nil
+}
+
+// LiquidityForOneTick returns the liquidity active in the range of the current tick, i.e. up to the next initialized
+// tick in both directions
+func (p *PoolSimulator) LiquidityForOneTick() *big.Int {
+	return new(big.Int).Set(p.liquidity)
+}
+
+// LiquidityForTickRange returns the net active liquidity of [tickLower, tickUpper): the liquidity active at tickLower,
+// derived from the liquidityNet of the ticks crossed to reach it from the current tick, plus the liquidityNet of the
+// initialized ticks crossed from tickLower up to tickUpper, i.e. the liquidity active at the top of the range.
+func (p *PoolSimulator) LiquidityForTickRange(tickLower, tickUpper int) (*big.Int, error) {
+	if tickLower >= tickUpper || tickLower < p.tickBounds.MinTick || tickUpper > p.tickBounds.MaxTick {
+		return nil, ErrInvalidTickRange
+	}
+
+	// active liquidity at tickLower: the ticks <= tick are crossed (liquidityNet added) when the price is at tick
+	currentTick := p.PriceTick()
+	liquidity := new(big.Int).Set(p.liquidity)
+	from, to, up := currentTick, tickLower, true
+	if tickLower < currentTick {
+		from, to, up = tickLower, currentTick, false
+	}
+	p.ticks.Ascend(from+1, func(tick v3Entities.Tick) bool {
+		if tick.Index > to {
+			return false
+		}
+		if up {
+			liquidity.Add(liquidity, tick.LiquidityNet)
+		} else {
+			liquidity.Sub(liquidity, tick.LiquidityNet)
+		}
+		return true
+	})
+
+	// then the ticks within the range
+	p.ticks.Ascend(tickLower+1, func(tick v3Entities.Tick) bool {
+		if tick.Index >= tickUpper {
+			return false
+		}
+		liquidity.Add(liquidity, tick.LiquidityNet)
+		return true
+	})
+	if liquidity.Sign() < 0 {
+		return nil, ErrInvalidLiquidity
+	}
+	return liquidity, nil
+}
+
+// GetLiquidityForPriceRange is LiquidityForTickRange between the ticks of two sqrt prices, e.g. for a depth chart.
+// The range is clamped to the initialized ticks [tickMin, tickMax], there is no liquidity out of them, so a range
+// without initialized ticks returns 0.
+func (p *PoolSimulator) GetLiquidityForPriceRange(sqrtPriceLower, sqrtPriceUpper *big.Int) (*big.Int, error) {
+	if sqrtPriceLower == nil || sqrtPriceUpper == nil || sqrtPriceLower.Cmp(sqrtPriceUpper) >= 0 {
+		return nil, ErrInvalidTickRange
+	}
+	tickLower, err := v3Utils.GetTickAtSqrtRatio(sqrtPriceLower)
+	if err != nil {
+		return nil, err
+	}
+	tickUpper, err := v3Utils.GetTickAtSqrtRatio(sqrtPriceUpper)
+	if err != nil {
+		return nil, err
+	}
+
+	if tickLower < p.tickMin {
+		tickLower = p.tickMin
+	}
+	if tickUpper > p.tickMax {
+		tickUpper = p.tickMax
+	}
+	if p.zeroLiquidity || tickLower >= tickUpper {
+		return new(big.Int), nil
+	}
+	return p.LiquidityForTickRange(tickLower, tickUpper)
+}
@@ -0,0 +1,193 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSimulator_AmountsForCurrentPosition(t *testing.T) {
+	// same pool as TestPoolSimulator_CalcAmountOut, current tick is 279543
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange: "",
+		Type:     "",
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":2822091172725,"globalState":{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":2985,"feeOtz":2985,"timepoint_index":65,"community_fee_token0":0,"community_fee_token1":0,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+	}, 1001)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		tickLower       int
+		tickUpper       int
+		expectedAmount0 string
+		expectedAmount1 string
+	}{
+		{273540, 285480, "218630191423", "304588206018378430022332"}, // in range
+		{273540, 279120, "0", "279975303306798717623832"},            // below current tick
+		{285480, 887220, "632689315822", "0"},                        // above current tick
+	}
+	liquidity := bignumber.NewBig10("1000000000000000000")
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			amount0, amount1, err := p.AmountsForCurrentPosition(liquidity, tc.tickLower, tc.tickUpper)
+			require.Nil(t, err)
+			assert.Equal(t, bignumber.NewBig10(tc.expectedAmount0), amount0)
+			assert.Equal(t, bignumber.NewBig10(tc.expectedAmount1), amount1)
+		})
+	}
+
+	_, _, err = p.AmountsForCurrentPosition(liquidity, 285480, 273540)
+	assert.ErrorIs(t, err, ErrInvalidTickRange)
+	_, _, err = p.AmountsForCurrentPosition(big.NewInt(-1), 273540, 285480)
+	assert.ErrorIs(t, err, ErrInvalidLiquidity)
+}
+
+func TestPoolSimulator_LiquidityForTickRange(t *testing.T) {
+	// initialized ticks: -887220 (+2822091172725), 273540 (+116315447200034), 279120 (-116315447200034),
+	// 285480 (-2822091172725), current tick 279543
+	p := newComparePool(t, 500, 500)
+	assert.Equal(t, big.NewInt(2822091172725), p.LiquidityForOneTick())
+
+	testcases := []struct {
+		tickLower   int
+		tickUpper   int
+		expected    int64
+		expectedErr error
+	}{
+		{279200, 279600, 2822091172725, nil},
+		{274000, 275000, 119137538372759, nil},
+		{273540, 279120, 119137538372759, nil},
+		{273000, 280000, 2822091172725, nil},
+		{285480, 290000, 0, nil},
+		{-887272, 887272, 0, nil},
+		{-887272, -887220, 0, nil},
+		{279600, 279600, 0, ErrInvalidTickRange},
+		{-887273, 0, 0, ErrInvalidTickRange},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			liquidity, err := p.LiquidityForTickRange(tc.tickLower, tc.tickUpper)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, big.NewInt(tc.expected).String(), liquidity.String())
+		})
+	}
+}
+
+func TestPoolSimulator_LiquidityForTickRange_TicksWithinRange(t *testing.T) {
+	// the ticks of TestPoolSimulator_LiquidityForTickRange and 280020 (+1e12), 280980 (+2e12), 282000 (-1e12) above
+	// the current tick, the active liquidity is unchanged
+	p := newComparePool(t, 500, 500)
+	for _, tick := range []v3Entities.Tick{
+		{Index: 280020, LiquidityGross: big.NewInt(1e12), LiquidityNet: big.NewInt(1e12)},
+		{Index: 280980, LiquidityGross: big.NewInt(2e12), LiquidityNet: big.NewInt(2e12)},
+		{Index: 282000, LiquidityGross: big.NewInt(1e12), LiquidityNet: big.NewInt(-1e12)},
+	} {
+		require.Nil(t, p.InsertTick(tick))
+	}
+
+	testcases := []struct {
+		tickLower int
+		tickUpper int
+		expected  int64
+	}{
+		// the three ticks are crossed, the sum of the ranges would be 17288364690900
+		{279600, 285000, 4822091172725},
+		{279600, 280980, 3822091172725},
+		{280020, 282000, 5822091172725},
+		// down to the ticks below the current tick
+		{273000, 285000, 4822091172725},
+		{280980, 290000, 2000000000000},
+		{285480, 290000, 2000000000000},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			liquidity, err := p.LiquidityForTickRange(tc.tickLower, tc.tickUpper)
+			require.Nil(t, err)
+			assert.Equal(t, big.NewInt(tc.expected).String(), liquidity.String())
+		})
+	}
+}
+
+func TestPoolSimulator_GetLiquidityForPriceRange(t *testing.T) {
+	// same ticks as TestPoolSimulator_LiquidityForTickRange
+	p := newComparePool(t, 500, 500)
+	sqrtPriceAt := func(tick int) *big.Int {
+		price, err := v3Utils.GetSqrtRatioAtTick(tick)
+		require.Nil(t, err)
+		return price
+	}
+
+	testcases := []struct {
+		sqrtPriceLower *big.Int
+		sqrtPriceUpper *big.Int
+		expected       int64
+		expectedErr    error
+	}{
+		{sqrtPriceAt(279200), sqrtPriceAt(279600), 2822091172725, nil},
+		{sqrtPriceAt(274000), sqrtPriceAt(275000), 119137538372759, nil},
+		{sqrtPriceAt(273000), sqrtPriceAt(280000), 2822091172725, nil},
+		// clamped to [-887220, 285480]
+		{v3Utils.MinSqrtRatio, sqrtPriceAt(887220), 2822091172725, nil},
+		{sqrtPriceAt(279200), sqrtPriceAt(290000), 2822091172725, nil},
+		// above the last initialized tick
+		{sqrtPriceAt(285480), sqrtPriceAt(290000), 0, nil},
+		{sqrtPriceAt(286000), sqrtPriceAt(290000), 0, nil},
+		{sqrtPriceAt(279600), sqrtPriceAt(279600), 0, ErrInvalidTickRange},
+		{nil, sqrtPriceAt(279600), 0, ErrInvalidTickRange},
+		{big.NewInt(1), sqrtPriceAt(279600), 0, v3Utils.ErrInvalidSqrtRatio},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			liquidity, err := p.GetLiquidityForPriceRange(tc.sqrtPriceLower, tc.sqrtPriceUpper)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, big.NewInt(tc.expected), liquidity)
+		})
+	}
+}
+
+func TestPoolSimulator_LiquidityTokenAmounts(t *testing.T) {
+	// initialized ticks: -887220, 273540, 279120, 285480, current tick 279543
+	p := newComparePool(t, 500, 500)
+	amounts, err := p.LiquidityTokenAmounts()
+	require.Nil(t, err)
+	amount0, amount1, err := p.AmountsForCurrentPosition(p.liquidity, 279120, 285480)
+	require.Nil(t, err)
+	assert.Equal(t, []*big.Int{amount0, amount1}, amounts)
+	assert.Equal(t, "616994", amounts[0].String())
+	assert.Equal(t, "69459855477488322", amounts[1].String())
+
+	// crossing 279120 moves to the range below, with its own liquidity
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e6)}
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut, SwapInfo: res.SwapInfo})
+	require.Less(t, p.PriceTick(), 279120)
+	amounts, err = p.LiquidityTokenAmounts()
+	require.Nil(t, err)
+	amount0, amount1, err = p.AmountsForCurrentPosition(p.liquidity, 273540, 279120)
+	require.Nil(t, err)
+	assert.Equal(t, []*big.Int{amount0, amount1}, amounts)
+	// 2822091172725 + 116315447200034
+	assert.Equal(t, "119137538372759", p.liquidity.String())
+}
@@ -0,0 +1,143 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
+	concpool "github.com/sourcegraph/conc/pool"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// priceOfSqrtPriceX96 returns the price of token0 in token1, or of token1 in token0 if invert, in raw units
+func priceOfSqrtPriceX96(sqrtPriceX96 *big.Int, invert bool) *big.Float {
+	sqrtPrice := new(big.Float).SetInt(sqrtPriceX96)
+	// price of token0 in token1 = (sqrtPriceX96 / 2^96)^2
+	price := new(big.Float).Mul(sqrtPrice, sqrtPrice)
+	price.SetMantExp(price, -192)
+	if !invert {
+		return price
+	}
+	return new(big.Float).Quo(big.NewFloat(1), price)
+}
+
+// spotPrice returns the current price of tokenIn in tokenOut, in raw units
+func (p *PoolSimulator) spotPrice(zeroForOne bool) *big.Float {
+	return priceOfSqrtPriceX96(p.globalState.Price, !zeroForOne)
+}
+
+// SpotPrice returns the marginal price of tokenIn in tokenOut after the current fee of the direction, in raw units,
+// see pool.IPoolSpotPrice
+func (p *PoolSimulator) SpotPrice(tokenIn, tokenOut string) (*big.Float, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenIn), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return nil, ErrInvalidToken
+	}
+
+	zeroForOne := tokenInIndex == 0
+	fee := p.globalState.FeeOtz
+	if zeroForOne {
+		fee = p.globalState.FeeZto
+	}
+	return new(big.Float).Mul(p.spotPrice(zeroForOne), big.NewFloat(1-float64(fee)/1e6)), nil
+}
+
+// EffectiveRate returns the amount of tokenOut received per tokenIn when swapping amountIn, fee and price impact
+// included, adjusted by the decimals of the tokens
+func (p *PoolSimulator) EffectiveRate(tokenIn, tokenOut string, amountIn *big.Int) (*big.Float, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenIn), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return nil, ErrInvalidToken
+	}
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return nil, ErrZeroAmountIn
+	}
+
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: amountIn}, tokenOut)
+	if err != nil {
+		return nil, err
+	}
+
+	amountOut := new(big.Float).Quo(new(big.Float).SetInt(res.TokenAmountOut.Amount),
+		bignumber.TenPowDecimals(p.decimals[tokenOutIndex]))
+	in := new(big.Float).Quo(new(big.Float).SetInt(amountInSwapped(res, amountIn)),
+		bignumber.TenPowDecimals(p.decimals[tokenInIndex]))
+	return amountOut.Quo(amountOut, in), nil
+}
+
+// TickToPrice returns the price at tick of token0 in token1, or of token1 in token0 if invert, in raw units
+// (not adjusted by the decimals), like tickToPrice of the Uniswap V3 SDK. The tick must be within the tick bounds
+// of the pool.
+func (p *PoolSimulator) TickToPrice(tick int, invert bool) (*big.Float, error) {
+	if tick < p.tickBounds.MinTick || tick > p.tickBounds.MaxTick {
+		return nil, ErrInvalidTick
+	}
+	sqrtPriceX96, err := v3Utils.GetSqrtRatioAtTick(tick)
+	if err != nil {
+		return nil, err
+	}
+	return priceOfSqrtPriceX96(sqrtPriceX96, invert), nil
+}
+
+// AveragePriceImpact returns the average price impact of swapping samples amounts evenly spaced in (0, maxAmount].
+// The price impact of an amount is 1 - executionPrice / (spotPrice * (1 - fee)), so the fee isn't counted as impact.
+// The samples are quoted concurrently, each on its own clone of the pool, and averaged in order so that the result
+// doesn't depend on the scheduling. If maxAmount < samples, the amounts rounding to 0 are skipped and the average is
+// over fewer samples than asked for.
+func (p *PoolSimulator) AveragePriceImpact(tokenIn, tokenOut string, samples int, maxAmount *big.Int) (float64, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenIn), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return 0, ErrInvalidToken
+	}
+	if samples <= 0 {
+		return 0, ErrInvalidSampleCount
+	}
+	if maxAmount == nil || maxAmount.Sign() <= 0 {
+		return 0, ErrZeroAmountIn
+	}
+
+	zeroForOne := tokenInIndex == 0
+	fee := p.globalState.FeeOtz
+	if zeroForOne {
+		fee = p.globalState.FeeZto
+	}
+	// spot price after the fee
+	spotPrice := new(big.Float).Mul(p.spotPrice(zeroForOne), big.NewFloat(1-float64(fee)/1e6))
+
+	impacts := make([]float64, samples)
+	quoted := make([]bool, samples)
+	g := concpool.New().WithErrors().WithMaxGoroutines(averagePriceImpactConcurrency)
+	for i := 0; i < samples; i++ {
+		i := i
+		amountIn := new(big.Int).Div(new(big.Int).Mul(maxAmount, big.NewInt(int64(i+1))), big.NewInt(int64(samples)))
+		if amountIn.Sign() == 0 {
+			continue
+		}
+		g.Go(func() error {
+			res, err := p.CloneState().CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: amountIn}, tokenOut)
+			if err != nil {
+				return err
+			}
+			executionPrice := new(big.Float).Quo(new(big.Float).SetInt(res.TokenAmountOut.Amount),
+				new(big.Float).SetInt(amountInSwapped(res, amountIn)))
+			ratio, _ := new(big.Float).Quo(executionPrice, spotPrice).Float64()
+			impacts[i], quoted[i] = 1-ratio, true
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+
+	// the last sample is maxAmount, so there is at least one
+	var total float64
+	count := 0
+	for i, impact := range impacts {
+		if quoted[i] {
+			total += impact
+			count++
+		}
+	}
+	return total / float64(count), nil
+}
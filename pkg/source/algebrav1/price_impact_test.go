@@ -0,0 +1,182 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+func TestPoolSimulator_AveragePriceImpact(t *testing.T) {
+	p := newComparePool(t, 100, 3000)
+	maxAmount := big.NewInt(1e17)
+
+	impact, err := p.AveragePriceImpact("B", "A", 4, maxAmount)
+	require.Nil(t, err)
+
+	// same as quoting the samples one by one
+	spotPrice, _ := new(big.Float).Mul(p.spotPrice(false), big.NewFloat(1-3000.0/1e6)).Float64()
+	var expected float64
+	for i := int64(1); i <= 4; i++ {
+		amountIn := new(big.Int).Div(new(big.Int).Mul(maxAmount, big.NewInt(i)), big.NewInt(4))
+		res, err := p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: amountIn}, "A")
+		require.Nil(t, err)
+		executionPrice, _ := new(big.Float).Quo(new(big.Float).SetInt(res.TokenAmountOut.Amount), new(big.Float).SetInt(amountIn)).Float64()
+		expected += (1 - executionPrice/spotPrice) / 4
+	}
+	assert.InDelta(t, expected, impact, 1e-9)
+	assert.Greater(t, impact, 0.0)
+
+	// the larger the trades, the larger the impact
+	largerImpact, err := p.AveragePriceImpact("B", "A", 4, new(big.Int).Mul(maxAmount, big.NewInt(10)))
+	require.Nil(t, err)
+	assert.Greater(t, largerImpact, impact)
+
+	// the pool isn't changed by the samples
+	again, err := p.AveragePriceImpact("B", "A", 4, maxAmount)
+	require.Nil(t, err)
+	assert.Equal(t, impact, again)
+
+	// more samples than goroutines, averaged in the same order whatever the scheduling
+	many, err := p.AveragePriceImpact("B", "A", 4*averagePriceImpactConcurrency+1, maxAmount)
+	require.Nil(t, err)
+	for i := 0; i < 4; i++ {
+		again, err := p.AveragePriceImpact("B", "A", 4*averagePriceImpactConcurrency+1, maxAmount)
+		require.Nil(t, err)
+		assert.Equal(t, many, again)
+	}
+
+	// fewer wei than samples, the samples rounding to 0 are skipped. Without fee, so that 1 wei has an output
+	dust, err := newComparePool(t, 0, 0).AveragePriceImpact("A", "B", 10, big.NewInt(3))
+	require.Nil(t, err)
+	assert.False(t, math.IsNaN(dust))
+
+	_, err = p.AveragePriceImpact("B", "A", 0, maxAmount)
+	assert.ErrorIs(t, err, ErrInvalidSampleCount)
+	_, err = p.AveragePriceImpact("B", "A", 4, big.NewInt(0))
+	assert.ErrorIs(t, err, ErrZeroAmountIn)
+	_, err = p.AveragePriceImpact("B", "C", 4, maxAmount)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestPoolSimulator_TickToPrice(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+
+	testcases := []struct {
+		tick     int
+		invert   bool
+		expected float64
+	}{
+		{0, false, 1},
+		{0, true, 1},
+		{6932, false, math.Pow(1.0001, 6932)},
+		{6932, true, math.Pow(1.0001, -6932)},
+		{-276324, false, 1e-12},
+		{-276324, true, 1e12},
+		{887272, false, math.Pow(1.0001, 887272)},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			price, err := p.TickToPrice(tc.tick, tc.invert)
+			require.Nil(t, err)
+			actual, _ := price.Float64()
+			assert.InEpsilon(t, tc.expected, actual, 1e-4)
+		})
+	}
+
+	_, err := p.TickToPrice(887273, false)
+	assert.ErrorIs(t, err, ErrInvalidTick)
+	_, err = p.TickToPrice(-887273, true)
+	assert.ErrorIs(t, err, ErrInvalidTick)
+}
+
+func TestPoolSimulator_SpotPrice(t *testing.T) {
+	p := newComparePool(t, 100, 3000)
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn int64
+		tokenOut string
+		fee      float64
+	}{
+		{"A", 1000, "B", 100},
+		{"B", 1e15, "A", 3000},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			price, err := p.SpotPrice(tc.tokenIn, tc.tokenOut)
+			require.Nil(t, err)
+			actual, _ := price.Float64()
+
+			expected := math.Pow(1.0001, 279543)
+			if tc.tokenIn == "B" {
+				expected = 1 / expected
+			}
+			assert.InEpsilon(t, expected*(1-tc.fee/1e6), actual, 1e-4)
+
+			// the price of a small swap, off by the price impact and the rounding of the fee of the thin pool
+			res, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: big.NewInt(tc.amountIn)}, tc.tokenOut)
+			require.Nil(t, err)
+			amountOut, _ := new(big.Float).SetInt(res.TokenAmountOut.Amount).Float64()
+			assert.InEpsilon(t, actual, amountOut/float64(tc.amountIn), 2e-3)
+		})
+	}
+
+	_, err := p.SpotPrice("A", "A")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// the same pool both ways is never an arbitrage
+	_, _, err = pool.FindArbCycle([]pool.IPoolSimulator{p, newComparePool(t, 500, 500)}, "A", 3)
+	assert.ErrorIs(t, err, pool.ErrNoArbCycle)
+}
+
+func TestPoolSimulator_EffectiveRate(t *testing.T) {
+	p := newComparePool(t, 500, 500)
+	// like USDC (6 decimals) and WETH (18 decimals)
+	p.decimals = []uint8{6, 18}
+
+	testcases := []struct {
+		tokenIn  string
+		amountIn *big.Int
+		tokenOut string
+	}{
+		{"A", big.NewInt(1e6), "B"},
+		{"A", big.NewInt(1e8), "B"},
+		{"B", big.NewInt(1e17), "A"},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			res, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: tc.amountIn}, tc.tokenOut)
+			require.Nil(t, err)
+			amountOut, _ := new(big.Float).SetInt(res.TokenAmountOut.Amount).Float64()
+			amountIn, _ := new(big.Float).SetInt(tc.amountIn).Float64()
+			expected := amountOut / amountIn * math.Pow10(int(p.decimals[p.GetTokenIndex(tc.tokenIn)])) /
+				math.Pow10(int(p.decimals[p.GetTokenIndex(tc.tokenOut)]))
+
+			rate, err := p.EffectiveRate(tc.tokenIn, tc.tokenOut, tc.amountIn)
+			require.Nil(t, err)
+			actual, _ := rate.Float64()
+			assert.InEpsilon(t, expected, actual, 1e-12)
+		})
+	}
+
+	// the rate goes down with the size
+	small, err := p.EffectiveRate("A", "B", big.NewInt(1e6))
+	require.Nil(t, err)
+	large, err := p.EffectiveRate("A", "B", big.NewInt(1e8))
+	require.Nil(t, err)
+	assert.Equal(t, 1, small.Cmp(large))
+
+	_, err = p.EffectiveRate("A", "A", big.NewInt(1e6))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = p.EffectiveRate("A", "B", big.NewInt(0))
+	assert.ErrorIs(t, err, ErrZeroAmountIn)
+}
@@ -0,0 +1,79 @@
+package algebrav1
+
+import (
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// CandidateSimulatorFactory builds the implementation under evaluation (e.g. a rewrite of the swap math)
+type CandidateSimulatorFactory func(entityPool entity.Pool, defaultGas int64) (pool.IPoolSimulator, error)
+
+// FastPathSimulator quotes with the fast path of PoolSimulator, see pool.PrecisionFast. The swaps are applied with
+// the exact path, so it stays on the state of the exact simulator.
+type FastPathSimulator struct {
+	*PoolSimulator
+}
+
+// NewFastPathSimulator is the CandidateSimulatorFactory of the fast path
+func NewFastPathSimulator(entityPool entity.Pool, defaultGas int64) (pool.IPoolSimulator, error) {
+	p, err := NewPoolSimulator(entityPool, defaultGas)
+	if err != nil {
+		return nil, err
+	}
+	return &FastPathSimulator{p}, nil
+}
+
+func (p *FastPathSimulator) CalcAmountOut(tokenAmountIn pool.TokenAmount, tokenOut string) (*pool.CalcAmountOutResult, error) {
+	return p.CalcAmountOutWithParams(pool.CalcAmountOutParams{
+		TokenAmountIn: tokenAmountIn,
+		TokenOut:      tokenOut,
+		Precision:     pool.PrecisionFast,
+	})
+}
+
+func (p *FastPathSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
+	res, err := p.PoolSimulator.CalcAmountOut(params.TokenAmountIn, params.TokenAmountOut.Token)
+	if err != nil {
+		return
+	}
+	p.PoolSimulator.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  params.TokenAmountIn,
+		TokenAmountOut: *res.TokenAmountOut,
+		Fee:            *res.Fee,
+		SwapInfo:       res.SwapInfo,
+	})
+}
+
+// NewShadowPoolSimulator runs the current PoolSimulator and a candidate implementation side by side,
+// see pool.ShadowPoolSimulator. The candidate is the fast path if newCandidate is nil, compared within
+// pool.FastPrecisionMaxRelativeError unless config.MaxRelativeDelta is set (negative to compare exactly), and the
+// divergences are logged if recorder is nil.
+func NewShadowPoolSimulator(
+	entityPool entity.Pool,
+	defaultGas int64,
+	newCandidate CandidateSimulatorFactory,
+	config pool.ShadowConfig,
+	recorder pool.ShadowRecorder,
+) (*pool.ShadowPoolSimulator, error) {
+	if newCandidate == nil {
+		newCandidate = NewFastPathSimulator
+		if config.MaxRelativeDelta == 0 {
+			config.MaxRelativeDelta = pool.FastPrecisionMaxRelativeError
+		}
+	}
+	if recorder == nil {
+		recorder = pool.LogShadowRecorder{}
+	}
+
+	current, err := NewPoolSimulator(entityPool, defaultGas)
+	if err != nil {
+		return nil, err
+	}
+
+	candidate, err := newCandidate(entityPool, defaultGas)
+	if err != nil {
+		return nil, err
+	}
+
+	return pool.NewShadowPoolSimulator(current, candidate, config, recorder), nil
+}
@@ -0,0 +1,160 @@
+package algebrav1
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// candidate with an injected off-by-one for big amounts
+type divergentPoolSimulator struct {
+	*PoolSimulator
+}
+
+func (p *divergentPoolSimulator) CalcAmountOut(tokenAmountIn pool.TokenAmount, tokenOut string) (*pool.CalcAmountOutResult, error) {
+	res, err := p.PoolSimulator.CalcAmountOut(tokenAmountIn, tokenOut)
+	if err == nil && tokenAmountIn.Amount.Cmp(big.NewInt(1000000)) >= 0 {
+		res.TokenAmountOut.Amount = new(big.Int).Sub(res.TokenAmountOut.Amount, bignumber.One)
+	}
+	return res, err
+}
+
+func TestNewShadowPoolSimulator(t *testing.T) {
+	entityPool := entity.Pool{
+		Exchange: "algebra",
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":954140562773509808028,"globalState":{"price":84125210470736011805469300802,"tick":1199,"feeZto":100,"feeOtz":3000,"timepoint_index":104,"community_fee_token0":150,"community_fee_token1":150,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":954140562773509808028,"LiquidityNet":954140562773509808028},{"Index":887220,"LiquidityGross":954140562773509808028,"LiquidityNet":-954140562773509808028}],"tickSpacing":60}`,
+	}
+	newCandidate := func(entityPool entity.Pool, defaultGas int64) (pool.IPoolSimulator, error) {
+		p, err := NewPoolSimulator(entityPool, defaultGas)
+		if err != nil {
+			return nil, err
+		}
+		return &divergentPoolSimulator{p}, nil
+	}
+
+	stats := pool.NewShadowStats()
+	s, err := NewShadowPoolSimulator(entityPool, 1001, newCandidate, pool.ShadowConfig{SampleRate: 1}, stats)
+	require.Nil(t, err)
+
+	for _, amountIn := range []string{"1000", "10000000", "100000000000"} {
+		in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10(amountIn)}
+		out, err := s.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		s.UpdateBalance(pool.UpdateBalanceParams{
+			TokenAmountIn:  in,
+			TokenAmountOut: *out.TokenAmountOut,
+			Fee:            *out.Fee,
+			SwapInfo:       out.SwapInfo,
+		})
+	}
+	s.Wait()
+
+	assert.Equal(t, map[int]pool.BucketStats{
+		3:  {Samples: 1, Divergences: 0, MaxDelta: big.NewInt(0)},
+		7:  {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(1)},
+		11: {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(1)},
+	}, stats.Get("algebra"))
+}
+
+func TestNewShadowPoolSimulator_FastPath(t *testing.T) {
+	entityPool := entity.Pool{
+		Exchange: "algebra",
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    `{"liquidity":954140562773509808028,"globalState":{"price":84125210470736011805469300802,"tick":1199,"feeZto":100,"feeOtz":3000,"timepoint_index":104,"community_fee_token0":150,"community_fee_token1":150,"unlocked":true},"ticks":[{"Index":-887220,"LiquidityGross":954140562773509808028,"LiquidityNet":954140562773509808028},{"Index":887220,"LiquidityGross":954140562773509808028,"LiquidityNet":-954140562773509808028}],"tickSpacing":60}`,
+	}
+	exact, err := NewPoolSimulator(entityPool, 1001)
+	require.Nil(t, err)
+
+	stats := pool.NewShadowStats()
+	s, err := NewShadowPoolSimulator(entityPool, 1001, nil, pool.ShadowConfig{SampleRate: 1},
+		pool.ShadowRecorders{stats, pool.LogShadowRecorder{}})
+	require.Nil(t, err)
+
+	for _, amountIn := range []string{"1000", "10000000", "100000000000000000000"} {
+		in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10(amountIn)}
+		out, err := s.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		expected, err := exact.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		assert.Equal(t, expected.TokenAmountOut, out.TokenAmountOut)
+
+		params := pool.UpdateBalanceParams{
+			TokenAmountIn:  in,
+			TokenAmountOut: *out.TokenAmountOut,
+			Fee:            *out.Fee,
+			SwapInfo:       out.SwapInfo,
+		}
+		s.UpdateBalance(params)
+		exact.UpdateBalance(params)
+	}
+	s.Wait()
+
+	// the fast path is within its error bound, not reported as diverging
+	buckets := stats.Get("algebra")
+	require.Len(t, buckets, 3)
+	for _, bucket := range buckets {
+		assert.Equal(t, uint64(1), bucket.Samples)
+		assert.Equal(t, uint64(0), bucket.Divergences)
+	}
+
+	// the exact differences are reported without tolerance
+	stats = pool.NewShadowStats()
+	s, err = NewShadowPoolSimulator(entityPool, 1001, nil, pool.ShadowConfig{SampleRate: 1, MaxRelativeDelta: -1}, stats)
+	require.Nil(t, err)
+	_, err = s.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("100000000000000000000")}, "B")
+	require.Nil(t, err)
+	s.Wait()
+	assert.Equal(t, uint64(1), stats.Get("algebra")[20].Divergences)
+	assert.Equal(t, 1, stats.Get("algebra")[20].MaxDelta.Sign())
+	assert.Equal(t, -1, stats.Get("algebra")[20].MaxDelta.Cmp(big.NewInt(1e17)))
+
+	// the candidate follows the state of the exact path
+	divergences := &divergenceRecorder{}
+	s, err = NewShadowPoolSimulator(entityPool, 1001, nil, pool.ShadowConfig{SampleRate: 1, Swap: true, MaxRelativeDelta: -1},
+		divergences)
+	require.Nil(t, err)
+	exact, err = NewPoolSimulator(entityPool, 1001)
+	require.Nil(t, err)
+	in := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("100000000000000000000")}
+	for i := 0; i < 2; i++ {
+		out, err := s.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		fast, err := exact.CalcAmountOutWithParams(pool.CalcAmountOutParams{
+			TokenAmountIn: in,
+			TokenOut:      "B",
+			Precision:     pool.PrecisionFast,
+		})
+		require.Nil(t, err)
+		assert.Equal(t, fast.TokenAmountOut, out.TokenAmountOut)
+		s.Wait()
+		expected, err := exact.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		assert.Equal(t, expected.TokenAmountOut.Amount, divergences.last.ShadowAmountOut)
+
+		s.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *out.TokenAmountOut})
+		exact.UpdateBalance(pool.UpdateBalanceParams{
+			TokenAmountIn:  in,
+			TokenAmountOut: *expected.TokenAmountOut,
+			Fee:            *expected.Fee,
+			SwapInfo:       expected.SwapInfo,
+		})
+	}
+}
+
+type divergenceRecorder struct {
+	last pool.Divergence
+}
+
+func (r *divergenceRecorder) RecordSample(string, int) {}
+
+func (r *divergenceRecorder) RecordDivergence(d pool.Divergence) {
+	r.last = d
+}
@@ -0,0 +1,101 @@
+package algebrav1
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// SetSimulationTimestamp recomputes the adaptive fees at the block timestamp, as the pool does before a swap: it writes
+// the timepoint of the block and takes the fees of the averages of the last WINDOW, see AdaptiveFee.getFee.
+// The fees computed by the tracker are used if the timestamp is 0 (the default), if the pool has no fee configuration
+// or timepoints, or if the timestamp is before the last timepoint.
+// While simulated, UpdateBalance writes the timepoint of the block and adds the volume of the swaps, so that the fees
+// of a later block account for them.
+func (p *PoolSimulator) SetSimulationTimestamp(timestamp int64) {
+	p.globalState.FeeZto, p.globalState.FeeOtz = p.trackedFeeZto, p.trackedFeeOtz
+	p.feeTimestamp = p.trackedTimestamp
+	if feeZto, feeOtz, ok := p.adaptiveFees(timestamp); ok {
+		p.globalState.FeeZto, p.globalState.FeeOtz = feeZto, feeOtz
+		p.feeTimestamp = timestamp
+	}
+}
+
+// adaptiveFees returns the fees of both directions at the block timestamp, false if they can't be computed
+func (p *PoolSimulator) adaptiveFees(timestamp int64) (uint16, uint16, bool) {
+	if timestamp <= 0 || timestamp > math.MaxUint32 || !isAdaptiveFee(p.feeConfigZto) || !isAdaptiveFee(p.feeConfigOtz) {
+		return 0, 0, false
+	}
+	last, ok := p.timepoints[p.globalState.TimepointIndex]
+	if !ok || !last.Initialized || timestamp < int64(last.BlockTimestamp) {
+		return 0, 0, false
+	}
+
+	volumePerLiquidityInBlock := p.volumePerLiquidityInBlock
+	if volumePerLiquidityInBlock == nil {
+		volumePerLiquidityInBlock = bignumber.ZeroBI
+	}
+	feeZto, feeOtz, err := getNewFees(p.timepoints, p.globalState, p.liquidity, uint32(timestamp),
+		volumePerLiquidityInBlock, p.feeConfigZto, p.feeConfigOtz)
+	if err != nil {
+		return 0, 0, false
+	}
+	return feeZto, feeOtz, true
+}
+
+// isAdaptiveFee returns true if the fee is computed from the configuration, false for a missing or zero configuration
+func isAdaptiveFee(config *FeeConfiguration) bool {
+	return config != nil && *config != FeeConfiguration{}
+}
+
+// writeSwapTimepoint updates the timepoints and the volume per liquidity of the block like a swap at the simulation
+// timestamp does, it must be called before the state of the swap is applied. The first swap of a block writes the
+// timepoint of the block, at the tick and liquidity before the swap, and resets the volume of the block, see
+// AlgebraPool._writeTimepoint. The volume of the swap is added afterwards with addSwapVolume.
+// Nothing is written if the fees aren't simulated, the block of the swap is unknown then.
+func (p *PoolSimulator) writeSwapTimepoint() {
+	if p.feeTimestamp == p.trackedTimestamp || !isAdaptiveFee(p.feeConfigZto) || !isAdaptiveFee(p.feeConfigOtz) {
+		return
+	}
+	last, ok := p.timepoints[p.globalState.TimepointIndex]
+	if !ok || !last.Initialized || p.feeTimestamp <= int64(last.BlockTimestamp) || p.feeTimestamp > math.MaxUint32 {
+		return
+	}
+
+	volumePerLiquidityInBlock := p.volumePerLiquidityInBlock
+	if volumePerLiquidityInBlock == nil {
+		volumePerLiquidityInBlock = bignumber.ZeroBI
+	}
+	ts := TimepointStorage{data: p.timepoints, updates: map[uint16]Timepoint{}}
+	index, err := ts.write(p.globalState.TimepointIndex, uint32(p.feeTimestamp), int24(p.globalState.Tick.Int64()),
+		p.liquidity, volumePerLiquidityInBlock)
+	if err != nil {
+		return
+	}
+
+	// copy on write, the timepoints might be shared with a clone
+	timepoints := make(map[uint16]Timepoint, len(p.timepoints)+len(ts.updates))
+	for i, timepoint := range p.timepoints {
+		timepoints[i] = timepoint
+	}
+	for i, timepoint := range ts.updates {
+		timepoints[i] = timepoint
+	}
+	p.timepoints = timepoints
+	p.globalState.TimepointIndex = index
+	p.volumePerLiquidityInBlock = new(big.Int)
+}
+
+// addSwapVolume adds the volume per liquidity of a swap of amount0 and amount1 to the one of the block, liquidity being
+// the liquidity after the swap, see AlgebraPool._calculateSwapAndLock
+func (p *PoolSimulator) addSwapVolume(liquidity, amount0, amount1 *big.Int) {
+	if !isAdaptiveFee(p.feeConfigZto) || !isAdaptiveFee(p.feeConfigOtz) {
+		return
+	}
+	volumePerLiquidityInBlock := calculateVolumePerLiquidity(liquidity, amount0, amount1)
+	if p.volumePerLiquidityInBlock != nil {
+		volumePerLiquidityInBlock.Add(volumePerLiquidityInBlock, p.volumePerLiquidityInBlock)
+	}
+	p.volumePerLiquidityInBlock = volumePerLiquidityInBlock
+}
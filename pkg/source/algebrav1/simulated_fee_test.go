@@ -0,0 +1,238 @@
+package algebrav1
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// newAdaptiveFeePool returns a full range pool at the state after the timepoints written every minute from start at
+// the ticks, with a fee of 500 computed by the tracker
+func newAdaptiveFeePool(t *testing.T, start uint32, ticks []int24, configZto, configOtz *FeeConfiguration) *PoolSimulator {
+	liquidity := big.NewInt(1e18)
+	timepoints, state := newTimepoints(t, start, ticks, liquidity)
+	price, err := v3Utils.GetSqrtRatioAtTick(int(state.Tick.Int64()))
+	require.Nil(t, err)
+	state.Price, state.FeeZto, state.FeeOtz, state.Unlocked = price, 500, 500, true
+
+	extra, err := json.Marshal(Extra{
+		Liquidity:   liquidity,
+		GlobalState: state,
+		Ticks: []v3Entities.Tick{
+			{Index: -887220, LiquidityGross: liquidity, LiquidityNet: liquidity},
+			{Index: 887220, LiquidityGross: liquidity, LiquidityNet: new(big.Int).Neg(liquidity)},
+		},
+		TickSpacing:  60,
+		FeeConfigZto: configZto,
+		FeeConfigOtz: configOtz,
+		Timepoints:   timepoints,
+	})
+	require.Nil(t, err)
+
+	p, err := NewPoolSimulator(entity.Pool{
+		Reserves: entity.PoolReserves{"1000000000000000000", "1000000000000000000"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra:    string(extra),
+		// tracked at the first timepoint
+		Timestamp: int64(start),
+	}, 1001)
+	require.Nil(t, err)
+	return p
+}
+
+func TestPoolSimulator_SetSimulationTimestamp(t *testing.T) {
+	start := uint32(1700000000)
+	ticks := []int24{1000, -1000, 1000, -1000, 1000, -1000}
+	timestamp := int64(start) + int64(len(ticks)+1)*60
+	feeConfOtz := defaultFeeConfig
+	feeConfOtz.BaseFee = 300
+	p := newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &feeConfOtz)
+
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e15)}
+	tracked, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+
+	p.SetSimulationTimestamp(timestamp)
+	feeZto, feeOtz, err := getNewFees(p.timepoints, p.globalState, p.liquidity, uint32(timestamp), big.NewInt(0),
+		&defaultFeeConfig, &feeConfOtz)
+	require.Nil(t, err)
+	assert.Equal(t, feeZto, p.globalState.FeeZto)
+	assert.Equal(t, feeOtz, p.globalState.FeeOtz)
+	assert.Greater(t, feeZto, uint16(500))
+	assert.Equal(t, feeConfOtz.BaseFee-defaultFeeConfig.BaseFee, feeOtz-feeZto)
+
+	simulated, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, -1, simulated.TokenAmountOut.Amount.Cmp(tracked.TokenAmountOut.Amount))
+	meta := p.GetMetaInfo("A", "B").(Meta)
+	assert.Equal(t, feeZto, meta.Fee.SwapFee)
+	assert.Equal(t, timestamp, meta.BlockTimestamp)
+	assert.Equal(t, p.globalState.TimepointIndex, meta.TimepointIndex)
+
+	p.SetSimulationTimestamp(0)
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, tracked.TokenAmountOut, res.TokenAmountOut)
+	assert.Equal(t, int64(1700000000), p.GetMetaInfo("A", "B").(Meta).BlockTimestamp)
+}
+
+func TestPoolSimulator_SetSimulationTimestamp_TrackedFee(t *testing.T) {
+	start := uint32(1700000000)
+	ticks := []int24{1000, -1000, 1000, -1000}
+	timestamp := int64(start) + int64(len(ticks)+1)*60
+
+	withoutTimepoints := newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &defaultFeeConfig)
+	withoutTimepoints.timepoints = nil
+
+	testcases := []struct {
+		pool      *PoolSimulator
+		timestamp int64
+	}{
+		{newAdaptiveFeePool(t, start, ticks, nil, nil), timestamp},
+		{newAdaptiveFeePool(t, start, ticks, &FeeConfiguration{}, &FeeConfiguration{}), timestamp},
+		{newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, nil), timestamp},
+		{withoutTimepoints, timestamp},
+		// before the last timepoint
+		{newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &defaultFeeConfig), int64(start) + 60},
+		{newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &defaultFeeConfig), -1},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			tc.pool.SetSimulationTimestamp(tc.timestamp)
+			assert.Equal(t, uint16(500), tc.pool.globalState.FeeZto)
+			assert.Equal(t, uint16(500), tc.pool.globalState.FeeOtz)
+		})
+	}
+}
+
+func TestPoolSimulator_GetMetaInfo_TwapTick(t *testing.T) {
+	p := newAdaptiveFeePool(t, 1700000000, []int24{1000, 1000, -500}, &defaultFeeConfig, &defaultFeeConfig)
+	twapTick := p.GetMetaInfo("A", "B").(Meta).TwapTick
+	require.NotNil(t, twapTick)
+	assert.Equal(t, 500, *twapTick)
+	assert.Equal(t, -500, p.GetMetaInfo("A", "B").(Meta).CurrentTick)
+
+	assert.Nil(t, newComparePool(t, 500, 500).GetMetaInfo("A", "B").(Meta).TwapTick)
+}
+
+func TestPoolSimulator_UpdateBalance_SimulatedTimepoint(t *testing.T) {
+	start := uint32(1700000000)
+	ticks := []int24{1000, -1000, 1000, -1000}
+	timestamp := int64(start) + int64(len(ticks)+1)*60
+	p := newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &defaultFeeConfig)
+	index := p.globalState.TimepointIndex
+	timepoints := p.timepoints
+
+	swap := func() {
+		in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e16)}
+		res, err := p.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut,
+			SwapInfo: res.SwapInfo})
+	}
+
+	// without simulated fees, the block of the swap is unknown
+	swap()
+	assert.Equal(t, index, p.globalState.TimepointIndex)
+	trackedVolume := new(big.Int).Set(p.volumePerLiquidityInBlock)
+	assert.Equal(t, 1, trackedVolume.Sign())
+
+	// the first swap of the block writes its timepoint with the volume of the previous block
+	p.SetSimulationTimestamp(timestamp)
+	feeZto, feeOtz := p.globalState.FeeZto, p.globalState.FeeOtz
+	swap()
+	require.Equal(t, index+1, p.globalState.TimepointIndex)
+	assert.Equal(t, uint32(timestamp), p.timepoints[index+1].BlockTimestamp)
+	assert.Equal(t, 1, p.volumePerLiquidityInBlock.Sign())
+	assert.NotContains(t, timepoints, index+1)
+
+	// the fees are fixed in the block
+	p.SetSimulationTimestamp(timestamp)
+	assert.Equal(t, feeZto, p.globalState.FeeZto)
+	assert.Equal(t, feeOtz, p.globalState.FeeOtz)
+
+	// the next swaps of the block add their volume
+	volume := new(big.Int).Set(p.volumePerLiquidityInBlock)
+	swap()
+	assert.Equal(t, index+1, p.globalState.TimepointIndex)
+	assert.Equal(t, 1, p.volumePerLiquidityInBlock.Cmp(volume))
+
+	// and the next block accounts for it
+	volume = new(big.Int).Set(p.volumePerLiquidityInBlock)
+	feeZto, feeOtz, err := getNewFees(p.timepoints, p.globalState, p.liquidity, uint32(timestamp+60), volume,
+		&defaultFeeConfig, &defaultFeeConfig)
+	require.Nil(t, err)
+	p.SetSimulationTimestamp(timestamp + 60)
+	assert.Equal(t, feeZto, p.globalState.FeeZto)
+	assert.Equal(t, feeOtz, p.globalState.FeeOtz)
+	withoutVolume, _, err := getNewFees(p.timepoints, p.globalState, p.liquidity, uint32(timestamp+60),
+		bignumber.ZeroBI, &defaultFeeConfig, &defaultFeeConfig)
+	require.Nil(t, err)
+	assert.NotEqual(t, withoutVolume, feeZto)
+}
+
+func TestPoolSimulator_CloneState_SimulatedFee(t *testing.T) {
+	start := uint32(1700000000)
+	ticks := []int24{1000, -1000, 1000, -1000}
+	timestamp := int64(start) + int64(len(ticks)+1)*60
+	p := newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &defaultFeeConfig)
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e16)}
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut,
+		SwapInfo: res.SwapInfo})
+
+	type state struct {
+		globalState GlobalState
+		liquidity   string
+		volume      string
+		timepoints  map[uint16]Timepoint
+		ticks       []v3Entities.Tick
+		reserves    []string
+		feeGrowth   FeeGrowth
+	}
+	current := func() state {
+		return state{p.globalState, p.liquidity.String(), p.volumePerLiquidityInBlock.String(), p.timepoints,
+			p.ticks.Ticks(), []string{p.Info.Reserves[0].String(), p.Info.Reserves[1].String()}, p.FeeGrowthGlobal()}
+	}
+	expected := current()
+	timepoints := make(map[uint16]Timepoint, len(p.timepoints))
+	for i, timepoint := range p.timepoints {
+		timepoints[i] = timepoint
+	}
+
+	// the clone simulates the swaps of a later block
+	cloned := p.CloneState().(*PoolSimulator)
+	cloned.SetSimulationTimestamp(timestamp)
+	fees := [2]uint16{cloned.globalState.FeeZto, cloned.globalState.FeeOtz}
+	for i := 0; i < 3; i++ {
+		res, err := cloned.CalcAmountOut(in, "B")
+		require.Nil(t, err)
+		cloned.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut,
+			SwapInfo: res.SwapInfo})
+	}
+	require.Nil(t, cloned.InsertTick(v3Entities.Tick{Index: 600, LiquidityGross: big.NewInt(1e12),
+		LiquidityNet: big.NewInt(-1e12)}))
+	cloned.SetSimulationTimestamp(timestamp + 60)
+	require.NotEqual(t, p.globalState.TimepointIndex, cloned.globalState.TimepointIndex)
+	require.NotEqual(t, p.globalState.FeeZto, cloned.globalState.FeeZto)
+
+	assert.Equal(t, expected, current())
+	assert.Equal(t, timepoints, p.timepoints)
+	assert.Equal(t, int64(start), p.feeTimestamp)
+
+	// the original still simulates the fees of the block from its own state
+	p.SetSimulationTimestamp(timestamp)
+	assert.Equal(t, fees, [2]uint16{p.globalState.FeeZto, p.globalState.FeeOtz})
+}
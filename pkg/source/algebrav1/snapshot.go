@@ -0,0 +1,99 @@
+package algebrav1
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+)
+
+// simulatorState is every field of the simulator that UpdateBalance, the tick updates and SetSimulationTimestamp
+// may change, and the address of the pool it was taken from
+type simulatorState struct {
+	Address                 string
+	Reserves                []*big.Int
+	Liquidity               *big.Int
+	GlobalState             GlobalState
+	TotalFeeGrowth          FeeGrowth
+	TickFeeGrowthOutside    map[int]FeeGrowth
+	CrossedFeeGrowthOutside map[int]FeeGrowth
+	Ticks                   []v3Entities.Tick
+	TickMin                 int
+	TickMax                 int
+	ZeroLiquidity           bool
+	FeeTimestamp            int64
+	Timepoints              map[uint16]Timepoint
+	VolumePerLiquidity      *big.Int
+}
+
+// Snapshot returns a binary copy of the mutable state, to be rolled back with Restore.
+// Unlike a clone, the snapshot doesn't share anything with the simulator.
+func (p *PoolSimulator) Snapshot() []byte {
+	var buf bytes.Buffer
+	// never fails, all the fields are encodable
+	_ = gob.NewEncoder(&buf).Encode(simulatorState{
+		Address:                 p.Info.Address,
+		Reserves:                p.Info.Reserves,
+		Liquidity:               p.liquidity,
+		GlobalState:             p.globalState,
+		TotalFeeGrowth:          p.totalFeeGrowth,
+		TickFeeGrowthOutside:    p.tickFeeGrowthOutside,
+		CrossedFeeGrowthOutside: p.crossedFeeGrowthOutside,
+		Ticks:                   p.ticks.Ticks(),
+		TickMin:                 p.tickMin,
+		TickMax:                 p.tickMax,
+		ZeroLiquidity:           p.zeroLiquidity,
+		FeeTimestamp:            p.feeTimestamp,
+		Timepoints:              p.timepoints,
+		VolumePerLiquidity:      p.volumePerLiquidityInBlock,
+	})
+	return buf.Bytes()
+}
+
+// Restore rolls the mutable state back to a Snapshot of this pool
+func (p *PoolSimulator) Restore(b []byte) error {
+	var state simulatorState
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&state); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if state.Address != p.Info.Address {
+		return fmt.Errorf("%w: taken from pool %s", ErrInvalidSnapshot, state.Address)
+	}
+	if len(state.Reserves) != len(p.Info.Tokens) || state.Liquidity == nil || state.GlobalState.Tick == nil {
+		return ErrInvalidSnapshot
+	}
+
+	// the ticks may not sum to a zero net liquidity after the tick updates, so they are set one by one
+	ticks, err := clmath.NewSparseTickList(nil, p.tickSpacing)
+	if err != nil {
+		return err
+	}
+	for _, tick := range state.Ticks {
+		if err := ticks.SetTick(tick); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+	}
+
+	// gob skips empty maps and slices
+	if state.TickFeeGrowthOutside == nil {
+		state.TickFeeGrowthOutside = map[int]FeeGrowth{}
+	}
+	if state.CrossedFeeGrowthOutside == nil {
+		state.CrossedFeeGrowthOutside = map[int]FeeGrowth{}
+	}
+
+	p.Info.Reserves = state.Reserves
+	p.liquidity = state.Liquidity
+	p.globalState = state.GlobalState
+	p.totalFeeGrowth = state.TotalFeeGrowth
+	p.tickFeeGrowthOutside = state.TickFeeGrowthOutside
+	p.crossedFeeGrowthOutside = state.CrossedFeeGrowthOutside
+	p.ticks = ticks
+	p.tickMin, p.tickMax, p.zeroLiquidity = state.TickMin, state.TickMax, state.ZeroLiquidity
+	p.feeTimestamp = state.FeeTimestamp
+	p.timepoints, p.volumePerLiquidityInBlock = state.Timepoints, state.VolumePerLiquidity
+	return nil
+}
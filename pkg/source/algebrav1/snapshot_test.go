@@ -0,0 +1,129 @@
+package algebrav1
+
+import (
+	"math/big"
+	"testing"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSimulator_SnapshotRestore(t *testing.T) {
+	p := newComparePool(t, 2985, 2985)
+
+	swap := func(tokenIn, amountIn, tokenOut string) *pool.CalcAmountOutResult {
+		in := pool.TokenAmount{Token: tokenIn, Amount: bignumber.NewBig10(amountIn)}
+		out, err := p.CalcAmountOut(in, tokenOut)
+		require.Nil(t, err)
+		p.UpdateBalance(pool.UpdateBalanceParams{
+			TokenAmountIn:  in,
+			TokenAmountOut: *out.TokenAmountOut,
+			Fee:            *out.Fee,
+			SwapInfo:       out.SwapInfo,
+		})
+		return out
+	}
+
+	swap("A", "1000", "B")
+	swap("B", "100000000000000000", "A")
+	snapshot := p.Snapshot()
+	liquidity, globalState, feeGrowth := p.liquidity, p.globalState, p.FeeGrowthGlobal()
+
+	// quotes after the snapshot
+	expected := []*pool.CalcAmountOutResult{
+		swap("A", "1000000000000000000", "B"),
+		swap("B", "10000000000000000", "A"),
+		swap("A", "100", "B"),
+	}
+	assert.NotEqual(t, globalState, p.globalState)
+
+	require.Nil(t, p.Restore(snapshot))
+	assert.Equal(t, liquidity, p.liquidity)
+	assert.Equal(t, globalState, p.globalState)
+	assert.Equal(t, feeGrowth, p.FeeGrowthGlobal())
+
+	// replaying gives the same results
+	assert.Equal(t, expected[0].TokenAmountOut, swap("A", "1000000000000000000", "B").TokenAmountOut)
+	assert.Equal(t, expected[1].TokenAmountOut, swap("B", "10000000000000000", "A").TokenAmountOut)
+	assert.Equal(t, expected[2].TokenAmountOut, swap("A", "100", "B").TokenAmountOut)
+
+	assert.ErrorIs(t, p.Restore(nil), ErrInvalidSnapshot)
+}
+
+func TestPoolSimulator_SnapshotRestore_TicksAndTimestamp(t *testing.T) {
+	start := uint32(1700000000)
+	ticks := []int24{1000, -1000, 1000, -1000}
+	timestamp := int64(start) + int64(len(ticks)+1)*60
+	p := newAdaptiveFeePool(t, start, ticks, &defaultFeeConfig, &defaultFeeConfig)
+
+	type state struct {
+		ticks         []v3Entities.Tick
+		tickMin       int
+		tickMax       int
+		zeroLiquidity bool
+		feeTimestamp  int64
+		globalState   GlobalState
+		timepoints    map[uint16]Timepoint
+		volume        *big.Int
+	}
+	current := func() state {
+		return state{p.ticks.Ticks(), p.tickMin, p.tickMax, p.zeroLiquidity, p.feeTimestamp, p.globalState,
+			p.timepoints, p.volumePerLiquidityInBlock}
+	}
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(1e15)}
+
+	p.SetSimulationTimestamp(timestamp)
+	require.Nil(t, p.InsertTick(v3Entities.Tick{Index: -600, LiquidityGross: big.NewInt(1e12), LiquidityNet: big.NewInt(1e12)}))
+	snapshot := p.Snapshot()
+	expected := current()
+	quote, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+
+	// a swap writing a timepoint, tick edits and timestamp changes after the snapshot
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *quote.TokenAmountOut,
+		SwapInfo: quote.SwapInfo})
+	require.NotEqual(t, expected.timepoints, p.timepoints)
+	require.Nil(t, p.InsertTick(v3Entities.Tick{Index: 600, LiquidityGross: big.NewInt(1e12), LiquidityNet: big.NewInt(-1e12)}))
+	require.Nil(t, p.RemoveTick(-887220))
+	p.SetSimulationTimestamp(0)
+	assert.NotEqual(t, expected, current())
+
+	require.Nil(t, p.Restore(snapshot))
+	assert.Equal(t, expected, current())
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	assert.Equal(t, quote.TokenAmountOut, res.TokenAmountOut)
+
+	// a snapshot of the pool without ticks
+	for _, tick := range p.TicksBetween(-887221, 887221) {
+		require.Nil(t, p.RemoveTick(tick))
+	}
+	require.True(t, p.zeroLiquidity)
+	empty := p.Snapshot()
+	expectedEmpty := current()
+	require.Nil(t, p.InsertTick(v3Entities.Tick{Index: -600, LiquidityGross: big.NewInt(1e12), LiquidityNet: big.NewInt(1e12)}))
+	p.SetSimulationTimestamp(timestamp + 60)
+
+	require.Nil(t, p.Restore(empty))
+	assert.Equal(t, expectedEmpty, current())
+	_, err = p.CalcAmountOut(in, "B")
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+
+	require.Nil(t, p.Restore(snapshot))
+	assert.Equal(t, expected, current())
+}
+
+func TestPoolSimulator_Restore_OtherPool(t *testing.T) {
+	p := newComparePool(t, 2985, 2985)
+	other := newComparePool(t, 2985, 2985)
+	other.Info.Address = "0x0000000000000000000000000000000000000001"
+	state := other.globalState
+
+	assert.ErrorIs(t, other.Restore(p.Snapshot()), ErrInvalidSnapshot)
+	assert.Equal(t, state, other.globalState)
+	assert.Nil(t, other.Restore(other.Snapshot()))
+}
@@ -0,0 +1,148 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+// rpcField is a field of the pool state read by its own call in the multicall of fetchRPCData
+type rpcField string
+
+const (
+	fieldLiquidity           rpcField = "liquidity"
+	fieldGlobalState         rpcField = "globalState"
+	fieldDataStorageOperator rpcField = "dataStorageOperator"
+	fieldTickSpacing         rpcField = "tickSpacing"
+	fieldReserve0            rpcField = "reserve0"
+	fieldReserve1            rpcField = "reserve1"
+)
+
+// isCritical returns true if the swap math can't be trusted for long with a stale value of the field
+func (f rpcField) isCritical() bool {
+	return f == fieldLiquidity || f == fieldGlobalState
+}
+
+// isFeeInput returns true if the field is needed to approximate the adaptive fee
+func (f rpcField) isFeeInput() bool {
+	return f == fieldLiquidity || f == fieldGlobalState || f == fieldDataStorageOperator
+}
+
+// hasFeeInput returns true if one of the fields is needed to approximate the adaptive fee
+func hasFeeInput(fields []rpcField) bool {
+	for _, field := range fields {
+		if field.isFeeInput() {
+			return true
+		}
+	}
+	return false
+}
+
+// reuseStaleFields fills the fields whose call failed with their values in the previous state of the pool, p, and
+// returns the fields reused. A field is reused for at most MaxStaleFieldReuses updates in a row: beyond it, the pool is
+// locked if the field is critical, otherwise the update fails as if the field couldn't be read at all. The reuse
+// counts are reset once the field is read again.
+func (d *PoolTracker) reuseStaleFields(p entity.Pool, res *FetchRPCResult, failed []rpcField) ([]rpcField, error) {
+	counts := d.takeStaleFieldCounts(p.Address, failed)
+	if len(failed) == 0 {
+		return nil, nil
+	}
+	if d.config.MaxStaleFieldReuses <= 0 {
+		return nil, ErrFieldCallFailed
+	}
+
+	prev, err := decodeExtra([]byte(p.Extra))
+	if err != nil || prev.Liquidity == nil || len(p.Reserves) != 2 {
+		return nil, ErrFieldCallFailed
+	}
+
+	var (
+		reused  []rpcField
+		disable bool
+	)
+	for _, field := range failed {
+		if counts[field] > d.config.MaxStaleFieldReuses {
+			if !field.isCritical() {
+				return nil, ErrFieldCallFailed
+			}
+			logger.WithFields(logger.Fields{
+				"poolAddress": p.Address,
+				"field":       field,
+				"reuses":      counts[field] - 1,
+			}).Warnf("[%v] field failed too many times in a row, disabling the pool", d.config.DexID)
+			disable = true
+		}
+
+		switch field {
+		case fieldLiquidity:
+			res.liquidity = new(big.Int).Set(prev.Liquidity)
+		case fieldGlobalState:
+			if prev.GlobalState.Price == nil {
+				return nil, ErrFieldCallFailed
+			}
+			res.state = prev.GlobalState
+		case fieldTickSpacing:
+			if prev.TickSpacing == 0 {
+				return nil, ErrFieldCallFailed
+			}
+			res.tickSpacing = big.NewInt(int64(prev.TickSpacing))
+		case fieldReserve0, fieldReserve1:
+			i := 0
+			if field == fieldReserve1 {
+				i = 1
+			}
+			reserve, ok := new(big.Int).SetString(p.Reserves[i], 10)
+			if !ok {
+				return nil, ErrFieldCallFailed
+			}
+			if i == 0 {
+				res.reserve0 = reserve
+			} else {
+				res.reserve1 = reserve
+			}
+		}
+		reused = append(reused, field)
+	}
+	if hasFeeInput(reused) {
+		// the fee isn't approximated from stale inputs, the configurations of the previous state are kept
+		res.feeConfigZto, res.feeConfigOtz = prev.FeeConfigZto, prev.FeeConfigOtz
+		res.timepoints, res.volumePerLiquidityInBlock = prev.Timepoints, prev.VolumePerLiquidityInBlock
+	}
+	if disable {
+		res.state.Unlocked = false
+	}
+
+	logger.WithFields(logger.Fields{
+		"poolAddress": p.Address,
+		"fields":      reused,
+	}).Warnf("[%v] reused the previous values of the fields that failed", d.config.DexID)
+
+	return reused, nil
+}
+
+// takeStaleFieldCounts counts the failure of the fields that failed, resets the ones of the others, and returns the
+// number of updates in a row each failed field has failed
+func (d *PoolTracker) takeStaleFieldCounts(poolAddress string, failed []rpcField) map[rpcField]int {
+	counts := make(map[rpcField]int, len(failed))
+	if d.staleFields == nil {
+		for _, field := range failed {
+			counts[field] = 1
+		}
+		return counts
+	}
+
+	var prev map[rpcField]int
+	if v, ok := d.staleFields.Get(poolAddress); ok {
+		prev = v.(map[rpcField]int)
+	}
+	for _, field := range failed {
+		counts[field] = prev[field] + 1
+	}
+	if len(counts) == 0 {
+		d.staleFields.Remove(poolAddress)
+	} else {
+		d.staleFields.Set(poolAddress, counts)
+	}
+	return counts
+}
@@ -0,0 +1,151 @@
+package algebrav1
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	cmap "github.com/orcaman/concurrent-map"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+)
+
+func newStaleFieldsPool(t *testing.T) entity.Pool {
+	extra, err := json.Marshal(Extra{
+		Liquidity: big.NewInt(2822091172725),
+		GlobalState: GlobalState{
+			Price:    bigFromString(t, "95152074251725958509173575126"),
+			Tick:     big.NewInt(279543),
+			FeeZto:   500,
+			FeeOtz:   600,
+			Unlocked: true,
+		},
+		TickSpacing:  60,
+		FeeConfigZto: &defaultFeeConfig,
+		FeeConfigOtz: &defaultFeeConfig,
+	})
+	require.Nil(t, err)
+	return entity.Pool{Address: "pool", Reserves: entity.PoolReserves{"723924", "36031866872048609640"}, Extra: string(extra)}
+}
+
+func bigFromString(t *testing.T, s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	require.True(t, ok)
+	return v
+}
+
+// freshRPCResult is the result of a multicall where all the calls succeeded but the failed ones
+func freshRPCResult(t *testing.T, failed ...rpcField) FetchRPCResult {
+	res := FetchRPCResult{
+		liquidity: big.NewInt(1000),
+		state: GlobalState{
+			Price:    bigFromString(t, "79228162514264337593543950336"),
+			Tick:     big.NewInt(0),
+			FeeZto:   100,
+			FeeOtz:   100,
+			Unlocked: true,
+		},
+		tickSpacing: big.NewInt(60),
+		reserve0:    big.NewInt(1),
+		reserve1:    big.NewInt(2),
+	}
+	for _, field := range failed {
+		switch field {
+		case fieldLiquidity:
+			res.liquidity = nil
+		case fieldGlobalState:
+			res.state = GlobalState{}
+		case fieldTickSpacing:
+			res.tickSpacing = nil
+		case fieldReserve0:
+			res.reserve0 = nil
+		case fieldReserve1:
+			res.reserve1 = nil
+		}
+	}
+	return res
+}
+
+func TestPoolTracker_reuseStaleFields(t *testing.T) {
+	p := newStaleFieldsPool(t)
+	d := &PoolTracker{
+		config:      &Config{DexID: "algebra", MaxStaleFieldReuses: 2},
+		staleFields: cmap.New(),
+	}
+
+	// liquidity reverts transiently, globalState is fresh
+	for i := 0; i < 2; i++ {
+		res := freshRPCResult(t, fieldLiquidity)
+		reused, err := d.reuseStaleFields(p, &res, []rpcField{fieldLiquidity})
+		require.Nil(t, err)
+		assert.Equal(t, []rpcField{fieldLiquidity}, reused)
+		assert.Equal(t, "2822091172725", res.liquidity.String())
+		assert.Equal(t, "79228162514264337593543950336", res.state.Price.String())
+		assert.True(t, res.state.Unlocked)
+		// the fee isn't approximated, the configurations of the previous state are kept
+		assert.Equal(t, &defaultFeeConfig, res.feeConfigZto)
+	}
+
+	// the third time in a row, the pool is disabled
+	res := freshRPCResult(t, fieldLiquidity)
+	reused, err := d.reuseStaleFields(p, &res, []rpcField{fieldLiquidity})
+	require.Nil(t, err)
+	assert.Equal(t, []rpcField{fieldLiquidity}, reused)
+	assert.False(t, res.state.Unlocked)
+
+	// once it's read again, the count starts over
+	res = freshRPCResult(t)
+	reused, err = d.reuseStaleFields(p, &res, nil)
+	require.Nil(t, err)
+	assert.Empty(t, reused)
+	assert.Nil(t, res.feeConfigZto)
+	assert.False(t, d.staleFields.Has("pool"))
+
+	res = freshRPCResult(t, fieldGlobalState, fieldReserve1)
+	reused, err = d.reuseStaleFields(p, &res, []rpcField{fieldGlobalState, fieldReserve1})
+	require.Nil(t, err)
+	assert.Equal(t, []rpcField{fieldGlobalState, fieldReserve1}, reused)
+	assert.Equal(t, "95152074251725958509173575126", res.state.Price.String())
+	assert.Equal(t, uint16(500), res.state.FeeZto)
+	assert.True(t, res.state.Unlocked)
+	assert.Equal(t, "1000", res.liquidity.String())
+	assert.Equal(t, "1", res.reserve0.String())
+	assert.Equal(t, "36031866872048609640", res.reserve1.String())
+}
+
+func TestPoolTracker_reuseStaleFields_Fail(t *testing.T) {
+	testcases := []struct {
+		config  Config
+		pool    func(t *testing.T) entity.Pool
+		failed  [][]rpcField // failed fields of each update
+		lastErr error
+	}{
+		// no reuse
+		{Config{}, newStaleFieldsPool, [][]rpcField{{fieldReserve0}}, ErrFieldCallFailed},
+		// nothing to reuse
+		{Config{MaxStaleFieldReuses: 2}, func(*testing.T) entity.Pool { return entity.Pool{Address: "pool"} },
+			[][]rpcField{{fieldTickSpacing}}, ErrFieldCallFailed},
+		// a non critical field failing too many times in a row fails the update
+		{Config{MaxStaleFieldReuses: 1}, newStaleFieldsPool, [][]rpcField{{fieldReserve0}, {fieldReserve0}},
+			ErrFieldCallFailed},
+		// not in a row
+		{Config{MaxStaleFieldReuses: 1}, newStaleFieldsPool, [][]rpcField{{fieldReserve0}, {}, {fieldReserve0}}, nil},
+		{Config{MaxStaleFieldReuses: 1}, newStaleFieldsPool, [][]rpcField{{fieldReserve0}, {fieldReserve1}}, nil},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			d := &PoolTracker{config: &tc.config, staleFields: cmap.New()}
+			p := tc.pool(t)
+			var err error
+			for _, failed := range tc.failed {
+				res := freshRPCResult(t, failed...)
+				_, err = d.reuseStaleFields(p, &res, failed)
+			}
+			assert.ErrorIs(t, err, tc.lastErr)
+		})
+	}
+}
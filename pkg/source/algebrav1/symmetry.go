@@ -0,0 +1,43 @@
+package algebrav1
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// IsSymmetric returns true if swapping an amount of token0 to token1 and the output back to token0 returns the
+// amount minus the fees of both directions, within a small tolerance. The swap back is quoted on the state after the
+// first swap, so the price impact cancels out. A pool that isn't symmetric, or can't quote the round trip, is in an
+// unusual state, e.g. manipulated or with inconsistent ticks, and should be flagged.
+func (p *PoolSimulator) IsSymmetric() bool {
+	token0, token1 := p.Info.Tokens[0], p.Info.Tokens[1]
+	amountIn := new(big.Int).Div(new(big.Int).Mul(p.Info.Reserves[0], big.NewInt(symmetryProbeBps)), big.NewInt(10000))
+	if amountIn.Sign() <= 0 {
+		return false
+	}
+
+	roundTrip := p.clone()
+	res, err := roundTrip.CalcAmountOut(pool.TokenAmount{Token: token0, Amount: amountIn}, token1)
+	if err != nil {
+		return false
+	}
+	roundTrip.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  pool.TokenAmount{Token: token0, Amount: amountInSwapped(res, amountIn)},
+		TokenAmountOut: *res.TokenAmountOut,
+		Fee:            *res.Fee,
+		SwapInfo:       res.SwapInfo,
+	})
+	back, err := roundTrip.amountOut(res.TokenAmountOut.Amount, token1, token0)
+	if err != nil {
+		return false
+	}
+
+	// amountIn * (1 - feeZto) * (1 - feeOtz) * (1 - tolerance), the fees are in hundredths of a bip
+	minBack := new(big.Int).Mul(amountIn, big.NewInt(int64(1e6-uint32(p.globalState.FeeZto))))
+	minBack.Mul(minBack, big.NewInt(int64(1e6-uint32(roundTrip.globalState.FeeOtz))))
+	minBack.Mul(minBack, big.NewInt(10000-symmetryToleranceBps))
+	minBack.Div(minBack, big.NewInt(1e16))
+
+	return back.Cmp(minBack) >= 0 && back.Cmp(amountIn) <= 0
+}
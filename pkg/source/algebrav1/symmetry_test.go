@@ -0,0 +1,39 @@
+package algebrav1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolSimulator_IsSymmetric(t *testing.T) {
+	testcases := []struct {
+		reserve0    string
+		globalState string
+		expected    bool
+	}{
+		{"723924", `{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":500,"feeOtz":3000,"unlocked":true}`, true},
+		// the tick doesn't match the price: the round trip crosses a tick that the price never reaches
+		{"723924", `{"price":93065132232889433968150957834858946,"tick":285500,"feeZto":500,"feeOtz":3000,"unlocked":true}`, false},
+		{"0", `{"price":93065132232889433968150957834858946,"tick":279543,"feeZto":500,"feeOtz":3000,"unlocked":true}`, false},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p, err := NewPoolSimulator(entity.Pool{
+				Reserves: entity.PoolReserves{tc.reserve0, "36031866872048609640"},
+				Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+				Extra:    `{"liquidity":2822091172725,"globalState":` + tc.globalState + `,"ticks":[{"Index":-887220,"LiquidityGross":2822091172725,"LiquidityNet":2822091172725},{"Index":273540,"LiquidityGross":116315447200034,"LiquidityNet":116315447200034},{"Index":279120,"LiquidityGross":116315447200034,"LiquidityNet":-116315447200034},{"Index":285480,"LiquidityGross":2822091172725,"LiquidityNet":-2822091172725}],"tickSpacing":60}`,
+			}, 1001)
+			require.Nil(t, err)
+			snapshot := p.Snapshot()
+
+			assert.Equal(t, tc.expected, p.IsSymmetric())
+			// the round trip is quoted on a clone
+			assert.Equal(t, snapshot, p.Snapshot())
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package algebrav1
+
+import (
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
+)
+
+// ValidateTick checks that tick can be an initialized tick of the pool: on the tick spacing, within the tick bounds,
+// with a positive uint128 LiquidityGross and a LiquidityNet not above it in absolute value
+func (p *PoolSimulator) ValidateTick(tick v3Entities.Tick) error {
+	if tick.Index%p.tickSpacing != 0 {
+		return v3Entities.ErrInvalidTickSpacing
+	}
+	if tick.Index < p.tickBounds.MinTick || tick.Index > p.tickBounds.MaxTick {
+		return ErrInvalidTick
+	}
+	if tick.LiquidityGross == nil || tick.LiquidityGross.Sign() <= 0 || !isUint128(tick.LiquidityGross) {
+		return ErrInvalidLiquidity
+	}
+	if tick.LiquidityNet == nil || tick.LiquidityNet.CmpAbs(tick.LiquidityGross) > 0 {
+		return ErrInvalidLiquidity
+	}
+	return nil
+}
+
+// InsertTick initializes tick, e.g. when a mint event initializes one of the ticks of its position. Returns
+// ErrTickAlreadyExists if the tick is already initialized. The active liquidity isn't changed.
+// The ticks are shared with the clones of the pool, so they are copied on write: only the word of the tick is copied.
+func (p *PoolSimulator) InsertTick(tick v3Entities.Tick) error {
+	if err := p.ValidateTick(tick); err != nil {
+		return err
+	}
+
+	if _, err := p.ticks.GetTick(tick.Index); err == nil {
+		return ErrTickAlreadyExists
+	}
+
+	ticks, err := p.ticks.WithTick(tick)
+	if err != nil {
+		return err
+	}
+	p.setTicks(ticks)
+	return nil
+}
+
+// RemoveTick uninitializes the tick of tickIndex, e.g. when a burn event takes LiquidityGross of the tick to 0.
+// Returns ErrTickNotInitialized if the tick isn't initialized. Like InsertTick, the active liquidity isn't changed and
+// the ticks are copied on write. Once its last tick is removed, the pool has no liquidity, see ErrNoLiquidity.
+func (p *PoolSimulator) RemoveTick(tickIndex int) error {
+	if _, err := p.ticks.GetTick(tickIndex); err != nil {
+		return ErrTickNotInitialized
+	}
+
+	ticks, err := p.ticks.WithTick(v3Entities.Tick{Index: tickIndex})
+	if err != nil {
+		return err
+	}
+	p.setTicks(ticks)
+	return nil
+}
+
+// setTicks replaces the ticks and updates the tick range [tickMin, tickMax] to their smallest and largest ones, the
+// first and last ticks of their first and last words
+func (p *PoolSimulator) setTicks(ticks *clmath.SparseTickList) {
+	p.ticks = ticks
+	smallest, ok := ticks.Smallest()
+	if !ok {
+		p.tickMin, p.tickMax, p.zeroLiquidity = 0, 0, true
+		return
+	}
+	largest, _ := ticks.Largest()
+	p.tickMin, p.tickMax, p.zeroLiquidity = smallest.Index, largest.Index, false
+}
@@ -0,0 +1,142 @@
+package algebrav1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
+)
+
+func TestPoolSimulator_InsertTick(t *testing.T) {
+	testcases := []struct {
+		tick            v3Entities.Tick
+		expectedIndexes []int
+		expectedErr     error
+	}{
+		{
+			v3Entities.Tick{Index: 282000, LiquidityGross: big.NewInt(1000), LiquidityNet: big.NewInt(-1000)},
+			[]int{-887220, 273540, 279120, 282000, 285480},
+			nil,
+		},
+		{
+			v3Entities.Tick{Index: 300000, LiquidityGross: big.NewInt(1000), LiquidityNet: big.NewInt(-1000)},
+			[]int{-887220, 273540, 279120, 285480, 300000},
+			nil,
+		},
+		{
+			v3Entities.Tick{Index: -887220, LiquidityGross: big.NewInt(1000), LiquidityNet: big.NewInt(1000)},
+			nil,
+			ErrTickAlreadyExists,
+		},
+		{
+			v3Entities.Tick{Index: 282010, LiquidityGross: big.NewInt(1000), LiquidityNet: big.NewInt(1000)},
+			nil,
+			v3Entities.ErrInvalidTickSpacing,
+		},
+		{
+			v3Entities.Tick{Index: 887280, LiquidityGross: big.NewInt(1000), LiquidityNet: big.NewInt(-1000)},
+			nil,
+			ErrInvalidTick,
+		},
+		{
+			v3Entities.Tick{Index: 282000, LiquidityGross: big.NewInt(0), LiquidityNet: big.NewInt(0)},
+			nil,
+			ErrInvalidLiquidity,
+		},
+		{
+			v3Entities.Tick{Index: 282000, LiquidityGross: big.NewInt(1000), LiquidityNet: big.NewInt(-1001)},
+			nil,
+			ErrInvalidLiquidity,
+		},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p := newComparePool(t, 500, 500)
+			cloned := p.clone()
+
+			err := p.InsertTick(tc.tick)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Equal(t, tickIndexesOf(cloned), tickIndexesOf(p))
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedIndexes, tickIndexesOf(p))
+			assert.Equal(t, tc.expectedIndexes[0], p.tickMin)
+			assert.Equal(t, tc.expectedIndexes[len(tc.expectedIndexes)-1], p.tickMax)
+
+			inserted, err := p.ticks.GetTick(tc.tick.Index)
+			require.Nil(t, err)
+			assert.Equal(t, tc.tick, inserted)
+
+			// the clones keep their ticks
+			_, err = cloned.ticks.GetTick(tc.tick.Index)
+			assert.ErrorIs(t, err, clmath.ErrTickNotFound)
+			assert.Equal(t, []int{-887220, 273540, 279120, 285480}, tickIndexesOf(cloned))
+		})
+	}
+}
+
+func TestPoolSimulator_RemoveTick(t *testing.T) {
+	testcases := []struct {
+		tickIndex       int
+		expectedIndexes []int
+		expectedErr     error
+	}{
+		{273540, []int{-887220, 279120, 285480}, nil},
+		{-887220, []int{273540, 279120, 285480}, nil},
+		{285480, []int{-887220, 273540, 279120}, nil},
+		{282000, nil, ErrTickNotInitialized},
+		{282010, nil, ErrTickNotInitialized},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p := newComparePool(t, 500, 500)
+			cloned := p.clone()
+
+			err := p.RemoveTick(tc.tickIndex)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Equal(t, tickIndexesOf(cloned), tickIndexesOf(p))
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedIndexes, tickIndexesOf(p))
+			assert.Equal(t, tc.expectedIndexes[0], p.tickMin)
+			assert.Equal(t, tc.expectedIndexes[len(tc.expectedIndexes)-1], p.tickMax)
+			_, err = p.ticks.GetTick(tc.tickIndex)
+			assert.NotNil(t, err)
+
+			// the clones keep their ticks
+			_, err = cloned.ticks.GetTick(tc.tickIndex)
+			assert.Nil(t, err)
+			assert.Equal(t, []int{-887220, 273540, 279120, 285480}, tickIndexesOf(cloned))
+		})
+	}
+
+	// removing all the ticks leaves a pool without liquidity
+	p := newComparePool(t, 500, 500)
+	for _, index := range []int{-887220, 273540, 279120, 285480} {
+		require.Nil(t, p.RemoveTick(index))
+	}
+	assert.True(t, p.zeroLiquidity)
+	_, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: big.NewInt(1000)}, "B")
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+}
+
+// tickIndexesOf returns the sorted indexes of the initialized ticks of p
+func tickIndexesOf(p *PoolSimulator) []int {
+	var indexes []int
+	for _, tick := range p.ticks.Ticks() {
+		indexes = append(indexes, tick.Index)
+	}
+	return indexes
+}
@@ -0,0 +1,76 @@
+package algebrav1
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/KyberNetwork/ethrpc"
+	"github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/samber/lo"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+var (
+	minWordIndex = utils.MinTick / maxWordSize
+)
+
+// getPoolTicksFromSC get all ticks of a pool from TickLens smart-contract (for dexes without subgraph)
+func (d *PoolTracker) getPoolTicksFromSC(ctx context.Context, poolAddress string, tickSpacing int) ([]TickResp, error) {
+	poolMinWordIdx := int16(minWordIndex/tickSpacing - 1)
+	poolMaxWordIdx := -poolMinWordIdx
+
+	wordIndexes := make([]int16, 0, int(poolMaxWordIdx)-int(poolMinWordIdx)+1)
+	for idx := poolMinWordIdx; idx <= poolMaxWordIdx; idx++ {
+		wordIndexes = append(wordIndexes, idx)
+	}
+
+	var ticks []TickResp
+	for _, chunk := range lo.Chunk[int16](wordIndexes, multicallBatchSize) {
+		rpcRequest := d.ethrpcClient.NewRequest()
+		rpcRequest.SetContext(util.NewContextWithTimestamp(ctx))
+
+		populatedTicks := make([][]populatedTick, len(chunk))
+		for i, wordIndex := range chunk {
+			rpcRequest.AddCall(&ethrpc.Call{
+				ABI:    tickLensABI,
+				Target: d.config.TickLensAddress,
+				Method: tickLensMethodGetPopulatedTicksInWord,
+				Params: []interface{}{common.HexToAddress(poolAddress), wordIndex},
+			}, []interface{}{&populatedTicks[i]})
+		}
+
+		resp, err := rpcRequest.TryAggregate()
+		if err != nil {
+			return nil, err
+		}
+
+		for j, result := range resp.Result {
+			if !result {
+				logger.Errorf("failed to try multicall with param: %v", resp.Request.Calls[j].Params)
+				continue
+			}
+
+			for _, pt := range populatedTicks[j] {
+				ticks = append(ticks, TickResp{
+					TickIdx:        pt.Tick.String(),
+					LiquidityGross: pt.LiquidityGross.String(),
+					LiquidityNet:   pt.LiquidityNet.String(),
+				})
+			}
+		}
+	}
+
+	// Sort the ticks because function NewTickListDataProvider needs
+	sort.SliceStable(ticks, func(i, j int) bool {
+		iTick, _ := strconv.Atoi(ticks[i].TickIdx)
+		jTick, _ := strconv.Atoi(ticks[j].TickIdx)
+
+		return iTick < jTick
+	})
+
+	return ticks, nil
+}
@@ -16,6 +16,11 @@ type Metadata struct {
 	LastPoolIds            []string `json:"lastPoolIds"` // pools that share lastCreatedAtTimestamp
 }
 
+// cursor of the RPC-only pools list updater
+type RPCMetadata struct {
+	LastBlockNumber uint64 `json:"lastBlockNumber"` // last block whose factory logs have been processed
+}
+
 type Token struct {
 	Address  string `json:"id"`
 	Name     string `json:"name"`
@@ -37,6 +42,12 @@ type TickResp struct {
 	LiquidityNet   string `json:"liquidityNet"`
 }
 
+type populatedTick struct {
+	Tick           *big.Int
+	LiquidityNet   *big.Int
+	LiquidityGross *big.Int
+}
+
 type SubgraphPoolTicks struct {
 	ID    string     `json:"id"`
 	Ticks []TickResp `json:"ticks"`
@@ -95,6 +106,12 @@ type FetchRPCResult struct {
 	tickSpacing *big.Int
 	reserve0    *big.Int
 	reserve1    *big.Int
+
+	feeConfigZto *FeeConfiguration
+	feeConfigOtz *FeeConfiguration
+
+	timepoints                map[uint16]Timepoint
+	volumePerLiquidityInBlock *big.Int
 }
 
 type Timepoint struct {
@@ -118,17 +135,88 @@ type TimepointRPC struct {
 	VolumePerLiquidityCumulative  *big.Int
 }
 
+// FeeGrowth is a pair of fee growth accumulators (Q128.128 fees per unit of liquidity), one per token
+type FeeGrowth struct {
+	Token0 *big.Int `json:"token0"`
+	Token1 *big.Int `json:"token1"`
+}
+
+type StaticExtra struct {
+	// off-chain fee rebate for swappers, in basis points, see Meta
+	EffectiveFeeAdjustmentBps int `json:"effectiveFeeAdjustmentBps,omitempty"`
+}
+
 type Extra struct {
 	Liquidity   *big.Int          `json:"liquidity"`
 	GlobalState GlobalState       `json:"globalState"`
 	Ticks       []v3Entities.Tick `json:"ticks"`
 	TickSpacing int24             `json:"tickSpacing"`
+
+	// adaptive fee configurations, nil if the fee is not calculated by the tracker (static fee)
+	FeeConfigZto *FeeConfiguration `json:"feeConfigZto,omitempty"`
+	FeeConfigOtz *FeeConfiguration `json:"feeConfigOtz,omitempty"`
+	// timepoints of the last WINDOW and the volume per liquidity of the current block, set along the configurations so
+	// that the simulator can recompute the fees at its simulation timestamp
+	Timepoints                map[uint16]Timepoint `json:"timepoints,omitempty"`
+	VolumePerLiquidityInBlock *big.Int             `json:"volumePerLiquidityInBlock,omitempty"`
+
+	// optional, the fee growth accumulators start from zero if they are not tracked
+	TotalFeeGrowth       *FeeGrowth        `json:"totalFeeGrowth,omitempty"`
+	TickFeeGrowthOutside map[int]FeeGrowth `json:"tickFeeGrowthOutside,omitempty"`
+
+	// drift in percent between the reserves and the amounts locked by the liquidity of the ticks, set by the tracker
+	ReserveDrift float64 `json:"reserveDrift,omitempty"`
+
+	// nil if the deployment uses the tick bounds of Uniswap V3
+	TickBounds *TickBounds `json:"tickBounds,omitempty"`
+}
+
+// TickBounds are the MIN_TICK and MAX_TICK of a deployment, some L2 deployments use narrower bounds than Uniswap V3
+type TickBounds struct {
+	MinTick int `json:"minTick"`
+	MaxTick int `json:"maxTick"`
+}
+
+// Gas is the gas of a swap: Swap, plus CrossInitTick for each initialized tick crossed
+type Gas struct {
+	Swap          int64
+	CrossInitTick int64
 }
 
 // we won't update the state when calculating amountOut, return this struct instead
 type StateUpdate struct {
 	Liquidity   *big.Int
 	GlobalState GlobalState
+
+	TotalFeeGrowth       FeeGrowth
+	TickFeeGrowthOutside map[int]FeeGrowth // new outer fee growth of the ticks crossed during the swap
+	CrossedTicks         int               // number of initialized ticks crossed during the swap
+	AmountIn             *big.Int          // amount in swapped, less than the requested one for a partial fill
+}
+
+// FeeBreakdown is the fee of a swap direction, the adjustment doesn't change the swap output
+type FeeBreakdown struct {
+	SwapFee                   uint16 `json:"swapFee"`                   // fee charged on-chain, in hundredths of a bip
+	EffectiveFeeAdjustmentBps int    `json:"effectiveFeeAdjustmentBps"` // off-chain rebate, clamped to the swap fee, 0 in strict mode
+}
+
+type Meta struct {
+	PoolAddress string       `json:"poolAddress"`
+	Fee         FeeBreakdown `json:"fee"`
+
+	// sqrtPriceLimitX96 of the simulated swaps, to be passed as is to the pool so that the swap stops at the same price
+	PriceLimit  *big.Int `json:"priceLimit"`
+	CurrentTick int      `json:"currentTick"`
+	// zeroForOne of the swap, true if tokenIn is token0 of the pool, the first token of the pool
+	ZeroForOne  bool `json:"zeroForOne"`
+	TickSpacing int  `json:"tickSpacing"`
+
+	// block timestamp of the fees of the quotes: the one of the tracking, or the one of SetSimulationTimestamp
+	BlockTimestamp int64 `json:"blockTimestamp"`
+	// index of the last timepoint of the data storage
+	TimepointIndex uint16 `json:"timepointIndex"`
+	// time-weighted average tick of the last day of timepoints, nil if the pool has no timepoints
+	TwapTick *int `json:"twapTick"`
 }
 
 func transformTickRespToTick(tickResp TickResp) (v3Entities.Tick, error) {
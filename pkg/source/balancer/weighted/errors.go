@@ -0,0 +1,8 @@
+package balancerweighted
+
+import "errors"
+
+var (
+	// ErrInvalidWeights is returned for the pools with a zero weight, which the power math would divide by
+	ErrInvalidWeights = errors.New("invalid weights")
+)
@@ -42,6 +42,9 @@ func NewPoolSimulator(entityPool entity.Pool) (*WeightedPool2Tokens, error) {
 		tokens[i] = entityPool.Tokens[i].Address
 		reserves[i] = bignumber.NewBig10(entityPool.Reserves[i])
 		weights[i] = big.NewInt(int64(entityPool.Tokens[i].Weight))
+		if weights[i].Sign() <= 0 {
+			return nil, ErrInvalidWeights
+		}
 		decimals[i] = uint(staticExtra.TokenDecimals[i])
 	}
 
@@ -73,6 +76,10 @@ func (t *WeightedPool2Tokens) CalcAmountOut(
 	var tokenIndexFrom = t.GetTokenIndex(tokenAmountIn.Token)
 	var tokenIndexTo = t.GetTokenIndex(tokenOut)
 	if tokenIndexFrom >= 0 && tokenIndexTo >= 0 {
+		if t.Weights[tokenIndexFrom].Sign() <= 0 || t.Weights[tokenIndexTo].Sign() <= 0 {
+			return &pool.CalcAmountOutResult{}, ErrInvalidWeights
+		}
+
 		var maxAmountIn = new(big.Int).Div(new(big.Int).Mul(t.Info.Reserves[tokenIndexFrom], MaxInRatio), bignumber.TenPowInt(2))
 
 		if tokenAmountIn.Amount.Cmp(bignumber.ZeroBI) < 0 {
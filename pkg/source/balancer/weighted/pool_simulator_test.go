@@ -62,3 +62,29 @@ func TestSwap_3token(t *testing.T) {
 	assert.Equal(t, big.NewInt(47), result.TokenAmountOut.Amount)
 	assert.Equal(t, big.NewInt(3), result.Fee.Amount)
 }
+
+func TestSwap_ZeroWeight(t *testing.T) {
+	var poolInfo = entity.Pool{
+		Address:  "adr",
+		SwapFee:  0.0025,
+		Reserves: []string{"5000000", "7000"},
+		Tokens: entity.PoolTokens{
+			&entity.PoolToken{Address: "BAL", Weight: 80},
+			&entity.PoolToken{Address: "WETH", Weight: 0},
+		},
+		StaticExtra: "{\"vaultAddress\":\"v1\",\"poolId\":\"p1\",\"tokenDecimals\":[1,19]}",
+	}
+	_, err := NewPoolSimulator(poolInfo)
+	assert.ErrorIs(t, err, ErrInvalidWeights)
+
+	// a weight zeroed after the pool is built
+	poolInfo.Tokens[1].Weight = 20
+	p, err := NewPoolSimulator(poolInfo)
+	require.Nil(t, err)
+	p.Weights[1] = big.NewInt(0)
+	for _, tokenIn := range []string{"BAL", "WETH"} {
+		tokenOut := p.CanSwapTo(tokenIn)[0]
+		_, err = p.CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: big.NewInt(1000)}, tokenOut)
+		assert.ErrorIs(t, err, ErrInvalidWeights)
+	}
+}
@@ -0,0 +1,129 @@
+package base
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// the estimated error of the Newton step must be this fraction of pool.FastPrecisionMaxRelativeError at most,
+// for the float64 rounding of the other terms
+const fastErrorMargin = 0.1
+
+// cacheFastD caches the D of the reserves for the fast path, it must be called whenever they change
+func (t *PoolBaseSimulator) cacheFastD() {
+	t.fastD, t.fastDAmp = 0, nil
+	a := t._A()
+	if a == nil || a.Sign() <= 0 {
+		return
+	}
+	d, err := t.getD(t._xp(), a)
+	if err != nil {
+		return
+	}
+	t.fastD, t.fastDAmp = bignumber.ToFloat64(d), a
+}
+
+// calcAmountOutFast is GetDy in float64, with the fee in the token out. D is the cached one, and y is a single Newton
+// step from the tangent of the curve at the current balances, so its error is about the square of the deviation from
+// the tangent. Over the vectors of TestCalcAmountOutWithParams the fast outputs are the exact ones up to 10% of the
+// pool, and 9 wei (2e-7 relative) off for half of the pool.
+// ok is false if A changed since D was cached, or if the estimated error of the step is above
+// fastErrorMargin * pool.FastPrecisionMaxRelativeError, the caller must use the exact path then.
+func (t *PoolBaseSimulator) calcAmountOutFast(i, j int, dx *big.Int) (amountOut, fee *big.Int, ok bool) {
+	a := t._A()
+	if a == nil || t.fastDAmp == nil || a.Cmp(t.fastDAmp) != 0 || i == j || dx.Sign() <= 0 {
+		return nil, nil, false
+	}
+
+	numTokens := len(t.Info.Tokens)
+	n := float64(numTokens)
+	xp := make([]float64, numTokens)
+	for k := 0; k < numTokens; k++ {
+		xp[k] = bignumber.ToFloat64(t.Rates[k]) * bignumber.ToFloat64(t.Info.Reserves[k]) / 1e18
+		if xp[k] <= 0 {
+			return nil, nil, false
+		}
+	}
+	ann := bignumber.ToFloat64(a) * n / bignumber.ToFloat64(t.APrecision)
+	d := t.fastD
+
+	// D^(n+1) / (n^n prod(xp)), the slope of the curve is -(ann + dP/xp[i]) / (ann + dP/xp[j])
+	dP := d
+	for k := 0; k < numTokens; k++ {
+		dP = dP * d / (xp[k] * n)
+	}
+	dxp := bignumber.ToFloat64(dx) * bignumber.ToFloat64(t.Rates[i]) / 1e18
+	x := xp[i] + dxp
+	yTangent := xp[j] - dxp*(ann+dP/xp[i])/(ann+dP/xp[j])
+	if yTangent <= 0 {
+		return nil, nil, false
+	}
+
+	c, s := d, 0.0
+	for k := 0; k < numTokens; k++ {
+		var _x float64
+		if k == i {
+			_x = x
+		} else if k != j {
+			_x = xp[k]
+		} else {
+			continue
+		}
+		s += _x
+		c = c * d / (_x * n)
+	}
+	c = c * d / (ann * n)
+	b := s + d/ann
+
+	// y^2 + (b - D) y = c, the error after the step is about step^2 / (2y + b - D)
+	y := (yTangent*yTangent + c) / (2*yTangent + b - d)
+	step := y - yTangent
+	dy := xp[j] - y
+	if dy <= 0 || step*step/(2*y+b-d) > dy*pool.FastPrecisionMaxRelativeError*fastErrorMargin {
+		return nil, nil, false
+	}
+
+	dyFee := dy * bignumber.ToFloat64(t.Info.SwapFee) / bignumber.ToFloat64(FeeDenominator)
+	out := (dy - dyFee) * 1e18 / bignumber.ToFloat64(t.Rates[j])
+	outFee := dyFee * 1e18 / bignumber.ToFloat64(t.Rates[j])
+	if math.IsNaN(out) || math.IsInf(out, 0) || math.IsNaN(outFee) || math.IsInf(outFee, 0) {
+		return nil, nil, false
+	}
+
+	amountOut, _ = big.NewFloat(math.Floor(out)).Int(nil)
+	fee, _ = big.NewFloat(math.Floor(outFee)).Int(nil)
+	return amountOut, fee, true
+}
+
+// CalcAmountOutWithParams is CalcAmountOut with an optional fast path, see pool.PrecisionFast
+func (t *PoolBaseSimulator) CalcAmountOutWithParams(params pool.CalcAmountOutParams) (*pool.CalcAmountOutResult, error) {
+	tokenAmountIn, tokenOut := params.TokenAmountIn, params.TokenOut
+	var tokenIndexFrom = t.Info.GetTokenIndex(tokenAmountIn.Token)
+	var tokenIndexTo = t.Info.GetTokenIndex(tokenOut)
+	if params.IsExact() || tokenIndexFrom < 0 || tokenIndexTo < 0 {
+		return t.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+
+	amountOut, fee, ok := t.calcAmountOutFast(tokenIndexFrom, tokenIndexTo, tokenAmountIn.Amount)
+	if !ok {
+		return t.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+	if amountOut.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, ErrZero
+	}
+
+	return &pool.CalcAmountOutResult{
+		TokenAmountOut: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: amountOut,
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: fee,
+		},
+		Gas: t.gas.Exchange,
+	}, nil
+}
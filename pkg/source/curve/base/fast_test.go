@@ -0,0 +1,101 @@
+package base
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcAmountOutWithParams(t *testing.T) {
+	// same pool as TestCalcAmountOut
+	testcases := []struct {
+		in                string
+		inAmount          int64
+		out               string
+		expectedOutAmount int64
+	}{
+		{"A", 5000, "B", 4998},
+		{"A", 50000, "B", 49986},
+		{"B", 50000, "A", 49983},
+		{"B", 51000, "A", 50982},
+		{"A", 10000000, "B", 0}, // ~10% of the pool, compared to the exact path
+		{"B", 50000000, "A", 0}, // ~50% of the pool
+	}
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange: "",
+		Type:     "",
+		Reserves: entity.PoolReserves{"101940884", "107546110", "208092128367874420986"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}},
+		Extra: fmt.Sprintf("{\"swapFee\": \"%v\", \"adminFee\": \"%v\", \"initialA\": \"%v\", \"futureA\": \"%v\"}",
+			"3000000",    // 0.0003
+			"5000000000", // 0.5
+			150000, 150000),
+		StaticExtra: fmt.Sprintf("{\"lpToken\": \"LP\", \"aPrecision\": \"%v\", \"precisionMultipliers\": [\"%v\", \"%v\"], \"rates\": [\"%v\", \"%v\"]}",
+			"100",
+			"1000000000000", "1000000000000",
+			"1000000000000000000000000000000", "1000000000000000000000000000000"),
+	})
+	require.Nil(t, err)
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: tc.in, Amount: big.NewInt(tc.inAmount)}
+			_, _, ok := p.calcAmountOutFast(p.GetTokenIndex(tc.in), p.GetTokenIndex(tc.out), in.Amount)
+			assert.True(t, ok)
+
+			exact, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+				TokenAmountIn: in,
+				TokenOut:      tc.out,
+				Precision:     pool.PrecisionExact,
+			})
+			require.Nil(t, err)
+			expected := big.NewInt(tc.expectedOutAmount)
+			if tc.expectedOutAmount == 0 {
+				expected = exact.TokenAmountOut.Amount
+			}
+
+			fast, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+				TokenAmountIn: in,
+				TokenOut:      tc.out,
+				Precision:     pool.PrecisionFast,
+			})
+			require.Nil(t, err)
+
+			// |fast - exact| <= max(exact * bound, 1)
+			diff := new(big.Float).SetInt(new(big.Int).Abs(new(big.Int).Sub(fast.TokenAmountOut.Amount, expected)))
+			bound := new(big.Float).Mul(new(big.Float).SetInt(expected), big.NewFloat(pool.FastPrecisionMaxRelativeError))
+			if bound.Cmp(big.NewFloat(1)) < 0 {
+				bound = big.NewFloat(1)
+			}
+			assert.True(t, diff.Cmp(bound) <= 0, "fast %v, exact %v", fast.TokenAmountOut.Amount, expected)
+
+			// the fee, in the token out, within the same bound
+			assert.Equal(t, tc.out, fast.Fee.Token)
+			require.NotNil(t, fast.Fee.Amount)
+			assert.InDelta(t, bignumber.ToFloat64(exact.Fee.Amount), bignumber.ToFloat64(fast.Fee.Amount),
+				math.Max(bignumber.ToFloat64(exact.Fee.Amount)*pool.FastPrecisionMaxRelativeError, 1))
+		})
+	}
+	// a single Newton step is too far off for a trade of the size of the pool
+	_, _, ok := p.calcAmountOutFast(0, 1, big.NewInt(100000000))
+	assert.False(t, ok)
+
+	// D is cached again after a swap
+	in := pool.TokenAmount{Token: "A", Amount: big.NewInt(50000000)}
+	res, err := p.CalcAmountOut(in, "B")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut, Fee: *res.Fee})
+	in = pool.TokenAmount{Token: "B", Amount: big.NewInt(10000000)}
+	exact, err := p.CalcAmountOut(in, "A")
+	require.Nil(t, err)
+	fast, _, ok := p.calcAmountOutFast(1, 0, in.Amount)
+	require.True(t, ok)
+	assert.InDelta(t, bignumber.ToFloat64(exact.TokenAmountOut.Amount), bignumber.ToFloat64(fast), 1)
+}
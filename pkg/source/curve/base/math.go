@@ -443,6 +443,7 @@ func (t *PoolBaseSimulator) AddLiquidity(amounts []*big.Int) (*big.Int, error) {
 		mint_amount = D1
 	}
 	t.LpSupply = new(big.Int).Add(t.LpSupply, mint_amount)
+	t.cacheFastD()
 	return mint_amount, nil
 }
 
@@ -453,6 +454,7 @@ func (t *PoolBaseSimulator) RemoveLiquidityOneCoin(tokenAmount *big.Int, i int)
 	}
 	t.Info.Reserves[i] = new(big.Int).Sub(t.Info.Reserves[i], new(big.Int).Add(dy, new(big.Int).Div(new(big.Int).Mul(dy_fee, t.AdminFee), FeeDenominator)))
 	t.LpSupply = new(big.Int).Sub(t.LpSupply, tokenAmount)
+	t.cacheFastD()
 	return dy, nil
 }
 
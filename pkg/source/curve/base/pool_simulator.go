@@ -27,6 +27,9 @@ type PoolBaseSimulator struct {
 	LpSupply     *big.Int
 	APrecision   *big.Int
 	gas          Gas
+	// D of the reserves and the A it was computed with, see calcAmountOutFast
+	fastD    float64
+	fastDAmp *big.Int
 }
 
 type Gas struct {
@@ -66,7 +69,7 @@ func NewPoolSimulator(entityPool entity.Pool) (*PoolBaseSimulator, error) {
 		aPrecision = bignumber.NewBig10(staticExtra.APrecision)
 	}
 
-	return &PoolBaseSimulator{
+	p := &PoolBaseSimulator{
 		Pool: pool.Pool{
 			Info: pool.PoolInfo{
 				Address:    strings.ToLower(entityPool.Address),
@@ -90,7 +93,9 @@ func NewPoolSimulator(entityPool entity.Pool) (*PoolBaseSimulator, error) {
 		LpSupply:     bignumber.NewBig10(entityPool.Reserves[numTokens]),
 		APrecision:   aPrecision,
 		gas:          DefaultGas,
-	}, nil
+	}
+	p.cacheFastD()
+	return p, nil
 }
 
 func (t *PoolBaseSimulator) CalcAmountOut(
@@ -151,6 +156,7 @@ func (t *PoolBaseSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 			t.Info.Reserves[i] = new(big.Int).Sub(t.Info.Reserves[i], outputAmount)
 		}
 	}
+	t.cacheFastD()
 }
 
 func (t *PoolBaseSimulator) GetMetaInfo(tokenIn string, tokenOut string) interface{} {
@@ -6,7 +6,7 @@ import (
 	"strings"
 
 	"github.com/KyberNetwork/ethrpc"
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -0,0 +1,13 @@
+package stableng
+
+import (
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+const MaxLoopLimit = 255
+
+var (
+	DefaultGas     = Gas{Exchange: 145000}
+	Precision      = bignumber.TenPowInt(18)
+	FeeDenominator = bignumber.TenPowInt(10)
+)
@@ -0,0 +1,14 @@
+package stableng
+
+import "errors"
+
+var (
+	ErrInvalidReserve         = errors.New("invalid reserve")
+	ErrInvalidRates           = errors.New("invalid rates")
+	ErrInvalidBalances        = errors.New("invalid balances")
+	ErrZero                   = errors.New("zero")
+	ErrDDoesNotConverge       = errors.New("d does not converge")
+	ErrTokenFromEqualsTokenTo = errors.New("can't compare token to itself")
+	ErrTokenIndexesOutOfRange = errors.New("token index out of range")
+	ErrAmountOutNotConverge   = errors.New("approximation did not converge")
+)
@@ -0,0 +1,226 @@
+package stableng
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	constant "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+/**
+  Vyper code: https://github.com/curvefi/stableswap-ng/blob/main/contracts/main/CurveStableSwapNG.vy
+  The rates are the stored_rates of the pool, so the oracle and ERC4626 rates are already applied.
+*/
+
+func (t *Pool) _xp() []*big.Int {
+	var nTokens = len(t.Info.Tokens)
+	result := make([]*big.Int, nTokens)
+	for i := 0; i < nTokens; i += 1 {
+		result[i] = new(big.Int).Div(new(big.Int).Mul(t.Rates[i], t.Info.Reserves[i]), Precision)
+	}
+	return result
+}
+
+func (t *Pool) timestamp() int64 {
+	if t.simulationTimestamp > 0 {
+		return t.simulationTimestamp
+	}
+	return time.Now().Unix()
+}
+
+// _A returns the amplification coefficient (multiplied by A_PRECISION), linearly ramping between
+// initial_A and future_A
+func (t *Pool) _A() *big.Int {
+	var t1 = t.FutureATime
+	var a1 = t.FutureA
+	var now = t.timestamp()
+	if t1 > now {
+		var t0 = t.InitialATime
+		var a0 = t.InitialA
+		var elapsed = new(big.Int).SetInt64(now - t0)
+		var duration = new(big.Int).SetInt64(t1 - t0)
+		if a1.Cmp(a0) > 0 {
+			return new(big.Int).Add(a0, new(big.Int).Div(new(big.Int).Mul(new(big.Int).Sub(a1, a0), elapsed), duration))
+		}
+		return new(big.Int).Sub(a0, new(big.Int).Div(new(big.Int).Mul(new(big.Int).Sub(a0, a1), elapsed), duration))
+	}
+	return a1
+}
+
+// _dynamicFee raises the fee when the balances of the two coins get off-peg, if the offpeg fee multiplier is set
+func (t *Pool) _dynamicFee(xpi, xpj *big.Int) *big.Int {
+	if t.OffpegFeeMultiplier.Cmp(FeeDenominator) <= 0 {
+		return t.Info.SwapFee
+	}
+
+	// xps2 = (xpi + xpj) ** 2
+	var xps2 = new(big.Int).Add(xpi, xpj)
+	xps2.Mul(xps2, xps2)
+	if xps2.Sign() == 0 {
+		return t.Info.SwapFee
+	}
+
+	// (feemul * fee) / ((feemul - FEE_DENOMINATOR) * 4 * xpi * xpj / xps2 + FEE_DENOMINATOR)
+	var denominator = new(big.Int).Sub(t.OffpegFeeMultiplier, FeeDenominator)
+	denominator.Mul(denominator, constant.Four)
+	denominator.Mul(denominator, xpi)
+	denominator.Mul(denominator, xpj)
+	denominator.Div(denominator, xps2)
+	denominator.Add(denominator, FeeDenominator)
+	return new(big.Int).Div(new(big.Int).Mul(t.OffpegFeeMultiplier, t.Info.SwapFee), denominator)
+}
+
+func (t *Pool) getD(xp []*big.Int, amp *big.Int) (*big.Int, error) {
+	var numTokens = len(xp)
+	var numTokensBI = big.NewInt(int64(numTokens))
+	var s = big.NewInt(0)
+	for i := 0; i < numTokens; i++ {
+		s = new(big.Int).Add(s, xp[i])
+	}
+	if s.Sign() == 0 {
+		return s, nil
+	}
+
+	// D_P is divided by N_COINS ** N_COINS once, unlike the older pools which divide by xp[i] * N_COINS
+	var nPowN = new(big.Int).Exp(numTokensBI, numTokensBI, nil)
+	var d = new(big.Int).Set(s)
+	var ann = new(big.Int).Mul(amp, numTokensBI)
+	var budget = pool.GetComputeBudget()
+	for i := 0; i < MaxLoopLimit; i++ {
+		if budget.NewtonIterationsExceeded(i + 1) {
+			return nil, pool.ErrComputeBudgetExceeded
+		}
+		var dP = new(big.Int).Set(d)
+		for j := 0; j < numTokens; j++ {
+			if xp[j].Sign() == 0 {
+				return nil, ErrZero
+			}
+			dP = new(big.Int).Div(new(big.Int).Mul(dP, d), xp[j])
+		}
+		dP.Div(dP, nPowN)
+		var prevD = d
+		d = new(big.Int).Div(
+			new(big.Int).Mul(
+				new(big.Int).Add(
+					new(big.Int).Div(new(big.Int).Mul(ann, s), t.APrecision),
+					new(big.Int).Mul(dP, numTokensBI),
+				),
+				d,
+			),
+			new(big.Int).Add(
+				new(big.Int).Div(new(big.Int).Mul(new(big.Int).Sub(ann, t.APrecision), d), t.APrecision),
+				new(big.Int).Mul(dP, big.NewInt(int64(numTokens+1))),
+			),
+		)
+		if new(big.Int).Sub(d, prevD).CmpAbs(constant.One) <= 0 {
+			return d, nil
+		}
+	}
+	return nil, ErrDDoesNotConverge
+}
+
+func (t *Pool) getY(
+	tokenIndexFrom int,
+	tokenIndexTo int,
+	x *big.Int,
+	xp []*big.Int,
+	amp *big.Int,
+	d *big.Int,
+) (*big.Int, error) {
+	var numTokens = len(xp)
+	if tokenIndexFrom == tokenIndexTo {
+		return nil, ErrTokenFromEqualsTokenTo
+	}
+	if tokenIndexFrom >= numTokens || tokenIndexTo >= numTokens {
+		return nil, ErrTokenIndexesOutOfRange
+	}
+
+	var numTokensBI = big.NewInt(int64(numTokens))
+	var c = new(big.Int).Set(d)
+	var s = big.NewInt(0)
+	var ann = new(big.Int).Mul(amp, numTokensBI)
+	var _x *big.Int
+	for i := 0; i < numTokens; i++ {
+		if i == tokenIndexFrom {
+			_x = x
+		} else if i != tokenIndexTo {
+			_x = xp[i]
+		} else {
+			continue
+		}
+		if _x.Sign() == 0 {
+			return nil, ErrZero
+		}
+		s = new(big.Int).Add(s, _x)
+		c = new(big.Int).Div(new(big.Int).Mul(c, d), new(big.Int).Mul(_x, numTokensBI))
+	}
+	if ann.Sign() == 0 {
+		return nil, ErrZero
+	}
+	c = new(big.Int).Div(
+		new(big.Int).Mul(new(big.Int).Mul(c, d), t.APrecision),
+		new(big.Int).Mul(ann, numTokensBI),
+	)
+	var b = new(big.Int).Add(s, new(big.Int).Div(new(big.Int).Mul(d, t.APrecision), ann))
+	var y = new(big.Int).Set(d)
+	var budget = pool.GetComputeBudget()
+	for i := 0; i < MaxLoopLimit; i++ {
+		if budget.NewtonIterationsExceeded(i + 1) {
+			return nil, pool.ErrComputeBudgetExceeded
+		}
+		var yPrev = y
+		y = new(big.Int).Div(
+			new(big.Int).Add(new(big.Int).Mul(y, y), c),
+			new(big.Int).Sub(new(big.Int).Add(new(big.Int).Mul(y, constant.Two), b), d),
+		)
+		if new(big.Int).Sub(y, yPrev).CmpAbs(constant.One) <= 0 {
+			return y, nil
+		}
+	}
+	return nil, ErrAmountOutNotConverge
+}
+
+// GetDy returns the output of get_dy and the fee, both in tokenOut, and the admin part of the fee in tokenOut,
+// which is taken out of the balances of the pool
+func (t *Pool) GetDy(
+	i int,
+	j int,
+	dx *big.Int,
+) (*big.Int, *big.Int, *big.Int, error) {
+	var amp = t._A()
+	var xp = t._xp()
+	var d, err = t.getD(xp, amp)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// x: uint256 = xp[i] + dx * rates[i] / PRECISION
+	var x = new(big.Int).Add(xp[i], new(big.Int).Div(new(big.Int).Mul(dx, t.Rates[i]), Precision))
+	y, err := t.getY(i, j, x, xp, amp, d)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// dy: uint256 = xp[j] - y - 1
+	var dy = new(big.Int).Sub(new(big.Int).Sub(xp[j], y), constant.One)
+	if dy.Sign() <= 0 {
+		return nil, nil, nil, ErrZero
+	}
+
+	// fee: uint256 = self._dynamic_fee((xp[i] + x) / 2, (xp[j] + y) / 2, base_fee, fee_multiplier) * dy / FEE_DENOMINATOR
+	var fee = t._dynamicFee(
+		new(big.Int).Div(new(big.Int).Add(xp[i], x), constant.Two),
+		new(big.Int).Div(new(big.Int).Add(xp[j], y), constant.Two),
+	)
+	fee = new(big.Int).Div(new(big.Int).Mul(fee, dy), FeeDenominator)
+
+	// dy_admin_fee: uint256 = dy_fee * admin_fee / FEE_DENOMINATOR, converted like dy
+	var adminFee = new(big.Int).Div(new(big.Int).Mul(fee, t.AdminFee), FeeDenominator)
+	adminFee = new(big.Int).Div(new(big.Int).Mul(adminFee, Precision), t.Rates[j])
+
+	// (dy - fee) * PRECISION / rates[j]
+	dy = new(big.Int).Div(new(big.Int).Mul(new(big.Int).Sub(dy, fee), Precision), t.Rates[j])
+	fee = new(big.Int).Div(new(big.Int).Mul(fee, Precision), t.Rates[j])
+	return dy, fee, adminFee, nil
+}
@@ -0,0 +1,221 @@
+package stableng
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/curve"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	constant "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	utils "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// Pool simulates the Curve stableswap-ng plain pools: ramping A, off-peg dynamic fee and rates of the coins
+type Pool struct {
+	pool.Pool
+	// extra fields
+	Rates               []*big.Int
+	InitialA            *big.Int
+	FutureA             *big.Int
+	InitialATime        int64
+	FutureATime         int64
+	AdminFee            *big.Int
+	OffpegFeeMultiplier *big.Int
+	LpToken             string
+	LpSupply            *big.Int
+	APrecision          *big.Int
+	gas                 Gas
+
+	simulationTimestamp int64
+}
+
+type Gas struct {
+	Exchange int64
+}
+
+type SwapInfo struct {
+	AdminFee *big.Int // admin part of the fee, in tokenOut
+}
+
+func NewPoolSimulator(entityPool entity.Pool) (*Pool, error) {
+	var staticExtra curve.PoolStableNgStaticExtra
+	if err := json.Unmarshal([]byte(entityPool.StaticExtra), &staticExtra); err != nil {
+		return nil, err
+	}
+
+	var extra curve.PoolStableNgExtra
+	if err := json.Unmarshal([]byte(entityPool.Extra), &extra); err != nil {
+		return nil, err
+	}
+
+	var numTokens = len(entityPool.Tokens)
+	// the reserves are the balances of the coins followed by the LP supply
+	if len(entityPool.Reserves) != numTokens+1 {
+		return nil, ErrInvalidReserve
+	}
+	if len(extra.Rates) != numTokens {
+		return nil, ErrInvalidRates
+	}
+
+	var tokens = make([]string, numTokens)
+	var reserves = make([]*big.Int, numTokens)
+	for i := 0; i < numTokens; i += 1 {
+		tokens[i] = entityPool.Tokens[i].Address
+		reserves[i] = utils.NewBig10(entityPool.Reserves[i])
+		if extra.Rates[i] == nil || extra.Rates[i].Sign() <= 0 {
+			return nil, ErrInvalidRates
+		}
+	}
+	reserves, err := balancesOf(reserves, staticExtra.IsRebasing, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	var aPrecision = constant.One
+	if len(staticExtra.APrecision) > 0 {
+		aPrecision = utils.NewBig10(staticExtra.APrecision)
+	}
+	var offpegFeeMultiplier = constant.ZeroBI
+	if len(extra.OffpegFeeMultiplier) > 0 {
+		offpegFeeMultiplier = utils.NewBig10(extra.OffpegFeeMultiplier)
+	}
+
+	return &Pool{
+		Pool: pool.Pool{
+			Info: pool.PoolInfo{
+				Address:    strings.ToLower(entityPool.Address),
+				ReserveUsd: entityPool.ReserveUsd,
+				SwapFee:    utils.NewBig10(extra.SwapFee),
+				Exchange:   entityPool.Exchange,
+				Type:       entityPool.Type,
+				Tokens:     tokens,
+				Reserves:   reserves,
+				Checked:    false,
+			},
+		},
+		Rates:               extra.Rates,
+		InitialA:            utils.NewBig10(extra.InitialA),
+		FutureA:             utils.NewBig10(extra.FutureA),
+		InitialATime:        extra.InitialATime,
+		FutureATime:         extra.FutureATime,
+		AdminFee:            utils.NewBig10(extra.AdminFee),
+		OffpegFeeMultiplier: offpegFeeMultiplier,
+		LpToken:             staticExtra.LpToken,
+		LpSupply:            utils.NewBig10(entityPool.Reserves[numTokens]),
+		APrecision:          aPrecision,
+		gas:                 DefaultGas,
+	}, nil
+}
+
+// balancesOf returns the balances used by the pool for the swaps, _balances of CurveStableSwapNG.vy: the stored
+// balances, or the balanceOf the pool of the coins if it's rebasing, less the admin balances. The reserves stand for
+// the source which isn't set. The stored balances of the rebasing coins lag behind their balanceOf, until a transfer
+// of the coin by the pool updates them.
+func balancesOf(reserves []*big.Int, isRebasing bool, extra curve.PoolStableNgExtra) ([]*big.Int, error) {
+	source := extra.StoredBalances
+	if isRebasing {
+		source = extra.BalancesOf
+	}
+	if source == nil {
+		if extra.AdminBalances == nil {
+			return reserves, nil
+		}
+		source = reserves
+	}
+	if len(source) != len(reserves) || extra.AdminBalances != nil && len(extra.AdminBalances) != len(reserves) {
+		return nil, ErrInvalidBalances
+	}
+
+	balances := make([]*big.Int, len(reserves))
+	for i := range balances {
+		if source[i] == nil {
+			return nil, ErrInvalidBalances
+		}
+		balances[i] = new(big.Int).Set(source[i])
+		if extra.AdminBalances != nil && extra.AdminBalances[i] != nil {
+			balances[i].Sub(balances[i], extra.AdminBalances[i])
+		}
+		if balances[i].Sign() < 0 {
+			return nil, ErrInvalidBalances
+		}
+	}
+	return balances, nil
+}
+
+// SetSimulationTimestamp sets the block timestamp A is ramped at, 0 (the default) means now
+func (t *Pool) SetSimulationTimestamp(timestamp int64) {
+	t.simulationTimestamp = timestamp
+}
+
+func (t *Pool) CalcAmountOut(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+) (*pool.CalcAmountOutResult, error) {
+	// swap from token to token
+	var tokenIndexFrom = t.Info.GetTokenIndex(tokenAmountIn.Token)
+	var tokenIndexTo = t.Info.GetTokenIndex(tokenOut)
+	if tokenIndexFrom >= 0 && tokenIndexTo >= 0 {
+		amountOut, fee, adminFee, err := t.GetDy(
+			tokenIndexFrom,
+			tokenIndexTo,
+			tokenAmountIn.Amount,
+		)
+		if err != nil {
+			return &pool.CalcAmountOutResult{}, err
+		}
+
+		if amountOut.Cmp(constant.ZeroBI) > 0 {
+			return &pool.CalcAmountOutResult{
+				TokenAmountOut: &pool.TokenAmount{
+					Token:  tokenOut,
+					Amount: amountOut,
+				},
+				Fee: &pool.TokenAmount{
+					Token:  tokenOut,
+					Amount: fee,
+				},
+				Gas:      t.gas.Exchange,
+				SwapInfo: SwapInfo{AdminFee: adminFee},
+			}, nil
+		}
+
+		return &pool.CalcAmountOutResult{}, errors.New("[core.CurveStableNg] - GetDy returns 0")
+	}
+	return &pool.CalcAmountOutResult{}, fmt.Errorf("tokenIndexFrom %v or TokenOutIndex %v is not correct", tokenIndexFrom, tokenIndexTo)
+}
+
+func (t *Pool) UpdateBalance(params pool.UpdateBalanceParams) {
+	input, output := params.TokenAmountIn, params.TokenAmountOut
+	var outputAmount = output.Amount
+	// the admin fee is moved to the admin balances, out of the balances of the pool
+	if swapInfo, ok := params.SwapInfo.(SwapInfo); ok && swapInfo.AdminFee != nil {
+		outputAmount = new(big.Int).Add(outputAmount, swapInfo.AdminFee)
+	}
+
+	for i := range t.Info.Tokens {
+		if t.Info.Tokens[i] == input.Token {
+			t.Info.Reserves[i] = new(big.Int).Add(t.Info.Reserves[i], input.Amount)
+		}
+		if t.Info.Tokens[i] == output.Token {
+			t.Info.Reserves[i] = new(big.Int).Sub(t.Info.Reserves[i], outputAmount)
+		}
+	}
+}
+
+func (t *Pool) GetLpToken() string {
+	return t.LpToken
+}
+
+func (t *Pool) GetMetaInfo(tokenIn string, tokenOut string) interface{} {
+	var fromId = t.GetTokenIndex(tokenIn)
+	var toId = t.GetTokenIndex(tokenOut)
+	return curve.Meta{
+		TokenInIndex:  fromId,
+		TokenOutIndex: toId,
+		Underlying:    false,
+	}
+}
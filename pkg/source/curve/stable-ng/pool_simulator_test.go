@@ -0,0 +1,188 @@
+package stableng
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A ramping from 100 to 200 over a day, an off-peg fee multiplier of 2 and rates of an 18 decimals coin with an
+// oracle rate of 1.15, a 6 decimals coin and an 18 decimals ERC4626 coin.
+// The expected amounts are computed with a port of get_dy and exchange of CurveStableSwapNG.vy.
+func newTestPool(t *testing.T) *Pool {
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange: "curve-stable-ng",
+		Type:     "curve-stable-ng",
+		Reserves: entity.PoolReserves{"1000000000000000000000", "1200000000", "900000000000000000000", "3200000000000000000000"},
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}, {Address: "C"}},
+		Extra: `{"rates":[1150000000000000000,1000000000000000000000000000000,1002345678901234567],` +
+			`"initialA":"10000","futureA":"20000","initialATime":1700000000,"futureATime":1700086400,` +
+			`"swapFee":"1000000","adminFee":"5000000000","offpegFeeMultiplier":"20000000000"}`,
+		StaticExtra: `{"lpToken":"LP","aPrecision":"100"}`,
+	})
+	require.Nil(t, err)
+	// mid-ramp, A = 134.72
+	p.SetSimulationTimestamp(1700030000)
+	return p
+}
+
+func TestCalcAmountOut(t *testing.T) {
+	testcases := []struct {
+		in                string
+		inAmount          string
+		out               string
+		expectedOutAmount string
+		expectedFee       string
+	}{
+		{"A", "1000000000000000000", "B", "1150217", "115"},
+		{"B", "1000000", "A", "869215594108724083", "86950683088983"},
+		{"A", "100000000000000000000", "C", "114378748668002632676", "11620528679435662"},
+		{"C", "500000000000000000000", "B", "500278311", "50266"},
+		{"B", "1", "C", "995319731254", "100551583"},
+	}
+	p := newTestPool(t)
+
+	assert.Equal(t, []string{"B", "C"}, p.CanSwapTo("A"))
+	assert.Equal(t, 0, len(p.CanSwapTo("LP")))
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			out, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.in, Amount: bignumber.NewBig10(tc.inAmount)}, tc.out)
+			require.Nil(t, err)
+			assert.Equal(t, bignumber.NewBig10(tc.expectedOutAmount), out.TokenAmountOut.Amount)
+			assert.Equal(t, bignumber.NewBig10(tc.expectedFee), out.Fee.Amount)
+			assert.Equal(t, tc.out, out.TokenAmountOut.Token)
+		})
+	}
+
+	// at the end of the ramp
+	p.SetSimulationTimestamp(1700086400)
+	out, err := p.CalcAmountOut(pool.TokenAmount{Token: "A", Amount: big.NewInt(1e18)}, "B")
+	require.Nil(t, err)
+	assert.Equal(t, big.NewInt(1150109), out.TokenAmountOut.Amount)
+}
+
+func TestUpdateBalance(t *testing.T) {
+	p := newTestPool(t)
+
+	amountIn := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("100000000000000000000")}
+	out, err := p.CalcAmountOut(amountIn, "B")
+	require.Nil(t, err)
+	assert.Equal(t, big.NewInt(114944006), out.TokenAmountOut.Amount)
+	p.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  amountIn,
+		TokenAmountOut: *out.TokenAmountOut,
+		Fee:            *out.Fee,
+		SwapInfo:       out.SwapInfo,
+	})
+	// the admin fee leaves the balances too
+	assert.Equal(t, big.NewInt(1200000000-114944006-5749), p.Info.Reserves[1])
+
+	out, err = p.CalcAmountOut(pool.TokenAmount{Token: "B", Amount: big.NewInt(1e6)}, "A")
+	require.Nil(t, err)
+	assert.Equal(t, bignumber.NewBig10("870409987727532157"), out.TokenAmountOut.Amount)
+}
+
+// newRebasingTestPool is the pool of newTestPool with the accounting of its balances, coin A rebasing: its balanceOf
+// is above its stored balance by the rebases since the last transfer
+func newRebasingTestPool(t *testing.T, isRebasing bool, reserves entity.PoolReserves, accounting string) (*Pool, error) {
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange: "curve-stable-ng",
+		Type:     "curve-stable-ng",
+		Reserves: reserves,
+		Tokens:   []*entity.PoolToken{{Address: "A"}, {Address: "B"}, {Address: "C"}},
+		Extra: `{"rates":[1150000000000000000,1000000000000000000000000000000,1002345678901234567],` +
+			`"initialA":"10000","futureA":"20000","initialATime":1700000000,"futureATime":1700086400,` +
+			`"swapFee":"1000000","adminFee":"5000000000","offpegFeeMultiplier":"20000000000"` + accounting + `}`,
+		StaticExtra: fmt.Sprintf(`{"lpToken":"LP","aPrecision":"100","isRebasing":%v}`, isRebasing),
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.SetSimulationTimestamp(1700030000)
+	return p, nil
+}
+
+func TestCalcAmountOut_Rebasing(t *testing.T) {
+	accounting := `,"storedBalances":[1000000000000000000000,1200000000,900000000000000000000],` +
+		`"balancesOf":[1031000000000000000000,1200000000,900000000000000000000],` +
+		`"adminBalances":[1000000000000000000,500,3000000000000000]`
+	reserves := entity.PoolReserves{"1000000000000000000000", "1200000000", "900000000000000000000", "3200000000000000000000"}
+
+	testcases := []struct {
+		isRebasing bool
+		// reserves of the pool whose balances are the expected ones
+		expectedBalances entity.PoolReserves
+	}{
+		{false, entity.PoolReserves{"999000000000000000000", "1199999500", "899997000000000000000", "3200000000000000000000"}},
+		{true, entity.PoolReserves{"1030000000000000000000", "1199999500", "899997000000000000000", "3200000000000000000000"}},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p, err := newRebasingTestPool(t, tc.isRebasing, reserves, accounting)
+			require.Nil(t, err)
+			expected, err := newRebasingTestPool(t, false, tc.expectedBalances, "")
+			require.Nil(t, err)
+			assert.Equal(t, expected.Info.Reserves, p.Info.Reserves)
+
+			for _, swap := range [][3]string{{"A", "1000000000000000000", "B"}, {"B", "1000000", "A"},
+				{"C", "1000000000000000000", "A"}} {
+				amountIn := pool.TokenAmount{Token: swap[0], Amount: bignumber.NewBig10(swap[1])}
+				out, err := p.CalcAmountOut(amountIn, swap[2])
+				require.Nil(t, err)
+				expectedOut, err := expected.CalcAmountOut(amountIn, swap[2])
+				require.Nil(t, err)
+				assert.Equal(t, expectedOut.TokenAmountOut, out.TokenAmountOut)
+			}
+		})
+	}
+
+	// the rebases of A make it cheaper
+	stored, err := newRebasingTestPool(t, false, reserves, accounting)
+	require.Nil(t, err)
+	rebasing, err := newRebasingTestPool(t, true, reserves, accounting)
+	require.Nil(t, err)
+	amountIn := pool.TokenAmount{Token: "B", Amount: big.NewInt(1e8)}
+	storedOut, err := stored.CalcAmountOut(amountIn, "A")
+	require.Nil(t, err)
+	rebasingOut, err := rebasing.CalcAmountOut(amountIn, "A")
+	require.Nil(t, err)
+	assert.Equal(t, 1, rebasingOut.TokenAmountOut.Amount.Cmp(storedOut.TokenAmountOut.Amount))
+
+	// without accounting, the reserves are the balances
+	p, err := newRebasingTestPool(t, true, reserves, "")
+	require.Nil(t, err)
+	assert.Equal(t, "1000000000000000000000", p.Info.Reserves[0].String())
+
+	for _, invalid := range []string{
+		`,"balancesOf":[1031000000000000000000,1200000000]`,
+		`,"balancesOf":[1031000000000000000000,1200000000,900000000000000000000],"adminBalances":[0,0]`,
+		`,"balancesOf":[1031000000000000000000,1200000000,900000000000000000000],"adminBalances":[0,1200000001,0]`,
+	} {
+		_, err := newRebasingTestPool(t, true, reserves, invalid)
+		assert.ErrorIs(t, err, ErrInvalidBalances, invalid)
+	}
+}
+
+func TestCalcAmountOut_ComputeBudget(t *testing.T) {
+	defer pool.SetComputeBudget(pool.ComputeBudget{})
+	reserves := entity.PoolReserves{"1000000000000000000000", "1200000000", "900000000000000000000", "3200000000000000000000"}
+	p, err := newRebasingTestPool(t, false, reserves, "")
+	require.Nil(t, err)
+	amountIn := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("1000000000000000000")}
+
+	pool.SetComputeBudget(pool.ComputeBudget{MaxNewtonIterations: 1})
+	_, err = p.CalcAmountOut(amountIn, "B")
+	assert.ErrorIs(t, err, pool.ErrComputeBudgetExceeded)
+
+	pool.SetComputeBudget(pool.ComputeBudget{MaxNewtonIterations: MaxLoopLimit})
+	_, err = p.CalcAmountOut(amountIn, "B")
+	assert.Nil(t, err)
+}
@@ -373,12 +373,32 @@ func (t *Pool) price_scale(k uint) *big.Int {
 //	return t._packed_view(k, t.LastPricesPacked)
 //}
 
+// blockTimestamp is the timestamp of the simulated block, the current time unless it is set by SetBlockTimestamp
+func (t *Pool) blockTimestamp() int64 {
+	if t.blockTimestampOverride > 0 {
+		return t.blockTimestampOverride
+	}
+	return time.Now().Unix()
+}
+
+// PriceOracle returns the unpacked EMA price oracle of coins 1..n-1 against coin 0
+func (t *Pool) PriceOracle() []*big.Int {
+	var nCoins = len(t.Info.Tokens)
+	var prices = make([]*big.Int, nCoins-1)
+	var packed_prices = t.PriceOraclePacked
+	for k := 0; k < nCoins-1; k += 1 {
+		prices[k] = new(big.Int).And(packed_prices, PriceMask)
+		packed_prices = new(big.Int).Rsh(packed_prices, PriceSize)
+	}
+	return prices
+}
+
 func (t *Pool) _A_gamma() []*big.Int {
 	var t1 = t.FutureAGammaTime
 	var A_gamma_1 = t.FutureAGamma
 	var gamma1 = new(big.Int).And(A_gamma_1, PriceMask)
 	var A1 = new(big.Int).Rsh(A_gamma_1, 128)
-	var now = time.Now().Unix()
+	var now = t.blockTimestamp()
 	if now < t1 {
 		var A_gamma_0 = t.InitialAGamma
 		var t0 = t.InitialAGammaTime
@@ -493,7 +513,7 @@ func (t *Pool) Exchange(i int, j int, dx *big.Int) (*big.Int, error) {
 			}
 			t.D = temp
 			xp[i] = x1
-			if time.Now().Unix() >= ti {
+			if t.blockTimestamp() >= ti {
 				t.FutureAGammaTime = 1
 			}
 		}
@@ -554,7 +574,7 @@ func (t *Pool) tweak_price(A_gamma []*big.Int, _xp []*big.Int, i int, p_i *big.I
 		last_prices[k] = new(big.Int).And(packed_prices, PriceMask)
 		packed_prices = new(big.Int).Rsh(packed_prices, PriceSize)
 	}
-	var blockTimestamp = time.Now().Unix()
+	var blockTimestamp = t.blockTimestamp()
 	if last_prices_timestamp < blockTimestamp {
 		var ma_half_time = t.MaHalfTime
 		var alpha, _ = halfpow(
@@ -10,6 +10,7 @@ import (
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/curve"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type Pool struct {
@@ -40,6 +41,10 @@ type Pool struct {
 	MaHalfTime         *big.Int
 	NotAdjusted        bool
 	gas                Gas
+
+	// timestamp of the simulated block, used by the A/gamma ramping and the price oracle EMA.
+	// 0 means the current time
+	blockTimestampOverride int64
 }
 
 type Gas struct {
@@ -156,12 +161,34 @@ func (t *Pool) CalcAmountOut(
 	return &pool.CalcAmountOutResult{}, fmt.Errorf("tokenIndexFrom %v or tokenIndexTo %v is not correct", tokenIndexFrom, tokenIndexTo)
 }
 
+// SetBlockTimestamp sets the timestamp of the simulated block, so that sequential swaps at different blocks
+// move the price oracle EMA accordingly
+func (t *Pool) SetBlockTimestamp(timestamp int64) {
+	t.blockTimestampOverride = timestamp
+}
+
+// UpdateBalance runs the exchange on the pool state: balances, D, and the price oracle EMA / last prices /
+// price scale via tweak_price, so that sequential quotes see the moved oracle
 func (t *Pool) UpdateBalance(params pool.UpdateBalanceParams) {
 	input, output := params.TokenAmountIn, params.TokenAmountOut
 	var inputAmount = input.Amount
 	var inputIndex = t.GetTokenIndex(input.Token)
 	var outputIndex = t.GetTokenIndex(output.Token)
-	_, _ = t.Exchange(inputIndex, outputIndex, inputAmount)
+
+	// Exchange moves the balances, D and the A/gamma ramp before it can fail, so it runs on a copy
+	// that replaces the pool state only on success. Exchange never mutates the big.Int values in place,
+	// only the reserves slice needs its own backing array
+	var next = *t
+	next.Info.Reserves = make([]*big.Int, len(t.Info.Reserves))
+	copy(next.Info.Reserves, t.Info.Reserves)
+	if _, err := next.Exchange(inputIndex, outputIndex, inputAmount); err != nil {
+		logger.WithFields(logger.Fields{
+			"poolAddress": t.Info.Address,
+			"error":       err,
+		}).Warn("failed to UpdateBalance for curve tricrypto pool")
+		return
+	}
+	*t = next
 }
 
 func (t *Pool) GetMetaInfo(tokenIn string, tokenOut string) interface{} {
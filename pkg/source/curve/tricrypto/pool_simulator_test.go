@@ -94,3 +94,109 @@ func TestUpdateBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateBalance_PriceOracle(t *testing.T) {
+	// same pool as TestUpdateBalance, lastPricesTimestamp = 1686881243, maHalfTime = 600
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange:    "",
+		Type:        "",
+		Reserves:    entity.PoolReserves{"54622071905620", "212612125596", "32702943198449356152968"},
+		Tokens:      []*entity.PoolToken{{Address: "A"}, {Address: "B"}, {Address: "C"}},
+		Extra:       "{\"A\":\"1707629\",\"D\":\"162178081891452839666627043\",\"gamma\":\"11809167828997\",\"priceScale\":[\"25182439404844022315525\",\"1651754874918630176109\",\"\"],\"lastPrices\":[\"25500942865479281498021\",\"1663587698754935470890\",\"\"],\"priceOracle\":[\"25539624777171725534648\",\"1663613751394784561740\",\"\"],\"feeGamma\":\"500000000000000\",\"midFee\":\"3000000\",\"outFee\":\"30000000\",\"futureAGammaTime\":0,\"futureAGamma\":\"581076037942835227425498917514114728328226821\",\"initialAGammaTime\":1633548703,\"initialAGamma\":\"183752478137306770270222288013175834186240000\",\"lastPricesTimestamp\":1686881243,\"lpSupply\":\"151202189871784267102739\",\"xcpProfit\":\"1063768898620289638\",\"virtualPrice\":\"1031885933288137559\",\"allowedExtraProfit\":\"2000000000000\",\"adjustmentStep\":\"490000000000000\",\"maHalfTime\":\"600\"}",
+		StaticExtra: "{\"lpToken\":\"LP\",\"precisionMultipliers\":[\"1000000000000\",\"10000000000\",\"1\"]}",
+	})
+	require.Nil(t, err)
+
+	// price_oracle = (last_prices * (1 - alpha) + price_oracle * alpha), alpha = 0.5 ** (dt / ma_half_time)
+	expectedPriceOracle := func(lastPrices, priceOracle []*big.Int, dt int64) []*big.Int {
+		alpha, err := halfpow(new(big.Int).Div(new(big.Int).Mul(big.NewInt(dt), bignumber.BONE), big.NewInt(600)), bignumber.TenPowInt(10))
+		require.Nil(t, err)
+		res := make([]*big.Int, len(priceOracle))
+		for k := range priceOracle {
+			res[k] = new(big.Int).Div(
+				new(big.Int).Add(new(big.Int).Mul(lastPrices[k], new(big.Int).Sub(bignumber.BONE, alpha)), new(big.Int).Mul(priceOracle[k], alpha)),
+				bignumber.BONE,
+			)
+		}
+		return res
+	}
+	lastPrices := func() []*big.Int {
+		return []*big.Int{
+			new(big.Int).And(p.LastPricesPacked, PriceMask),
+			new(big.Int).And(new(big.Int).Rsh(p.LastPricesPacked, PriceSize), PriceMask),
+		}
+	}
+
+	testcases := []struct {
+		in        string
+		inAmount  string
+		out       string
+		timestamp int64
+	}{
+		{"A", "100000000", "C", 1686881243 + 600},           // half of the way to the last prices
+		{"C", "1000000000000000000", "A", 1686881243 + 600}, // same block, the oracle doesn't move
+		{"B", "1000000", "C", 1686881243 + 1800},
+		{"C", "10000000000000000000", "B", 1686881243 + 1812},
+	}
+
+	prevTimestamp := p.LastPricesTimestamp
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p.SetBlockTimestamp(tc.timestamp)
+			expected := p.PriceOracle()
+			if tc.timestamp > prevTimestamp {
+				expected = expectedPriceOracle(lastPrices(), expected, tc.timestamp-prevTimestamp)
+			}
+
+			amountIn := pool.TokenAmount{Token: tc.in, Amount: bignumber.NewBig10(tc.inAmount)}
+			out, err := p.CalcAmountOut(amountIn, tc.out)
+			require.Nil(t, err)
+			p.UpdateBalance(pool.UpdateBalanceParams{
+				TokenAmountIn:  amountIn,
+				TokenAmountOut: *out.TokenAmountOut,
+				Fee:            *out.Fee,
+				SwapInfo:       out.SwapInfo,
+			})
+
+			assert.Equal(t, expected, p.PriceOracle())
+			assert.Equal(t, tc.timestamp, p.LastPricesTimestamp)
+			prevTimestamp = tc.timestamp
+
+			// the next quote sees the moved state
+			next, err := p.CalcAmountOut(amountIn, tc.out)
+			require.Nil(t, err)
+			assert.NotEqual(t, out.TokenAmountOut.Amount, next.TokenAmountOut.Amount)
+		})
+	}
+}
+
+func TestUpdateBalance_ExchangeFails(t *testing.T) {
+	p, err := NewPoolSimulator(entity.Pool{
+		Exchange:    "",
+		Type:        "",
+		Reserves:    entity.PoolReserves{"54622071905620", "212612125596", "32702943198449356152968"},
+		Tokens:      []*entity.PoolToken{{Address: "A"}, {Address: "B"}, {Address: "C"}},
+		Extra:       "{\"A\":\"1707629\",\"D\":\"162178081891452839666627043\",\"gamma\":\"11809167828997\",\"priceScale\":[\"25182439404844022315525\",\"1651754874918630176109\",\"\"],\"lastPrices\":[\"25500942865479281498021\",\"1663587698754935470890\",\"\"],\"priceOracle\":[\"25539624777171725534648\",\"1663613751394784561740\",\"\"],\"feeGamma\":\"500000000000000\",\"midFee\":\"3000000\",\"outFee\":\"30000000\",\"futureAGammaTime\":1686881243,\"futureAGamma\":\"581076037942835227425498917514114728328226821\",\"initialAGammaTime\":1633548703,\"initialAGamma\":\"183752478137306770270222288013175834186240000\",\"lastPricesTimestamp\":1686881243,\"lpSupply\":\"151202189871784267102739\",\"xcpProfit\":\"1063768898620289638\",\"virtualPrice\":\"1031885933288137559\",\"allowedExtraProfit\":\"2000000000000\",\"adjustmentStep\":\"490000000000000\",\"maHalfTime\":\"600\"}",
+		StaticExtra: "{\"lpToken\":\"LP\",\"precisionMultipliers\":[\"1000000000000\",\"10000000000\",\"1\"]}",
+	})
+	require.Nil(t, err)
+	p.SetBlockTimestamp(1686881243 + 600)
+
+	// the ramp is over, so Exchange recomputes D and ends the ramp before newton_y rejects the unbalanced pool
+	amountIn := pool.TokenAmount{Token: "A", Amount: bignumber.NewBig10("1000000000000000000000000")}
+	probe := *p
+	probe.Info.Reserves = append([]*big.Int(nil), p.Info.Reserves...)
+	_, err = probe.Exchange(0, 2, amountIn.Amount)
+	require.NotNil(t, err)
+	require.NotEqual(t, p.FutureAGammaTime, probe.FutureAGammaTime)
+	require.NotEqual(t, p.Info.Reserves[0], probe.Info.Reserves[0])
+
+	before := *p
+	before.Info.Reserves = append([]*big.Int(nil), p.Info.Reserves...)
+	p.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  amountIn,
+		TokenAmountOut: pool.TokenAmount{Token: "C", Amount: bignumber.ZeroBI},
+	})
+
+	assert.Equal(t, before, *p)
+}
@@ -55,6 +55,14 @@ type PoolPlainOracleStaticExtra struct {
 	Oracle               string   `json:"oracle"`
 }
 
+type PoolStableNgStaticExtra struct {
+	LpToken    string `json:"lpToken"`
+	APrecision string `json:"aPrecision"`
+	// POOL_IS_REBASING_IMPLEMENTATION, the pool has rebasing coins: its balances are the balanceOf of the coins
+	// instead of the stored balances
+	IsRebasing bool `json:"isRebasing,omitempty"`
+}
+
 type PoolBaseStaticExtra struct {
 	LpToken              string   `json:"lpToken"`
 	APrecision           string   `json:"aPrecision"`
@@ -103,6 +111,23 @@ type PoolPlainOracleExtra struct {
 	AdminFee     string     `json:"adminFee"`
 }
 
+type PoolStableNgExtra struct {
+	// stored_rates of the pool: the rate multipliers adjusted by the oracle and ERC4626 rates of the coins
+	Rates               []*big.Int `json:"rates"`
+	InitialA            string     `json:"initialA"`
+	FutureA             string     `json:"futureA"`
+	InitialATime        int64      `json:"initialATime"`
+	FutureATime         int64      `json:"futureATime"`
+	SwapFee             string     `json:"swapFee"`
+	AdminFee            string     `json:"adminFee"`
+	OffpegFeeMultiplier string     `json:"offpegFeeMultiplier"`
+
+	// optional, the accounting of the balances of the pool, the reserves are the balances if they are not set
+	StoredBalances []*big.Int `json:"storedBalances,omitempty"` // stored_balances, updated by the transfers only
+	BalancesOf     []*big.Int `json:"balancesOf,omitempty"`     // balanceOf the pool of the coins, rebases included
+	AdminBalances  []*big.Int `json:"adminBalances,omitempty"`  // admin_balances, excluded from the balances
+}
+
 type PoolMetaExtra struct {
 	InitialA     string `json:"initialA"`
 	FutureA      string `json:"futureA"`
@@ -142,7 +142,8 @@ func (p *PoolSimulator) CalcAmountOut(
 				Token:  tokenAmountIn.Token,
 				Amount: mtFee,
 			},
-			Gas: totalGas,
+			Gas:      totalGas,
+			SwapInfo: newSwapInfo(amountOutF, mtFeeF, p.Tokens[1].Decimals),
 		}, nil
 	} else if tokenAmountIn.Token == p.Info.Tokens[1] {
 		if strings.EqualFold(p.Meta.Type, TypeV1Pool) {
@@ -169,7 +170,8 @@ func (p *PoolSimulator) CalcAmountOut(
 				Token:  tokenAmountIn.Token,
 				Amount: mtFee,
 			},
-			Gas: totalGas,
+			Gas:      totalGas,
+			SwapInfo: newSwapInfo(amountOutF, mtFeeF, p.Tokens[0].Decimals),
 		}, nil
 	}
 	return &pool.CalcAmountOutResult{}, errors.New("could not calculate the amountOut")
@@ -184,8 +186,13 @@ func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 		isSellBase = false
 	}
 	inputAmount := input.Amount
-	// output.Amount was already fee-deducted in CalcAmountOut above, need to add back to update balances
-	outputAmount := new(big.Int).Add(output.Amount, params.Fee.Amount)
+	var outputAmount *big.Int
+	if swapInfo, ok := params.SwapInfo.(SwapInfo); ok && swapInfo.GrossAmountOut != nil {
+		outputAmount = swapInfo.GrossAmountOut
+	} else {
+		// output.Amount was already fee-deducted in CalcAmountOut above, need to add back to update balances
+		outputAmount = new(big.Int).Add(output.Amount, params.Fee.Amount)
+	}
 
 	if isSellBase {
 		// amountInF = inputAmount / 10^Tokens[0].Decimals
@@ -223,6 +230,20 @@ func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 	}
 }
 
+// newSwapInfo converts the user's output and the maintainer fee to wei of tokenOut,
+// the gross output is rounded down as a whole so that it never exceeds what the pool actually sends
+func newSwapInfo(amountOutF, mtFeeF *big.Float, decimals uint8) SwapInfo {
+	unit := bignumber.TenPowDecimals(decimals)
+	amountOut, _ := new(big.Float).Mul(amountOutF, unit).Int(nil)
+	grossAmountOut, _ := new(big.Float).Mul(new(big.Float).Add(amountOutF, mtFeeF), unit).Int(nil)
+
+	return SwapInfo{
+		AmountOut:      amountOut,
+		MtFee:          new(big.Int).Sub(grossAmountOut, amountOut),
+		GrossAmountOut: grossAmountOut,
+	}
+}
+
 func (p *PoolSimulator) GetLpToken() string {
 	return p.Info.Address
 }
@@ -75,6 +75,51 @@ func TestCalcAmountOut(t *testing.T) {
 	}
 }
 
+func TestCalcAmountOut_FeeRecipient(t *testing.T) {
+	// quote has 6 decimals, the maintainer fee must be expressed in wei of tokenOut
+	p, err := NewPoolSimulator(entity.Pool{
+		SwapFee:  0.001 + 0.002,
+		Tokens:   []*entity.PoolToken{{Address: "BASE", Decimals: 18}, {Address: "QUOTE", Decimals: 6}},
+		Reserves: entity.PoolReserves{decStr(10), "1000000000"},
+		Extra: fmt.Sprintf("{\"reserves\": [%v, %v], \"targetReserves\": [%v, %v],\"i\": %v,\"k\": %v,\"rStatus\": %v,\"mtFeeRate\": \"%v\",\"lpFeeRate\": \"%v\" }",
+			decStr(10), "1000000000",
+			decStr(10), "1000000000",
+			"100000000",          // i=100, scaled by 10^(18-18+6)
+			"100000000000000000", // k=0.1
+			0,
+			"0.001",
+			"0.002",
+		),
+		StaticExtra: fmt.Sprintf("{\"tokens\": [\"%v\",\"%v\"], \"type\": \"%v\", \"dodoV1SellHelper\": \"%v\"}",
+			"BASE", "QUOTE", "DPP", ""),
+	})
+	require.Nil(t, err)
+
+	amountIn := pool.TokenAmount{Token: "BASE", Amount: bignumber.NewBig10(decStr(1))}
+	out, err := p.CalcAmountOut(amountIn, "QUOTE")
+	require.Nil(t, err)
+
+	swapInfo, ok := out.SwapInfo.(SwapInfo)
+	require.True(t, ok)
+	assert.Equal(t, out.TokenAmountOut.Amount, swapInfo.AmountOut)
+	assert.Equal(t, new(big.Int).Add(swapInfo.AmountOut, swapInfo.MtFee), swapInfo.GrossAmountOut)
+
+	// the gross output excludes the lp fee (0.2%) which stays in the pool, the maintainer gets 0.1% of the curve output
+	expectedMtFee := new(big.Int).Div(swapInfo.GrossAmountOut, big.NewInt(998))
+	assert.InDelta(t, expectedMtFee.Int64(), swapInfo.MtFee.Int64(), 1)
+
+	reserveOut := new(big.Int).Set(p.Info.Reserves[1])
+	p.UpdateBalance(pool.UpdateBalanceParams{
+		TokenAmountIn:  amountIn,
+		TokenAmountOut: *out.TokenAmountOut,
+		Fee:            *out.Fee,
+		SwapInfo:       out.SwapInfo,
+	})
+
+	// both the user's output and the maintainer fee leave the reserves
+	assert.Equal(t, new(big.Int).Sub(reserveOut, swapInfo.GrossAmountOut), p.Info.Reserves[1])
+}
+
 func TestCanSwapTo(t *testing.T) {
 	p, err := NewPoolSimulator(entity.Pool{
 		Exchange: "",
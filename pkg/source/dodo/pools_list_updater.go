@@ -7,11 +7,11 @@ import (
 	"math/big"
 	"strconv"
 
-	"github.com/KyberNetwork/logger"
 	"github.com/machinebox/graphql"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	graphqlPkg "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/graphql"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolsListUpdater struct {
@@ -85,3 +85,11 @@ type Gas struct {
 	SellBaseV2 int64
 	BuyBaseV2  int64
 }
+
+// SwapInfo splits the output of a swap between the user and the maintainer, the fee recipient of the pool.
+// The maintainer fee is transferred out of the pool, so it leaves the reserves along with the user's output.
+type SwapInfo struct {
+	AmountOut      *big.Int `json:"amountOut"`      // received by the user, same as TokenAmountOut
+	MtFee          *big.Int `json:"mtFee"`          // sent to the maintainer, in tokenOut
+	GrossAmountOut *big.Int `json:"grossAmountOut"` // AmountOut + MtFee, removed from the reserves
+}
@@ -10,12 +10,12 @@ import (
 	"github.com/KyberNetwork/elastic-go-sdk/v2/constants"
 	elasticEntities "github.com/KyberNetwork/elastic-go-sdk/v2/entities"
 	elasticUtils "github.com/KyberNetwork/elastic-go-sdk/v2/utils"
-	"github.com/KyberNetwork/logger"
 	coreEntities "github.com/daoleno/uniswap-sdk-core/entities"
 	"github.com/ethereum/go-ethereum/common"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
 )
 
@@ -0,0 +1,12 @@
+package ghogsm
+
+import "math/big"
+
+const DexTypeGhoGsm = "gho-gsm"
+
+var (
+	DefaultGas = Gas{BuyAsset: 120000, SellAsset: 120000}
+
+	// PercentageFactor is the denominator of the fees, in bps
+	PercentageFactor = big.NewInt(1e4)
+)
@@ -0,0 +1,12 @@
+package ghogsm
+
+import "errors"
+
+var (
+	ErrInvalidToken          = errors.New("invalid token")
+	ErrInvalidExtra          = errors.New("invalid extra")
+	ErrFrozen                = errors.New("gsm: frozen")
+	ErrExposureCapExceeded   = errors.New("gsm: exposure cap exceeded")
+	ErrInsufficientLiquidity = errors.New("gsm: insufficient available exogenous asset liquidity")
+	ErrInvalidAmount         = errors.New("gsm: invalid amount")
+)
@@ -0,0 +1,87 @@
+package ghogsm
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// GSM implements the Gsm contract with FixedPriceStrategy and FixedFeeStrategy
+// https://github.com/aave/gho-core/blob/main/src/contracts/facilitators/gsm/Gsm.sol
+
+// assetPriceInGho implements FixedPriceStrategy.getAssetPriceInGho
+func (g *GSM) assetPriceInGho(assetAmount *big.Int, roundUp bool) *big.Int {
+	return mulDiv(assetAmount, g.PriceRatio, g.AssetUnits, roundUp)
+}
+
+// ghoPriceInAsset implements FixedPriceStrategy.getGhoPriceInAsset
+func (g *GSM) ghoPriceInAsset(ghoAmount *big.Int, roundUp bool) *big.Int {
+	return mulDiv(ghoAmount, g.AssetUnits, g.PriceRatio, roundUp)
+}
+
+// sellAsset returns the GHO bought for assetAmount and the fee in GHO, see _calculateGhoAmountForSellAsset
+func (g *GSM) sellAsset(assetAmount *big.Int) (*big.Int, *big.Int, error) {
+	if g.IsFrozen {
+		return nil, nil, ErrFrozen
+	}
+	if assetAmount.Sign() <= 0 {
+		return nil, nil, ErrInvalidAmount
+	}
+
+	grossAmount := g.assetPriceInGho(assetAmount, false)
+	fee := mulDiv(grossAmount, g.SellFee, PercentageFactor, true)
+	ghoBought := new(big.Int).Sub(grossAmount, fee)
+	if ghoBought.Sign() <= 0 {
+		return nil, nil, ErrInvalidAmount
+	}
+
+	if new(big.Int).Add(g.CurrentExposure, assetAmount).Cmp(g.ExposureCap) > 0 {
+		return nil, nil, ErrExposureCapExceeded
+	}
+
+	return ghoBought, fee, nil
+}
+
+// buyAsset returns the asset bought for at most ghoAmount and the fee in GHO, see getAssetAmountForBuyAsset
+func (g *GSM) buyAsset(ghoAmount *big.Int) (*big.Int, *big.Int, error) {
+	if g.IsFrozen {
+		return nil, nil, ErrFrozen
+	}
+	if ghoAmount.Sign() <= 0 {
+		return nil, nil, ErrInvalidAmount
+	}
+
+	// FixedFeeStrategy.getGrossAmountFromTotalBought
+	grossAmount := mulDiv(ghoAmount, PercentageFactor, new(big.Int).Add(PercentageFactor, g.BuyFee), false)
+	assetAmount := g.ghoPriceInAsset(grossAmount, false)
+	if assetAmount.Sign() <= 0 {
+		return nil, nil, ErrInvalidAmount
+	}
+
+	if assetAmount.Cmp(g.CurrentExposure) > 0 {
+		return nil, nil, ErrInsufficientLiquidity
+	}
+
+	// the GHO actually sold for assetAmount, see _calculateGhoAmountForBuyAsset
+	grossAmount = g.assetPriceInGho(assetAmount, true)
+	fee := mulDiv(grossAmount, g.BuyFee, PercentageFactor, true)
+
+	return assetAmount, fee, nil
+}
+
+func (g *GSM) updateBalanceSellingAsset(assetAmount *big.Int) {
+	g.CurrentExposure = new(big.Int).Add(g.CurrentExposure, assetAmount)
+}
+
+func (g *GSM) updateBalanceBuyingAsset(assetAmount *big.Int) {
+	g.CurrentExposure = new(big.Int).Sub(g.CurrentExposure, assetAmount)
+}
+
+func mulDiv(x, y, denominator *big.Int, roundUp bool) *big.Int {
+	product := new(big.Int).Mul(x, y)
+	quotient, remainder := new(big.Int).QuoRem(product, denominator, new(big.Int))
+	if roundUp && remainder.Sign() > 0 {
+		quotient.Add(quotient, bignumber.One)
+	}
+	return quotient
+}
@@ -0,0 +1,113 @@
+package ghogsm
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// PoolSimulator swaps GHO, the first token of the pool, for the asset of the GSM, the second one
+type PoolSimulator struct {
+	pool.Pool
+
+	GSM GSM
+
+	gas Gas
+}
+
+func NewPoolSimulator(entityPool entity.Pool) (*PoolSimulator, error) {
+	var extra Extra
+	if err := json.Unmarshal([]byte(entityPool.Extra), &extra); err != nil {
+		return nil, err
+	}
+	if len(entityPool.Tokens) != 2 {
+		return nil, ErrInvalidToken
+	}
+	gsm := extra.GSM
+	if gsm.PriceRatio == nil || gsm.PriceRatio.Sign() <= 0 || gsm.BuyFee == nil || gsm.SellFee == nil ||
+		gsm.SellFee.Cmp(PercentageFactor) >= 0 || gsm.CurrentExposure == nil || gsm.ExposureCap == nil {
+		return nil, ErrInvalidExtra
+	}
+
+	tokens := make([]string, 0, len(entityPool.Tokens))
+	for _, poolToken := range entityPool.Tokens {
+		tokens = append(tokens, poolToken.Address)
+	}
+	gsm.AssetUnits = bignumber.TenPowInt(entityPool.Tokens[1].Decimals)
+
+	return &PoolSimulator{
+		Pool: pool.Pool{
+			Info: pool.PoolInfo{
+				Address:  entityPool.Address,
+				Exchange: entityPool.Exchange,
+				Type:     entityPool.Type,
+				Tokens:   tokens,
+				Reserves: []*big.Int{bignumber.ZeroBI, new(big.Int).Set(gsm.CurrentExposure)},
+			},
+		},
+		GSM: gsm,
+		gas: DefaultGas,
+	}, nil
+}
+
+func (p *PoolSimulator) CalcAmountOut(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+) (*pool.CalcAmountOutResult, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenAmountIn.Token), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return &pool.CalcAmountOutResult{}, ErrInvalidToken
+	}
+
+	if tokenInIndex == 0 {
+		assetAmount, fee, err := p.GSM.buyAsset(tokenAmountIn.Amount)
+		if err != nil {
+			return &pool.CalcAmountOutResult{}, err
+		}
+		return &pool.CalcAmountOutResult{
+			TokenAmountOut: &pool.TokenAmount{
+				Token:  tokenOut,
+				Amount: assetAmount,
+			},
+			Fee: &pool.TokenAmount{
+				Token:  tokenAmountIn.Token,
+				Amount: fee,
+			},
+			Gas: p.gas.BuyAsset,
+		}, nil
+	}
+
+	ghoAmount, fee, err := p.GSM.sellAsset(tokenAmountIn.Amount)
+	if err != nil {
+		return &pool.CalcAmountOutResult{}, err
+	}
+	return &pool.CalcAmountOutResult{
+		TokenAmountOut: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: ghoAmount,
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: fee,
+		},
+		Gas: p.gas.SellAsset,
+	}, nil
+}
+
+func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
+	input, output := params.TokenAmountIn, params.TokenAmountOut
+	if strings.EqualFold(input.Token, p.Info.Tokens[0]) {
+		p.GSM.updateBalanceBuyingAsset(output.Amount)
+	} else {
+		p.GSM.updateBalanceSellingAsset(input.Amount)
+	}
+	p.Info.Reserves[1] = new(big.Int).Set(p.GSM.CurrentExposure)
+}
+
+func (p *PoolSimulator) GetMetaInfo(_ string, _ string) interface{} {
+	return nil
+}
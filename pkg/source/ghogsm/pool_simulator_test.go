@@ -0,0 +1,129 @@
+package ghogsm
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// newPool returns a GSM of GHO and a 6 decimals asset, the exposure and the cap in asset units
+func newPool(t *testing.T, buyFee, sellFee, exposure, exposureCap int64, isFrozen bool) *PoolSimulator {
+	p, err := NewPoolSimulator(entity.Pool{
+		Address: "gsm",
+		Tokens:  []*entity.PoolToken{{Address: "GHO", Decimals: 18}, {Address: "USDC", Decimals: 6}},
+		Extra: fmt.Sprintf(`{"gsm":{"priceRatio":1000000000000000000,"buyFee":%d,"sellFee":%d,`+
+			`"currentExposure":%d,"exposureCap":%d,"isFrozen":%v}}`,
+			buyFee, sellFee, exposure*1e6, exposureCap*1e6, isFrozen),
+	})
+	require.Nil(t, err)
+	return p
+}
+
+func TestCalcAmountOut(t *testing.T) {
+	testcases := []struct {
+		buyFee, sellFee   int64
+		in                pool.TokenAmount
+		out               string
+		expectedAmountOut string
+		expectedFee       string
+	}{
+		// sellAsset: 100 USDC, 0.1% fee in GHO
+		{20, 10, pool.TokenAmount{Token: "USDC", Amount: big.NewInt(100e6)}, "GHO", "99900000000000000000", "100000000000000000"},
+		{0, 0, pool.TokenAmount{Token: "USDC", Amount: big.NewInt(100e6)}, "GHO", "100000000000000000000", "0"},
+		// buyAsset: 100.2 GHO for 100 USDC with a 0.2% fee
+		{20, 10, pool.TokenAmount{Token: "GHO", Amount: bignumber.NewBig10("100200000000000000000")}, "USDC", "100000000", "200000000000000000"},
+		// the asset is rounded down
+		{20, 10, pool.TokenAmount{Token: "GHO", Amount: bignumber.NewBig10("100199999999999999999")}, "USDC", "99999999", "199999998000000000"},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p := newPool(t, tc.buyFee, tc.sellFee, 500, 1000, false)
+			res, err := p.CalcAmountOut(tc.in, tc.out)
+			require.Nil(t, err)
+			assert.Equal(t, tc.out, res.TokenAmountOut.Token)
+			assert.Equal(t, tc.expectedAmountOut, res.TokenAmountOut.Amount.String())
+			assert.Equal(t, tc.expectedFee, res.Fee.Amount.String())
+		})
+	}
+}
+
+func TestCalcAmountOut_ExposureCap(t *testing.T) {
+	p := newPool(t, 20, 10, 900, 1000, false)
+	in := pool.TokenAmount{Token: "USDC", Amount: big.NewInt(100e6)}
+
+	// up to the cap
+	_, err := p.CalcAmountOut(pool.TokenAmount{Token: "USDC", Amount: big.NewInt(100e6 + 1)}, "GHO")
+	assert.ErrorIs(t, err, ErrExposureCapExceeded)
+	res, err := p.CalcAmountOut(in, "GHO")
+	require.Nil(t, err)
+
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut, Fee: *res.Fee})
+	assert.Equal(t, big.NewInt(1000e6), p.Info.Reserves[1])
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "USDC", Amount: big.NewInt(1)}, "GHO")
+	assert.ErrorIs(t, err, ErrExposureCapExceeded)
+
+	// buying the asset makes room again
+	in = pool.TokenAmount{Token: "GHO", Amount: bignumber.NewBig10("100200000000000000000")}
+	res, err = p.CalcAmountOut(in, "USDC")
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{TokenAmountIn: in, TokenAmountOut: *res.TokenAmountOut, Fee: *res.Fee})
+	assert.Equal(t, big.NewInt(900e6), p.GSM.CurrentExposure)
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "USDC", Amount: big.NewInt(100e6)}, "GHO")
+	assert.Nil(t, err)
+}
+
+func TestCalcAmountOut_Fail(t *testing.T) {
+	testcases := []struct {
+		pool        *PoolSimulator
+		in          pool.TokenAmount
+		out         string
+		expectedErr error
+	}{
+		// more asset than held by the GSM
+		{newPool(t, 0, 0, 100, 1000, false), pool.TokenAmount{Token: "GHO", Amount: bignumber.NewBig10("100000000000000000001")},
+			"USDC", nil},
+		{newPool(t, 0, 0, 100, 1000, false), pool.TokenAmount{Token: "GHO", Amount: bignumber.NewBig10("100000001000000000000")},
+			"USDC", ErrInsufficientLiquidity},
+		{newPool(t, 0, 0, 100, 1000, true), pool.TokenAmount{Token: "USDC", Amount: big.NewInt(1e6)}, "GHO", ErrFrozen},
+		{newPool(t, 0, 0, 100, 1000, true), pool.TokenAmount{Token: "GHO", Amount: big.NewInt(1e18)}, "USDC", ErrFrozen},
+		// less than a unit of the asset
+		{newPool(t, 0, 0, 100, 1000, false), pool.TokenAmount{Token: "GHO", Amount: big.NewInt(1e11)}, "USDC", ErrInvalidAmount},
+		{newPool(t, 0, 0, 100, 1000, false), pool.TokenAmount{Token: "USDC", Amount: big.NewInt(1e6)}, "USDC", ErrInvalidToken},
+		{newPool(t, 0, 0, 100, 1000, false), pool.TokenAmount{Token: "DAI", Amount: big.NewInt(1e6)}, "GHO", ErrInvalidToken},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			_, err := tc.pool.CalcAmountOut(tc.in, tc.out)
+			if tc.expectedErr == nil {
+				assert.Nil(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestNewPoolSimulator_InvalidExtra(t *testing.T) {
+	for idx, extra := range []string{
+		`{"gsm":{"priceRatio":0,"buyFee":0,"sellFee":0,"currentExposure":0,"exposureCap":0}}`,
+		`{"gsm":{"priceRatio":1000000000000000000,"buyFee":0,"sellFee":10000,"currentExposure":0,"exposureCap":0}}`,
+		`{"gsm":{"priceRatio":1000000000000000000,"buyFee":0,"sellFee":0}}`,
+	} {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			_, err := NewPoolSimulator(entity.Pool{
+				Tokens: []*entity.PoolToken{{Address: "GHO", Decimals: 18}, {Address: "USDC", Decimals: 6}},
+				Extra:  extra,
+			})
+			assert.ErrorIs(t, err, ErrInvalidExtra)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package ghogsm
+
+import "math/big"
+
+// GSM is the state of a GHO Stability Module with a fixed price and fixed fees
+type GSM struct {
+	PriceRatio      *big.Int `json:"priceRatio"`      // price of the asset in GHO, in wad
+	BuyFee          *big.Int `json:"buyFee"`          // fee of buyAsset, in bps
+	SellFee         *big.Int `json:"sellFee"`         // fee of sellAsset, in bps
+	CurrentExposure *big.Int `json:"currentExposure"` // asset held by the GSM
+	ExposureCap     *big.Int `json:"exposureCap"`     // max asset held by the GSM
+	IsFrozen        bool     `json:"isFrozen"`
+
+	AssetUnits *big.Int `json:"-"` // 10 ** decimals of the asset
+}
+
+type Extra struct {
+	GSM GSM `json:"gsm"`
+}
+
+type Gas struct {
+	BuyAsset  int64
+	SellAsset int64
+}
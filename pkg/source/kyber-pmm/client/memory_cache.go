@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 
-	"github.com/KyberNetwork/logger"
 	"github.com/dgraph-io/ristretto"
 
 	kyberpmm "github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/kyber-pmm"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 const (
@@ -9,6 +9,12 @@ type Config struct {
 	RFQContractAddress string            `mapstructure:"rfq_contract_address" json:"rfq_contract_address,omitempty"`
 	HTTP               HTTPConfig        `mapstructure:"http" json:"http,omitempty"`
 	MemoryCache        MemoryCacheConfig `mapstructure:"memory_cache" json:"memory_cache,omitempty"`
+
+	// validity of the price levels fetched by the tracker, they don't expire if 0
+	QuoteTTL durationjson.Duration `mapstructure:"quote_ttl" json:"quote_ttl,omitempty"`
+
+	// minimum amount in of the swaps by token address, in token units, below which the market maker rejects the quotes
+	MinAmounts map[string]float64 `mapstructure:"min_amounts" json:"min_amounts,omitempty"`
 }
 
 type HTTPConfig struct {
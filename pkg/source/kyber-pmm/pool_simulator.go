@@ -21,6 +21,12 @@ type PoolSimulator struct {
 	baseToQuotePriceLevels []PriceLevel
 	quoteToBasePriceLevels []PriceLevel
 	gas                    Gas
+
+	expiry         int64
+	minBaseAmount  float64
+	minQuoteAmount float64
+	// the price levels are quoted as of this unix timestamp, the current time if 0
+	simulationTimestamp int64
 }
 
 func NewPoolSimulator(entityPool entity.Pool) (*PoolSimulator, error) {
@@ -74,6 +80,9 @@ func NewPoolSimulator(entityPool entity.Pool) (*PoolSimulator, error) {
 		baseToQuotePriceLevels: extra.BaseToQuotePriceLevels,
 		quoteToBasePriceLevels: extra.QuoteToBasePriceLevels,
 		gas:                    DefaultGas,
+		expiry:                 extra.Expiry,
+		minBaseAmount:          extra.MinBaseAmount,
+		minQuoteAmount:         extra.MinQuoteAmount,
 	}, nil
 }
 
@@ -81,6 +90,14 @@ func (p *PoolSimulator) CalcAmountOut(
 	tokenAmountIn pool.TokenAmount,
 	tokenOut string,
 ) (*pool.CalcAmountOutResult, error) {
+	if pool.IsExpired(p.expiry, p.simulationTimestamp) {
+		return nil, pool.ErrQuoteExpired
+	}
+
+	if err := pool.CheckMinSwapAmount(tokenAmountIn, p.MinSwapAmount(tokenAmountIn.Token)); err != nil {
+		return nil, err
+	}
+
 	swapDirection := p.getSwapDirection(tokenAmountIn.Token)
 
 	if swapDirection == SwapDirectionBaseToQuote {
@@ -90,6 +107,42 @@ func (p *PoolSimulator) CalcAmountOut(
 	return p.swapQuoteToBase(tokenAmountIn, tokenOut)
 }
 
+// SetSimulationTimestamp sets the timestamp the expiry of the price levels is checked against,
+// see pool.IPoolExpirable
+func (p *PoolSimulator) SetSimulationTimestamp(timestamp int64) {
+	p.simulationTimestamp = timestamp
+}
+
+// MinSwapAmount returns the minimum amount in of tokenIn accepted by the market maker, see pool.IPoolMinSwapAmount
+func (p *PoolSimulator) MinSwapAmount(tokenIn string) *big.Int {
+	minAmount, decimals := p.minQuoteAmount, p.quoteToken.Decimals
+	if p.getSwapDirection(tokenIn) == SwapDirectionBaseToQuote {
+		minAmount, decimals = p.minBaseAmount, p.baseToken.Decimals
+	}
+	if minAmount <= 0 {
+		return nil
+	}
+
+	minAmountWei, accuracy := new(big.Float).Mul(
+		new(big.Float).SetFloat64(minAmount),
+		bignumber.TenPowDecimals(decimals),
+	).Int(nil)
+	if accuracy == big.Below {
+		minAmountWei.Add(minAmountWei, integer.One())
+	}
+	return minAmountWei
+}
+
+// Capabilities reports the features of the simulator. An amount in above the price levels is partially swapped.
+func (p *PoolSimulator) Capabilities() pool.Capabilities {
+	return pool.Capabilities{
+		GasEstimation: true,
+		PartialFill:   true,
+		Expiry:        true,
+		MinSwapAmount: true,
+	}
+}
+
 func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 	swapDirection := p.getSwapDirection(params.TokenAmountIn.Token)
 
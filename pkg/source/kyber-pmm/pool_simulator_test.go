@@ -5,6 +5,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
 )
 
 func TestPoolSimulator_getAmountOut(t *testing.T) {
@@ -198,3 +202,74 @@ func TestPoolSimulator_getNewPriceLevelsState(t *testing.T) {
 		})
 	}
 }
+
+func TestPoolSimulator_CalcAmountOut_Expiry(t *testing.T) {
+	newPool := func(extra string) *PoolSimulator {
+		p, err := NewPoolSimulator(entity.Pool{
+			Address:     "kyber_pmm_base_quote",
+			Exchange:    "kyber-pmm",
+			Type:        DexTypeKyberPMM,
+			Reserves:    entity.PoolReserves{poolReserve, poolReserve},
+			Tokens:      []*entity.PoolToken{{Address: "base", Decimals: 18}, {Address: "quote", Decimals: 6}},
+			StaticExtra: `{"pairID":"base/quote","baseTokenAddress":"base","quoteTokenAddress":"quote"}`,
+			Extra:       extra,
+		})
+		require.Nil(t, err)
+		return p
+	}
+	amountIn := pool.TokenAmount{Token: "base", Amount: big.NewInt(1e18)}
+
+	p := newPool(`{"baseToQuotePriceLevels":[{"price":1800,"amount":10}],"quoteToBasePriceLevels":[],"expiry":1000}`)
+	p.SetSimulationTimestamp(1000)
+	res, err := p.CalcAmountOut(amountIn, "quote")
+	require.Nil(t, err)
+	assert.Equal(t, big.NewInt(1800e6), res.TokenAmountOut.Amount)
+
+	p.SetSimulationTimestamp(1001)
+	_, err = p.CalcAmountOut(amountIn, "quote")
+	assert.ErrorIs(t, err, pool.ErrQuoteExpired)
+
+	// expired now
+	p.SetSimulationTimestamp(0)
+	_, err = p.CalcAmountOut(amountIn, "quote")
+	assert.ErrorIs(t, err, pool.ErrQuoteExpired)
+
+	// price levels without expiry
+	_, err = newPool(`{"baseToQuotePriceLevels":[{"price":1800,"amount":10}],"quoteToBasePriceLevels":[]}`).CalcAmountOut(amountIn, "quote")
+	assert.Nil(t, err)
+}
+
+func TestPoolSimulator_CalcAmountOut_MinSwapAmount(t *testing.T) {
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:     "kyber_pmm_base_quote",
+		Exchange:    "kyber-pmm",
+		Type:        DexTypeKyberPMM,
+		Reserves:    entity.PoolReserves{poolReserve, poolReserve},
+		Tokens:      []*entity.PoolToken{{Address: "base", Decimals: 18}, {Address: "quote", Decimals: 6}},
+		StaticExtra: `{"pairID":"base/quote","baseTokenAddress":"base","quoteTokenAddress":"quote"}`,
+		Extra:       `{"baseToQuotePriceLevels":[{"price":1800,"amount":10}],"quoteToBasePriceLevels":[{"price":0.0005,"amount":18000}],"minBaseAmount":0.01,"minQuoteAmount":0}`,
+	})
+	require.Nil(t, err)
+	assert.True(t, pool.CapabilitiesOf(p).MinSwapAmount)
+
+	minimum := p.MinSwapAmount("base")
+	assert.Equal(t, big.NewInt(1e16), minimum)
+	assert.Nil(t, p.MinSwapAmount("quote"))
+
+	// exactly at the minimum
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: "base", Amount: minimum}, "quote")
+	require.Nil(t, err)
+	assert.Equal(t, big.NewInt(18e6), res.TokenAmountOut.Amount)
+
+	// one wei below
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "base", Amount: new(big.Int).Sub(minimum, big.NewInt(1))}, "quote")
+	assert.ErrorIs(t, err, pool.ErrBelowMinimum)
+	var belowMinimum *pool.BelowMinimumError
+	require.ErrorAs(t, err, &belowMinimum)
+	assert.Equal(t, "base", belowMinimum.Token)
+	assert.Equal(t, minimum, belowMinimum.Minimum)
+
+	// no minimum the other way
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "quote", Amount: big.NewInt(1)}, "base")
+	assert.Nil(t, err)
+}
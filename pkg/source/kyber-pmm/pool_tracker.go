@@ -5,11 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/KyberNetwork/logger"
-
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolTracker struct {
@@ -46,6 +46,10 @@ func (t *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 	}
 
 	extra.BaseToQuotePriceLevels, extra.QuoteToBasePriceLevels = transformPriceLevels(priceLevels)
+	if t.config.QuoteTTL.Duration > 0 {
+		extra.Expiry = time.Now().Add(t.config.QuoteTTL.Duration).Unix()
+	}
+	extra.MinBaseAmount, extra.MinQuoteAmount = t.getMinAmountsForPool(p)
 
 	extraBytes, err := json.Marshal(extra)
 	if err != nil {
@@ -90,6 +94,21 @@ func (t *PoolTracker) getPriceLevelsForPool(ctx context.Context, p entity.Pool)
 	return PriceItem{}, ErrNoPriceLevelsForPool
 }
 
+// getMinAmountsForPool returns the configured minimum amounts in of the base and quote tokens of the pool
+func (t *PoolTracker) getMinAmountsForPool(p entity.Pool) (float64, float64) {
+	if len(t.config.MinAmounts) == 0 {
+		return 0, 0
+	}
+
+	var staticExtra StaticExtra
+	if err := json.Unmarshal([]byte(p.StaticExtra), &staticExtra); err != nil {
+		return 0, 0
+	}
+
+	return t.config.MinAmounts[strings.ToLower(staticExtra.BaseTokenAddress)],
+		t.config.MinAmounts[strings.ToLower(staticExtra.QuoteTokenAddress)]
+}
+
 // For computing prices based on a quote token amount
 // we invert the order book (bids become asks and vice versa)
 // new price = 1 / price
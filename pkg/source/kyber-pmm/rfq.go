@@ -4,7 +4,8 @@ import (
 	"context"
 	"encoding/json"
 
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type RFQHandler struct {
@@ -46,6 +47,15 @@ func (h *RFQHandler) RFQ(ctx context.Context, recipient string, params any) (any
 		return nil, err
 	}
 
+	// the order would revert on-chain
+	if pool.IsExpired(result.Order.Expiry, 0) {
+		logger.WithFields(logger.Fields{
+			"params": params,
+			"expiry": result.Order.Expiry,
+		}).Errorf("firm quote already expired")
+		return nil, pool.ErrQuoteExpired
+	}
+
 	return RFQExtra{
 		RFQContractAddress: h.config.RFQContractAddress,
 		Info:               result.Order.Info,
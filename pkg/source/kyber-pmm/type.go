@@ -46,6 +46,13 @@ type StaticExtra struct {
 type Extra struct {
 	BaseToQuotePriceLevels []PriceLevel `json:"baseToQuotePriceLevels"`
 	QuoteToBasePriceLevels []PriceLevel `json:"quoteToBasePriceLevels"`
+
+	// unix time after which the price levels must not be quoted, 0 if they don't expire
+	Expiry int64 `json:"expiry,omitempty"`
+
+	// minimum amounts in of the swaps in token units, 0 if there is none
+	MinBaseAmount  float64 `json:"minBaseAmount,omitempty"`
+	MinQuoteAmount float64 `json:"minQuoteAmount,omitempty"`
 }
 
 type PriceLevel struct {
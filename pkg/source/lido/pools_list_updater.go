@@ -9,9 +9,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/KyberNetwork/logger"
-
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolsListUpdater struct {
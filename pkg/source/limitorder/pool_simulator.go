@@ -6,12 +6,11 @@ import (
 	"math/big"
 	"strings"
 
-	"github.com/KyberNetwork/logger"
-
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
 	constant "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
 	utils "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
 )
 
@@ -25,6 +24,9 @@ type (
 		buyOrderIDs  []int64
 
 		contractAddress string
+
+		// the orders are filled as of this unix timestamp, the current time if 0
+		simulationTimestamp int64
 	}
 )
 
@@ -93,6 +95,11 @@ func (p *PoolSimulator) CalcAmountOut(
 	return p.calcAmountOut(tokenAmountIn, tokenOut)
 }
 
+// SetSimulationTimestamp sets the timestamp the expiry of the orders is checked against, see pool.IPoolExpirable
+func (p *PoolSimulator) SetSimulationTimestamp(timestamp int64) {
+	p.simulationTimestamp = timestamp
+}
+
 func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 	swapInfo, ok := params.SwapInfo.(SwapInfo)
 	if !ok {
@@ -119,6 +126,9 @@ func (p *PoolSimulator) calcAmountOut(
 	tokenOut string,
 ) (*pool.CalcAmountOutResult, error) {
 	swapSide := p.getSwapSide(tokenAmountIn.Token, tokenOut)
+	if err := pool.CheckMinSwapAmount(tokenAmountIn, p.minSwapAmount(swapSide)); err != nil {
+		return nil, err
+	}
 	amountOut, swapInfo, feeAmount, err := p.calcAmountWithSwapInfo(swapSide, tokenAmountIn)
 	if err != nil {
 		return nil, err
@@ -138,6 +148,49 @@ func (p *PoolSimulator) calcAmountOut(
 	}, nil
 }
 
+// MinSwapAmount returns the smallest amount of tokenIn filling at least 1 wei of the best order, the contract rejects
+// the fills of 0 making amount. See pool.IPoolMinSwapAmount
+func (p *PoolSimulator) MinSwapAmount(tokenIn string) *big.Int {
+	tokenInIndex := p.GetTokenIndex(tokenIn)
+	if tokenInIndex < 0 {
+		return nil
+	}
+	return p.minSwapAmount(p.getSwapSide(tokenIn, p.Info.Tokens[1-tokenInIndex]))
+}
+
+// minSwapAmount returns the minimum amount in of the first order to be filled, nil if no order can be filled
+func (p *PoolSimulator) minSwapAmount(swapSide SwapSide) *big.Int {
+	for _, orderID := range p.getOrderIDsBySwapSide(swapSide) {
+		order, ok := p.ordersMapping[orderID]
+		if !ok || pool.IsExpired(order.ExpiredAt, p.simulationTimestamp) ||
+			order.MakingAmount.Cmp(order.FilledMakingAmount) <= 0 || order.MakingAmount.Sign() <= 0 {
+			continue
+		}
+
+		// ceil(takingAmount / makingAmount), one more wei if the rounding of the rate fills 0 at that amount
+		minAmount := new(big.Int).Add(order.TakingAmount, order.MakingAmount)
+		minAmount.Sub(minAmount, constant.One).Div(minAmount, order.MakingAmount)
+		if minAmount.Sign() <= 0 {
+			minAmount.SetInt64(1)
+		}
+		rate := new(big.Float).Quo(new(big.Float).SetInt(order.MakingAmount), new(big.Float).SetInt(order.TakingAmount))
+		if filledMakingAmount, _ := new(big.Float).Mul(new(big.Float).SetInt(minAmount), rate).Int(nil); filledMakingAmount.Sign() <= 0 {
+			minAmount.Add(minAmount, constant.One)
+		}
+		return minAmount
+	}
+	return nil
+}
+
+// Capabilities reports the features of the simulator
+func (p *PoolSimulator) Capabilities() pool.Capabilities {
+	return pool.Capabilities{
+		GasEstimation: true,
+		Expiry:        true,
+		MinSwapAmount: true,
+	}
+}
+
 func (p *PoolSimulator) calcAmountWithSwapInfo(swapSide SwapSide, tokenAmountIn pool.TokenAmount) (*big.Int, SwapInfo, *big.Int, error) {
 
 	orderIDs := p.getOrderIDsBySwapSide(swapSide)
@@ -157,11 +210,17 @@ func (p *PoolSimulator) calcAmountWithSwapInfo(swapSide SwapSide, tokenAmountIn
 	totalFeeAmountWei := new(big.Int)
 
 	totalMakingAmountWei := new(big.Int)
+	hasExpiredOrders := false
 	for i, orderID := range orderIDs {
 		order, ok := p.ordersMapping[orderID]
 		if !ok {
 			return nil, swapInfo, nil, fmt.Errorf("order %d is not existed in pool", orderID)
 		}
+		// the contract rejects the expired orders
+		if pool.IsExpired(order.ExpiredAt, p.simulationTimestamp) {
+			hasExpiredOrders = true
+			continue
+		}
 		// rate should be the result of making amount/taking amount when dividing decimals per token.
 		// However, we can also use rate with making amount/taking amount (wei) to calculate the amount out instead of converting to measure per token. Because we will return amount out(wei) (we have to multip amountOut(taken out) with decimals)
 		rate := new(big.Float).Quo(new(big.Float).SetInt(order.MakingAmount), new(big.Float).SetInt(order.TakingAmount))
@@ -194,7 +253,7 @@ func (p *PoolSimulator) calcAmountWithSwapInfo(swapSide SwapSide, tokenAmountIn
 					break
 				}
 				order, ok := p.ordersMapping[orderIDs[j]]
-				if !ok {
+				if !ok || pool.IsExpired(order.ExpiredAt, p.simulationTimestamp) {
 					continue
 				}
 				remainingMakingAmountWei := new(big.Int).Sub(order.MakingAmount, order.FilledMakingAmount)
@@ -217,6 +276,9 @@ func (p *PoolSimulator) calcAmountWithSwapInfo(swapSide SwapSide, tokenAmountIn
 		swapInfo.FilledOrders = append(swapInfo.FilledOrders, filledOrderInfo)
 	}
 	if !isFulfillAmountIn {
+		if hasExpiredOrders {
+			return nil, SwapInfo{}, nil, pool.ErrQuoteExpired
+		}
 		return nil, SwapInfo{}, nil, ErrCannotFulfillAmountIn
 	}
 	return totalAmountOutWei, swapInfo, totalFeeAmountWei, nil
@@ -860,3 +860,122 @@ func marshalPoolExtra(extra *Extra) string {
 	bytesData, _ := json.Marshal(extra)
 	return string(bytesData)
 }
+
+func TestPool_CalcAmountOut_ExpiredOrders(t *testing.T) {
+	newOrder := func(id int64, expiredAt int64) *order {
+		return &order{
+			ID:                 id,
+			TakerAsset:         "0x2791bca1f2de4661ed88a30c99a7a9449aa84174",
+			MakerAsset:         "0xc2132d05d31c914a87c6611c10748aeb04b58e8f",
+			TakingAmount:       parseBigInt("100"),
+			MakingAmount:       parseBigInt("200"),
+			FilledMakingAmount: parseBigInt("0"),
+			FilledTakingAmount: parseBigInt("0"),
+			ExpiredAt:          expiredAt,
+		}
+	}
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:  "pool_limit_order_",
+		Exchange: "kyberswap_limit-order",
+		Type:     DexTypeLimitOrder,
+		Reserves: []string{"10000000000000000000", "10000000000000000000"},
+		Tokens: []*entity.PoolToken{
+			{Address: "0xc2132d05d31c914a87c6611c10748aeb04b58e8f", Decimals: 6},
+			{Address: "0x2791bca1f2de4661ed88a30c99a7a9449aa84174", Decimals: 6},
+		},
+		Extra: marshalPoolExtra(&Extra{BuyOrders: []*order{newOrder(1, 1000), newOrder(2, 2000)}}),
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		timestamp       int64
+		amountIn        string
+		expectedOrderID int64
+		expectedErr     error
+	}{
+		{500, "100", 1, nil},
+		// the first order expired
+		{1500, "100", 2, nil},
+		{1500, "150", 0, pool.ErrQuoteExpired},
+		{2500, "100", 0, pool.ErrQuoteExpired},
+	}
+	for _, tt := range tests {
+		p.SetSimulationTimestamp(tt.timestamp)
+		got, err := p.CalcAmountOut(pool.TokenAmount{Token: "0x2791bca1f2de4661ed88a30c99a7a9449aa84174", Amount: parseBigInt(tt.amountIn)}, "0xc2132d05d31c914a87c6611c10748aeb04b58e8f")
+		assert.ErrorIs(t, err, tt.expectedErr)
+		if tt.expectedErr == nil {
+			assert.Equal(t, parseBigInt("200"), got.TokenAmountOut.Amount)
+			assert.Equal(t, tt.expectedOrderID, got.SwapInfo.(SwapInfo).FilledOrders[0].OrderID)
+		}
+	}
+
+	// orders without expiry never expire
+	p, err = NewPoolSimulator(entity.Pool{
+		Address:  "pool_limit_order_",
+		Reserves: []string{"10000000000000000000", "10000000000000000000"},
+		Tokens:   []*entity.PoolToken{{Address: "0xc2132d05d31c914a87c6611c10748aeb04b58e8f"}, {Address: "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"}},
+		Extra:    marshalPoolExtra(&Extra{BuyOrders: []*order{newOrder(1, 0)}}),
+	})
+	assert.Nil(t, err)
+	_, err = p.CalcAmountOut(pool.TokenAmount{Token: "0x2791bca1f2de4661ed88a30c99a7a9449aa84174", Amount: parseBigInt("100")}, "0xc2132d05d31c914a87c6611c10748aeb04b58e8f")
+	assert.Nil(t, err)
+}
+
+func TestPool_CalcAmountOut_MinSwapAmount(t *testing.T) {
+	tokenIn, tokenOut := "0x2791bca1f2de4661ed88a30c99a7a9449aa84174", "0xc2132d05d31c914a87c6611c10748aeb04b58e8f"
+	newOrder := func(id int64, takingAmount, makingAmount string, expiredAt int64) *order {
+		return &order{
+			ID:                 id,
+			TakerAsset:         tokenIn,
+			MakerAsset:         tokenOut,
+			TakingAmount:       parseBigInt(takingAmount),
+			MakingAmount:       parseBigInt(makingAmount),
+			FilledMakingAmount: parseBigInt("0"),
+			FilledTakingAmount: parseBigInt("0"),
+			ExpiredAt:          expiredAt,
+		}
+	}
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:  "pool_limit_order_",
+		Exchange: "kyberswap_limit-order",
+		Type:     DexTypeLimitOrder,
+		Reserves: []string{"10000000000000000000", "10000000000000000000"},
+		Tokens:   []*entity.PoolToken{{Address: tokenOut, Decimals: 6}, {Address: tokenIn, Decimals: 6}},
+		Extra: marshalPoolExtra(&Extra{BuyOrders: []*order{
+			newOrder(1, "100", "200", 1000),
+			newOrder(2, "1000", "3", 0),
+		}}),
+	})
+	assert.Nil(t, err)
+	assert.True(t, pool.CapabilitiesOf(p).MinSwapAmount)
+	assert.Nil(t, p.MinSwapAmount(tokenOut))
+	assert.Nil(t, p.MinSwapAmount("unknown"))
+
+	tests := []struct {
+		timestamp       int64
+		expectedMinimum string
+	}{
+		{500, "1"},
+		// the first order expired, the second one fills 0 below ceil(1000 / 3)
+		{1500, "334"},
+	}
+	for _, tt := range tests {
+		p.SetSimulationTimestamp(tt.timestamp)
+		minimum := p.MinSwapAmount(tokenIn)
+		assert.Equal(t, parseBigInt(tt.expectedMinimum), minimum)
+
+		// exactly at the minimum
+		got, err := p.CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: minimum}, tokenOut)
+		if assert.Nil(t, err) {
+			assert.Positive(t, got.TokenAmountOut.Amount.Sign())
+		}
+
+		// one wei below
+		_, err = p.CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: new(big.Int).Sub(minimum, big.NewInt(1))}, tokenOut)
+		assert.ErrorIs(t, err, pool.ErrBelowMinimum)
+		var belowMinimum *pool.BelowMinimumError
+		if assert.ErrorAs(t, err, &belowMinimum) {
+			assert.Equal(t, minimum, belowMinimum.Minimum)
+		}
+	}
+}
@@ -7,10 +7,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/KyberNetwork/logger"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolTracker struct {
@@ -4,7 +4,7 @@ import (
 	"context"
 
 	"github.com/KyberNetwork/ethrpc"
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 )
 
@@ -0,0 +1,27 @@
+package pancakeinfinity
+
+const DexTypePancakeInfinityCL = "pancake-infinity-cl"
+
+const (
+	// LPFeeLibrary
+	dynamicFeeFlag  = 0x800000
+	maxLpFee        = 1000000
+	pipsDenominator = 1000000
+
+	// ProtocolFeeLibrary, the protocol fee of each direction is on 12 bits
+	protocolFeeBits = 12
+	protocolFeeMask = 0xfff
+
+	// offsets of the hooks registration bitmap in the parameters of the pool key, see CLHooks
+	hooksBeforeSwapOffset             = 6
+	hooksAfterSwapOffset              = 7
+	hooksBeforeSwapReturnsDeltaOffset = 10
+	hooksAfterSwapReturnsDeltaOffset  = 11
+
+	// the tick spacing is the int24 after the hooks registration bitmap in the parameters
+	tickSpacingOffset = 16
+)
+
+var (
+	DefaultGas = Gas{Swap: 130000, HookCall: 40000}
+)
@@ -0,0 +1,15 @@
+package pancakeinfinity
+
+import "errors"
+
+var (
+	ErrTickNil                    = errors.New("tick is nil")
+	ErrTicksEmpty                 = errors.New("ticks list is empty")
+	ErrInvalidToken               = errors.New("invalid token")
+	ErrInvalidParameters          = errors.New("invalid pool parameters")
+	ErrInvalidFee                 = errors.New("invalid fee")
+	ErrUnsupportedHook            = errors.New("unsupported hook")
+	ErrHookDeltaExceedsSwapAmount = errors.New("HookDeltaExceedsSwapAmount")
+	ErrZeroAmountIn               = errors.New("amountIn is 0")
+	ErrZeroAmountOut              = errors.New("amountOut is 0")
+)
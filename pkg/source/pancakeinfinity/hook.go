@@ -0,0 +1,61 @@
+package pancakeinfinity
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Hook simulates the swap callbacks of a hook contract, only the callbacks enabled in the hooks registration bitmap
+// of the pool are called. The deltas are the amounts the hook takes from the swapper, negative if the hook gives
+// them: e.g. a hook replacing the pool curve takes the whole amount in with DeltaSpecified and gives the amount out
+// with a negative DeltaUnspecified.
+type Hook interface {
+	BeforeSwap(params BeforeSwapParams) (BeforeSwapResult, error)
+	// AfterSwap returns the delta of the token out
+	AfterSwap(params AfterSwapParams) (*big.Int, error)
+}
+
+type BeforeSwapParams struct {
+	ZeroForOne bool
+	AmountIn   *big.Int
+}
+
+type BeforeSwapResult struct {
+	DeltaSpecified   *big.Int // delta of the token in, only applied if the hook returns deltas
+	DeltaUnspecified *big.Int // delta of the token out, only applied if the hook returns deltas
+
+	// LP fee of this swap, only applied to dynamic fee pools
+	OverrideFee bool
+	LpFee       uint32
+}
+
+type AfterSwapParams struct {
+	ZeroForOne bool
+	AmountIn   *big.Int // amount swapped by the pool, after the beforeSwap delta
+	AmountOut  *big.Int // amount swapped by the pool, before the hook deltas
+}
+
+// HookFactory builds the simulator of a hook from Extra.HookExtra of the pool
+type HookFactory func(hookExtra json.RawMessage) (Hook, error)
+
+var (
+	hookFactoriesMu sync.RWMutex
+	hookFactories   = map[string]HookFactory{}
+)
+
+// RegisterHook registers the simulator of the hook contract deployed at address.
+// Pools whose hook has swap callbacks can only be simulated if their hook is registered.
+func RegisterHook(address string, factory HookFactory) {
+	hookFactoriesMu.Lock()
+	defer hookFactoriesMu.Unlock()
+	hookFactories[strings.ToLower(address)] = factory
+}
+
+func getHookFactory(address string) (HookFactory, bool) {
+	hookFactoriesMu.RLock()
+	defer hookFactoriesMu.RUnlock()
+	factory, ok := hookFactories[strings.ToLower(address)]
+	return factory, ok
+}
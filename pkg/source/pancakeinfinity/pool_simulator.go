@@ -0,0 +1,327 @@
+package pancakeinfinity
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	coreEntities "github.com/daoleno/uniswap-sdk-core/entities"
+	"github.com/daoleno/uniswapv3-sdk/constants"
+	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+// PoolSimulator simulates the CL pools of the PancakeSwap Infinity pool manager. The swap math of the pool manager
+// is the one of Uniswap V3, only the fee and the hooks differ.
+type PoolSimulator struct {
+	pool.Pool
+	V3Pool *v3Entities.Pool
+
+	staticExtra       StaticExtra
+	hooksRegistration uint16
+	hook              Hook // nil if the pool has no swap callbacks
+
+	protocolFee uint32
+	lpFee       uint32
+
+	gas     Gas
+	tickMin int
+	tickMax int
+}
+
+func NewPoolSimulator(entityPool entity.Pool, chainID valueobject.ChainID) (*PoolSimulator, error) {
+	if len(entityPool.Tokens) != 2 || len(entityPool.Reserves) != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	var staticExtra StaticExtra
+	if err := json.Unmarshal([]byte(entityPool.StaticExtra), &staticExtra); err != nil {
+		return nil, err
+	}
+	var extra Extra
+	if err := json.Unmarshal([]byte(entityPool.Extra), &extra); err != nil {
+		return nil, err
+	}
+	if extra.Tick == nil {
+		return nil, ErrTickNil
+	}
+
+	hooksRegistration, tickSpacing, err := parseParameters(staticExtra.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	lpFee := staticExtra.Fee
+	if isDynamicFee(staticExtra.Fee) {
+		lpFee = extra.LpFee
+	}
+	if lpFee > maxLpFee {
+		return nil, ErrInvalidFee
+	}
+
+	var hook Hook
+	if common.HexToAddress(staticExtra.Hooks) != (common.Address{}) && hasSwapCallbacks(hooksRegistration) {
+		factory, ok := getHookFactory(staticExtra.Hooks)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedHook, staticExtra.Hooks)
+		}
+		if hook, err = factory(extra.HookExtra); err != nil {
+			return nil, err
+		}
+	}
+
+	token0 := coreEntities.NewToken(uint(chainID), common.HexToAddress(entityPool.Tokens[0].Address), uint(entityPool.Tokens[0].Decimals), entityPool.Tokens[0].Symbol, entityPool.Tokens[0].Name)
+	token1 := coreEntities.NewToken(uint(chainID), common.HexToAddress(entityPool.Tokens[1].Address), uint(entityPool.Tokens[1].Decimals), entityPool.Tokens[1].Symbol, entityPool.Tokens[1].Name)
+
+	var v3Ticks []v3Entities.Tick
+	// Ticks are sorted from the pool service, so we don't have to do it again here
+	for _, t := range extra.Ticks {
+		// LiquidityGross = 0 means that the tick is uninitialized
+		if t.LiquidityGross.Sign() == 0 {
+			continue
+		}
+
+		v3Ticks = append(v3Ticks, v3Entities.Tick{
+			Index:          t.Index,
+			LiquidityGross: t.LiquidityGross,
+			LiquidityNet:   t.LiquidityNet,
+		})
+	}
+
+	// if the tick list is empty, the pool should be ignored
+	if len(v3Ticks) == 0 {
+		return nil, ErrTicksEmpty
+	}
+
+	ticks, err := v3Entities.NewTickListDataProvider(v3Ticks, tickSpacing)
+	if err != nil {
+		return nil, err
+	}
+
+	// the fee of the V3 pool is replaced by the swap fee of each swap
+	v3Pool, err := v3Entities.NewPool(token0, token1, 0, extra.SqrtPriceX96, extra.Liquidity, int(extra.Tick.Int64()), ticks)
+	if err != nil {
+		return nil, err
+	}
+
+	info := pool.PoolInfo{
+		Address:    strings.ToLower(entityPool.Address),
+		ReserveUsd: entityPool.ReserveUsd,
+		SwapFee:    big.NewInt(int64(lpFee)),
+		Exchange:   entityPool.Exchange,
+		Type:       entityPool.Type,
+		Tokens:     []string{entityPool.Tokens[0].Address, entityPool.Tokens[1].Address},
+		Reserves:   []*big.Int{bignumber.NewBig10(entityPool.Reserves[0]), bignumber.NewBig10(entityPool.Reserves[1])},
+	}
+
+	return &PoolSimulator{
+		Pool:   pool.Pool{Info: info},
+		V3Pool: v3Pool,
+
+		staticExtra:       staticExtra,
+		hooksRegistration: hooksRegistration,
+		hook:              hook,
+
+		protocolFee: extra.ProtocolFee,
+		lpFee:       lpFee,
+
+		gas:     DefaultGas,
+		tickMin: v3Ticks[0].Index,
+		tickMax: v3Ticks[len(v3Ticks)-1].Index,
+	}, nil
+}
+
+// parseParameters decodes the hooks registration bitmap and the tick spacing of the pool key parameters
+func parseParameters(parameters string) (uint16, int, error) {
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(parameters, "0x"), 16)
+	if !ok || value.BitLen() > 256 {
+		return 0, 0, ErrInvalidParameters
+	}
+
+	hooksRegistration := uint16(value.Uint64())
+	// int24, the minimum tick spacing is 1
+	tickSpacing := new(big.Int).Rsh(value, tickSpacingOffset).Uint64() & 0xffffff
+	if tickSpacing == 0 || tickSpacing >= 1<<23 {
+		return 0, 0, ErrInvalidParameters
+	}
+	return hooksRegistration, int(tickSpacing), nil
+}
+
+func isDynamicFee(fee uint32) bool {
+	return fee == dynamicFeeFlag
+}
+
+func hasSwapCallbacks(hooksRegistration uint16) bool {
+	return hooksRegistration&(1<<hooksBeforeSwapOffset|1<<hooksAfterSwapOffset) != 0
+}
+
+func (p *PoolSimulator) hasPermission(offset int) bool {
+	return p.hook != nil && p.hooksRegistration&(1<<offset) != 0
+}
+
+// swapFee is ProtocolFeeLibrary.calculateSwapFee: the LP fee is charged on the amount remaining after the protocol fee
+func swapFee(protocolFee, lpFee uint32) uint32 {
+	return protocolFee + lpFee - uint32(uint64(protocolFee)*uint64(lpFee)/pipsDenominator)
+}
+
+func (p *PoolSimulator) protocolFeeOf(zeroForOne bool) uint32 {
+	if zeroForOne {
+		return p.protocolFee & protocolFeeMask
+	}
+	return p.protocolFee >> protocolFeeBits & protocolFeeMask
+}
+
+/**
+ * getSqrtPriceLimit get the price limit of pool based on the initialized ticks that this pool has
+ */
+func (p *PoolSimulator) getSqrtPriceLimit(zeroForOne bool) *big.Int {
+	tickLimit := p.tickMax
+	if zeroForOne {
+		tickLimit = p.tickMin
+	}
+
+	sqrtPriceX96Limit, err := v3Utils.GetSqrtRatioAtTick(tickLimit)
+	if err != nil {
+		return nil
+	}
+	return sqrtPriceX96Limit
+}
+
+func (p *PoolSimulator) CalcAmountOut(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+) (*pool.CalcAmountOutResult, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenAmountIn.Token), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 || tokenInIndex == tokenOutIndex {
+		return &pool.CalcAmountOutResult{}, ErrInvalidToken
+	}
+	if tokenAmountIn.Amount == nil || tokenAmountIn.Amount.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, ErrZeroAmountIn
+	}
+	zeroForOne := tokenInIndex == 0
+
+	gas := p.gas.Swap
+	lpFee := p.lpFee
+	amountToSwap := tokenAmountIn.Amount
+	hookDeltaOut := big.NewInt(0)
+
+	if p.hasPermission(hooksBeforeSwapOffset) {
+		res, err := p.hook.BeforeSwap(BeforeSwapParams{ZeroForOne: zeroForOne, AmountIn: tokenAmountIn.Amount})
+		if err != nil {
+			return &pool.CalcAmountOutResult{}, err
+		}
+		gas += p.gas.HookCall
+
+		if res.OverrideFee && isDynamicFee(p.staticExtra.Fee) {
+			if res.LpFee > maxLpFee {
+				return &pool.CalcAmountOutResult{}, ErrInvalidFee
+			}
+			lpFee = res.LpFee
+		}
+		if p.hasPermission(hooksBeforeSwapReturnsDeltaOffset) {
+			if res.DeltaSpecified != nil {
+				amountToSwap = new(big.Int).Sub(amountToSwap, res.DeltaSpecified)
+				// the exact input swap can't become an exact output swap
+				if amountToSwap.Sign() < 0 {
+					return &pool.CalcAmountOutResult{}, ErrHookDeltaExceedsSwapAmount
+				}
+			}
+			if res.DeltaUnspecified != nil {
+				hookDeltaOut.Add(hookDeltaOut, res.DeltaUnspecified)
+			}
+		}
+	}
+
+	fee := swapFee(p.protocolFeeOf(zeroForOne), lpFee)
+	if fee >= pipsDenominator {
+		return &pool.CalcAmountOutResult{}, ErrInvalidFee
+	}
+
+	amountOut := big.NewInt(0)
+	swapInfo := SwapInfo{
+		nextStateSqrtRatioX96: p.V3Pool.SqrtRatioX96,
+		nextStateLiquidity:    p.V3Pool.Liquidity,
+		nextStateTickCurrent:  p.V3Pool.TickCurrent,
+	}
+	// the pool manager skips the swap if the hook took the whole amount
+	if amountToSwap.Sign() > 0 {
+		v3Pool := *p.V3Pool
+		v3Pool.Fee = constants.FeeAmount(fee)
+
+		tokenIn := v3Pool.Token1
+		if zeroForOne {
+			tokenIn = v3Pool.Token0
+		}
+		out, newPoolState, err := v3Pool.GetOutputAmount(coreEntities.FromRawAmount(tokenIn, amountToSwap), p.getSqrtPriceLimit(zeroForOne))
+		if err != nil {
+			return &pool.CalcAmountOutResult{}, fmt.Errorf("can not GetOutputAmount, err: %+v", err)
+		}
+		amountOut = out.Quotient()
+		swapInfo = SwapInfo{
+			nextStateSqrtRatioX96: new(big.Int).Set(newPoolState.SqrtRatioX96),
+			nextStateLiquidity:    new(big.Int).Set(newPoolState.Liquidity),
+			nextStateTickCurrent:  newPoolState.TickCurrent,
+		}
+	}
+
+	if p.hasPermission(hooksAfterSwapOffset) {
+		delta, err := p.hook.AfterSwap(AfterSwapParams{ZeroForOne: zeroForOne, AmountIn: amountToSwap, AmountOut: amountOut})
+		if err != nil {
+			return &pool.CalcAmountOutResult{}, err
+		}
+		gas += p.gas.HookCall
+
+		if delta != nil && p.hasPermission(hooksAfterSwapReturnsDeltaOffset) {
+			hookDeltaOut.Add(hookDeltaOut, delta)
+		}
+	}
+
+	amountOut = new(big.Int).Sub(amountOut, hookDeltaOut)
+	if amountOut.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, ErrZeroAmountOut
+	}
+
+	return &pool.CalcAmountOutResult{
+		TokenAmountOut: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: amountOut,
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenAmountIn.Token,
+			Amount: nil,
+		},
+		Gas:      gas,
+		SwapInfo: swapInfo,
+	}, nil
+}
+
+func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
+	si, ok := params.SwapInfo.(SwapInfo)
+	if !ok {
+		logger.Warnf("failed to UpdateBalance for PancakeSwap Infinity %v pool, wrong swapInfo type", p.Info.Address)
+		return
+	}
+
+	v3Pool := *p.V3Pool
+	v3Pool.SqrtRatioX96 = si.nextStateSqrtRatioX96
+	v3Pool.Liquidity = si.nextStateLiquidity
+	v3Pool.TickCurrent = si.nextStateTickCurrent
+	p.V3Pool = &v3Pool
+}
+
+func (p *PoolSimulator) GetMetaInfo(tokenIn string, tokenOut string) interface{} {
+	return Meta{
+		PoolManager: p.staticExtra.PoolManager,
+		Hooks:       p.staticExtra.Hooks,
+		Fee:         p.staticExtra.Fee,
+		Parameters:  p.staticExtra.Parameters,
+	}
+}
@@ -0,0 +1,213 @@
+package pancakeinfinity
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/uniswapv3"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+const (
+	token0 = "0x2170ed0880ac9a755fd29b2688956bd959f933f8"
+	token1 = "0x55d398326f99059ff775485246999027b3197955"
+
+	testExtra = `{"liquidity":2822091172725,"sqrtPriceX96":93065132232889433968150957834858946,"tick":279543,"ticks":[{"index":-887220,"liquidityGross":2822091172725,"liquidityNet":2822091172725},{"index":273540,"liquidityGross":116315447200034,"liquidityNet":116315447200034},{"index":279120,"liquidityGross":116315447200034,"liquidityNet":-116315447200034},{"index":285480,"liquidityGross":2822091172725,"liquidityNet":-2822091172725}]%s}`
+
+	// tick spacing 60, without hooks registration
+	noHookParameters = "0x00000000000000000000000000000000000000000000000000000000003c0000"
+
+	dynamicFeeHook  = "0x0000000000000000000000000000000000001001"
+	outputFeeHook   = "0x0000000000000000000000000000000000001002"
+	constantSumHook = "0x0000000000000000000000000000000000001003"
+)
+
+// parameters returns the pool key parameters with tick spacing 60 and the hooks registration bits at offsets
+func parameters(offsets ...int) string {
+	bitmap := 0
+	for _, offset := range offsets {
+		bitmap |= 1 << offset
+	}
+	return fmt.Sprintf("0x%064x", 60<<tickSpacingOffset|bitmap)
+}
+
+func newTestEntityPool(staticExtra StaticExtra, extra string) entity.Pool {
+	staticExtraBytes, _ := json.Marshal(staticExtra)
+	return entity.Pool{
+		Address:     "0x1f7f8d0b33b0f5a1c7b6b2b8c8a5d0e7a1c7b6b2b8c8a5d0e7a1c7b6b2b8c8a5",
+		Exchange:    string(valueobject.ExchangePancakeInfinityCL),
+		Type:        DexTypePancakeInfinityCL,
+		Reserves:    entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:      []*entity.PoolToken{{Address: token0, Decimals: 18}, {Address: token1, Decimals: 18}},
+		Extra:       extra,
+		StaticExtra: string(staticExtraBytes),
+	}
+}
+
+func newTestPool(t *testing.T, staticExtra StaticExtra, extra string) *PoolSimulator {
+	p, err := NewPoolSimulator(newTestEntityPool(staticExtra, extra), valueobject.ChainIDBSC)
+	require.Nil(t, err)
+	return p
+}
+
+// dynamicFeeHookSim overrides the LP fee of every swap
+type dynamicFeeHookSim struct{ lpFee uint32 }
+
+func (h *dynamicFeeHookSim) BeforeSwap(BeforeSwapParams) (BeforeSwapResult, error) {
+	return BeforeSwapResult{OverrideFee: true, LpFee: h.lpFee}, nil
+}
+
+func (h *dynamicFeeHookSim) AfterSwap(AfterSwapParams) (*big.Int, error) { return nil, nil }
+
+// outputFeeHookSim takes a fee in bps of the amount out
+type outputFeeHookSim struct {
+	FeeBps int64 `json:"feeBps"`
+}
+
+func (h *outputFeeHookSim) BeforeSwap(BeforeSwapParams) (BeforeSwapResult, error) {
+	return BeforeSwapResult{}, nil
+}
+
+func (h *outputFeeHookSim) AfterSwap(params AfterSwapParams) (*big.Int, error) {
+	return new(big.Int).Div(new(big.Int).Mul(params.AmountOut, big.NewInt(h.FeeBps)), big.NewInt(10000)), nil
+}
+
+// constantSumHookSim replaces the curve of the pool, swapping 1:1
+type constantSumHookSim struct{}
+
+func (h *constantSumHookSim) BeforeSwap(params BeforeSwapParams) (BeforeSwapResult, error) {
+	return BeforeSwapResult{DeltaSpecified: params.AmountIn, DeltaUnspecified: new(big.Int).Neg(params.AmountIn)}, nil
+}
+
+func (h *constantSumHookSim) AfterSwap(AfterSwapParams) (*big.Int, error) { return nil, nil }
+
+func init() {
+	RegisterHook(dynamicFeeHook, func(json.RawMessage) (Hook, error) { return &dynamicFeeHookSim{lpFee: 500}, nil })
+	RegisterHook(outputFeeHook, func(hookExtra json.RawMessage) (Hook, error) {
+		var h outputFeeHookSim
+		err := json.Unmarshal(hookExtra, &h)
+		return &h, err
+	})
+	RegisterHook(constantSumHook, func(json.RawMessage) (Hook, error) { return &constantSumHookSim{}, nil })
+}
+
+func calcAmountOut(t *testing.T, p pool.IPoolSimulator, tokenIn string, amountIn string, tokenOut string) *big.Int {
+	amount, _ := new(big.Int).SetString(amountIn, 10)
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: tokenIn, Amount: amount}, tokenOut)
+	require.Nil(t, err)
+	return res.TokenAmountOut.Amount
+}
+
+func TestCalcAmountOut_NoHook(t *testing.T) {
+	p := newTestPool(t, StaticExtra{Fee: 3000, Parameters: noHookParameters}, fmt.Sprintf(testExtra, ""))
+
+	v3EntityPool := newTestEntityPool(StaticExtra{}, fmt.Sprintf(testExtra, ""))
+	v3EntityPool.SwapFee = 3000
+	v3Pool, err := uniswapv3.NewPoolSimulator(v3EntityPool, valueobject.ChainIDBSC)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		tokenIn           string
+		amountIn          string
+		tokenOut          string
+		expectedAmountOut string
+	}{
+		{token0, "1000", token1, "1375085809786534"},
+		{token0, "1000000", token1, "1308751942822025876"},
+		// crosses the tick 279120
+		{token0, "100000000", token1, "35765914137493045379"},
+		{token1, "1000000000000000", token0, "722"},
+		{token1, "100000000000000000000", token0, "616994"},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			amountOut := calcAmountOut(t, p, tc.tokenIn, tc.amountIn, tc.tokenOut)
+			assert.Equal(t, tc.expectedAmountOut, amountOut.String())
+			// same math as Uniswap V3
+			assert.Equal(t, calcAmountOut(t, v3Pool, tc.tokenIn, tc.amountIn, tc.tokenOut), amountOut)
+		})
+	}
+
+	// the state after the swap matches too
+	amountIn := big.NewInt(100000000)
+	res, err := p.CalcAmountOut(pool.TokenAmount{Token: token0, Amount: amountIn}, token1)
+	require.Nil(t, err)
+	p.UpdateBalance(pool.UpdateBalanceParams{SwapInfo: res.SwapInfo})
+	v3Res, err := v3Pool.CalcAmountOut(pool.TokenAmount{Token: token0, Amount: amountIn}, token1)
+	require.Nil(t, err)
+	v3Pool.UpdateBalance(pool.UpdateBalanceParams{SwapInfo: v3Res.SwapInfo})
+	assert.Equal(t, calcAmountOut(t, v3Pool, token1, "1000000000000", token0), calcAmountOut(t, p, token1, "1000000000000", token0))
+
+	assert.Equal(t, Meta{Fee: 3000, Parameters: noHookParameters}, p.GetMetaInfo(token0, token1))
+}
+
+func TestCalcAmountOut_Fees(t *testing.T) {
+	// protocol fee of 0.1% for zeroForOne and 0.05% for oneForZero
+	withProtocolFee := newTestPool(t, StaticExtra{Fee: 2000, Parameters: noHookParameters}, fmt.Sprintf(testExtra, `,"protocolFee":2049000`))
+	assert.Equal(t, uint32(2998), swapFee(1000, 2000))
+	assert.Equal(t,
+		calcAmountOut(t, newTestPool(t, StaticExtra{Fee: 2998, Parameters: noHookParameters}, fmt.Sprintf(testExtra, "")), token0, "1000000", token1),
+		calcAmountOut(t, withProtocolFee, token0, "1000000", token1))
+	assert.Equal(t,
+		calcAmountOut(t, newTestPool(t, StaticExtra{Fee: 2499, Parameters: noHookParameters}, fmt.Sprintf(testExtra, "")), token1, "1000000000000000", token0),
+		calcAmountOut(t, withProtocolFee, token1, "1000000000000000", token0))
+
+	// the LP fee of a dynamic fee pool is in slot0
+	dynamic := newTestPool(t, StaticExtra{Fee: dynamicFeeFlag, Parameters: noHookParameters}, fmt.Sprintf(testExtra, `,"lpFee":500`))
+	static := newTestPool(t, StaticExtra{Fee: 500, Parameters: noHookParameters}, fmt.Sprintf(testExtra, ""))
+	assert.Equal(t, calcAmountOut(t, static, token0, "1000000", token1), calcAmountOut(t, dynamic, token0, "1000000", token1))
+}
+
+func TestCalcAmountOut_Hooks(t *testing.T) {
+	noHook := newTestPool(t, StaticExtra{Fee: 3000, Parameters: noHookParameters}, fmt.Sprintf(testExtra, ""))
+	lowFee := newTestPool(t, StaticExtra{Fee: 500, Parameters: noHookParameters}, fmt.Sprintf(testExtra, ""))
+
+	// the fee override only applies to dynamic fee pools
+	dynamicFee := newTestPool(t, StaticExtra{Hooks: dynamicFeeHook, Fee: dynamicFeeFlag, Parameters: parameters(hooksBeforeSwapOffset)}, fmt.Sprintf(testExtra, `,"lpFee":3000`))
+	assert.Equal(t, calcAmountOut(t, lowFee, token0, "1000000", token1), calcAmountOut(t, dynamicFee, token0, "1000000", token1))
+	staticFee := newTestPool(t, StaticExtra{Hooks: dynamicFeeHook, Fee: 3000, Parameters: parameters(hooksBeforeSwapOffset)}, fmt.Sprintf(testExtra, ""))
+	assert.Equal(t, calcAmountOut(t, noHook, token0, "1000000", token1), calcAmountOut(t, staticFee, token0, "1000000", token1))
+
+	outputFee := newTestPool(t, StaticExtra{Hooks: outputFeeHook, Fee: 3000, Parameters: parameters(hooksAfterSwapOffset, hooksAfterSwapReturnsDeltaOffset)}, fmt.Sprintf(testExtra, `,"hookExtra":{"feeBps":100}`))
+	res, err := outputFee.CalcAmountOut(pool.TokenAmount{Token: token0, Amount: big.NewInt(1000000)}, token1)
+	require.Nil(t, err)
+	assert.Equal(t, "1295664423393805618", res.TokenAmountOut.Amount.String())
+	assert.Equal(t, DefaultGas.Swap+DefaultGas.HookCall, res.Gas)
+	// without the permission to return a delta, the delta is ignored
+	outputFeeNoDelta := newTestPool(t, StaticExtra{Hooks: outputFeeHook, Fee: 3000, Parameters: parameters(hooksAfterSwapOffset)}, fmt.Sprintf(testExtra, `,"hookExtra":{"feeBps":100}`))
+	assert.Equal(t, calcAmountOut(t, noHook, token0, "1000000", token1), calcAmountOut(t, outputFeeNoDelta, token0, "1000000", token1))
+
+	constantSum := newTestPool(t, StaticExtra{Hooks: constantSumHook, Fee: 3000, Parameters: parameters(hooksBeforeSwapOffset, hooksBeforeSwapReturnsDeltaOffset)}, fmt.Sprintf(testExtra, ""))
+	res, err = constantSum.CalcAmountOut(pool.TokenAmount{Token: token0, Amount: big.NewInt(1000000)}, token1)
+	require.Nil(t, err)
+	assert.Equal(t, big.NewInt(1000000), res.TokenAmountOut.Amount)
+	// the pool didn't swap
+	assert.Equal(t, SwapInfo{
+		nextStateSqrtRatioX96: constantSum.V3Pool.SqrtRatioX96,
+		nextStateLiquidity:    constantSum.V3Pool.Liquidity,
+		nextStateTickCurrent:  constantSum.V3Pool.TickCurrent,
+	}, res.SwapInfo)
+
+	// a hook without swap callbacks doesn't need a simulator
+	_, err = NewPoolSimulator(newTestEntityPool(StaticExtra{Hooks: "0x0000000000000000000000000000000000002001", Fee: 3000, Parameters: parameters(0, 2)}, fmt.Sprintf(testExtra, "")), valueobject.ChainIDBSC)
+	assert.Nil(t, err)
+	_, err = NewPoolSimulator(newTestEntityPool(StaticExtra{Hooks: "0x0000000000000000000000000000000000002001", Fee: 3000, Parameters: parameters(hooksAfterSwapOffset)}, fmt.Sprintf(testExtra, "")), valueobject.ChainIDBSC)
+	assert.ErrorIs(t, err, ErrUnsupportedHook)
+}
+
+func TestNewPoolSimulator_InvalidParameters(t *testing.T) {
+	for idx, parameters := range []string{"", "0x", "0x1"} {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			_, err := NewPoolSimulator(newTestEntityPool(StaticExtra{Fee: 3000, Parameters: parameters}, fmt.Sprintf(testExtra, "")), valueobject.ChainIDBSC)
+			assert.ErrorIs(t, err, ErrInvalidParameters)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package pancakeinfinity
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+type Gas struct {
+	Swap     int64
+	HookCall int64 // each call of beforeSwap or afterSwap
+}
+
+type Tick struct {
+	Index          int      `json:"index"`
+	LiquidityGross *big.Int `json:"liquidityGross"`
+	LiquidityNet   *big.Int `json:"liquidityNet"`
+}
+
+// StaticExtra is the pool key, without the currencies
+type StaticExtra struct {
+	PoolManager string `json:"poolManager"`
+	Hooks       string `json:"hooks"`
+	Fee         uint32 `json:"fee"`        // LP fee, or dynamicFeeFlag if the hook sets it
+	Parameters  string `json:"parameters"` // bytes32: hooks registration bitmap and tick spacing
+}
+
+type Extra struct {
+	Liquidity    *big.Int `json:"liquidity"`
+	SqrtPriceX96 *big.Int `json:"sqrtPriceX96"`
+	Tick         *big.Int `json:"tick"`
+	Ticks        []Tick   `json:"ticks"`
+
+	// slot0 of the pool manager, the LP fee only matters for dynamic fee pools
+	ProtocolFee uint32 `json:"protocolFee"` // zeroForOne fee on the lower 12 bits, oneForZero fee on the next 12 bits
+	LpFee       uint32 `json:"lpFee"`
+
+	// state of the hook, decoded by its HookFactory
+	HookExtra json.RawMessage `json:"hookExtra,omitempty"`
+}
+
+// SwapInfo is the state of the pool after a swap
+type SwapInfo struct {
+	nextStateSqrtRatioX96 *big.Int
+	nextStateLiquidity    *big.Int
+	nextStateTickCurrent  int
+}
+
+// Meta is the pool key needed to encode the swap
+type Meta struct {
+	PoolManager string `json:"poolManager"`
+	Hooks       string `json:"hooks"`
+	Fee         uint32 `json:"fee"`
+	Parameters  string `json:"parameters"`
+}
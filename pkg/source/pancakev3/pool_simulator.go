@@ -7,7 +7,6 @@ import (
 	"math/big"
 	"strings"
 
-	"github.com/KyberNetwork/logger"
 	"github.com/KyberNetwork/pancake-v3-sdk/constants"
 	v3Entities "github.com/KyberNetwork/pancake-v3-sdk/entities"
 	v3Utils "github.com/KyberNetwork/pancake-v3-sdk/utils"
@@ -16,6 +15,7 @@ import (
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
 )
 
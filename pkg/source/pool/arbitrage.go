@@ -0,0 +1,117 @@
+package pool
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+var (
+	ErrInvalidMaxHops = errors.New("invalid max hops")
+	// ErrNoArbCycle is returned by FindArbCycle if no cycle of the pools is profitable at the spot prices
+	ErrNoArbCycle = errors.New("no arbitrage cycle")
+)
+
+// IPoolSpotPrice is implemented by the pools able to give their marginal price without simulating a swap
+type IPoolSpotPrice interface {
+	// SpotPrice returns the amount of tokenOut got for an infinitesimal amount of tokenIn, in raw units
+	// (not adjusted by the decimals), after the swap fee
+	SpotPrice(tokenIn, tokenOut string) (*big.Float, error)
+}
+
+// arbCycleRateUnit is the fixed point unit of the rates returned by FindArbCycle
+var arbCycleRateUnit = big.NewFloat(1e18)
+
+// arbCycleEpsilon is the margin below 0 of the weight of a profitable cycle: the logs of reciprocal prices don't
+// always cancel out exactly in float64
+const arbCycleEpsilon = 1e-12
+
+type arbEdge struct {
+	tokenIn, tokenOut string
+	weight            float64 // -log(spot price)
+}
+
+// FindArbCycle looks for a profitable cycle of at most maxHops swaps from startToken back to itself at the spot prices
+// of the pools, with Bellman-Ford on -log(price). The cycle with the fewest hops is returned, else a profitable cycle
+// repeated would always win, then the most profitable one of that length. It returns the tokens of the cycle,
+// startToken first and last, and its rate: the amount of startToken got back for 1e18 swapped, so greater than 1e18.
+// Between two tokens the pool with the best price is used. Only the pools implementing IPoolSpotPrice are considered,
+// and the cycle may go through a token other than startToken more than once. Returns ErrNoArbCycle if no cycle is
+// profitable.
+func FindArbCycle(pools []IPoolSimulator, startToken string, maxHops int) ([]string, *big.Int, error) {
+	if maxHops < 2 {
+		return nil, nil, ErrInvalidMaxHops
+	}
+
+	// best edge of each direction the pools can swap, CanSwapFrom being the tokens out of a token in, in the order of the pools so that the result is deterministic
+	var edges []arbEdge
+	edgeIndexes := map[[2]string]int{}
+	for _, p := range pools {
+		spotPricePool, ok := p.(IPoolSpotPrice)
+		if !ok {
+			continue
+		}
+		for _, tokenIn := range p.GetTokens() {
+			for _, tokenOut := range p.CanSwapFrom(tokenIn) {
+				price, err := spotPricePool.SpotPrice(tokenIn, tokenOut)
+				if err != nil || price.Sign() <= 0 {
+					continue
+				}
+				priceFloat, _ := price.Float64()
+				weight := -math.Log(priceFloat)
+				if math.IsInf(weight, 0) || math.IsNaN(weight) {
+					continue
+				}
+
+				key := [2]string{tokenIn, tokenOut}
+				if idx, ok := edgeIndexes[key]; !ok {
+					edgeIndexes[key] = len(edges)
+					edges = append(edges, arbEdge{tokenIn: tokenIn, tokenOut: tokenOut, weight: weight})
+				} else if weight < edges[idx].weight {
+					edges[idx].weight = weight
+				}
+			}
+		}
+	}
+
+	// dist[k][token] is the lowest weight of the paths of k hops from startToken to token, parent[k][token] the
+	// previous token of that path
+	dist := []map[string]float64{{startToken: 0}}
+	parent := []map[string]string{{}}
+	var (
+		bestHops   int
+		bestWeight float64
+	)
+	for k := 1; k <= maxHops; k++ {
+		dist = append(dist, map[string]float64{})
+		parent = append(parent, map[string]string{})
+		for _, edge := range edges {
+			d, ok := dist[k-1][edge.tokenIn]
+			if !ok {
+				continue
+			}
+			if current, ok := dist[k][edge.tokenOut]; !ok || d+edge.weight < current {
+				dist[k][edge.tokenOut] = d + edge.weight
+				parent[k][edge.tokenOut] = edge.tokenIn
+			}
+		}
+		// a negative weight is a product of prices above 1
+		if d, ok := dist[k][startToken]; ok && d < -arbCycleEpsilon {
+			bestHops, bestWeight = k, d
+			break
+		}
+	}
+
+	if bestHops == 0 {
+		return nil, nil, ErrNoArbCycle
+	}
+
+	cycle := make([]string, bestHops+1)
+	cycle[bestHops] = startToken
+	for k := bestHops; k > 0; k-- {
+		cycle[k-1] = parent[k][cycle[k]]
+	}
+
+	rate, _ := new(big.Float).Mul(big.NewFloat(math.Exp(-bestWeight)), arbCycleRateUnit).Int(nil)
+	return cycle, rate, nil
+}
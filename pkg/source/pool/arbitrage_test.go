@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spotPricePool quotes at fixed prices from token0 to token1 and back
+type spotPricePool struct {
+	fakePool
+	price, reversePrice float64
+}
+
+func (p *spotPricePool) SpotPrice(tokenIn, tokenOut string) (*big.Float, error) {
+	if tokenIn == p.Info.Tokens[0] {
+		return big.NewFloat(p.price), nil
+	}
+	return big.NewFloat(p.reversePrice), nil
+}
+
+func newSpotPricePool(address, token0, token1 string, price float64) *spotPricePool {
+	p := &spotPricePool{fakePool: *newDenylistTestPool(address, token0, token1), price: price}
+	// 0.3% fee each way
+	p.price *= 0.997
+	p.reversePrice = 0.997 / price
+	return p
+}
+
+// oneWaySpotPricePool only swaps token0 to token1
+type oneWaySpotPricePool struct {
+	spotPricePool
+}
+
+func (p *oneWaySpotPricePool) CanSwapTo(address string) []string {
+	if address == p.Info.Tokens[1] {
+		return []string{p.Info.Tokens[0]}
+	}
+	return nil
+}
+
+func (p *oneWaySpotPricePool) CanSwapFrom(address string) []string {
+	if address == p.Info.Tokens[0] {
+		return []string{p.Info.Tokens[1]}
+	}
+	return nil
+}
+
+func TestFindArbCycle(t *testing.T) {
+	fair := []IPoolSimulator{
+		newSpotPricePool("ab", "A", "B", 2),
+		newSpotPricePool("bc", "B", "C", 3),
+		newSpotPricePool("ca", "C", "A", 1.0/6),
+		newSpotPricePool("cd", "C", "D", 10),
+		// no spot price, never considered
+		newDenylistTestPool("fake", "A", "D"),
+	}
+	_, _, err := FindArbCycle(fair, "A", 4)
+	assert.ErrorIs(t, err, ErrNoArbCycle)
+
+	// A is 2% cheaper in C on the seeded pool
+	seeded := append([]IPoolSimulator{}, fair...)
+	seeded[2] = newSpotPricePool("ca", "C", "A", 1.02/6)
+
+	testcases := []struct {
+		pools         []IPoolSimulator
+		startToken    string
+		maxHops       int
+		expectedCycle []string
+		expectedRate  float64
+	}{
+		{seeded, "A", 3, []string{"A", "B", "C", "A"}, 1.02 * 0.997 * 0.997 * 0.997},
+		{seeded, "C", 6, []string{"C", "A", "B", "C"}, 1.02 * 0.997 * 0.997 * 0.997},
+		// a 2 hops arbitrage between two pools of the same pair, found before the longer one
+		{append([]IPoolSimulator{newSpotPricePool("ab2", "A", "B", 2.1)}, seeded...), "A", 4,
+			[]string{"A", "B", "A"}, 2.1 * 0.997 * 0.997 / 2},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			cycle, rate, err := FindArbCycle(tc.pools, tc.startToken, tc.maxHops)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedCycle, cycle)
+			assert.InDelta(t, tc.expectedRate*1e18, float64(rate.Int64()), 1e6)
+			assert.Positive(t, rate.Cmp(big.NewInt(1e18)))
+		})
+	}
+
+	// reciprocal prices without fee, the rounding of the logs is no arbitrage
+	reciprocal := []IPoolSimulator{
+		&spotPricePool{fakePool: *newDenylistTestPool("ab", "A", "B"), price: 10, reversePrice: 1.0 / 10},
+		&spotPricePool{fakePool: *newDenylistTestPool("bc", "B", "C"), price: 1.1, reversePrice: 1 / 1.1},
+		&spotPricePool{fakePool: *newDenylistTestPool("ca", "C", "A"), price: 1 / 11.0, reversePrice: 11},
+	}
+	_, _, err = FindArbCycle(reciprocal, "A", 4)
+	assert.ErrorIs(t, err, ErrNoArbCycle)
+
+	// the arbitrage needs 3 hops
+	_, _, err = FindArbCycle(seeded, "A", 2)
+	assert.ErrorIs(t, err, ErrNoArbCycle)
+	_, _, err = FindArbCycle(fair, "A", 1)
+	assert.ErrorIs(t, err, ErrInvalidMaxHops)
+
+	// the cycles follow the direction of the one way pools
+	oneWay := []IPoolSimulator{
+		&oneWaySpotPricePool{spotPricePool: *newSpotPricePool("ab", "A", "B", 2)},
+		newSpotPricePool("bc", "B", "C", 3),
+		newSpotPricePool("ca", "C", "A", 1.02/6),
+	}
+	cycle, _, err := FindArbCycle(oneWay, "A", 3)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"A", "B", "C", "A"}, cycle)
+	// the profitable cycle would swap B to A
+	oneWay[2] = newSpotPricePool("ca", "C", "A", 0.98/6)
+	_, _, err = FindArbCycle(oneWay, "A", 3)
+	assert.ErrorIs(t, err, ErrNoArbCycle)
+}
@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBudgetExhausted = errors.New("budget exhausted")
+)
+
+// BudgetConfig bounds the work of the simulators for one request, a zero value is unlimited
+type BudgetConfig struct {
+	MaxPools    int           `json:"maxPools"`    // distinct pools quoted
+	MaxDuration time.Duration `json:"maxDuration"` // wall time spent in the simulators
+}
+
+// Budget counts the simulator invocations of a request and the time spent in them, see CalcAmountOutParams.Budget.
+// Once a limit is reached, CalcAmountOutWithParams fails fast with ErrBudgetExhausted so that the route search can
+// wind down with the routes found so far. The call reaching the time limit completes, so the time spent is over it by
+// at most one quote.
+// A Budget is safe for concurrent use.
+type Budget struct {
+	config BudgetConfig
+
+	mu          sync.Mutex
+	pools       map[string]struct{}
+	invocations int
+	elapsed     time.Duration
+
+	now func() time.Time
+}
+
+func NewBudget(config BudgetConfig) *Budget {
+	return &Budget{
+		config: config,
+		pools:  map[string]struct{}{},
+		now:    time.Now,
+	}
+}
+
+// acquire counts an invocation of the simulator of pool, or returns ErrBudgetExhausted
+func (b *Budget) acquire(pool string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config.MaxDuration > 0 && b.elapsed >= b.config.MaxDuration {
+		return ErrBudgetExhausted
+	}
+	if _, ok := b.pools[pool]; !ok {
+		if b.config.MaxPools > 0 && len(b.pools) >= b.config.MaxPools {
+			return ErrBudgetExhausted
+		}
+		b.pools[pool] = struct{}{}
+	}
+	b.invocations++
+	return nil
+}
+
+func (b *Budget) spend(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.elapsed += d
+}
+
+// Invocations returns the number of simulator invocations within the budget
+func (b *Budget) Invocations() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.invocations
+}
+
+// Pools returns the number of distinct pools quoted
+func (b *Budget) Pools() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pools)
+}
+
+// Elapsed returns the wall time spent in the simulators
+func (b *Budget) Elapsed() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.elapsed
+}
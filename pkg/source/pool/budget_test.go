@@ -0,0 +1,163 @@
+package pool
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowPool advances the clock of the budget by duration at each quote
+type slowPool struct {
+	*fakePool
+	now      *time.Time
+	duration time.Duration
+}
+
+func (p *slowPool) CalcAmountOut(tokenAmountIn TokenAmount, tokenOut string) (*CalcAmountOutResult, error) {
+	*p.now = p.now.Add(p.duration)
+	return p.fakePool.CalcAmountOut(tokenAmountIn, tokenOut)
+}
+
+// fastSlowPool has a fast path taking a tenth of the time of the exact one, without the last digit
+type fastSlowPool struct {
+	*slowPool
+	fastQuotes int
+}
+
+func (p *fastSlowPool) CalcAmountOutWithParams(params CalcAmountOutParams) (*CalcAmountOutResult, error) {
+	if params.IsExact() {
+		return p.CalcAmountOut(params.TokenAmountIn, params.TokenOut)
+	}
+	p.fastQuotes++
+	*p.now = p.now.Add(p.duration / 10)
+	res, err := p.fakePool.CalcAmountOut(params.TokenAmountIn, params.TokenOut)
+	if err != nil {
+		return nil, err
+	}
+	res.TokenAmountOut.Amount.Div(res.TokenAmountOut.Amount, big.NewInt(10))
+	return res, nil
+}
+
+func newTestBudget(config BudgetConfig) (*Budget, *time.Time) {
+	b := NewBudget(config)
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestCalcAmountOutWithParams_Budget(t *testing.T) {
+	amountIn := TokenAmount{Token: "A", Amount: big.NewInt(10)}
+
+	testcases := []struct {
+		config              BudgetConfig
+		pools               int
+		duration            time.Duration
+		expectedInvocations int
+	}{
+		// unlimited
+		{BudgetConfig{}, 3, time.Millisecond, 30},
+		// the pools already quoted can be quoted again
+		{BudgetConfig{MaxPools: 2}, 3, time.Millisecond, 20},
+		// the quote reaching the limit completes
+		{BudgetConfig{MaxDuration: 10 * time.Millisecond}, 3, 3 * time.Millisecond, 4},
+		{BudgetConfig{MaxPools: 1, MaxDuration: 5 * time.Millisecond}, 3, time.Millisecond, 5},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			b, now := newTestBudget(tc.config)
+			succeeded := 0
+			// 10 rounds over the pools
+			for i := 0; i < 10*tc.pools; i++ {
+				p := &slowPool{fakePool: newDenylistTestPool(fmt.Sprintf("pool%d", i%tc.pools), "A", "B"), now: now,
+					duration: tc.duration}
+				res, err := CalcAmountOutWithParams(p, CalcAmountOutParams{TokenAmountIn: amountIn, TokenOut: "B", Budget: b})
+				if err != nil {
+					assert.ErrorIs(t, err, ErrBudgetExhausted)
+					assert.Nil(t, res)
+					continue
+				}
+				assert.Equal(t, "100", res.TokenAmountOut.Amount.String())
+				succeeded++
+			}
+
+			assert.Equal(t, tc.expectedInvocations, succeeded)
+			assert.Equal(t, tc.expectedInvocations, b.Invocations())
+			assert.Equal(t, time.Duration(tc.expectedInvocations)*tc.duration, b.Elapsed())
+			if tc.config.MaxDuration > 0 {
+				assert.Less(t, b.Elapsed(), tc.config.MaxDuration+tc.duration)
+			}
+			if tc.config.MaxPools > 0 {
+				assert.Equal(t, tc.config.MaxPools, b.Pools())
+			}
+		})
+	}
+}
+
+func TestCalcAmountOutWithParams_BudgetConcurrent(t *testing.T) {
+	b := NewBudget(BudgetConfig{MaxPools: 5})
+	amountIn := TokenAmount{Token: "A", Amount: big.NewInt(10)}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	quoted := map[string]struct{}{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := newDenylistTestPool(fmt.Sprintf("pool%d", i), "A", "B")
+			if _, err := CalcAmountOutWithParams(p, CalcAmountOutParams{TokenAmountIn: amountIn, TokenOut: "B", Budget: b}); err == nil {
+				mu.Lock()
+				quoted[p.GetAddress()] = struct{}{}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, quoted, 5)
+	assert.Equal(t, 5, b.Pools())
+	assert.Equal(t, 5, b.Invocations())
+}
+
+func TestCalcAmountOutWithParams_NilBudget(t *testing.T) {
+	res, err := CalcAmountOutWithParams(newFakePool(0), CalcAmountOutParams{
+		TokenAmountIn: TokenAmount{Token: "A", Amount: big.NewInt(10)},
+		TokenOut:      "B",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "100", res.TokenAmountOut.Amount.String())
+}
+
+func TestCalcAmountOutWithParams_FastWithBudget(t *testing.T) {
+	b, now := newTestBudget(BudgetConfig{MaxDuration: 10 * time.Millisecond})
+	p := &fastSlowPool{slowPool: &slowPool{fakePool: newFakePool(0), now: now, duration: 10 * time.Millisecond}}
+	params := CalcAmountOutParams{
+		TokenAmountIn: TokenAmount{Token: "A", Amount: big.NewInt(10)},
+		TokenOut:      "B",
+		Precision:     PrecisionFast,
+		Budget:        b,
+	}
+
+	// the fast quotes are counted at their own cost
+	for i := 0; i < 10; i++ {
+		res, err := CalcAmountOutWithParams(p, params)
+		require.Nil(t, err)
+		assert.Equal(t, "10", res.TokenAmountOut.Amount.String())
+	}
+	assert.Equal(t, 10, p.fastQuotes)
+	assert.Equal(t, 10, b.Invocations())
+	assert.Equal(t, 10*time.Millisecond, b.Elapsed())
+
+	_, err := CalcAmountOutWithParams(p, params)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+	params.Precision = PrecisionExact
+	_, err = CalcAmountOutWithParams(p, params)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+	assert.Equal(t, 10, p.fastQuotes)
+}
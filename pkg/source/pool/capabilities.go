@@ -0,0 +1,25 @@
+package pool
+
+// Capabilities are the features supported by a simulator, so that the callers can branch on them
+type Capabilities struct {
+	CalcAmountIn  bool // implements IPoolExactOut, quotes the swaps by exact amount out
+	GasEstimation bool // the results have an estimation of the gas of the swap
+	PartialFill   bool // an amount in above the liquidity is partially swapped instead of failing
+	Clone         bool // implements IPoolCloner, the copy being updated independently
+	FastPrecision bool // implements IPoolApproximator
+	Expiry        bool // the quotes or orders expire, implements IPoolExpirable
+	MinSwapAmount bool // implements IPoolMinSwapAmount
+}
+
+// IPoolCapabilities is implemented by the simulators reporting their capabilities
+type IPoolCapabilities interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of the simulator, none if it doesn't report them
+func CapabilitiesOf(p IPoolSimulator) Capabilities {
+	if c, ok := p.(IPoolCapabilities); ok {
+		return c.Capabilities()
+	}
+	return Capabilities{}
+}
@@ -0,0 +1,299 @@
+package pool
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+var (
+	// ErrCircuitOpen is the exclusion reason of the pools whose circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrInvalidCircuitBreakerConfig is returned by NewCircuitBreaker for a config that would never let the breakers
+	// close, e.g. the zero value
+	ErrInvalidCircuitBreakerConfig = errors.New("invalid circuit breaker config")
+)
+
+// SettlementFeedback receives the outcome of the on-chain execution of the routes, per pool of the route.
+// The pools are identified by chain and address, the same address may be deployed on several chains.
+type SettlementFeedback interface {
+	RecordSuccess(chainID valueobject.ChainID, pool string)
+	RecordRevert(chainID valueobject.ChainID, pool string)
+}
+
+type BreakerState int
+
+const (
+	// BreakerClosed lets the pool be quoted
+	BreakerClosed BreakerState = iota
+	// BreakerOpen stops quoting the pool until the cooldown is over
+	BreakerOpen
+	// BreakerHalfOpen quotes the pool again, at most HalfOpenProbes times, until enough executions succeed to close
+	// the breaker or one reverts
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type CircuitBreakerConfig struct {
+	Window         time.Duration `json:"window"`         // sliding window of the failure rate
+	MinSamples     int           `json:"minSamples"`     // executions needed in the window before the breaker can open
+	FailureRate    float64       `json:"failureRate"`    // failure rate in [0, 1] opening the breaker
+	Cooldown       time.Duration `json:"cooldown"`       // time an open breaker waits before probing the pool
+	HalfOpenProbes int           `json:"halfOpenProbes"` // admissions and successful executions closing a half-open breaker
+}
+
+// BreakerStatus is the state of the breaker of a pool, as reported in the health snapshot
+type BreakerStatus struct {
+	ChainID        valueobject.ChainID
+	Pool           string
+	State          BreakerState
+	Samples        int     // executions in the window
+	FailureRate    float64 // reverts / samples in the window
+	Transitions    uint64  // number of state changes
+	LastTransition time.Time
+}
+
+type executionOutcome struct {
+	at       time.Time
+	reverted bool
+}
+
+type breakerKey struct {
+	chainID valueobject.ChainID
+	pool    string
+}
+
+// newBreakerKey lowercases the address of the pool, the feedback and the pool loading may not agree on the case
+func newBreakerKey(chainID valueobject.ChainID, pool string) breakerKey {
+	return breakerKey{chainID: chainID, pool: strings.ToLower(pool)}
+}
+
+type poolBreaker struct {
+	state          BreakerState
+	outcomes       []executionOutcome
+	openedAt       time.Time
+	probes         int
+	admitted       int       // quotes admitted while half-open
+	admittedAt     time.Time // last admission while half-open
+	transitions    uint64
+	lastTransition time.Time
+}
+
+// CircuitBreaker stops quoting the pools whose routes keep reverting on-chain, for a cooldown period.
+// It consumes the settlement feedback and is consulted by the pool loading together with the kill switches.
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mu    sync.Mutex
+	pools map[breakerKey]*poolBreaker
+}
+
+// NewCircuitBreaker returns ErrInvalidCircuitBreakerConfig unless every field of the config is positive and
+// FailureRate is at most 1: without samples or failure rate a breaker opens on any execution, even a successful one,
+// and without probes a half-open breaker never closes.
+func NewCircuitBreaker(config CircuitBreakerConfig) (*CircuitBreaker, error) {
+	if config.Window <= 0 || config.MinSamples <= 0 || config.FailureRate <= 0 || config.FailureRate > 1 ||
+		config.Cooldown <= 0 || config.HalfOpenProbes <= 0 {
+		return nil, ErrInvalidCircuitBreakerConfig
+	}
+
+	return &CircuitBreaker{
+		config: config,
+		now:    time.Now,
+		pools:  map[breakerKey]*poolBreaker{},
+	}, nil
+}
+
+func (b *CircuitBreaker) breaker(key breakerKey) *poolBreaker {
+	pb, ok := b.pools[key]
+	if !ok {
+		pb = &poolBreaker{}
+		b.pools[key] = pb
+	}
+	return pb
+}
+
+func (b *CircuitBreaker) transition(pb *poolBreaker, state BreakerState, now time.Time) {
+	pb.state = state
+	pb.transitions++
+	pb.lastTransition = now
+	pb.probes = 0
+	pb.admitted = 0
+	if state == BreakerOpen {
+		pb.openedAt = now
+	}
+	if state == BreakerClosed {
+		pb.outcomes = nil
+	}
+}
+
+// prune drops the outcomes out of the window
+func (b *CircuitBreaker) prune(pb *poolBreaker, now time.Time) {
+	start := now.Add(-b.config.Window)
+	idx := sort.Search(len(pb.outcomes), func(i int) bool { return pb.outcomes[i].at.After(start) })
+	pb.outcomes = pb.outcomes[idx:]
+}
+
+func failureRate(outcomes []executionOutcome) float64 {
+	if len(outcomes) == 0 {
+		return 0
+	}
+	reverts := 0
+	for _, o := range outcomes {
+		if o.reverted {
+			reverts++
+		}
+	}
+	return float64(reverts) / float64(len(outcomes))
+}
+
+// refresh moves an open breaker to half-open once the cooldown is over. The admissions of a half-open breaker
+// whose executions weren't reported within a cooldown are given back, the routes were likely never executed.
+func (b *CircuitBreaker) refresh(pb *poolBreaker, now time.Time) {
+	if pb.state == BreakerOpen && !now.Before(pb.openedAt.Add(b.config.Cooldown)) {
+		b.transition(pb, BreakerHalfOpen, now)
+	}
+	if pb.state == BreakerHalfOpen && pb.admitted > pb.probes && !now.Before(pb.admittedAt.Add(b.config.Cooldown)) {
+		pb.admitted = pb.probes
+	}
+}
+
+// idle returns true if the breaker is closed with no outcome nor transition in the window, it can be evicted
+func (b *CircuitBreaker) idle(pb *poolBreaker, now time.Time) bool {
+	return pb.state == BreakerClosed && len(pb.outcomes) == 0 &&
+		!pb.lastTransition.After(now.Add(-b.config.Window))
+}
+
+func (b *CircuitBreaker) record(chainID valueobject.ChainID, pool string, reverted bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	pb := b.breaker(newBreakerKey(chainID, pool))
+	b.refresh(pb, now)
+
+	switch pb.state {
+	case BreakerOpen:
+		// executions of routes quoted before the breaker opened
+		return
+	case BreakerHalfOpen:
+		if reverted {
+			b.transition(pb, BreakerOpen, now)
+			return
+		}
+		pb.probes++
+		if pb.probes >= b.config.HalfOpenProbes {
+			b.transition(pb, BreakerClosed, now)
+		}
+		return
+	}
+
+	pb.outcomes = append(pb.outcomes, executionOutcome{at: now, reverted: reverted})
+	b.prune(pb, now)
+	if len(pb.outcomes) >= b.config.MinSamples && failureRate(pb.outcomes) >= b.config.FailureRate {
+		b.transition(pb, BreakerOpen, now)
+	}
+}
+
+func (b *CircuitBreaker) RecordSuccess(chainID valueobject.ChainID, pool string) {
+	b.record(chainID, pool, false)
+}
+
+func (b *CircuitBreaker) RecordRevert(chainID valueobject.ChainID, pool string) {
+	b.record(chainID, pool, true)
+}
+
+// Check returns ErrCircuitOpen if the pool of the chain must not be quoted. A half-open breaker admits
+// HalfOpenProbes quotes, then returns ErrCircuitOpen until their executions are reported.
+func (b *CircuitBreaker) Check(chainID valueobject.ChainID, pool string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := newBreakerKey(chainID, pool)
+	pb, ok := b.pools[key]
+	if !ok {
+		return nil
+	}
+	now := b.now()
+	b.refresh(pb, now)
+
+	switch pb.state {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if pb.admitted >= b.config.HalfOpenProbes {
+			return ErrCircuitOpen
+		}
+		pb.admitted++
+		pb.admittedAt = now
+		return nil
+	}
+
+	b.prune(pb, now)
+	if b.idle(pb, now) {
+		delete(b.pools, key)
+	}
+	return nil
+}
+
+// FilterPools drops the pools of the chain whose breaker is open
+func (b *CircuitBreaker) FilterPools(chainID valueobject.ChainID, pools []IPoolSimulator) []IPoolSimulator {
+	result := make([]IPoolSimulator, 0, len(pools))
+	for _, p := range pools {
+		if b.Check(chainID, p.GetAddress()) == nil {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Snapshot returns the status of the breakers of all the pools with recent feedback, sorted by chain then pool.
+// The idle breakers are evicted.
+func (b *CircuitBreaker) Snapshot() []BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	result := make([]BreakerStatus, 0, len(b.pools))
+	for key, pb := range b.pools {
+		b.refresh(pb, now)
+		b.prune(pb, now)
+		if b.idle(pb, now) {
+			delete(b.pools, key)
+			continue
+		}
+		result = append(result, BreakerStatus{
+			ChainID:        key.chainID,
+			Pool:           key.pool,
+			State:          pb.state,
+			Samples:        len(pb.outcomes),
+			FailureRate:    failureRate(pb.outcomes),
+			Transitions:    pb.transitions,
+			LastTransition: pb.lastTransition,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ChainID != result[j].ChainID {
+			return result[i].ChainID < result[j].ChainID
+		}
+		return result[i].Pool < result[j].Pool
+	})
+	return result
+}
@@ -0,0 +1,194 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+const testChainID = valueobject.ChainIDEthereum
+
+var testCircuitBreakerConfig = CircuitBreakerConfig{
+	Window:         time.Minute,
+	MinSamples:     5,
+	FailureRate:    0.5,
+	Cooldown:       30 * time.Second,
+	HalfOpenProbes: 2,
+}
+
+func newTestCircuitBreaker() (*CircuitBreaker, *time.Time) {
+	b, _ := NewCircuitBreaker(testCircuitBreakerConfig)
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestNewCircuitBreaker_InvalidConfig(t *testing.T) {
+	_, err := NewCircuitBreaker(CircuitBreakerConfig{})
+	assert.ErrorIs(t, err, ErrInvalidCircuitBreakerConfig)
+
+	for idx, update := range []func(config *CircuitBreakerConfig){
+		func(config *CircuitBreakerConfig) { config.Window = 0 },
+		func(config *CircuitBreakerConfig) { config.MinSamples = 0 },
+		func(config *CircuitBreakerConfig) { config.FailureRate = 0 },
+		func(config *CircuitBreakerConfig) { config.FailureRate = 1.5 },
+		func(config *CircuitBreakerConfig) { config.Cooldown = -time.Second },
+		func(config *CircuitBreakerConfig) { config.HalfOpenProbes = 0 },
+	} {
+		config := testCircuitBreakerConfig
+		update(&config)
+		_, err := NewCircuitBreaker(config)
+		assert.ErrorIs(t, err, ErrInvalidCircuitBreakerConfig, "test %d", idx)
+	}
+
+	b, err := NewCircuitBreaker(testCircuitBreakerConfig)
+	require.Nil(t, err)
+	b.RecordSuccess(testChainID, "pool")
+	assert.Nil(t, b.Check(testChainID, "pool"))
+}
+
+func TestCircuitBreaker_RevertBurst(t *testing.T) {
+	b, now := newTestCircuitBreaker()
+	pools := []IPoolSimulator{newDenylistTestPool("reverting", "a", "b"), newDenylistTestPool("healthy", "a", "b")}
+
+	b.RecordSuccess(testChainID, "reverting")
+	b.RecordSuccess(testChainID, "reverting")
+	b.RecordRevert(testChainID, "reverting")
+	b.RecordRevert(testChainID, "reverting")
+	// not enough samples yet
+	assert.Nil(t, b.Check(testChainID, "reverting"))
+	b.RecordRevert(testChainID, "reverting")
+	b.RecordSuccess(testChainID, "healthy")
+
+	// opens: 3 reverts out of 5
+	assert.ErrorIs(t, b.Check(testChainID, "reverting"), ErrCircuitOpen)
+	assert.Nil(t, b.Check(testChainID, "healthy"))
+	filtered := b.FilterPools(testChainID, pools)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "healthy", filtered[0].GetAddress())
+
+	// outcomes of routes quoted before the breaker opened are ignored
+	b.RecordSuccess(testChainID, "reverting")
+	b.RecordSuccess(testChainID, "reverting")
+	assert.ErrorIs(t, b.Check(testChainID, "reverting"), ErrCircuitOpen)
+
+	// half-open after the cooldown, a probe reverting opens it again
+	*now = now.Add(30 * time.Second)
+	assert.Nil(t, b.Check(testChainID, "reverting"))
+	b.RecordRevert(testChainID, "reverting")
+	assert.ErrorIs(t, b.Check(testChainID, "reverting"), ErrCircuitOpen)
+
+	// the cooldown restarts, then enough successful probes close it
+	*now = now.Add(29 * time.Second)
+	assert.ErrorIs(t, b.Check(testChainID, "reverting"), ErrCircuitOpen)
+	*now = now.Add(time.Second)
+	assert.Nil(t, b.Check(testChainID, "reverting"))
+	b.RecordSuccess(testChainID, "reverting")
+	// a minute later, the success of the healthy pool is out of the window, its breaker is evicted
+	assert.Equal(t, []BreakerStatus{
+		{ChainID: testChainID, Pool: "reverting", State: BreakerHalfOpen, Transitions: 4, LastTransition: *now},
+	}, b.Snapshot())
+	b.RecordSuccess(testChainID, "reverting")
+	assert.Equal(t, []BreakerStatus{
+		{ChainID: testChainID, Pool: "reverting", State: BreakerClosed, Transitions: 5, LastTransition: *now},
+	}, b.Snapshot())
+
+	// evicted once the window is over
+	*now = now.Add(time.Minute)
+	assert.Nil(t, b.Check(testChainID, "reverting"))
+	assert.Empty(t, b.Snapshot())
+}
+
+func TestCircuitBreaker_SlidingWindow(t *testing.T) {
+	b, now := newTestCircuitBreaker()
+
+	for i := 0; i < 4; i++ {
+		b.RecordRevert(testChainID, "pool")
+	}
+	// the reverts are out of the window once the new outcomes come
+	*now = now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		b.RecordSuccess(testChainID, "pool")
+	}
+	b.RecordRevert(testChainID, "pool")
+	b.RecordRevert(testChainID, "pool")
+
+	assert.Nil(t, b.Check(testChainID, "pool"))
+	snapshot := b.Snapshot()
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, BreakerClosed, snapshot[0].State)
+	assert.Equal(t, 5, snapshot[0].Samples)
+	assert.InDelta(t, 0.4, snapshot[0].FailureRate, 1e-9)
+	assert.Equal(t, "half-open", BreakerHalfOpen.String())
+}
+
+func TestCircuitBreaker_SameAddressOnSeveralChains(t *testing.T) {
+	b, _ := newTestCircuitBreaker()
+	pools := []IPoolSimulator{newDenylistTestPool("pool", "a", "b")}
+
+	// the pool is deployed at the same address on both chains, only the one on Ethereum reverts
+	for i := 0; i < 5; i++ {
+		b.RecordRevert(valueobject.ChainIDEthereum, "pool")
+		b.RecordSuccess(valueobject.ChainIDBSC, "pool")
+	}
+
+	assert.ErrorIs(t, b.Check(valueobject.ChainIDEthereum, "pool"), ErrCircuitOpen)
+	assert.Nil(t, b.Check(valueobject.ChainIDBSC, "pool"))
+	assert.Empty(t, b.FilterPools(valueobject.ChainIDEthereum, pools))
+	assert.Len(t, b.FilterPools(valueobject.ChainIDBSC, pools), 1)
+
+	snapshot := b.Snapshot()
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, valueobject.ChainIDEthereum, snapshot[0].ChainID)
+	assert.Equal(t, BreakerOpen, snapshot[0].State)
+	assert.Equal(t, valueobject.ChainIDBSC, snapshot[1].ChainID)
+	assert.Equal(t, BreakerClosed, snapshot[1].State)
+}
+
+func TestCircuitBreaker_AddressCase(t *testing.T) {
+	b, _ := newTestCircuitBreaker()
+	pools := []IPoolSimulator{newDenylistTestPool("0xAbCd", "a", "b")}
+
+	for i := 0; i < 5; i++ {
+		b.RecordRevert(testChainID, "0xABCD")
+	}
+
+	assert.ErrorIs(t, b.Check(testChainID, "0xabcd"), ErrCircuitOpen)
+	assert.Empty(t, b.FilterPools(testChainID, pools))
+	snapshot := b.Snapshot()
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, "0xabcd", snapshot[0].Pool)
+}
+
+func TestCircuitBreaker_HalfOpenAdmissions(t *testing.T) {
+	b, now := newTestCircuitBreaker()
+	for i := 0; i < 5; i++ {
+		b.RecordRevert(testChainID, "pool")
+	}
+	*now = now.Add(30 * time.Second)
+
+	// half-open, only HalfOpenProbes quotes are admitted until their executions are reported
+	assert.Nil(t, b.Check(testChainID, "pool"))
+	assert.Nil(t, b.Check(testChainID, "pool"))
+	assert.ErrorIs(t, b.Check(testChainID, "pool"), ErrCircuitOpen)
+	b.RecordSuccess(testChainID, "pool")
+	assert.ErrorIs(t, b.Check(testChainID, "pool"), ErrCircuitOpen)
+
+	// the unreported admission is given back after a cooldown
+	*now = now.Add(29 * time.Second)
+	assert.ErrorIs(t, b.Check(testChainID, "pool"), ErrCircuitOpen)
+	*now = now.Add(time.Second)
+	assert.Nil(t, b.Check(testChainID, "pool"))
+	assert.ErrorIs(t, b.Check(testChainID, "pool"), ErrCircuitOpen)
+	b.RecordSuccess(testChainID, "pool")
+
+	// closed, the pool is quoted freely again
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, b.Check(testChainID, "pool"))
+	}
+	assert.Equal(t, BreakerClosed, b.Snapshot()[0].State)
+}
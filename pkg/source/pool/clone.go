@@ -0,0 +1,18 @@
+package pool
+
+// IPoolCloner is implemented by the simulators reporting the Clone capability. The clone shares the immutable data of
+// the simulator, e.g. its ticks, and has its own copy of the state changed by UpdateBalance, so that the candidate
+// routes evaluated in parallel can update their own copy of a shared simulator.
+type IPoolCloner interface {
+	CloneState() IPoolSimulator
+}
+
+// CloneState returns a copy of the simulator that can be updated independently, or false if it can't be cloned.
+// Building the simulator again from its entity pool is the fallback.
+func CloneState(pool IPoolSimulator) (IPoolSimulator, bool) {
+	cloner, ok := pool.(IPoolCloner)
+	if !ok {
+		return nil, false
+	}
+	return cloner.CloneState(), true
+}
@@ -0,0 +1,29 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clonerPool struct {
+	*fakePool
+}
+
+func (p *clonerPool) CloneState() IPoolSimulator {
+	cloned := *p.fakePool
+	return &clonerPool{fakePool: &cloned}
+}
+
+func TestCloneState(t *testing.T) {
+	_, ok := CloneState(newFakePool(0))
+	assert.False(t, ok)
+
+	p := &clonerPool{fakePool: newFakePool(0)}
+	cloned, ok := CloneState(p)
+	require.True(t, ok)
+	cloned.UpdateBalance(UpdateBalanceParams{SwapInfo: int64(10)})
+	assert.Equal(t, 1, cloned.(*clonerPool).updates)
+	assert.Equal(t, 0, p.updates)
+}
@@ -0,0 +1,42 @@
+package pool
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	ErrComputeBudgetExceeded = errors.New("compute budget exceeded")
+)
+
+// ComputeBudget bounds the work of a single quote of the simulators supporting it, a zero value is unlimited. A quote
+// going over it fails with ErrComputeBudgetExceeded instead of running unbounded on pathological inputs.
+type ComputeBudget struct {
+	MaxTickCrossings    int `json:"maxTickCrossings"`    // initialized ticks crossed by a swap, concentrated liquidity pools
+	MaxNewtonIterations int `json:"maxNewtonIterations"` // iterations of each Newton solve, e.g. D and y of curve
+}
+
+var computeBudget atomic.Pointer[ComputeBudget]
+
+// SetComputeBudget replaces the compute budget of all the simulators, it takes effect on the next quote
+func SetComputeBudget(budget ComputeBudget) {
+	computeBudget.Store(&budget)
+}
+
+// GetComputeBudget returns the compute budget of all the simulators, unlimited if it hasn't been set
+func GetComputeBudget() ComputeBudget {
+	if budget := computeBudget.Load(); budget != nil {
+		return *budget
+	}
+	return ComputeBudget{}
+}
+
+// TickCrossingsExceeded returns true if crossing n ticks is over the budget
+func (b ComputeBudget) TickCrossingsExceeded(n int) bool {
+	return b.MaxTickCrossings > 0 && n > b.MaxTickCrossings
+}
+
+// NewtonIterationsExceeded returns true if running n iterations is over the budget
+func (b ComputeBudget) NewtonIterationsExceeded(n int) bool {
+	return b.MaxNewtonIterations > 0 && n > b.MaxNewtonIterations
+}
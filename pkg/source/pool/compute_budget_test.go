@@ -0,0 +1,23 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeBudget(t *testing.T) {
+	defer SetComputeBudget(ComputeBudget{})
+
+	// unlimited by default
+	assert.Equal(t, ComputeBudget{}, GetComputeBudget())
+	assert.False(t, GetComputeBudget().TickCrossingsExceeded(1000000))
+	assert.False(t, GetComputeBudget().NewtonIterationsExceeded(1000000))
+
+	SetComputeBudget(ComputeBudget{MaxTickCrossings: 10, MaxNewtonIterations: 64})
+	budget := GetComputeBudget()
+	assert.False(t, budget.TickCrossingsExceeded(10))
+	assert.True(t, budget.TickCrossingsExceeded(11))
+	assert.False(t, budget.NewtonIterationsExceeded(64))
+	assert.True(t, budget.NewtonIterationsExceeded(65))
+}
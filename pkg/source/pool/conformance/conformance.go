@@ -0,0 +1,259 @@
+// Package conformance checks the contracts every pool simulator of the library must follow, the ones each new source
+// used to learn the hard way: no state shared between simulators or mutated by the quotes, errors instead of panics,
+// capabilities matching the implemented interfaces, meta info usable by the encoders.
+//
+// Every source must run the suite from its tests, with fixtures covering its pool types, before it is registered:
+//
+//	func TestPoolSimulator_Conformance(t *testing.T) {
+//		conformance.Run(t, factory, fixtures, conformance.Options{})
+//	}
+package conformance
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+)
+
+// Factory builds the simulator of an entity pool, the same way the router does
+type Factory func(entityPool entity.Pool) (pool.IPoolSimulator, error)
+
+// Swap is a swap supported by the pool of a fixture
+type Swap struct {
+	TokenIn  string
+	AmountIn *big.Int
+	TokenOut string
+}
+
+// Fixture is an entity pool and swaps that it must quote
+type Fixture struct {
+	Name  string
+	Pool  entity.Pool
+	Swaps []Swap
+}
+
+// Options relaxes the checks for the sources which can't follow them
+type Options struct {
+	// AllowNilMeta is for the sources whose swaps are encoded without meta info
+	AllowNilMeta bool
+}
+
+// snapshotter is implemented by the simulators able to roll their state back
+type snapshotter interface {
+	Snapshot() []byte
+	Restore(b []byte) error
+}
+
+// Run checks the simulators built by factory from the fixtures, one subtest per fixture and contract
+func Run(t *testing.T, factory Factory, fixtures []Fixture, opts Options) {
+	for _, fixture := range fixtures {
+		fixture := fixture
+		require.NotEmpty(t, fixture.Swaps, fixture.Name)
+
+		t.Run(fixture.Name, func(t *testing.T) {
+			t.Run("construction", func(t *testing.T) { checkConstruction(t, factory, fixture) })
+			t.Run("purity", func(t *testing.T) { checkPurity(t, factory, fixture) })
+			t.Run("isolation", func(t *testing.T) { checkIsolation(t, factory, fixture) })
+			t.Run("errors", func(t *testing.T) { checkErrors(t, factory, fixture) })
+			t.Run("capabilities", func(t *testing.T) { checkCapabilities(t, factory, fixture) })
+			t.Run("meta", func(t *testing.T) { checkMeta(t, factory, fixture, opts) })
+			t.Run("snapshot", func(t *testing.T) { checkSnapshot(t, factory, fixture) })
+		})
+	}
+}
+
+func build(t *testing.T, factory Factory, fixture Fixture) pool.IPoolSimulator {
+	p, err := factory(fixture.Pool)
+	require.Nil(t, err)
+	require.NotNil(t, p)
+	return p
+}
+
+// quote returns the result of the swap, failing the test if it isn't a valid quote
+func quote(t *testing.T, p pool.IPoolSimulator, swap Swap) *pool.CalcAmountOutResult {
+	res, err := pool.CalcAmountOut(p, pool.TokenAmount{Token: swap.TokenIn, Amount: new(big.Int).Set(swap.AmountIn)},
+		swap.TokenOut)
+	require.Nil(t, err, "%s -> %s", swap.TokenIn, swap.TokenOut)
+	require.True(t, res.IsValid(), "%s -> %s", swap.TokenIn, swap.TokenOut)
+	assert.Equal(t, swap.TokenOut, res.TokenAmountOut.Token)
+	return res
+}
+
+func amountsOut(t *testing.T, p pool.IPoolSimulator, swaps []Swap) []string {
+	amounts := make([]string, 0, len(swaps))
+	for _, swap := range swaps {
+		amounts = append(amounts, quote(t, p, swap).TokenAmountOut.Amount.String())
+	}
+	return amounts
+}
+
+func update(p pool.IPoolSimulator, swap Swap, res *pool.CalcAmountOutResult) {
+	params := pool.UpdateBalanceParams{
+		TokenAmountIn:  pool.TokenAmount{Token: swap.TokenIn, Amount: new(big.Int).Set(swap.AmountIn)},
+		TokenAmountOut: *res.TokenAmountOut,
+		SwapInfo:       res.SwapInfo,
+	}
+	if res.Fee != nil {
+		params.Fee = *res.Fee
+	}
+	p.UpdateBalance(params)
+}
+
+// checkConstruction: the factory doesn't change the entity pool and builds the same simulator every time
+func checkConstruction(t *testing.T, factory Factory, fixture Fixture) {
+	before, err := json.Marshal(fixture.Pool)
+	require.Nil(t, err)
+	a, b := build(t, factory, fixture), build(t, factory, fixture)
+	after, err := json.Marshal(fixture.Pool)
+	require.Nil(t, err)
+	assert.JSONEq(t, string(before), string(after), "the factory changed the entity pool")
+
+	assert.Equal(t, a.GetAddress(), b.GetAddress())
+	assert.Equal(t, a.GetExchange(), b.GetExchange())
+	assert.Equal(t, a.GetType(), b.GetType())
+	assert.Equal(t, a.GetTokens(), b.GetTokens())
+	for idx, token := range a.GetTokens() {
+		assert.Equal(t, idx, a.GetTokenIndex(token))
+		actual, err := a.GetToken(idx)
+		require.Nil(t, err)
+		assert.Equal(t, token, actual)
+	}
+	assert.Equal(t, amountsOut(t, a, fixture.Swaps), amountsOut(t, b, fixture.Swaps))
+}
+
+// checkPurity: quoting doesn't change the simulator, only UpdateBalance does
+func checkPurity(t *testing.T, factory Factory, fixture Fixture) {
+	p := build(t, factory, fixture)
+	expected := amountsOut(t, p, fixture.Swaps)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, expected, amountsOut(t, p, fixture.Swaps), "the quotes changed the simulator")
+	}
+	assert.Equal(t, expected, amountsOut(t, build(t, factory, fixture), fixture.Swaps))
+}
+
+// checkIsolation: the simulators built from the same entity pool, and the clones, don't share a mutable state
+func checkIsolation(t *testing.T, factory Factory, fixture Fixture) {
+	updated, untouched := build(t, factory, fixture), build(t, factory, fixture)
+	expected := amountsOut(t, untouched, fixture.Swaps)
+
+	swap := fixture.Swaps[0]
+	cloned, _ := pool.CloneState(updated)
+	update(updated, swap, quote(t, updated, swap))
+	assert.Equal(t, expected, amountsOut(t, untouched, fixture.Swaps), "UpdateBalance changed another simulator")
+
+	if cloned == nil {
+		return
+	}
+	assert.Equal(t, expected, amountsOut(t, cloned, fixture.Swaps), "UpdateBalance changed a clone")
+	original := build(t, factory, fixture)
+	clone, _ := pool.CloneState(original)
+	update(clone, swap, quote(t, clone, swap))
+	assert.Equal(t, expected, amountsOut(t, original, fixture.Swaps), "UpdateBalance of a clone changed the original")
+}
+
+// checkErrors: the invalid swaps fail with an error, without a panic nor a valid looking result
+func checkErrors(t *testing.T, factory Factory, fixture Fixture) {
+	p := build(t, factory, fixture)
+	swap := fixture.Swaps[0]
+	invalid := []struct {
+		name     string
+		tokenIn  string
+		amountIn *big.Int
+		tokenOut string
+	}{
+		{"unknown token in", "0xunknown", swap.AmountIn, swap.TokenOut},
+		{"unknown token out", swap.TokenIn, swap.AmountIn, "0xunknown"},
+		{"zero amount in", swap.TokenIn, big.NewInt(0), swap.TokenOut},
+	}
+	for _, tc := range invalid {
+		res, err := pool.CalcAmountOut(p, pool.TokenAmount{Token: tc.tokenIn, Amount: tc.amountIn}, tc.tokenOut)
+		require.NotNil(t, err, tc.name)
+		assert.NotErrorIs(t, err, pool.ErrCalcAmountOutPanic, tc.name)
+		assert.False(t, res != nil && res.IsValid(), tc.name)
+	}
+
+	_, err := p.GetToken(len(p.GetTokens()))
+	assert.ErrorIs(t, err, pool.ErrInvalidTokenIndex)
+	assert.Equal(t, -1, p.GetTokenIndex("0xunknown"))
+}
+
+// checkCapabilities: the reported capabilities match the implemented interfaces and the results
+func checkCapabilities(t *testing.T, factory Factory, fixture Fixture) {
+	p := build(t, factory, fixture)
+	capabilities := pool.CapabilitiesOf(p)
+
+	_, ok := p.(pool.IPoolExactOut)
+	assert.Equal(t, capabilities.CalcAmountIn, ok, "CalcAmountIn")
+	_, ok = p.(pool.IPoolCloner)
+	assert.Equal(t, capabilities.Clone, ok, "Clone")
+	_, ok = p.(pool.IPoolApproximator)
+	assert.Equal(t, capabilities.FastPrecision, ok, "FastPrecision")
+	// the simulators may take the simulation timestamp without expiring, e.g. for time dependent fees
+	if _, ok = p.(pool.IPoolExpirable); capabilities.Expiry {
+		assert.True(t, ok, "Expiry")
+	}
+	_, ok = p.(pool.IPoolMinSwapAmount)
+	assert.Equal(t, capabilities.MinSwapAmount, ok, "MinSwapAmount")
+
+	for _, swap := range fixture.Swaps {
+		res := quote(t, p, swap)
+		if capabilities.GasEstimation {
+			assert.Positive(t, res.Gas, "GasEstimation")
+		}
+		if capabilities.FastPrecision {
+			fast, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+				TokenAmountIn: pool.TokenAmount{Token: swap.TokenIn, Amount: new(big.Int).Set(swap.AmountIn)},
+				TokenOut:      swap.TokenOut,
+				Precision:     pool.PrecisionFast,
+			})
+			require.Nil(t, err)
+			assert.InEpsilon(t, toFloat(res.TokenAmountOut.Amount), toFloat(fast.TokenAmountOut.Amount),
+				pool.FastPrecisionMaxRelativeError, "FastPrecision")
+		}
+	}
+}
+
+// checkMeta: the meta info of the swaps is deterministic and can be sent to the encoders as JSON
+func checkMeta(t *testing.T, factory Factory, fixture Fixture, opts Options) {
+	p := build(t, factory, fixture)
+	for _, swap := range fixture.Swaps {
+		meta := p.GetMetaInfo(swap.TokenIn, swap.TokenOut)
+		if meta == nil {
+			assert.True(t, opts.AllowNilMeta, "nil meta info of %s -> %s", swap.TokenIn, swap.TokenOut)
+			continue
+		}
+		encoded, err := json.Marshal(meta)
+		require.Nil(t, err)
+		again, err := json.Marshal(p.GetMetaInfo(swap.TokenIn, swap.TokenOut))
+		require.Nil(t, err)
+		assert.JSONEq(t, string(encoded), string(again))
+	}
+}
+
+// checkSnapshot: for the simulators implementing it, Restore rolls back to the quotes of the Snapshot
+func checkSnapshot(t *testing.T, factory Factory, fixture Fixture) {
+	p := build(t, factory, fixture)
+	s, ok := p.(snapshotter)
+	if !ok {
+		t.Skip("no snapshot")
+	}
+	expected := amountsOut(t, p, fixture.Swaps)
+	snapshot := s.Snapshot()
+
+	for _, swap := range fixture.Swaps {
+		update(p, swap, quote(t, p, swap))
+	}
+	require.Nil(t, s.Restore(snapshot))
+	assert.Equal(t, expected, amountsOut(t, p, fixture.Swaps))
+}
+
+func toFloat(n *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
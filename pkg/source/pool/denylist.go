@@ -0,0 +1,117 @@
+package pool
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+var (
+	// ErrTokenDenied is the exclusion reason of pools and paths touching a denied token
+	ErrTokenDenied = errors.New("token is denied on this chain")
+)
+
+// PoolSimulatorFactory builds the simulator of an entity pool
+type PoolSimulatorFactory func(entityPool entity.Pool) (IPoolSimulator, error)
+
+type denylistSet map[valueobject.ChainID]map[string]struct{}
+
+// TokenDenylist is a per-chain list of tokens that must never appear in a route.
+// It's safe for concurrent use, Reload replaces the lists without blocking the readers.
+type TokenDenylist struct {
+	lists atomic.Pointer[denylistSet]
+}
+
+func NewTokenDenylist(lists map[valueobject.ChainID][]string) *TokenDenylist {
+	d := &TokenDenylist{}
+	d.Reload(lists)
+	return d
+}
+
+// Reload replaces the denied tokens of all the chains, it takes effect on the next check
+func (d *TokenDenylist) Reload(lists map[valueobject.ChainID][]string) {
+	set := make(denylistSet, len(lists))
+	for chainID, tokens := range lists {
+		chainSet := make(map[string]struct{}, len(tokens))
+		for _, token := range tokens {
+			chainSet[strings.ToLower(token)] = struct{}{}
+		}
+		set[chainID] = chainSet
+	}
+	d.lists.Store(&set)
+}
+
+// IsDenied returns true if the token is denied on the chain. A nil denylist denies nothing.
+func (d *TokenDenylist) IsDenied(chainID valueobject.ChainID, token string) bool {
+	if d == nil {
+		return false
+	}
+	set := d.lists.Load()
+	if set == nil {
+		return false
+	}
+	_, ok := (*set)[chainID][strings.ToLower(token)]
+	return ok
+}
+
+// CheckTokens returns ErrTokenDenied if any of the tokens is denied on the chain
+func (d *TokenDenylist) CheckTokens(chainID valueobject.ChainID, tokens []string) error {
+	for _, token := range tokens {
+		if d.IsDenied(chainID, token) {
+			return ErrTokenDenied
+		}
+	}
+	return nil
+}
+
+// CheckPool returns ErrTokenDenied if the pool has a denied token
+func (d *TokenDenylist) CheckPool(chainID valueobject.ChainID, entityPool entity.Pool) error {
+	for _, token := range entityPool.Tokens {
+		if d.IsDenied(chainID, token.Address) {
+			return ErrTokenDenied
+		}
+	}
+	return nil
+}
+
+// WrapFactory makes factory refuse to build the simulators of the pools having a denied token.
+// The denylist is checked on every call, so reloads apply to the pools built afterwards.
+func (d *TokenDenylist) WrapFactory(chainID valueobject.ChainID, factory PoolSimulatorFactory) PoolSimulatorFactory {
+	return func(entityPool entity.Pool) (IPoolSimulator, error) {
+		if err := d.CheckPool(chainID, entityPool); err != nil {
+			return nil, err
+		}
+		return factory(entityPool)
+	}
+}
+
+// FilterTokens returns the tokens that aren't denied on the chain, to build the token set of the route graph
+func (d *TokenDenylist) FilterTokens(chainID valueobject.ChainID, tokens []string) []string {
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if !d.IsDenied(chainID, token) {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// FilterPools drops the pools having a denied token. A pool is dropped even if the denied token isn't
+// the one being swapped, otherwise it could still be used as an intermediate hop of a multi-hop path.
+func (d *TokenDenylist) FilterPools(chainID valueobject.ChainID, pools []IPoolSimulator) []IPoolSimulator {
+	result := make([]IPoolSimulator, 0, len(pools))
+	for _, p := range pools {
+		if d.CheckTokens(chainID, p.GetTokens()) == nil {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// CheckPath returns ErrTokenDenied if any token of the path, the intermediate hops included, is denied
+func (d *TokenDenylist) CheckPath(chainID valueobject.ChainID, path []string) error {
+	return d.CheckTokens(chainID, path)
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+func newDenylistTestPool(address string, tokens ...string) *fakePool {
+	p := newFakePool(0)
+	p.Info.Address = address
+	p.Info.Tokens = tokens
+	return p
+}
+
+func entityPoolOf(tokens ...string) entity.Pool {
+	entityPool := entity.Pool{Address: "pool"}
+	for _, token := range tokens {
+		entityPool.Tokens = append(entityPool.Tokens, &entity.PoolToken{Address: token})
+	}
+	return entityPool
+}
+
+func TestTokenDenylist_DirectPool(t *testing.T) {
+	d := NewTokenDenylist(map[valueobject.ChainID][]string{
+		valueobject.ChainIDEthereum: {"0xDENIED"},
+	})
+
+	built := 0
+	factory := d.WrapFactory(valueobject.ChainIDEthereum, func(entityPool entity.Pool) (IPoolSimulator, error) {
+		built++
+		return newDenylistTestPool(entityPool.Address, "a", "b"), nil
+	})
+
+	_, err := factory(entityPoolOf("0xdenied", "a"))
+	assert.ErrorIs(t, err, ErrTokenDenied)
+	assert.Equal(t, 0, built)
+
+	p, err := factory(entityPoolOf("a", "b"))
+	require.Nil(t, err)
+	assert.NotNil(t, p)
+	assert.Equal(t, 1, built)
+
+	// the list is per chain
+	assert.Nil(t, d.CheckPool(valueobject.ChainIDPolygon, entityPoolOf("0xdenied", "a")))
+
+	assert.Equal(t, []string{"a", "b"}, d.FilterTokens(valueobject.ChainIDEthereum, []string{"a", "0xdenied", "b"}))
+}
+
+func TestTokenDenylist_IntermediateHop(t *testing.T) {
+	d := NewTokenDenylist(map[valueobject.ChainID][]string{
+		valueobject.ChainIDEthereum: {"x"},
+	})
+
+	// a -> x -> b is the only path, x must never be used even as an intermediate hop
+	pools := []IPoolSimulator{
+		newDenylistTestPool("ax", "a", "x"),
+		newDenylistTestPool("xb", "x", "b"),
+		newDenylistTestPool("ac", "a", "c"),
+	}
+
+	filtered := d.FilterPools(valueobject.ChainIDEthereum, pools)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "ac", filtered[0].GetAddress())
+
+	assert.ErrorIs(t, d.CheckPath(valueobject.ChainIDEthereum, []string{"a", "x", "b"}), ErrTokenDenied)
+	assert.Nil(t, d.CheckPath(valueobject.ChainIDEthereum, []string{"a", "c"}))
+}
+
+func TestTokenDenylist_Reload(t *testing.T) {
+	d := NewTokenDenylist(nil)
+	factory := d.WrapFactory(valueobject.ChainIDBSC, func(entityPool entity.Pool) (IPoolSimulator, error) {
+		return newDenylistTestPool(entityPool.Address, "a", "b"), nil
+	})
+
+	_, err := factory(entityPoolOf("a", "b"))
+	assert.Nil(t, err)
+
+	d.Reload(map[valueobject.ChainID][]string{
+		valueobject.ChainIDBSC: {"B"},
+	})
+	_, err = factory(entityPoolOf("a", "b"))
+	assert.ErrorIs(t, err, ErrTokenDenied)
+	assert.True(t, d.IsDenied(valueobject.ChainIDBSC, "b"))
+
+	d.Reload(map[valueobject.ChainID][]string{})
+	_, err = factory(entityPoolOf("a", "b"))
+	assert.Nil(t, err)
+
+	// a nil denylist denies nothing
+	var nilDenylist *TokenDenylist
+	assert.False(t, nilDenylist.IsDenied(valueobject.ChainIDBSC, "b"))
+}
+
+func TestTokenDenylist_SameAddressOnSeveralChains(t *testing.T) {
+	// the same CREATE2 address is a scam token on Ethereum and a legit one on Polygon, and the other way around
+	d := NewTokenDenylist(map[valueobject.ChainID][]string{
+		valueobject.ChainIDEthereum: {"0xC2EA7E"},
+		valueobject.ChainIDPolygon:  {"0xb0b"},
+	})
+
+	newFactory := func(chainID valueobject.ChainID) PoolSimulatorFactory {
+		return d.WrapFactory(chainID, func(entityPool entity.Pool) (IPoolSimulator, error) {
+			return newDenylistTestPool(entityPool.Address, "a", "b"), nil
+		})
+	}
+	_, err := newFactory(valueobject.ChainIDEthereum)(entityPoolOf("0xc2ea7e", "a"))
+	assert.ErrorIs(t, err, ErrTokenDenied)
+	_, err = newFactory(valueobject.ChainIDPolygon)(entityPoolOf("0xc2ea7e", "a"))
+	assert.Nil(t, err)
+	_, err = newFactory(valueobject.ChainIDEthereum)(entityPoolOf("0xB0B", "a"))
+	assert.Nil(t, err)
+	_, err = newFactory(valueobject.ChainIDPolygon)(entityPoolOf("0xB0B", "a"))
+	assert.ErrorIs(t, err, ErrTokenDenied)
+
+	pools := []IPoolSimulator{
+		newDenylistTestPool("ac", "a", "0xc2ea7e"),
+		newDenylistTestPool("ab", "a", "0xb0b"),
+	}
+	filtered := d.FilterPools(valueobject.ChainIDEthereum, pools)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "ab", filtered[0].GetAddress())
+	filtered = d.FilterPools(valueobject.ChainIDPolygon, pools)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "ac", filtered[0].GetAddress())
+
+	assert.ErrorIs(t, d.CheckPath(valueobject.ChainIDEthereum, []string{"a", "0xc2ea7e"}), ErrTokenDenied)
+	assert.Nil(t, d.CheckPath(valueobject.ChainIDPolygon, []string{"a", "0xc2ea7e"}))
+	// a chain without a list denies neither
+	assert.Equal(t, []string{"0xc2ea7e", "0xb0b"}, d.FilterTokens(valueobject.ChainIDBSC, []string{"0xc2ea7e", "0xb0b"}))
+}
@@ -0,0 +1,168 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+)
+
+var (
+	ErrInvalidPool = errors.New("invalid pool")
+)
+
+// IPoolSink receives the pools found by a pools list updater, the production one stores them
+type IPoolSink interface {
+	Write(ctx context.Context, pools []entity.Pool) error
+}
+
+// PoolSinkFunc adapts a function, e.g. the method of a repository, to IPoolSink
+type PoolSinkFunc func(ctx context.Context, pools []entity.Pool) error
+
+func (f PoolSinkFunc) Write(ctx context.Context, pools []entity.Pool) error {
+	return f(ctx, pools)
+}
+
+// MemoryPoolSink keeps the pools in memory, for the dry runs
+type MemoryPoolSink struct {
+	Pools []entity.Pool
+}
+
+func (s *MemoryPoolSink) Write(_ context.Context, pools []entity.Pool) error {
+	s.Pools = append(s.Pools, pools...)
+	return nil
+}
+
+// RunPoolsListUpdater calls GetNewPools until the metadata stops changing, at most maxRounds times, and writes the pools
+// of each round to the sink. A round may find no pool but still move the metadata forward, e.g. a block range without
+// pool creation, so it doesn't stop the run. It returns the number of rounds and the metadata for the next run.
+func RunPoolsListUpdater(
+	ctx context.Context,
+	updater IPoolsListUpdater,
+	sink IPoolSink,
+	metadataBytes []byte,
+	maxRounds int,
+) (int, []byte, error) {
+	rounds := 0
+	for rounds < maxRounds {
+		pools, newMetadataBytes, err := updater.GetNewPools(ctx, metadataBytes)
+		if err != nil {
+			return rounds, metadataBytes, err
+		}
+		rounds++
+		if len(pools) > 0 {
+			if err := sink.Write(ctx, pools); err != nil {
+				return rounds, metadataBytes, err
+			}
+		}
+		if bytes.Equal(newMetadataBytes, metadataBytes) {
+			break
+		}
+		metadataBytes = newMetadataBytes
+	}
+	return rounds, metadataBytes, nil
+}
+
+// DryRunFailure is a pool found by a dry run which wouldn't be usable
+type DryRunFailure struct {
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+}
+
+// DryRunReport is the result of running a pools list updater without storing its pools
+type DryRunReport struct {
+	Rounds             int             `json:"rounds"`
+	PoolsFound         int             `json:"poolsFound"`
+	Tokens             []string        `json:"tokens"` // resolved tokens, sorted
+	ValidationFailures []DryRunFailure `json:"validationFailures"`
+	TotalExtraSize     int             `json:"totalExtraSize"` // bytes of the Extra and StaticExtra of the pools
+	MaxExtraSize       int             `json:"maxExtraSize"`
+	Metadata           string          `json:"metadata"` // metadata of the last round
+}
+
+// DryRunPoolsListUpdater runs the updater like RunPoolsListUpdater from the beginning, into a MemoryPoolSink, and
+// reports what it found. The error is the one of the updater, the report is filled up to it.
+func DryRunPoolsListUpdater(ctx context.Context, updater IPoolsListUpdater, maxRounds int) (*DryRunReport, error) {
+	var sink MemoryPoolSink
+	rounds, metadataBytes, err := RunPoolsListUpdater(ctx, updater, &sink, nil, maxRounds)
+
+	report := DryRunReport{
+		Rounds:             rounds,
+		PoolsFound:         len(sink.Pools),
+		Tokens:             []string{},
+		ValidationFailures: []DryRunFailure{},
+		Metadata:           string(metadataBytes),
+	}
+	tokens := map[string]struct{}{}
+	for _, p := range sink.Pools {
+		if validationErr := ValidatePool(p); validationErr != nil {
+			report.ValidationFailures = append(report.ValidationFailures,
+				DryRunFailure{Address: p.Address, Reason: validationErr.Error()})
+		}
+		for _, token := range p.Tokens {
+			if token != nil && token.Address != "" {
+				tokens[token.Address] = struct{}{}
+			}
+		}
+		extraSize := len(p.Extra) + len(p.StaticExtra)
+		report.TotalExtraSize += extraSize
+		if extraSize > report.MaxExtraSize {
+			report.MaxExtraSize = extraSize
+		}
+	}
+	for token := range tokens {
+		report.Tokens = append(report.Tokens, token)
+	}
+	sort.Strings(report.Tokens)
+
+	return &report, err
+}
+
+// DryRunWithConfigFile builds an updater from the content of the config file and dry runs it, for command line tools
+func DryRunWithConfigFile(
+	ctx context.Context,
+	configPath string,
+	newUpdater func(configBytes []byte) (IPoolsListUpdater, error),
+	maxRounds int,
+) (*DryRunReport, error) {
+	configBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	updater, err := newUpdater(configBytes)
+	if err != nil {
+		return nil, err
+	}
+	return DryRunPoolsListUpdater(ctx, updater, maxRounds)
+}
+
+// ValidatePool returns ErrInvalidPool if the pool found by an updater can't be tracked and simulated
+func ValidatePool(p entity.Pool) error {
+	switch {
+	case p.Address == "":
+		return fmt.Errorf("%w: empty address", ErrInvalidPool)
+	case p.Exchange == "" || p.Type == "":
+		return fmt.Errorf("%w: empty exchange or type", ErrInvalidPool)
+	case len(p.Tokens) < 2:
+		return fmt.Errorf("%w: %d tokens", ErrInvalidPool, len(p.Tokens))
+	case len(p.Reserves) != len(p.Tokens):
+		return fmt.Errorf("%w: %d reserves for %d tokens", ErrInvalidPool, len(p.Reserves), len(p.Tokens))
+	}
+	for i, token := range p.Tokens {
+		if token == nil || token.Address == "" {
+			return fmt.Errorf("%w: token %d has no address", ErrInvalidPool, i)
+		}
+	}
+	if p.Extra != "" && !json.Valid([]byte(p.Extra)) {
+		return fmt.Errorf("%w: invalid extra", ErrInvalidPool)
+	}
+	if p.StaticExtra != "" && !json.Valid([]byte(p.StaticExtra)) {
+		return fmt.Errorf("%w: invalid staticExtra", ErrInvalidPool)
+	}
+	return nil
+}
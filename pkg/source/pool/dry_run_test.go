@@ -0,0 +1,59 @@
+package pool
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+)
+
+// cursorPoolsListUpdater scans one block per round up to lastBlock, the pools being created at the blocks of pools.
+// Like the updaters reading the logs, the cursor moves forward on the blocks without pool.
+type cursorPoolsListUpdater struct {
+	lastBlock int
+	pools     map[int]entity.Pool
+}
+
+func (u *cursorPoolsListUpdater) GetNewPools(_ context.Context, metadataBytes []byte) ([]entity.Pool, []byte, error) {
+	block := 0
+	if len(metadataBytes) > 0 {
+		block, _ = strconv.Atoi(string(metadataBytes))
+	}
+	if block >= u.lastBlock {
+		return []entity.Pool{}, metadataBytes, nil
+	}
+
+	block++
+	var pools []entity.Pool
+	if p, ok := u.pools[block]; ok {
+		pools = append(pools, p)
+	}
+	return pools, []byte(strconv.Itoa(block)), nil
+}
+
+func TestRunPoolsListUpdater_EmptyRounds(t *testing.T) {
+	updater := &cursorPoolsListUpdater{
+		lastBlock: 5,
+		pools:     map[int]entity.Pool{2: {Address: "a"}, 4: {Address: "b"}},
+	}
+
+	var sink MemoryPoolSink
+	rounds, metadataBytes, err := RunPoolsListUpdater(context.Background(), updater, &sink, nil, 10)
+	require.Nil(t, err)
+	// the blocks 1, 3 and 5 have no pool, the last round doesn't move the cursor
+	assert.Equal(t, 6, rounds)
+	assert.Equal(t, "5", string(metadataBytes))
+	assert.Equal(t, []entity.Pool{{Address: "a"}, {Address: "b"}}, sink.Pools)
+
+	// stopped by maxRounds, the cursor is the one of the last round
+	sink = MemoryPoolSink{}
+	rounds, metadataBytes, err = RunPoolsListUpdater(context.Background(), updater, &sink, nil, 3)
+	require.Nil(t, err)
+	assert.Equal(t, 3, rounds)
+	assert.Equal(t, "3", string(metadataBytes))
+	assert.Equal(t, []entity.Pool{{Address: "a"}}, sink.Pools)
+}
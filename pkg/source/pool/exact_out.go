@@ -0,0 +1,43 @@
+package pool
+
+import (
+	"errors"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+var (
+	ErrCalcAmountInPanic        = errors.New("calcAmountIn was panic")
+	ErrCalcAmountInNotSupported = errors.New("calcAmountIn is not supported")
+)
+
+// IPoolExactOut is implemented by the pools quoting the swaps by exact amount out, see Capabilities.CalcAmountIn.
+// An amount out above what the pool can provide must fail rather than return the amount in of a partial fill.
+type IPoolExactOut interface {
+	CalcAmountIn(
+		tokenAmountOut TokenAmount,
+		tokenIn string,
+	) (*CalcAmountInResult, error)
+}
+
+// CalcAmountIn wraps around the CalcAmountIn of the pool and catches panic.
+// Returns ErrCalcAmountInNotSupported if the pool doesn't implement IPoolExactOut.
+func CalcAmountIn(pool IPoolSimulator, tokenAmountOut TokenAmount, tokenIn string) (res *CalcAmountInResult, err error) {
+	exactOut, ok := pool.(IPoolExactOut)
+	if !ok {
+		return nil, ErrCalcAmountInNotSupported
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = ErrCalcAmountInPanic
+			logger.WithFields(
+				logger.Fields{
+					"recover":     r,
+					"poolAddress": pool.GetAddress(),
+				}).Warn(err.Error())
+		}
+	}()
+
+	return exactOut.CalcAmountIn(tokenAmountOut, tokenIn)
+}
@@ -0,0 +1,39 @@
+package pool
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exactOutPool struct {
+	*fakePool
+}
+
+func (p *exactOutPool) CalcAmountIn(tokenAmountOut TokenAmount, tokenIn string) (*CalcAmountInResult, error) {
+	amountIn := new(big.Int).Div(tokenAmountOut.Amount, big.NewInt(p.rate))
+	return &CalcAmountInResult{
+		TokenAmountIn: &TokenAmount{Token: tokenIn, Amount: amountIn},
+		Fee:           &TokenAmount{Token: tokenIn},
+		SwapInfo:      p.rate,
+	}, nil
+}
+
+func TestCalcAmountIn(t *testing.T) {
+	p := &exactOutPool{newFakePool(0)}
+	res, err := CalcAmountIn(p, TokenAmount{Token: "B", Amount: big.NewInt(100)}, "A")
+	require.Nil(t, err)
+	assert.True(t, res.IsValid())
+	assert.Equal(t, TokenAmount{Token: "A", Amount: big.NewInt(10)}, *res.TokenAmountIn)
+
+	// the fake pool panics on a nil amount
+	_, err = CalcAmountIn(p, TokenAmount{Token: "B"}, "A")
+	assert.ErrorIs(t, err, ErrCalcAmountInPanic)
+
+	_, err = CalcAmountIn(newFakePool(0), TokenAmount{Token: "B", Amount: big.NewInt(100)}, "A")
+	assert.ErrorIs(t, err, ErrCalcAmountInNotSupported)
+
+	assert.False(t, (&CalcAmountInResult{}).IsValid())
+}
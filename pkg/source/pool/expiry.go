@@ -0,0 +1,31 @@
+package pool
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrQuoteExpired is returned by the simulators of RFQ quotes and limit orders once the quote or the orders expired
+	ErrQuoteExpired = errors.New("quote expired")
+)
+
+// IPoolExpirable is implemented by the pools whose quotes or orders expire. The expiries are checked against the
+// simulation timestamp, the current time unless it's set, e.g. to the timestamp of the block the route is built for.
+// Other pools may implement it to simulate at that timestamp, e.g. for time dependent fees, Capabilities.Expiry tells
+// whether the quotes expire.
+type IPoolExpirable interface {
+	SetSimulationTimestamp(timestamp int64)
+}
+
+// IsExpired returns true if a quote expiring at expiry, in unix seconds, expired at the simulation timestamp.
+// An expiry of 0 never expires, a timestamp of 0 is the current time.
+func IsExpired(expiry, timestamp int64) bool {
+	if expiry <= 0 {
+		return false
+	}
+	if timestamp <= 0 {
+		timestamp = time.Now().Unix()
+	}
+	return timestamp > expiry
+}
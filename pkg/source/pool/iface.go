@@ -35,6 +35,7 @@ type IPoolSimulator interface {
 	GetType() string
 	GetMetaInfo(tokenIn string, tokenOut string) interface{}
 	GetTokenIndex(address string) int
+	GetToken(index int) (string, error)
 }
 
 type IPoolRFQ interface {
@@ -0,0 +1,42 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrBelowMinimum is returned by the simulators of the pools reverting the swaps below a minimum amount in,
+	// wrapped in a BelowMinimumError carrying the minimum
+	ErrBelowMinimum = errors.New("amount in is below the minimum swap amount")
+)
+
+// BelowMinimumError is the error of a swap of Token below the Minimum amount in, it unwraps to ErrBelowMinimum
+type BelowMinimumError struct {
+	Token   string
+	Minimum *big.Int
+}
+
+func (e *BelowMinimumError) Error() string {
+	return fmt.Sprintf("%s: %s of %s", ErrBelowMinimum, e.Minimum, e.Token)
+}
+
+func (e *BelowMinimumError) Unwrap() error {
+	return ErrBelowMinimum
+}
+
+// IPoolMinSwapAmount is implemented by the pools reverting the swaps below a minimum amount in, e.g. RFQ minimums or
+// dust guards, so that the router can skip the orders too small for them instead of quoting swaps that can't execute.
+type IPoolMinSwapAmount interface {
+	// MinSwapAmount returns the minimum amount of tokenIn of a swap, nil if there is none
+	MinSwapAmount(tokenIn string) *big.Int
+}
+
+// CheckMinSwapAmount returns a BelowMinimumError if the amount in is below minimum, nil is no minimum
+func CheckMinSwapAmount(tokenAmountIn TokenAmount, minimum *big.Int) error {
+	if minimum == nil || tokenAmountIn.Amount == nil || tokenAmountIn.Amount.Cmp(minimum) >= 0 {
+		return nil
+	}
+	return &BelowMinimumError{Token: tokenAmountIn.Token, Minimum: new(big.Int).Set(minimum)}
+}
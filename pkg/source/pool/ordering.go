@@ -0,0 +1,24 @@
+package pool
+
+import (
+	"sort"
+	"strings"
+)
+
+// Ordering contract: whenever a list of pools is built from unordered data (maps, concurrent fetches...) or two
+// pools are equally good for a swap, the pools are ordered by address, then by exchange. This makes the results of
+// identical requests identical, which the caching of the routes and the comparison of the quotes rely on. Code
+// iterating over maps of pools must sort the keys, or the resulting pools with SortPools.
+
+// ComparePools returns -1, 0 or 1 depending on the order of the pools in the contract above
+func ComparePools(a, b IPoolSimulator) int {
+	if c := strings.Compare(a.GetAddress(), b.GetAddress()); c != 0 {
+		return c
+	}
+	return strings.Compare(a.GetExchange(), b.GetExchange())
+}
+
+// SortPools sorts the pools in place by address, then by exchange
+func SortPools(pools []IPoolSimulator) {
+	sort.SliceStable(pools, func(i, j int) bool { return ComparePools(pools[i], pools[j]) < 0 })
+}
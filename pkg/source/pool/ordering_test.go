@@ -0,0 +1,40 @@
+package pool
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortPools(t *testing.T) {
+	var pools []IPoolSimulator
+	for _, p := range []struct{ address, exchange string }{
+		{"0xb", "uniswap-v3"}, {"0xa", "uniswap-v3"}, {"0xb", "algebra-v1"}, {"0xc", "curve"}, {"0xa", "camelot-v3"},
+	} {
+		fp := newDenylistTestPool(p.address, "a", "b")
+		fp.Info.Exchange = p.exchange
+		pools = append(pools, fp)
+	}
+
+	serialize := func(pools []IPoolSimulator) string {
+		keys := make([][2]string, 0, len(pools))
+		for _, p := range pools {
+			keys = append(keys, [2]string{p.GetAddress(), p.GetExchange()})
+		}
+		b, err := json.Marshal(keys)
+		require.Nil(t, err)
+		return string(b)
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	expected := `[["0xa","camelot-v3"],["0xa","uniswap-v3"],["0xb","algebra-v1"],["0xb","uniswap-v3"],["0xc","curve"]]`
+	for i := 0; i < 50; i++ {
+		rng.Shuffle(len(pools), func(i, j int) { pools[i], pools[j] = pools[j], pools[i] })
+		SortPools(pools)
+		assert.Equal(t, expected, serialize(pools))
+	}
+	assert.Equal(t, 0, ComparePools(pools[0], pools[0]))
+}
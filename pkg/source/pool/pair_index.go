@@ -0,0 +1,188 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+)
+
+type tokenPair struct {
+	tokenIn  string
+	tokenOut string
+}
+
+// PairIndex indexes the pools of a universe by the pairs (tokenIn, tokenOut) they can swap, from CanSwapTo, so that
+// the pools of a pair are found without scanning all the pools. A multi-token pool is indexed under every pair it
+// supports. The pairs hold the positions of the pools, not copies, and a position is kept for the address of a
+// removed pool so that adding it back reuses it. The pools of a pair are in the order of ComparePools.
+// It's safe for concurrent use. The iterators aren't affected by the updates made after their creation, except that
+// the pools removed since are skipped and the pools replaced since are returned in their new version.
+type PairIndex struct {
+	mu        sync.RWMutex
+	pools     []IPoolSimulator // by position, nil once removed
+	pairsOf   [][]tokenPair    // pairs of the pool at each position
+	positions map[string]int32 // by address
+	pairs     map[tokenPair][]int32
+}
+
+// NewPairIndex returns the index of the pools. A pool whose address is already indexed replaces the previous one.
+func NewPairIndex(pools []IPoolSimulator) *PairIndex {
+	idx := &PairIndex{
+		pools:     make([]IPoolSimulator, 0, len(pools)),
+		pairsOf:   make([][]tokenPair, 0, len(pools)),
+		positions: make(map[string]int32, len(pools)),
+		pairs:     make(map[tokenPair][]int32),
+	}
+
+	// the pairs are sorted once, instead of inserting the pools one by one
+	for _, p := range pools {
+		if position, ok := idx.positions[p.GetAddress()]; ok {
+			idx.pools[position] = p
+			continue
+		}
+		idx.positions[p.GetAddress()] = int32(len(idx.pools))
+		idx.pools = append(idx.pools, p)
+		idx.pairsOf = append(idx.pairsOf, nil)
+	}
+	for position, p := range idx.pools {
+		idx.pairsOf[position] = pairsOf(p)
+		for _, pair := range idx.pairsOf[position] {
+			idx.pairs[pair] = append(idx.pairs[pair], int32(position))
+		}
+	}
+	for _, positions := range idx.pairs {
+		sort.Slice(positions, func(i, j int) bool {
+			return ComparePools(idx.pools[positions[i]], idx.pools[positions[j]]) < 0
+		})
+	}
+	return idx
+}
+
+// pairsOf returns the pairs supported by the pool, the tokens out being its tokens and the tokens swappable from them
+func pairsOf(p IPoolSimulator) []tokenPair {
+	tokens := append([]string(nil), p.GetTokens()...)
+	seen := make(map[string]struct{}, len(tokens))
+	for _, token := range tokens {
+		seen[token] = struct{}{}
+	}
+	// e.g. the underlying tokens of a meta pool aren't tokens of the pool
+	for _, token := range p.GetTokens() {
+		for _, other := range append(p.CanSwapTo(token), p.CanSwapFrom(token)...) {
+			if _, ok := seen[other]; !ok {
+				seen[other] = struct{}{}
+				tokens = append(tokens, other)
+			}
+		}
+	}
+
+	var pairs []tokenPair
+	added := make(map[tokenPair]struct{})
+	for _, tokenOut := range tokens {
+		for _, tokenIn := range p.CanSwapTo(tokenOut) {
+			pair := tokenPair{tokenIn: tokenIn, tokenOut: tokenOut}
+			if _, ok := added[pair]; ok || tokenIn == tokenOut {
+				continue
+			}
+			added[pair] = struct{}{}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// Add indexes the pool, replacing the pool of the same address if any
+func (idx *PairIndex) Add(p IPoolSimulator) {
+	pairs := pairsOf(p)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	position, ok := idx.positions[p.GetAddress()]
+	if ok {
+		idx.removeAt(position)
+	} else {
+		position = int32(len(idx.pools))
+		idx.positions[p.GetAddress()] = position
+		idx.pools = append(idx.pools, nil)
+		idx.pairsOf = append(idx.pairsOf, nil)
+	}
+	idx.pools[position], idx.pairsOf[position] = p, pairs
+
+	for _, pair := range pairs {
+		// the pairs are copied on write, the iterators keep the previous ones
+		positions := idx.pairs[pair]
+		i := sort.Search(len(positions), func(i int) bool { return ComparePools(idx.pools[positions[i]], p) > 0 })
+		updated := make([]int32, 0, len(positions)+1)
+		updated = append(append(append(updated, positions[:i]...), position), positions[i:]...)
+		idx.pairs[pair] = updated
+	}
+}
+
+// Remove removes the pool of the address from the index, e.g. once it's disabled
+func (idx *PairIndex) Remove(address string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if position, ok := idx.positions[address]; ok {
+		idx.removeAt(position)
+	}
+}
+
+func (idx *PairIndex) removeAt(position int32) {
+	for _, pair := range idx.pairsOf[position] {
+		positions := idx.pairs[pair]
+		if len(positions) == 1 {
+			delete(idx.pairs, pair)
+			continue
+		}
+		updated := make([]int32, 0, len(positions)-1)
+		for _, other := range positions {
+			if other != position {
+				updated = append(updated, other)
+			}
+		}
+		idx.pairs[pair] = updated
+	}
+	idx.pools[position], idx.pairsOf[position] = nil, nil
+}
+
+// PoolsForPair returns an iterator over the pools that can swap tokenIn to tokenOut, in the order of ComparePools
+func (idx *PairIndex) PoolsForPair(tokenIn, tokenOut string) *PairIterator {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return &PairIterator{index: idx, positions: idx.pairs[tokenPair{tokenIn: tokenIn, tokenOut: tokenOut}]}
+}
+
+// PairIterator iterates over the pools of a pair:
+//
+//	for it := idx.PoolsForPair(tokenIn, tokenOut); it.Next(); {
+//		p := it.Pool()
+//	}
+type PairIterator struct {
+	index     *PairIndex
+	positions []int32
+	next      int
+	pool      IPoolSimulator
+}
+
+// Next moves to the next pool, false once all the pools have been iterated over
+func (it *PairIterator) Next() bool {
+	it.index.mu.RLock()
+	defer it.index.mu.RUnlock()
+
+	for it.next < len(it.positions) {
+		p := it.index.pools[it.positions[it.next]]
+		it.next++
+		if p != nil {
+			it.pool = p
+			return true
+		}
+	}
+	it.pool = nil
+	return false
+}
+
+// Pool returns the current pool, nil before the first call to Next and after the last one
+func (it *PairIterator) Pool() IPoolSimulator {
+	return it.pool
+}
@@ -0,0 +1,157 @@
+package pool
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// oneWayPool only swaps its first token to its second one, like a deposit
+type oneWayPool struct {
+	*fakePool
+}
+
+func (p *oneWayPool) CanSwapTo(address string) []string {
+	if address == p.Info.Tokens[1] {
+		return []string{p.Info.Tokens[0]}
+	}
+	return nil
+}
+
+func (p *oneWayPool) CanSwapFrom(address string) []string {
+	if address == p.Info.Tokens[0] {
+		return []string{p.Info.Tokens[1]}
+	}
+	return nil
+}
+
+func addressesOf(it *PairIterator) []string {
+	var addresses []string
+	for it.Next() {
+		addresses = append(addresses, it.Pool().GetAddress())
+	}
+	return addresses
+}
+
+func TestPairIndex_PoolsForPair(t *testing.T) {
+	index := NewPairIndex([]IPoolSimulator{
+		newDenylistTestPool("p2", "A", "B", "C"),
+		newDenylistTestPool("p1", "A", "B"),
+		&oneWayPool{newDenylistTestPool("p3", "A", "B")},
+		newDenylistTestPool("p0", "B", "A"),
+	})
+
+	testcases := []struct {
+		tokenIn, tokenOut string
+		expected          []string
+	}{
+		{"A", "B", []string{"p0", "p1", "p2", "p3"}},
+		{"B", "A", []string{"p0", "p1", "p2"}},
+		{"A", "C", []string{"p2"}},
+		{"C", "B", []string{"p2"}},
+		{"A", "A", nil},
+		{"A", "D", nil},
+	}
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.Equal(t, tc.expected, addressesOf(index.PoolsForPair(tc.tokenIn, tc.tokenOut)))
+		})
+	}
+}
+
+func TestPairIndex_Update(t *testing.T) {
+	idx := NewPairIndex([]IPoolSimulator{newDenylistTestPool("p1", "A", "B"), newDenylistTestPool("p3", "A", "B")})
+
+	before := idx.PoolsForPair("A", "B")
+	idx.Add(newDenylistTestPool("p2", "A", "B"))
+	idx.Add(newDenylistTestPool("p0", "A", "C"))
+	assert.Equal(t, []string{"p1", "p2", "p3"}, addressesOf(idx.PoolsForPair("A", "B")))
+	assert.Equal(t, []string{"p0"}, addressesOf(idx.PoolsForPair("C", "A")))
+	// the iterators keep the pools of their creation
+	assert.Equal(t, []string{"p1", "p3"}, addressesOf(before))
+
+	// the removed pools are skipped by the current iterators
+	before = idx.PoolsForPair("A", "B")
+	assert.True(t, before.Next())
+	idx.Remove("p2")
+	idx.Remove("p0")
+	idx.Remove("unknown")
+	assert.Equal(t, []string{"p3"}, addressesOf(before))
+	assert.Equal(t, []string{"p1", "p3"}, addressesOf(idx.PoolsForPair("A", "B")))
+	assert.Nil(t, addressesOf(idx.PoolsForPair("A", "C")))
+
+	// a pool of the same address replaces the previous one, at its position
+	replaced := newDenylistTestPool("p1", "A", "C")
+	idx.Add(replaced)
+	assert.Equal(t, []string{"p3"}, addressesOf(idx.PoolsForPair("A", "B")))
+	it := idx.PoolsForPair("A", "C")
+	assert.True(t, it.Next())
+	assert.Same(t, replaced, it.Pool())
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Pool())
+	idx.Add(newDenylistTestPool("p2", "A", "B"))
+	assert.Len(t, idx.pools, 4)
+}
+
+// newPairIndexUniverse returns 50k pools of 2 or 3 out of 1000 tokens
+func newPairIndexUniverse() ([]IPoolSimulator, [][2]string) {
+	r := rand.New(rand.NewSource(1))
+	token := func() string { return fmt.Sprintf("token%d", r.Intn(1000)) }
+
+	pools := make([]IPoolSimulator, 0, 50000)
+	pairs := make([][2]string, 0, 100)
+	for i := 0; i < 50000; i++ {
+		tokens := []string{token(), token()}
+		if i%20 == 0 {
+			tokens = append(tokens, token())
+		}
+		pools = append(pools, newDenylistTestPool(fmt.Sprintf("pool%d", i), tokens...))
+		if i%500 == 0 {
+			pairs = append(pairs, [2]string{tokens[0], tokens[1]})
+		}
+	}
+	return pools, pairs
+}
+
+func BenchmarkPairIndex_PoolsForPair(b *testing.B) {
+	pools, pairs := newPairIndexUniverse()
+	idx := NewPairIndex(pools)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pair := pairs[i%len(pairs)]
+		for it := idx.PoolsForPair(pair[0], pair[1]); it.Next(); {
+			_ = it.Pool()
+		}
+	}
+}
+
+func BenchmarkPairIndex_FullScan(b *testing.B) {
+	pools, pairs := newPairIndexUniverse()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pair := pairs[i%len(pairs)]
+		var matched []IPoolSimulator
+		for _, p := range pools {
+			for _, tokenIn := range p.CanSwapTo(pair[1]) {
+				if tokenIn == pair[0] {
+					matched = append(matched, p)
+					break
+				}
+			}
+		}
+		SortPools(matched)
+	}
+}
+
+func BenchmarkNewPairIndex(b *testing.B) {
+	pools, _ := newPairIndexUniverse()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		NewPairIndex(pools)
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"errors"
 	"math/big"
 
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 var (
 	ErrCalcAmountOutPanic = errors.New("calcAmountOut was panic")
+	ErrInvalidTokenIndex  = errors.New("invalid token index")
 )
 
 type Pool struct {
@@ -63,21 +64,40 @@ func (t *Pool) GetTokenIndex(address string) int {
 	return t.Info.GetTokenIndex(address)
 }
 
+// GetToken is the reverse of GetTokenIndex, it returns ErrInvalidTokenIndex if the pool has no token at index
+func (t *Pool) GetToken(index int) (string, error) {
+	return t.Info.GetToken(index)
+}
+
 func (t *Pool) GetType() string {
 	return t.Info.Type
 }
 
 type CalcAmountOutResult struct {
-	TokenAmountOut *TokenAmount
-	Fee            *TokenAmount
-	Gas            int64
-	SwapInfo       interface{}
+	TokenAmountOut         *TokenAmount
+	RemainingTokenAmountIn *TokenAmount // the part of the amount in the pool can't swap, for the partial fills
+	Fee                    *TokenAmount
+	Gas                    int64
+	SwapInfo               interface{}
 }
 
 func (r *CalcAmountOutResult) IsValid() bool {
 	return r.TokenAmountOut != nil && r.TokenAmountOut.Amount != nil && r.TokenAmountOut.Amount.Cmp(ZeroBI) > 0
 }
 
+// CalcAmountInResult is the quote of a swap by exact amount out
+type CalcAmountInResult struct {
+	TokenAmountIn           *TokenAmount
+	RemainingTokenAmountOut *TokenAmount // the part of the amount out the pool can't provide, for the partial fills
+	Fee                     *TokenAmount
+	Gas                     int64
+	SwapInfo                interface{}
+}
+
+func (r *CalcAmountInResult) IsValid() bool {
+	return r.TokenAmountIn != nil && r.TokenAmountIn.Amount != nil && r.TokenAmountIn.Amount.Cmp(ZeroBI) > 0
+}
+
 type UpdateBalanceParams struct {
 	TokenAmountIn  TokenAmount
 	TokenAmountOut TokenAmount
@@ -113,6 +133,13 @@ func (t *PoolInfo) GetTokenIndex(address string) int {
 	return -1
 }
 
+func (t *PoolInfo) GetToken(index int) (string, error) {
+	if index < 0 || index >= len(t.Tokens) {
+		return "", ErrInvalidTokenIndex
+	}
+	return t.Tokens[index], nil
+}
+
 // wrap around pool.CalcAmountOut and catch panic
 func CalcAmountOut(pool IPoolSimulator, tokenAmountIn TokenAmount, tokenOut string) (res *CalcAmountOutResult, err error) {
 	defer func() {
@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+// Precision is the trade-off between accuracy and speed of a quote
+type Precision int
+
+const (
+	// PrecisionExact is the wei-exact simulation of the on-chain math, used for the final route
+	PrecisionExact Precision = iota
+	// PrecisionFast is an approximate quote, used to prune candidate pools.
+	// Results are within FastPrecisionMaxRelativeError of the exact output (or 1 wei for dust amounts), their SwapInfo
+	// is nil so they must not be passed to UpdateBalance, see CalcAmountOutParams.ForUpdateBalance.
+	PrecisionFast
+)
+
+// FastPrecisionMaxRelativeError is the documented error bound of PrecisionFast quotes (0.1%)
+const FastPrecisionMaxRelativeError = 1e-3
+
+func (p Precision) String() string {
+	switch p {
+	case PrecisionExact:
+		return "exact"
+	case PrecisionFast:
+		return "fast"
+	default:
+		return "unknown"
+	}
+}
+
+// CalcAmountOutParams are the inputs of a quote
+type CalcAmountOutParams struct {
+	TokenAmountIn TokenAmount
+	TokenOut      string
+	Precision     Precision // PrecisionExact by default
+	// true if the result is passed to UpdateBalance: only the exact path returns the SwapInfo of the swap, so the quote
+	// is exact whatever the Precision
+	ForUpdateBalance bool
+	Budget           *Budget // the budget of the request the quote is counted in, nil for unlimited
+}
+
+// IsExact returns true if the quote must be computed by the exact path
+func (p CalcAmountOutParams) IsExact() bool {
+	return p.Precision != PrecisionFast || p.ForUpdateBalance
+}
+
+// IPoolApproximator is implemented by pools that have a fast approximate path.
+// Pools without it are always quoted exactly.
+type IPoolApproximator interface {
+	CalcAmountOutWithParams(params CalcAmountOutParams) (*CalcAmountOutResult, error)
+}
+
+// CalcAmountOutWithParams is the same as CalcAmountOut but uses the pool's fast path if it has one and the params allow
+// it, see CalcAmountOutParams.IsExact. The quote is counted in params.Budget, it fails with ErrBudgetExhausted once
+// the budget is exhausted.
+func CalcAmountOutWithParams(pool IPoolSimulator, params CalcAmountOutParams) (*CalcAmountOutResult, error) {
+	budget := params.Budget
+	if budget == nil {
+		return calcAmountOutWithParams(pool, params)
+	}
+	if err := budget.acquire(pool.GetAddress()); err != nil {
+		return nil, err
+	}
+
+	start := budget.now()
+	defer func() { budget.spend(budget.now().Sub(start)) }()
+	return calcAmountOutWithParams(pool, params)
+}
+
+func calcAmountOutWithParams(pool IPoolSimulator, params CalcAmountOutParams) (res *CalcAmountOutResult, err error) {
+	approximator, ok := pool.(IPoolApproximator)
+	if !ok || params.IsExact() {
+		return CalcAmountOut(pool, params.TokenAmountIn, params.TokenOut)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = ErrCalcAmountOutPanic
+			logger.WithFields(
+				logger.Fields{
+					"recover":     r,
+					"poolAddress": pool.GetAddress(),
+					"precision":   params.Precision.String(),
+				}).Warn(err.Error())
+		}
+	}()
+
+	return approximator.CalcAmountOutWithParams(params)
+}
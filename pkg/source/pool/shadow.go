@@ -0,0 +1,280 @@
+package pool
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+// ShadowConfig configures the side by side evaluation of two implementations of a source
+type ShadowConfig struct {
+	SampleRate float64 `json:"sampleRate"` // fraction of the CalcAmountOut calls evaluated on the shadow, in [0, 1]
+	Swap       bool    `json:"swap"`       // serve the second implementation and shadow the first one
+	// relative difference of the outputs under which they are not reported as diverging (at least 1 wei if positive),
+	// for a shadow that isn't wei-exact, e.g. FastPrecisionMaxRelativeError
+	MaxRelativeDelta float64 `json:"maxRelativeDelta"`
+}
+
+// Divergence is a sampled call where the shadow implementation didn't give the same result as the primary one
+type Divergence struct {
+	Pool     string
+	Exchange string
+	TokenIn  string
+	TokenOut string
+	AmountIn *big.Int
+
+	PrimaryAmountOut *big.Int // nil if the primary failed
+	ShadowAmountOut  *big.Int // nil if the shadow failed
+	Delta            *big.Int // |primary - shadow| in wei, nil if exactly one of them failed
+	Bucket           int      // order of magnitude of AmountIn (number of digits - 1)
+}
+
+// ShadowRecorder receives the results of the shadow evaluations, see LogShadowRecorder and ShadowStats
+type ShadowRecorder interface {
+	RecordSample(exchange string, bucket int)
+	RecordDivergence(d Divergence)
+}
+
+// LogShadowRecorder reports every divergence as a warning of the logger, throttled by logger.SetWarnLimit
+type LogShadowRecorder struct{}
+
+func (LogShadowRecorder) RecordSample(string, int) {}
+
+func (LogShadowRecorder) RecordDivergence(d Divergence) {
+	logger.WithFields(logger.Fields{
+		"poolAddress":      d.Pool,
+		"exchange":         d.Exchange,
+		"tokenIn":          d.TokenIn,
+		"tokenOut":         d.TokenOut,
+		"amountIn":         d.AmountIn,
+		"primaryAmountOut": d.PrimaryAmountOut,
+		"shadowAmountOut":  d.ShadowAmountOut,
+		"delta":            d.Delta,
+		"bucket":           d.Bucket,
+	}).Warn("shadow simulator diverged")
+}
+
+// ShadowRecorders reports the results to each of the recorders, e.g. to a ShadowStats and a LogShadowRecorder
+type ShadowRecorders []ShadowRecorder
+
+func (r ShadowRecorders) RecordSample(exchange string, bucket int) {
+	for _, recorder := range r {
+		recorder.RecordSample(exchange, bucket)
+	}
+}
+
+func (r ShadowRecorders) RecordDivergence(d Divergence) {
+	for _, recorder := range r {
+		recorder.RecordDivergence(d)
+	}
+}
+
+// ShadowPoolSimulator serves the primary simulator and evaluates the shadow one on a sample of the calls,
+// asynchronously, reporting divergences to the recorder. Both simulators are kept on the same state.
+type ShadowPoolSimulator struct {
+	IPoolSimulator
+	shadow   IPoolSimulator
+	config   ShadowConfig
+	recorder ShadowRecorder
+
+	calls atomic.Uint64
+	// the evaluations start under the read lock, so that UpdateBalance can wait for them under the write lock
+	mu      sync.RWMutex
+	pending sync.WaitGroup
+}
+
+// NewShadowPoolSimulator wraps current and candidate, two simulators of the same pool,
+// current is the primary unless config.Swap is set
+func NewShadowPoolSimulator(current, candidate IPoolSimulator, config ShadowConfig, recorder ShadowRecorder) *ShadowPoolSimulator {
+	primary, shadow := current, candidate
+	if config.Swap {
+		primary, shadow = candidate, current
+	}
+
+	return &ShadowPoolSimulator{
+		IPoolSimulator: primary,
+		shadow:         shadow,
+		config:         config,
+		recorder:       recorder,
+	}
+}
+
+// sampled spreads the sampled calls evenly: the n-th call is sampled if floor(n * rate) increases
+func (s *ShadowPoolSimulator) sampled() bool {
+	if s.config.SampleRate <= 0 || s.recorder == nil {
+		return false
+	}
+	n := s.calls.Add(1)
+	return uint64(float64(n)*s.config.SampleRate) > uint64(float64(n-1)*s.config.SampleRate)
+}
+
+func (s *ShadowPoolSimulator) CalcAmountOut(tokenAmountIn TokenAmount, tokenOut string) (*CalcAmountOutResult, error) {
+	if !s.sampled() {
+		return s.IPoolSimulator.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+
+	// the primary is quoted under the read lock too, so that both are on the same state
+	s.mu.RLock()
+	res, err := s.IPoolSimulator.CalcAmountOut(tokenAmountIn, tokenOut)
+	s.pending.Add(1)
+	s.mu.RUnlock()
+
+	var primaryAmountOut *big.Int
+	if err == nil && res.TokenAmountOut != nil {
+		primaryAmountOut = res.TokenAmountOut.Amount
+	}
+	go func() {
+		defer s.pending.Done()
+		s.evaluate(tokenAmountIn, tokenOut, primaryAmountOut)
+	}()
+
+	return res, err
+}
+
+func (s *ShadowPoolSimulator) evaluate(tokenAmountIn TokenAmount, tokenOut string, primaryAmountOut *big.Int) {
+	var shadowAmountOut *big.Int
+	if res, err := CalcAmountOut(s.shadow, tokenAmountIn, tokenOut); err == nil && res.TokenAmountOut != nil {
+		shadowAmountOut = res.TokenAmountOut.Amount
+	}
+
+	bucket := 0
+	if tokenAmountIn.Amount != nil && tokenAmountIn.Amount.Sign() > 0 {
+		bucket = len(tokenAmountIn.Amount.String()) - 1
+	}
+	s.recorder.RecordSample(s.GetExchange(), bucket)
+
+	d := Divergence{
+		Pool:             s.GetAddress(),
+		Exchange:         s.GetExchange(),
+		TokenIn:          tokenAmountIn.Token,
+		TokenOut:         tokenOut,
+		AmountIn:         tokenAmountIn.Amount,
+		PrimaryAmountOut: primaryAmountOut,
+		ShadowAmountOut:  shadowAmountOut,
+		Bucket:           bucket,
+	}
+	switch {
+	case primaryAmountOut == nil && shadowAmountOut == nil:
+		return
+	case primaryAmountOut == nil || shadowAmountOut == nil:
+		s.recorder.RecordDivergence(d)
+	case primaryAmountOut.Cmp(shadowAmountOut) != 0:
+		d.Delta = new(big.Int).Abs(new(big.Int).Sub(primaryAmountOut, shadowAmountOut))
+		if s.withinTolerance(d.Delta, primaryAmountOut) {
+			return
+		}
+		s.recorder.RecordDivergence(d)
+	}
+}
+
+// withinTolerance returns true if delta is at most config.MaxRelativeDelta of amountOut, or 1 wei
+func (s *ShadowPoolSimulator) withinTolerance(delta, amountOut *big.Int) bool {
+	if s.config.MaxRelativeDelta <= 0 {
+		return false
+	}
+	maxDelta, _ := new(big.Float).Mul(
+		new(big.Float).SetInt(amountOut),
+		big.NewFloat(s.config.MaxRelativeDelta),
+	).Int(nil)
+	if maxDelta.Sign() <= 0 {
+		maxDelta.SetInt64(1)
+	}
+	return delta.Cmp(maxDelta) <= 0
+}
+
+// UpdateBalance applies the swap to both simulators. The SwapInfo of the primary can't be used by the shadow,
+// so the swap is simulated again on the shadow to get its own.
+func (s *ShadowPoolSimulator) UpdateBalance(params UpdateBalanceParams) {
+	// evaluations in flight must see the state they were sampled on
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending.Wait()
+
+	s.IPoolSimulator.UpdateBalance(params)
+
+	res, err := CalcAmountOut(s.shadow, params.TokenAmountIn, params.TokenAmountOut.Token)
+	if err != nil {
+		return
+	}
+	var fee TokenAmount
+	if res.Fee != nil {
+		fee = *res.Fee
+	}
+	s.shadow.UpdateBalance(UpdateBalanceParams{
+		TokenAmountIn:  params.TokenAmountIn,
+		TokenAmountOut: *res.TokenAmountOut,
+		Fee:            fee,
+		SwapInfo:       res.SwapInfo,
+	})
+}
+
+// Wait blocks until all the sampled evaluations are reported
+func (s *ShadowPoolSimulator) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending.Wait()
+}
+
+// BucketStats is the divergence stats of an order of magnitude of amountIn
+type BucketStats struct {
+	Samples     uint64
+	Divergences uint64
+	MaxDelta    *big.Int
+}
+
+// ShadowStats is an in-memory ShadowRecorder aggregating the results per exchange and amount bucket
+type ShadowStats struct {
+	mu    sync.Mutex
+	stats map[string]map[int]*BucketStats
+}
+
+func NewShadowStats() *ShadowStats {
+	return &ShadowStats{stats: map[string]map[int]*BucketStats{}}
+}
+
+func (s *ShadowStats) bucket(exchange string, bucket int) *BucketStats {
+	buckets, ok := s.stats[exchange]
+	if !ok {
+		buckets = map[int]*BucketStats{}
+		s.stats[exchange] = buckets
+	}
+	b, ok := buckets[bucket]
+	if !ok {
+		b = &BucketStats{MaxDelta: big.NewInt(0)}
+		buckets[bucket] = b
+	}
+	return b
+}
+
+func (s *ShadowStats) RecordSample(exchange string, bucket int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bucket(exchange, bucket).Samples++
+}
+
+func (s *ShadowStats) RecordDivergence(d Divergence) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.bucket(d.Exchange, d.Bucket)
+	b.Divergences++
+	if d.Delta != nil && d.Delta.Cmp(b.MaxDelta) > 0 {
+		b.MaxDelta = new(big.Int).Set(d.Delta)
+	}
+}
+
+// Get returns a copy of the stats of an exchange by amount bucket
+func (s *ShadowStats) Get(exchange string) map[int]BucketStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make(map[int]BucketStats, len(s.stats[exchange]))
+	for bucket, b := range s.stats[exchange] {
+		res[bucket] = BucketStats{
+			Samples:     b.Samples,
+			Divergences: b.Divergences,
+			MaxDelta:    new(big.Int).Set(b.MaxDelta),
+		}
+	}
+	return res
+}
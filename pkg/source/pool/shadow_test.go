@@ -0,0 +1,121 @@
+package pool
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// constant product-ish fake: amountOut = amountIn * rate, plus a fixed bias
+type fakePool struct {
+	Pool
+	rate    int64
+	bias    int64
+	updates int
+}
+
+func (p *fakePool) CalcAmountOut(tokenAmountIn TokenAmount, tokenOut string) (*CalcAmountOutResult, error) {
+	amountOut := new(big.Int).Mul(tokenAmountIn.Amount, big.NewInt(p.rate))
+	return &CalcAmountOutResult{
+		TokenAmountOut: &TokenAmount{Token: tokenOut, Amount: amountOut.Add(amountOut, big.NewInt(p.bias))},
+		Fee:            &TokenAmount{Token: tokenAmountIn.Token},
+		SwapInfo:       p.rate,
+	}, nil
+}
+
+func (p *fakePool) UpdateBalance(params UpdateBalanceParams) {
+	p.updates++
+	p.rate = params.SwapInfo.(int64) - 1
+}
+
+func (p *fakePool) GetMetaInfo(string, string) interface{} {
+	return nil
+}
+
+func newFakePool(bias int64) *fakePool {
+	return &fakePool{
+		Pool: Pool{Info: PoolInfo{Address: "pool", Exchange: "dex", Tokens: []string{"A", "B"}}},
+		rate: 10,
+		bias: bias,
+	}
+}
+
+func TestShadowPoolSimulator(t *testing.T) {
+	current, candidate := newFakePool(0), newFakePool(3)
+	stats := NewShadowStats()
+	s := NewShadowPoolSimulator(current, candidate, ShadowConfig{SampleRate: 0.5}, ShadowRecorders{stats, LogShadowRecorder{}})
+
+	for _, amountIn := range []int64{1, 2, 10, 20, 100, 200} {
+		res, err := s.CalcAmountOut(TokenAmount{Token: "A", Amount: big.NewInt(amountIn)}, "B")
+		require.Nil(t, err)
+		// always the result of the primary
+		assert.Equal(t, big.NewInt(amountIn*10), res.TokenAmountOut.Amount)
+	}
+	s.Wait()
+
+	// every other call is sampled, one per bucket
+	assert.Equal(t, map[int]BucketStats{
+		0: {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(3)},
+		1: {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(3)},
+		2: {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(3)},
+	}, stats.Get("dex"))
+
+	// both are updated with their own swap info
+	res, err := s.CalcAmountOut(TokenAmount{Token: "A", Amount: big.NewInt(1)}, "B")
+	require.Nil(t, err)
+	s.UpdateBalance(UpdateBalanceParams{
+		TokenAmountIn:  TokenAmount{Token: "A", Amount: big.NewInt(1)},
+		TokenAmountOut: *res.TokenAmountOut,
+		SwapInfo:       res.SwapInfo,
+	})
+	assert.Equal(t, 1, current.updates)
+	assert.Equal(t, 1, candidate.updates)
+	assert.Equal(t, current.rate, candidate.rate)
+}
+
+func TestShadowPoolSimulator_Swap(t *testing.T) {
+	current, candidate := newFakePool(0), newFakePool(0)
+	candidate.rate = 11
+	stats := NewShadowStats()
+	s := NewShadowPoolSimulator(current, candidate, ShadowConfig{SampleRate: 1, Swap: true}, stats)
+
+	res, err := s.CalcAmountOut(TokenAmount{Token: "A", Amount: big.NewInt(1000)}, "B")
+	require.Nil(t, err)
+	assert.Equal(t, big.NewInt(11000), res.TokenAmountOut.Amount)
+	s.Wait()
+
+	assert.Equal(t, map[int]BucketStats{
+		3: {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(1000)},
+	}, stats.Get("dex"))
+
+	// no divergence, no sampling
+	same := NewShadowPoolSimulator(newFakePool(0), newFakePool(0), ShadowConfig{SampleRate: 1}, stats)
+	_, err = same.CalcAmountOut(TokenAmount{Token: "A", Amount: big.NewInt(1)}, "B")
+	require.Nil(t, err)
+	unsampled := NewShadowPoolSimulator(newFakePool(0), newFakePool(5), ShadowConfig{}, stats)
+	_, err = unsampled.CalcAmountOut(TokenAmount{Token: "A", Amount: big.NewInt(1)}, "B")
+	require.Nil(t, err)
+	same.Wait()
+	unsampled.Wait()
+
+	assert.Equal(t, BucketStats{Samples: 1, Divergences: 0, MaxDelta: big.NewInt(0)}, stats.Get("dex")[0])
+}
+
+func TestShadowPoolSimulator_MaxRelativeDelta(t *testing.T) {
+	stats := NewShadowStats()
+	s := NewShadowPoolSimulator(newFakePool(0), newFakePool(3), ShadowConfig{SampleRate: 1, MaxRelativeDelta: 1e-3}, stats)
+
+	for _, amountIn := range []int64{1, 1000} {
+		_, err := s.CalcAmountOut(TokenAmount{Token: "A", Amount: big.NewInt(amountIn)}, "B")
+		require.Nil(t, err)
+	}
+	s.Wait()
+
+	// 3 wei is more than the 1 wei allowed for 10, within the 0.1% of 10000
+	assert.Equal(t, map[int]BucketStats{
+		0: {Samples: 1, Divergences: 1, MaxDelta: big.NewInt(3)},
+		3: {Samples: 1, Divergences: 0, MaxDelta: big.NewInt(0)},
+	}, stats.Get("dex"))
+}
@@ -0,0 +1,47 @@
+package uniswap
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool/conformance"
+)
+
+func TestPoolSimulator_Conformance(t *testing.T) {
+	factory := func(entityPool entity.Pool) (pool.IPoolSimulator, error) {
+		return NewPoolSimulator(entityPool)
+	}
+	swaps := []conformance.Swap{
+		{TokenIn: "a", AmountIn: big.NewInt(1000000), TokenOut: "b"},
+		{TokenIn: "b", AmountIn: big.NewInt(1000000000000000000), TokenOut: "a"},
+	}
+
+	conformance.Run(t, factory, []conformance.Fixture{
+		{
+			Name: "fee 0.3%",
+			Pool: entity.Pool{
+				Address:  "0xpool",
+				Exchange: "uniswap",
+				Type:     "uniswap-v2",
+				SwapFee:  0.003,
+				Reserves: entity.PoolReserves{"1000000000000", "500000000000000000000"},
+				Tokens:   []*entity.PoolToken{{Address: "a"}, {Address: "b"}},
+			},
+			Swaps: swaps,
+		},
+		{
+			Name: "weighted",
+			Pool: entity.Pool{
+				Address:  "0xpool",
+				Exchange: "polydex",
+				Type:     "uniswap-v2",
+				SwapFee:  0.002,
+				Reserves: entity.PoolReserves{"1000000000000", "500000000000000000000"},
+				Tokens:   []*entity.PoolToken{{Address: "a", Weight: 80}, {Address: "b", Weight: 20}},
+			},
+			Swaps: swaps,
+		},
+	}, conformance.Options{})
+}
@@ -7,9 +7,10 @@ import (
 )
 
 var (
-	uniswapV3PoolABI abi.ABI
-	tickLensABI      abi.ABI
-	erc20ABI         abi.ABI
+	uniswapV3PoolABI    abi.ABI
+	uniswapV3FactoryABI abi.ABI
+	tickLensABI         abi.ABI
+	erc20ABI            abi.ABI
 )
 
 func init() {
@@ -18,6 +19,7 @@ func init() {
 		data []byte
 	}{
 		{&uniswapV3PoolABI, uniswapV3PoolJson},
+		{&uniswapV3FactoryABI, uniswapV3FactoryJson},
 		{&tickLensABI, tickLensProxyJson},
 		{&erc20ABI, erc20Json},
 	}
@@ -7,8 +7,24 @@ type Config struct {
 	PreGenesisPoolPath string `json:"preGenesisPoolPath"`
 	AllowSubgraphError bool   `json:"allowSubgraphError"`
 	preGenesisPoolIDs  []string
+
+	// drift in percent between the balances of a pool and the amounts locked by its tracked liquidity,
+	// beyond which the ticks of the pool are read again from TickLens. 0 to disable
+	ReserveDriftThreshold float64 `json:"reserveDriftThreshold"`
+
+	// for dexes without a subgraph: pools are discovered from factory logs and ticks are read from TickLens
+	RPCOnly           bool   `json:"rpcOnly"`
+	FactoryAddress    string `json:"factoryAddress"`
+	StartBlock        uint64 `json:"startBlock"`        // factory deployment block, to start scanning logs from
+	BlockRange        uint64 `json:"blockRange"`        // max number of blocks to scan per round
+	MaxLogsPerRequest int    `json:"maxLogsPerRequest"` // the block range will be shrunk until a request returns at most this many logs
 }
 
 func (c *Config) IsAllowSubgraphError() bool {
 	return c.AllowSubgraphError
 }
+
+// IsRPCOnly returns true if the dex is configured without a subgraph, so everything must be fetched from RPC
+func (c *Config) IsRPCOnly() bool {
+	return c.RPCOnly
+}
@@ -0,0 +1,38 @@
+package uniswapv3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool/conformance"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+func TestPoolSimulator_Conformance(t *testing.T) {
+	factory := func(entityPool entity.Pool) (pool.IPoolSimulator, error) {
+		return NewPoolSimulator(entityPool, valueobject.ChainIDEthereum)
+	}
+	token0, token1 := "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"
+
+	conformance.Run(t, factory, []conformance.Fixture{
+		{
+			Name: "fee 0.3%",
+			Pool: entity.Pool{
+				Address:  "0x0000000000000000000000000000000000000003",
+				Exchange: "uniswapv3",
+				Type:     "uniswapv3",
+				SwapFee:  3000,
+				Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+				Tokens:   []*entity.PoolToken{{Address: token0, Decimals: 6}, {Address: token1, Decimals: 18}},
+				Extra:    `{"liquidity":2822091172725,"sqrtPriceX96":93065132232889433968150957834858946,"tick":279543,"ticks":[{"index":-887220,"liquidityGross":2822091172725,"liquidityNet":2822091172725},{"index":273540,"liquidityGross":116315447200034,"liquidityNet":116315447200034},{"index":279120,"liquidityGross":116315447200034,"liquidityNet":-116315447200034},{"index":285480,"liquidityGross":2822091172725,"liquidityNet":-2822091172725}]}`,
+			},
+			Swaps: []conformance.Swap{
+				{TokenIn: token0, AmountIn: big.NewInt(1000), TokenOut: token1},
+				{TokenIn: token0, AmountIn: big.NewInt(100000000), TokenOut: token1},
+				{TokenIn: token1, AmountIn: big.NewInt(1000000000000000000), TokenOut: token0},
+			},
+		},
+	}, conformance.Options{AllowNilMeta: true})
+}
@@ -21,6 +21,15 @@ const (
 	methodGetSlot0                        = "slot0"
 	tickLensMethodGetPopulatedTicksInWord = "getPopulatedTicksInWord"
 	erc20MethodBalanceOf                  = "balanceOf"
+	erc20MethodDecimals                   = "decimals"
+	erc20MethodSymbol                     = "symbol"
+	erc20MethodName                       = "name"
+	factoryEventPoolCreated               = "PoolCreated"
+)
+
+const (
+	defaultBlockRange        = uint64(5000)
+	defaultMaxLogsPerRequest = 1000
 )
 
 var (
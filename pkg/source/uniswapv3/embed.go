@@ -10,6 +10,9 @@ var uniswapV3PoolJson []byte
 //go:embed abis/TickLensProxy.json
 var tickLensProxyJson []byte
 
+//go:embed abis/UniswapV3Factory.json
+var uniswapV3FactoryJson []byte
+
 //go:embed abis/ERC20.json
 var erc20Json []byte
 
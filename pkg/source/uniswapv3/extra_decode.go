@@ -0,0 +1,104 @@
+package uniswapv3
+
+import (
+	"encoding/json"
+	"math/bits"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/jsonscan"
+)
+
+// decodeExtra decodes the Extra written by the tracker with a scanner, falling back to encoding/json for the inputs
+// the scanner doesn't read (unknown or differently cased keys, quoted numbers...), so both accept exactly the same
+// inputs with the same result.
+func decodeExtra(data []byte) (Extra, error) {
+	var extra Extra
+	s := jsonscan.New(data)
+	scanExtra(s, &extra)
+	s.End()
+	if !s.Failed() {
+		return extra, nil
+	}
+
+	extra = Extra{}
+	err := json.Unmarshal(data, &extra)
+	return extra, err
+}
+
+func scanExtra(s *jsonscan.Scanner, extra *Extra) {
+	if s.Null() {
+		return
+	}
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "liquidity":
+			s.Seen(&seen, 0)
+			extra.Liquidity = s.BigInt()
+		case "sqrtPriceX96":
+			s.Seen(&seen, 1)
+			extra.SqrtPriceX96 = s.BigInt()
+		case "tick":
+			s.Seen(&seen, 2)
+			extra.Tick = s.BigInt()
+		case "ticks":
+			s.Seen(&seen, 3)
+			extra.Ticks = scanTicks(s)
+		case "reserveDrift":
+			s.Seen(&seen, 4)
+			if !s.Null() {
+				extra.ReserveDrift = s.Float64()
+			}
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
+
+func scanTicks(s *jsonscan.Scanner) []Tick {
+	if s.Null() {
+		return nil
+	}
+	ticks := make([]Tick, 0)
+	s.ArrayStart()
+	for i := 0; s.NextElem(i); i++ {
+		var tick Tick
+		if !s.Null() {
+			scanTick(s, &tick)
+		}
+		ticks = append(ticks, tick)
+	}
+	return ticks
+}
+
+func scanTick(s *jsonscan.Scanner, tick *Tick) {
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "index":
+			s.Seen(&seen, 0)
+			if !s.Null() {
+				tick.Index = int(s.Int(bits.UintSize))
+			}
+		case "liquidityGross":
+			s.Seen(&seen, 1)
+			tick.LiquidityGross = s.BigInt()
+		case "liquidityNet":
+			s.Seen(&seen, 2)
+			tick.LiquidityNet = s.BigInt()
+		default:
+			s.Fail()
+			return
+		}
+	}
+}
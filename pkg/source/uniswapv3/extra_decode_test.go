@@ -0,0 +1,138 @@
+package uniswapv3
+
+import (
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/jsonscan"
+)
+
+func randomBigInt(rng *rand.Rand, maxBits int) *big.Int {
+	n := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(1+rng.Intn(maxBits))))
+	if rng.Intn(4) == 0 {
+		n.Neg(n)
+	}
+	return n
+}
+
+func randomExtra(rng *rand.Rand) Extra {
+	extra := Extra{
+		Liquidity:    randomBigInt(rng, 128),
+		SqrtPriceX96: randomBigInt(rng, 160),
+		Tick:         big.NewInt(int64(rng.Intn(1774544) - 887272)),
+	}
+	for i := rng.Intn(50); i > 0; i-- {
+		extra.Ticks = append(extra.Ticks, Tick{
+			Index:          rng.Intn(1774544) - 887272,
+			LiquidityGross: randomBigInt(rng, 128),
+			LiquidityNet:   randomBigInt(rng, 128),
+		})
+	}
+	if rng.Intn(2) == 0 {
+		extra.ReserveDrift = rng.Float64() * 100
+	}
+	return extra
+}
+
+// replaceValue replaces the value of the first occurrence of key, if the value is a number, a literal or an array
+// without nested ones
+func replaceValue(blob, key, value string) string {
+	start := strings.Index(blob, key)
+	if start < 0 {
+		return blob
+	}
+	start += len(key)
+	end := strings.IndexAny(blob[start:], ",}") + start
+	if blob[start] == '[' {
+		end = strings.IndexByte(blob[start:], ']') + start + 1
+	}
+	return blob[:start] + value + blob[end:]
+}
+
+// same as the mutations of the algebrav1 Extra
+var extraMutations = []func(rng *rand.Rand, blob string) string{
+	func(_ *rand.Rand, blob string) string { return blob },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"liquidity":`, `"liquidity":"`, 1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"liquidityNet":`, `"liquidityNet":"`, 1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"sqrtPriceX96"`, `"SqrtPriceX96"`, 1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `{`, `{"foo":[1,{"a":null}],`, 1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"tick":`, `"tick":1,"tick":`, 1) },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `,`, " ,\n\t", -1) },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"tick":`, "null") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"ticks":`, "null") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"ticks":`, "[]") },
+	func(_ *rand.Rand, blob string) string { return strings.Replace(blob, `"ticks":[`, `"ticks":[null,`, 1) },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"index":`, "1.5") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"index":`, "9223372036854775808") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"liquidity":`, "1e18") },
+	func(_ *rand.Rand, blob string) string { return replaceValue(blob, `"liquidity":`, "true") },
+	func(_ *rand.Rand, blob string) string { return `null` },
+	func(rng *rand.Rand, blob string) string { return blob[:rng.Intn(len(blob))] },
+	func(_ *rand.Rand, blob string) string { return blob + " x" },
+	func(rng *rand.Rand, blob string) string {
+		b := []byte(blob)
+		b[rng.Intn(len(b))] = "{}[],:\"0-.e \\"[rng.Intn(13)]
+		return string(b)
+	},
+}
+
+func TestDecodeExtra_Equivalence(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 5000; i++ {
+		b, err := json.Marshal(randomExtra(rng))
+		require.Nil(t, err)
+		blob := extraMutations[rng.Intn(len(extraMutations))](rng, string(b))
+
+		var expected Extra
+		expectedErr := json.Unmarshal([]byte(blob), &expected)
+		actual, err := decodeExtra([]byte(blob))
+		if expectedErr != nil {
+			assert.Equal(t, expectedErr.Error(), err.Error(), blob)
+			continue
+		}
+		require.Nil(t, err, blob)
+		expectedJSON, _ := json.Marshal(expected)
+		actualJSON, _ := json.Marshal(actual)
+		assert.Equal(t, string(expectedJSON), string(actualJSON), blob)
+	}
+
+	// the tracker output is read by the scanner, not by the fallback
+	for i := 0; i < 100; i++ {
+		b, err := json.Marshal(randomExtra(rng))
+		require.Nil(t, err)
+		var actual Extra
+		s := jsonscan.New(b)
+		scanExtra(s, &actual)
+		s.End()
+		require.False(t, s.Failed(), string(b))
+	}
+}
+
+func BenchmarkDecodeExtra(b *testing.B) {
+	rng := rand.New(rand.NewSource(42))
+	extra := randomExtra(rng)
+	for len(extra.Ticks) < 200 {
+		extra.Ticks = append(extra.Ticks, randomExtra(rng).Ticks...)
+	}
+	blob, _ := json.Marshal(extra)
+
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var extra Extra
+			_ = json.Unmarshal(blob, &extra)
+		}
+	})
+	b.Run("scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = decodeExtra(blob)
+		}
+	})
+}
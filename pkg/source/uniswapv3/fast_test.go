@@ -0,0 +1,70 @@
+package uniswapv3
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+func TestPoolSimulator_CalcAmountOutWithParams(t *testing.T) {
+	token0, token1 := "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:  "0x0000000000000000000000000000000000000003",
+		SwapFee:  3000,
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: token0, Decimals: 6}, {Address: token1, Decimals: 18}},
+		Extra:    `{"liquidity":2822091172725,"sqrtPriceX96":93065132232889433968150957834858946,"tick":279543,"ticks":[{"index":-887220,"liquidityGross":2822091172725,"liquidityNet":2822091172725},{"index":273540,"liquidityGross":116315447200034,"liquidityNet":116315447200034},{"index":279120,"liquidityGross":116315447200034,"liquidityNet":-116315447200034},{"index":285480,"liquidityGross":2822091172725,"liquidityNet":-2822091172725}]}`,
+	}, valueobject.ChainIDEthereum)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		in       string
+		inAmount int64
+		out      string
+		fastPath bool
+	}{
+		{token0, 10, token1, true},
+		{token0, 1000, token1, true},
+		{token0, 10000, token1, true},
+		{token0, 100000000, token1, false}, // crosses the tick 279120
+		{token1, 100000000000000000, token0, true},
+		{token1, 1000000000000000000, token0, true},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			in := pool.TokenAmount{Token: tc.in, Amount: big.NewInt(tc.inAmount)}
+
+			_, ok := p.calcAmountOutFast(tc.in == token0, in.Amount)
+			assert.Equal(t, tc.fastPath, ok)
+
+			fast, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+				TokenAmountIn: in,
+				TokenOut:      tc.out,
+				Precision:     pool.PrecisionFast,
+			})
+			require.Nil(t, err)
+			exact, err := pool.CalcAmountOutWithParams(p, pool.CalcAmountOutParams{
+				TokenAmountIn: in,
+				TokenOut:      tc.out,
+				Precision:     pool.PrecisionExact,
+			})
+			require.Nil(t, err)
+
+			// |fast - exact| <= max(exact * bound, 1)
+			diff := new(big.Float).SetInt(new(big.Int).Abs(new(big.Int).Sub(fast.TokenAmountOut.Amount, exact.TokenAmountOut.Amount)))
+			bound := new(big.Float).Mul(new(big.Float).SetInt(exact.TokenAmountOut.Amount), big.NewFloat(pool.FastPrecisionMaxRelativeError))
+			if bound.Cmp(big.NewFloat(1)) < 0 {
+				bound = big.NewFloat(1)
+			}
+			assert.True(t, diff.Cmp(bound) <= 0, "fast %v, exact %v", fast.TokenAmountOut.Amount, exact.TokenAmountOut.Amount)
+		})
+	}
+}
@@ -1,13 +1,11 @@
 package uniswapv3
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
 
-	"github.com/KyberNetwork/logger"
 	coreEntities "github.com/daoleno/uniswap-sdk-core/entities"
 	"github.com/daoleno/uniswapv3-sdk/constants"
 	v3Entities "github.com/daoleno/uniswapv3-sdk/entities"
@@ -16,6 +14,7 @@ import (
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
 )
 
@@ -30,11 +29,13 @@ type PoolSimulator struct {
 	gas     Gas
 	tickMin int
 	tickMax int
+
+	tickIndexes []int // sorted indexes of the initialized ticks, used by the fast path
 }
 
 func NewPoolSimulator(entityPool entity.Pool, chainID valueobject.ChainID) (*PoolSimulator, error) {
-	var extra Extra
-	if err := json.Unmarshal([]byte(entityPool.Extra), &extra); err != nil {
+	extra, err := decodeExtra([]byte(entityPool.Extra))
+	if err != nil {
 		return nil, err
 	}
 
@@ -96,6 +97,11 @@ func NewPoolSimulator(entityPool entity.Pool, chainID valueobject.ChainID) (*Poo
 		return nil, err
 	}
 
+	tickIndexes := make([]int, len(v3Ticks))
+	for i, tick := range v3Ticks {
+		tickIndexes[i] = tick.Index
+	}
+
 	tickMin := v3Ticks[0].Index
 	tickMax := v3Ticks[len(v3Ticks)-1].Index
 
@@ -116,6 +122,8 @@ func NewPoolSimulator(entityPool entity.Pool, chainID valueobject.ChainID) (*Poo
 		gas:     defaultGas,
 		tickMin: tickMin,
 		tickMax: tickMax,
+
+		tickIndexes: tickIndexes,
 	}, nil
 }
 
@@ -194,6 +202,38 @@ func (p *PoolSimulator) CalcAmountOut(
 	return &pool.CalcAmountOutResult{}, fmt.Errorf("tokenInIndex %v or tokenOutIndex %v is not correct", tokenInIndex, tokenOutIndex)
 }
 
+// CalcAmountOutWithParams is CalcAmountOut with an optional fast path, see pool.PrecisionFast.
+// The fast path only handles swaps that stay within the current initialized tick range,
+// other swaps are quoted exactly.
+func (p *PoolSimulator) CalcAmountOutWithParams(params pool.CalcAmountOutParams) (*pool.CalcAmountOutResult, error) {
+	tokenAmountIn, tokenOut := params.TokenAmountIn, params.TokenOut
+	var tokenInIndex = p.GetTokenIndex(tokenAmountIn.Token)
+	var tokenOutIndex = p.GetTokenIndex(tokenOut)
+	if params.IsExact() || tokenInIndex < 0 || tokenOutIndex < 0 {
+		return p.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+
+	amountOut, ok := p.calcAmountOutFast(tokenInIndex == 0, tokenAmountIn.Amount)
+	if !ok {
+		return p.CalcAmountOut(tokenAmountIn, tokenOut)
+	}
+	if amountOut.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, errors.New("amountOut is 0")
+	}
+
+	return &pool.CalcAmountOutResult{
+		TokenAmountOut: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: amountOut,
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenAmountIn.Token,
+			Amount: nil,
+		},
+		Gas: p.gas.Swap,
+	}, nil
+}
+
 func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 	si, ok := params.SwapInfo.(UniV3SwapInfo)
 	if !ok {
@@ -205,6 +245,30 @@ func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
 	p.V3Pool.TickCurrent = si.nextStateTickCurrent
 }
 
+// CloneState returns a copy of the simulator that can be updated independently of p, see pool.IPoolCloner.
+// UpdateBalance changes the state of the sdk pool, so it's copied, the tick data provider is shared.
+func (p *PoolSimulator) CloneState() pool.IPoolSimulator {
+	cloned := *p
+	v3Pool := *p.V3Pool
+	v3Pool.SqrtRatioX96 = new(big.Int).Set(p.V3Pool.SqrtRatioX96)
+	v3Pool.Liquidity = new(big.Int).Set(p.V3Pool.Liquidity)
+	cloned.V3Pool = &v3Pool
+	cloned.Info.Reserves = make([]*big.Int, len(p.Info.Reserves))
+	for i, reserve := range p.Info.Reserves {
+		cloned.Info.Reserves[i] = new(big.Int).Set(reserve)
+	}
+	return &cloned
+}
+
+// Capabilities reports the features of the simulator
+func (p *PoolSimulator) Capabilities() pool.Capabilities {
+	return pool.Capabilities{
+		GasEstimation: true,
+		Clone:         true,
+		FastPrecision: true,
+	}
+}
+
 func (p *PoolSimulator) GetMetaInfo(tokenIn string, tokenOut string) interface{} {
 	return nil
 }
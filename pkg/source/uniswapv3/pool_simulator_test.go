@@ -0,0 +1,193 @@
+package uniswapv3
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	v3Utils "github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/valueobject"
+)
+
+// the same deployment on several chains must quote the same, the chain only goes into the tokens of the sdk
+func TestPoolSimulator_CalcAmountOut_SameAcrossChains(t *testing.T) {
+	token0, token1 := "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"
+	entityPool := entity.Pool{
+		Address:  "0x0000000000000000000000000000000000000003",
+		SwapFee:  3000,
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: token0, Decimals: 6}, {Address: token1, Decimals: 18}},
+		Extra:    `{"liquidity":2822091172725,"sqrtPriceX96":93065132232889433968150957834858946,"tick":279543,"ticks":[{"index":-887220,"liquidityGross":2822091172725,"liquidityNet":2822091172725},{"index":273540,"liquidityGross":116315447200034,"liquidityNet":116315447200034},{"index":279120,"liquidityGross":116315447200034,"liquidityNet":-116315447200034},{"index":285480,"liquidityGross":2822091172725,"liquidityNet":-2822091172725}]}`,
+	}
+
+	testcases := []struct {
+		in       string
+		inAmount string
+		out      string
+		expected string
+	}{
+		{token0, "1000", token1, "1375085809786534"},
+		{token0, "100000000", token1, "35765914137493045379"},
+		{token1, "1000000000000000000", token0, "555499"},
+	}
+
+	for _, chainID := range []valueobject.ChainID{valueobject.ChainIDEthereum, valueobject.ChainIDPolygon,
+		valueobject.ChainIDArbitrumOne, valueobject.ChainIDOptimism, valueobject.ChainIDBSC} {
+		p, err := NewPoolSimulator(entityPool, chainID)
+		require.Nil(t, err)
+		for idx, tc := range testcases {
+			t.Run(fmt.Sprintf("chain %d test %d", chainID, idx), func(t *testing.T) {
+				amountIn, _ := new(big.Int).SetString(tc.inAmount, 10)
+				out, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.in, Amount: amountIn}, tc.out)
+				require.Nil(t, err)
+				assert.Equal(t, tc.expected, out.TokenAmountOut.Amount.String())
+			})
+		}
+	}
+}
+
+// An input exactly moving the price to an initialized tick crosses it, like in the contract: the liquidity is the one
+// beyond the tick and, for zeroForOne, the tick is the one below although the price is the one of the tick. One less
+// stops just before the tick. The same boundary is pinned for algebrav1.
+func TestPoolSimulator_CalcAmountOut_TickBoundary(t *testing.T) {
+	token0, token1 := "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"
+	// a tick above the current one, the last tick can't be reached
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:  "0x0000000000000000000000000000000000000003",
+		SwapFee:  3000,
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: token0, Decimals: 6}, {Address: token1, Decimals: 18}},
+		Extra:    `{"liquidity":2822091172725,"sqrtPriceX96":93065132232889433968150957834858946,"tick":279543,"ticks":[{"index":-887220,"liquidityGross":2822091172725,"liquidityNet":2822091172725},{"index":273540,"liquidityGross":116315447200034,"liquidityNet":116315447200034},{"index":279120,"liquidityGross":116315447200034,"liquidityNet":-116315447200034},{"index":280020,"liquidityGross":1000000000000,"liquidityNet":1000000000000},{"index":285480,"liquidityGross":3822091172725,"liquidityNet":-3822091172725}]}`,
+	}, valueobject.ChainIDEthereum)
+	require.Nil(t, err)
+	sqrtPrice, _ := new(big.Int).SetString("93065132232889433968150957834858946", 10)
+	liquidity := big.NewInt(2822091172725)
+
+	testcases := []struct {
+		tokenIn, tokenOut string
+		tick              int
+		delta             int64 // added to the amount reaching the tick
+		expectedTick      int
+		expectedLiquidity string
+	}{
+		{token0, token1, 279120, 0, 279119, "119137538372759"},
+		{token0, token1, 279120, -1, 279120, "2822091172725"},
+		{token1, token0, 280020, 0, 280020, "3822091172725"},
+		{token1, token0, 280020, -1, 280019, "2822091172725"},
+	}
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			// the amount of the swap step plus its fee, rounded up like in ComputeSwapStep
+			tickPrice, err := v3Utils.GetSqrtRatioAtTick(tc.tick)
+			require.Nil(t, err)
+			var amountIn *big.Int
+			if tc.tokenIn == token0 {
+				amountIn = v3Utils.GetAmount0Delta(tickPrice, sqrtPrice, liquidity, true)
+			} else {
+				amountIn = v3Utils.GetAmount1Delta(sqrtPrice, tickPrice, liquidity, true)
+			}
+			amountIn.Add(amountIn, v3Utils.MulDivRoundingUp(amountIn, big.NewInt(3000), big.NewInt(997000)))
+			amountIn.Add(amountIn, big.NewInt(tc.delta))
+
+			res, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.tokenIn, Amount: amountIn}, tc.tokenOut)
+			require.Nil(t, err)
+
+			si := res.SwapInfo.(UniV3SwapInfo)
+			assert.Equal(t, tc.delta == 0, si.nextStateSqrtRatioX96.Cmp(tickPrice) == 0)
+			assert.Equal(t, tc.expectedTick, si.nextStateTickCurrent)
+			assert.Equal(t, tc.expectedLiquidity, si.nextStateLiquidity.String())
+
+			// the output of the step up to the price reached, rounded down
+			var expectedOut *big.Int
+			if tc.tokenIn == token0 {
+				expectedOut = v3Utils.GetAmount1Delta(si.nextStateSqrtRatioX96, sqrtPrice, liquidity, false)
+			} else {
+				expectedOut = v3Utils.GetAmount0Delta(sqrtPrice, si.nextStateSqrtRatioX96, liquidity, false)
+			}
+			assert.Equal(t, expectedOut, res.TokenAmountOut.Amount)
+		})
+	}
+}
+
+func newCloneTestPool(t testing.TB) (*PoolSimulator, string, string) {
+	token0, token1 := "0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:  "0x0000000000000000000000000000000000000003",
+		SwapFee:  3000,
+		Reserves: entity.PoolReserves{"723924", "36031866872048609640"},
+		Tokens:   []*entity.PoolToken{{Address: token0, Decimals: 6}, {Address: token1, Decimals: 18}},
+		Extra:    `{"liquidity":2822091172725,"sqrtPriceX96":93065132232889433968150957834858946,"tick":279543,"ticks":[{"index":-887220,"liquidityGross":2822091172725,"liquidityNet":2822091172725},{"index":273540,"liquidityGross":116315447200034,"liquidityNet":116315447200034},{"index":279120,"liquidityGross":116315447200034,"liquidityNet":-116315447200034},{"index":285480,"liquidityGross":2822091172725,"liquidityNet":-2822091172725}]}`,
+	}, valueobject.ChainIDEthereum)
+	require.Nil(t, err)
+	return p, token0, token1
+}
+
+func TestPoolSimulator_CloneState(t *testing.T) {
+	p, token0, token1 := newCloneTestPool(t)
+	in := pool.TokenAmount{Token: token0, Amount: big.NewInt(1000000)}
+	expected, err := p.CalcAmountOut(in, token1)
+	require.Nil(t, err)
+
+	cloned, ok := pool.CloneState(p)
+	require.True(t, ok)
+	for i := 0; i < 3; i++ {
+		res, err := cloned.CalcAmountOut(in, token1)
+		require.Nil(t, err)
+		cloned.UpdateBalance(pool.UpdateBalanceParams{
+			TokenAmountIn:  in,
+			TokenAmountOut: *res.TokenAmountOut,
+			SwapInfo:       res.SwapInfo,
+		})
+	}
+	assert.NotEqual(t, p.V3Pool.TickCurrent, cloned.(*PoolSimulator).V3Pool.TickCurrent)
+
+	res, err := p.CalcAmountOut(in, token1)
+	require.Nil(t, err)
+	assert.Equal(t, expected, res)
+	assert.Equal(t, 279543, p.V3Pool.TickCurrent)
+}
+
+// newManyTicksEntityPool returns a pool with 2n initialized ticks around the current tick 0
+func newManyTicksEntityPool(n int) entity.Pool {
+	ticks := make([]string, 0, 2*n)
+	for i := n; i > 0; i-- {
+		ticks = append(ticks, fmt.Sprintf(`{"index":%d,"liquidityGross":1000000,"liquidityNet":1000000}`, -i*60))
+	}
+	for i := 1; i <= n; i++ {
+		ticks = append(ticks, fmt.Sprintf(`{"index":%d,"liquidityGross":1000000,"liquidityNet":-1000000}`, i*60))
+	}
+	return entity.Pool{
+		Address:  "0x0000000000000000000000000000000000000003",
+		SwapFee:  3000,
+		Reserves: entity.PoolReserves{"1000000000000", "1000000000000"},
+		Tokens: []*entity.PoolToken{{Address: "0x0000000000000000000000000000000000000001", Decimals: 18},
+			{Address: "0x0000000000000000000000000000000000000002", Decimals: 18}},
+		Extra: fmt.Sprintf(`{"liquidity":%d,"sqrtPriceX96":79228162514264337593543950336,"tick":0,"ticks":[%s]}`,
+			n*1000000, strings.Join(ticks, ",")),
+	}
+}
+
+func BenchmarkPoolSimulator_CloneState(b *testing.B) {
+	entityPool := newManyTicksEntityPool(1500)
+	p, err := NewPoolSimulator(entityPool, valueobject.ChainIDEthereum)
+	require.Nil(b, err)
+
+	b.Run("NewPoolSimulator", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = NewPoolSimulator(entityPool, valueobject.ChainIDEthereum)
+		}
+	})
+	b.Run("CloneState", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = p.CloneState()
+		}
+	})
+}
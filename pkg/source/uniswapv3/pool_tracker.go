@@ -8,20 +8,25 @@ import (
 	"time"
 
 	"github.com/KyberNetwork/ethrpc"
-	"github.com/KyberNetwork/logger"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/machinebox/graphql"
+	cmap "github.com/orcaman/concurrent-map"
 	"github.com/samber/lo"
 	"github.com/sourcegraph/conc/pool"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/clmath"
 	graphqlPkg "github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/graphql"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 type PoolTracker struct {
 	config        *Config
 	ethrpcClient  *ethrpc.Client
 	graphqlClient *graphql.Client
+
+	// pools whose ticks must be read from TickLens on the next update, see clmath.ReconcileReserves
+	forceRefresh cmap.ConcurrentMap
 }
 
 func NewPoolTracker(
@@ -39,6 +44,7 @@ func NewPoolTracker(
 		config:        initializedCfg,
 		ethrpcClient:  ethrpcClient,
 		graphqlClient: graphqlClient,
+		forceRefresh:  cmap.New(),
 	}, nil
 }
 
@@ -78,6 +84,8 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 		poolTicks []TickResp
 	)
 
+	forceRefresh := clmath.TakeForceRefresh(d.forceRefresh, p.Address)
+
 	g := pool.New().WithContext(ctx)
 	g.Go(func(context.Context) error {
 		var err error
@@ -98,7 +106,9 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 		// Link to issue: https://www.notion.so/kybernetwork/Aggregator-1-20-defect-1caec6062f9d4da0918fc3443e6e1963#0810d1462cc14f0a9465f935c9e641fe
 		// TLDR: Optimism has some pre-genesis Uniswap V3 pool. Subgraph does not have data for these pools
 		// So we have to fetch ticks data from the TickLens smart contract (which is slower).
-		if lo.Contains[string](d.config.preGenesisPoolIDs, p.Address) {
+		// Dexes without subgraph (RPCOnly) always use the TickLens path as well,
+		// and so do the pools whose reserves drifted from the ticks of the subgraph.
+		if d.config.IsRPCOnly() || forceRefresh || lo.Contains[string](d.config.preGenesisPoolIDs, p.Address) {
 			poolTicks, err = d.getPoolTicksFromSC(ctx, p)
 			if err != nil {
 				logger.WithFields(logger.Fields{
@@ -141,11 +151,15 @@ func (d *PoolTracker) GetNewPoolState(ctx context.Context, p entity.Pool) (entit
 		ticks = append(ticks, tick)
 	}
 
+	reserveDrift := clmath.ReconcileReserves(d.config.DexID, p.Address, tickLiquidities(ticks), rpcData.slot0.SqrtPriceX96,
+		rpcData.reserve0, rpcData.reserve1, d.config.ReserveDriftThreshold, d.flagForceRefresh)
+
 	extraBytes, err := json.Marshal(Extra{
 		Liquidity:    rpcData.liquidity,
 		SqrtPriceX96: rpcData.slot0.SqrtPriceX96,
 		Tick:         rpcData.slot0.Tick,
 		Ticks:        ticks,
+		ReserveDrift: reserveDrift,
 	})
 	if err != nil {
 		logger.WithFields(logger.Fields{
@@ -279,3 +293,16 @@ func (d *PoolTracker) getPoolTicks(ctx context.Context, poolAddress string) ([]T
 
 	return ticks, nil
 }
+
+// flagForceRefresh flags the pool to read its ticks from TickLens on the next update
+func (d *PoolTracker) flagForceRefresh(poolAddress string) error {
+	return clmath.FlagForceRefresh(d.forceRefresh, poolAddress, d.config.TickLensAddress)
+}
+
+func tickLiquidities(ticks []Tick) []clmath.TickLiquidity {
+	tickLiquidities := make([]clmath.TickLiquidity, 0, len(ticks))
+	for _, tick := range ticks {
+		tickLiquidities = append(tickLiquidities, clmath.TickLiquidity{Index: tick.Index, LiquidityNet: tick.LiquidityNet})
+	}
+	return tickLiquidities
+}
@@ -10,11 +10,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/samber/lo"
 
-	"github.com/KyberNetwork/logger"
-
 	"github.com/KyberNetwork/ethrpc"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 
 	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util"
 )
@@ -21,6 +21,11 @@ type Metadata struct {
 	LastCreatedAtTimestamp *big.Int `json:"lastCreatedAtTimestamp"`
 }
 
+// cursor of the RPC-only pools list updater
+type RPCMetadata struct {
+	LastBlockNumber uint64 `json:"lastBlockNumber"` // last block whose factory logs have been processed
+}
+
 type Token struct {
 	Address  string `json:"id"`
 	Name     string `json:"name"`
@@ -63,6 +68,9 @@ type Extra struct {
 	SqrtPriceX96 *big.Int `json:"sqrtPriceX96"`
 	Tick         *big.Int `json:"tick"`
 	Ticks        []Tick   `json:"ticks"`
+
+	// drift in percent between the reserves and the amounts locked by the liquidity of the ticks, set by the tracker
+	ReserveDrift float64 `json:"reserveDrift,omitempty"`
 }
 
 type Slot0 struct {
@@ -0,0 +1,28 @@
+package xave
+
+import "math/big"
+
+const DexTypeXave = "xave"
+
+const (
+	// maxTradeIterations is the number of iterations of CurveMath.calculateTrade before it reverts
+	maxTradeIterations = 32
+
+	// rateDecimals is the number of decimals of the oracle rates used by the assimilators
+	rateDecimals = 8
+)
+
+var (
+	DefaultGas = Gas{Swap: 150000}
+
+	// 64.64 fixed point constants of CurveMath
+	one64x64     = new(big.Int).Lsh(big.NewInt(1), 64)
+	maxFee64x64  = big.NewInt(0x4000000000000000) // 0.25
+	maxDiff64x64 = big.NewInt(-0x10C6F7A0B5EE)
+
+	// the trade converges when two iterations are equal once divided by convergencePrecision
+	convergencePrecision = big.NewInt(1e13)
+
+	wad       = big.NewInt(1e18)
+	rateScale = big.NewInt(1e8)
+)
@@ -0,0 +1,17 @@
+package xave
+
+import "errors"
+
+var (
+	ErrInvalidToken            = errors.New("invalid token")
+	ErrInvalidAssets           = errors.New("assets don't match the pool tokens")
+	ErrInvalidRate             = errors.New("invalid oracle rate")
+	ErrZeroAmountIn            = errors.New("amountIn is 0")
+	ErrZeroAmountOut           = errors.New("amountOut is 0")
+	ErrDivisionByZero          = errors.New("division by zero")
+	ErrUpperHalt               = errors.New("Curve/upper-halt")
+	ErrLowerHalt               = errors.New("Curve/lower-halt")
+	ErrSwapInvariantViolation  = errors.New("Curve/swap-invariant-violation")
+	ErrSwapConvergenceFailed   = errors.New("Curve/swap-convergence-failed")
+	ErrInsufficientPoolBalance = errors.New("insufficient pool balance")
+)
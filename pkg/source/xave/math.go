@@ -0,0 +1,274 @@
+package xave
+
+import (
+	"math/big"
+)
+
+// 64.64 fixed point operations of ABDKMath64x64, with the same rounding
+
+// divu is (x << 64) / y for unsigned x and y, rounded down
+func divu(x, y *big.Int) (*big.Int, error) {
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return new(big.Int).Quo(new(big.Int).Lsh(x, 64), y), nil
+}
+
+// mulu is (x * y) >> 64 for a non-negative 64.64 x and an unsigned y, rounded down
+func mulu(x, y *big.Int) *big.Int {
+	if x.Sign() <= 0 || y.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Rsh(new(big.Int).Mul(x, y), 64)
+}
+
+// mul is (x * y) >> 64, arithmetic shift so negative results are rounded toward negative infinity
+func mul(x, y *big.Int) *big.Int {
+	return new(big.Int).Rsh(new(big.Int).Mul(x, y), 64)
+}
+
+// div is (x << 64) / y, truncated toward zero
+func div(x, y *big.Int) (*big.Int, error) {
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return new(big.Int).Quo(new(big.Int).Lsh(x, 64), y), nil
+}
+
+// curveParams is Storage.Curve of the FX pool in 64.64
+type curveParams struct {
+	alpha   *big.Int
+	beta    *big.Int
+	delta   *big.Int
+	epsilon *big.Int
+	lambda  *big.Int
+	weights []*big.Int
+}
+
+// assimilator converts between the raw amounts of a token and its numeraire (USD) amounts in 64.64
+type assimilator struct {
+	rate         *big.Int // oracle rate, in 1e8
+	baseDecimals *big.Int // 10^decimals of the token
+}
+
+// viewNumeraireAmount is ((amount * rate) / 1e8).divu(baseDecimals)
+func (a assimilator) viewNumeraireAmount(amount *big.Int) (*big.Int, error) {
+	return divu(new(big.Int).Quo(new(big.Int).Mul(amount, a.rate), rateScale), a.baseDecimals)
+}
+
+// viewNumeraireBalance is viewNumeraireAmount of the vault balance of the pool
+func (a assimilator) viewNumeraireBalance(balance *big.Int) (*big.Int, error) {
+	if balance.Sign() <= 0 {
+		return new(big.Int), nil
+	}
+	return a.viewNumeraireAmount(balance)
+}
+
+// viewRawAmount is (amount.mulu(baseDecimals) * 1e8) / rate
+func (a assimilator) viewRawAmount(amount *big.Int) *big.Int {
+	return new(big.Int).Quo(new(big.Int).Mul(mulu(amount, a.baseDecimals), rateScale), a.rate)
+}
+
+// viewOriginSwap returns the amount of the output token for originAmount of the input token,
+// as computed by Swaps.viewOriginSwap of the FX pool
+func viewOriginSwap(
+	curve *curveParams,
+	assimilators []assimilator,
+	balances []*big.Int,
+	inputIndex, outputIndex int,
+	originAmount *big.Int,
+) (*big.Int, error) {
+	o, t := assimilators[inputIndex], assimilators[outputIndex]
+	if inputIndex == outputIndex {
+		amount, err := o.viewNumeraireAmount(originAmount)
+		if err != nil {
+			return nil, err
+		}
+		return t.viewRawAmount(amount), nil
+	}
+
+	amount, oGLiq, nGLiq, oBals, nBals, err := viewOriginSwapData(assimilators, balances, inputIndex, outputIndex, originAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err = calculateTrade(curve, oGLiq, nGLiq, oBals, nBals, amount, outputIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	amount = mul(amount, new(big.Int).Sub(one64x64, curve.epsilon))
+
+	return t.viewRawAmount(new(big.Int).Abs(amount)), nil
+}
+
+// viewOriginSwapData returns the numeraire amount of the input and the balances and liquidity before and after
+// the input is added, the output balance being the first guess of calculateTrade
+func viewOriginSwapData(
+	assimilators []assimilator,
+	balances []*big.Int,
+	inputIndex, outputIndex int,
+	originAmount *big.Int,
+) (amount, oGLiq, nGLiq *big.Int, oBals, nBals []*big.Int, err error) {
+	length := len(assimilators)
+	oBals, nBals = make([]*big.Int, length), make([]*big.Int, length)
+	oGLiq, nGLiq = new(big.Int), new(big.Int)
+
+	for i := 0; i < length; i++ {
+		if i != inputIndex {
+			bal, err := assimilators[i].viewNumeraireBalance(balances[i])
+			if err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+			oBals[i], nBals[i] = bal, new(big.Int).Set(bal)
+		} else {
+			if amount, err = assimilators[i].viewNumeraireAmount(originAmount); err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+			bal, err := assimilators[i].viewNumeraireAmount(balances[i])
+			if err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+			oBals[i], nBals[i] = bal, new(big.Int).Add(bal, amount)
+		}
+		oGLiq.Add(oGLiq, oBals[i])
+		nGLiq.Add(nGLiq, nBals[i])
+	}
+
+	nGLiq.Sub(nGLiq, amount)
+	nBals[outputIndex].Sub(nBals[outputIndex], amount)
+
+	return amount, oGLiq, nGLiq, oBals, nBals, nil
+}
+
+// calculateTrade is CurveMath.calculateTrade: iterates on the output amount until the fees of the new balances
+// converge, then checks the halts and the swap invariant
+func calculateTrade(
+	curve *curveParams,
+	oGLiq, nGLiq *big.Int,
+	oBals, nBals []*big.Int,
+	inputAmount *big.Int,
+	outputIndex int,
+) (*big.Int, error) {
+	outputAmount := new(big.Int).Neg(inputAmount)
+
+	omega, err := calculateFee(oGLiq, oBals, curve.beta, curve.delta, curve.weights)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxTradeIterations; i++ {
+		psi, err := calculateFee(nGLiq, nBals, curve.beta, curve.delta, curve.weights)
+		if err != nil {
+			return nil, err
+		}
+
+		prevAmount := outputAmount
+		if omega.Cmp(psi) < 0 {
+			// -(inputAmount + omega - psi)
+			outputAmount = new(big.Int).Sub(new(big.Int).Add(inputAmount, omega), psi)
+		} else {
+			// -(inputAmount + lambda * (omega - psi))
+			outputAmount = new(big.Int).Add(inputAmount, mul(curve.lambda, new(big.Int).Sub(omega, psi)))
+		}
+		outputAmount.Neg(outputAmount)
+
+		nGLiq = new(big.Int).Add(new(big.Int).Add(oGLiq, inputAmount), outputAmount)
+		nBals[outputIndex] = new(big.Int).Add(oBals[outputIndex], outputAmount)
+
+		if new(big.Int).Quo(outputAmount, convergencePrecision).Cmp(new(big.Int).Quo(prevAmount, convergencePrecision)) == 0 {
+			if err := enforceHalts(curve, oGLiq, nGLiq, oBals, nBals, curve.weights); err != nil {
+				return nil, err
+			}
+			if err := enforceSwapInvariant(oGLiq, omega, nGLiq, psi); err != nil {
+				return nil, err
+			}
+			return outputAmount, nil
+		}
+	}
+
+	return nil, ErrSwapConvergenceFailed
+}
+
+func calculateFee(gLiq *big.Int, bals []*big.Int, beta, delta *big.Int, weights []*big.Int) (*big.Int, error) {
+	psi := new(big.Int)
+	for i := range bals {
+		ideal := mul(gLiq, weights[i])
+		fee, err := calculateMicroFee(bals[i], ideal, beta, delta)
+		if err != nil {
+			return nil, err
+		}
+		psi.Add(psi, fee)
+	}
+	return psi, nil
+}
+
+// calculateMicroFee is the fee of a balance out of the [ideal * (1 - beta), ideal * (1 + beta)] range
+func calculateMicroFee(bal, ideal, beta, delta *big.Int) (*big.Int, error) {
+	var feeMargin *big.Int
+	if bal.Cmp(ideal) < 0 {
+		threshold := mul(ideal, new(big.Int).Sub(one64x64, beta))
+		if bal.Cmp(threshold) >= 0 {
+			return new(big.Int), nil
+		}
+		feeMargin = new(big.Int).Sub(threshold, bal)
+	} else {
+		threshold := mul(ideal, new(big.Int).Add(one64x64, beta))
+		if bal.Cmp(threshold) <= 0 {
+			return new(big.Int), nil
+		}
+		feeMargin = new(big.Int).Sub(bal, threshold)
+	}
+
+	fee, err := div(feeMargin, ideal)
+	if err != nil {
+		return nil, err
+	}
+	fee = mul(fee, delta)
+	if fee.Cmp(maxFee64x64) > 0 {
+		fee = new(big.Int).Set(maxFee64x64)
+	}
+	return mul(fee, feeMargin), nil
+}
+
+func enforceHalts(curve *curveParams, oGLiq, nGLiq *big.Int, oBals, nBals []*big.Int, weights []*big.Int) error {
+	for i := range nBals {
+		nIdeal := mul(nGLiq, weights[i])
+		if nBals[i].Cmp(nIdeal) > 0 {
+			upperAlpha := new(big.Int).Add(one64x64, curve.alpha)
+			nHalt := mul(nIdeal, upperAlpha)
+			if nBals[i].Cmp(nHalt) > 0 {
+				oHalt := mul(mul(oGLiq, weights[i]), upperAlpha)
+				if oBals[i].Cmp(oHalt) < 0 {
+					return ErrUpperHalt
+				}
+				if new(big.Int).Sub(nBals[i], nHalt).Cmp(new(big.Int).Sub(oBals[i], oHalt)) > 0 {
+					return ErrUpperHalt
+				}
+			}
+		} else {
+			lowerAlpha := new(big.Int).Sub(one64x64, curve.alpha)
+			nHalt := mul(nIdeal, lowerAlpha)
+			if nBals[i].Cmp(nHalt) < 0 {
+				oHalt := mul(mul(oGLiq, weights[i]), lowerAlpha)
+				if oBals[i].Cmp(oHalt) > 0 {
+					return ErrLowerHalt
+				}
+				if new(big.Int).Sub(nHalt, nBals[i]).Cmp(new(big.Int).Sub(oHalt, oBals[i])) > 0 {
+					return ErrLowerHalt
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func enforceSwapInvariant(oGLiq, omega, nGLiq, psi *big.Int) error {
+	nextUtil := new(big.Int).Sub(nGLiq, psi)
+	prevUtil := new(big.Int).Sub(oGLiq, omega)
+	diff := nextUtil.Sub(nextUtil, prevUtil)
+	if diff.Sign() > 0 || diff.Cmp(maxDiff64x64) >= 0 {
+		return nil
+	}
+	return ErrSwapInvariantViolation
+}
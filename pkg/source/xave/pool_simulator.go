@@ -0,0 +1,150 @@
+package xave
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// PoolSimulator simulates the Balancer v2 FX pools of Xave, swapping forex stables at the oracle rates
+// along the curve of the pool (Swaps.viewOriginSwap)
+type PoolSimulator struct {
+	pool.Pool
+	curve        *curveParams
+	assimilators []assimilator
+	gas          Gas
+}
+
+func NewPoolSimulator(entityPool entity.Pool) (*PoolSimulator, error) {
+	var extra Extra
+	if err := json.Unmarshal([]byte(entityPool.Extra), &extra); err != nil {
+		return nil, err
+	}
+	if len(extra.Assets) != len(entityPool.Tokens) || len(entityPool.Reserves) != len(entityPool.Tokens) {
+		return nil, ErrInvalidAssets
+	}
+
+	curve, err := newCurveParams(extra)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]string, len(entityPool.Tokens))
+	reserves := make([]*big.Int, len(entityPool.Tokens))
+	assimilators := make([]assimilator, len(entityPool.Tokens))
+	for i, token := range entityPool.Tokens {
+		if extra.Assets[i].Rate == nil || extra.Assets[i].Rate.Sign() <= 0 {
+			return nil, ErrInvalidRate
+		}
+		tokens[i] = token.Address
+		if reserves[i] = bignumber.NewBig10(entityPool.Reserves[i]); reserves[i] == nil {
+			return nil, ErrInvalidAssets
+		}
+		assimilators[i] = assimilator{
+			rate:         extra.Assets[i].Rate,
+			baseDecimals: bignumber.TenPowInt(token.Decimals),
+		}
+	}
+
+	return &PoolSimulator{
+		Pool: pool.Pool{
+			Info: pool.PoolInfo{
+				Address:  strings.ToLower(entityPool.Address),
+				SwapFee:  extra.Curve.Epsilon,
+				Exchange: entityPool.Exchange,
+				Type:     entityPool.Type,
+				Tokens:   tokens,
+				Reserves: reserves,
+			},
+		},
+		curve:        curve,
+		assimilators: assimilators,
+		gas:          DefaultGas,
+	}, nil
+}
+
+// newCurveParams converts the parameters from 1e18 back to 64.64, the result may differ from the storage of the pool
+// by a few units of 2^-64 since viewParameters rounds down
+func newCurveParams(extra Extra) (*curveParams, error) {
+	params := []*big.Int{extra.Curve.Alpha, extra.Curve.Beta, extra.Curve.Delta, extra.Curve.Epsilon, extra.Curve.Lambda}
+	for _, asset := range extra.Assets {
+		params = append(params, asset.Weight)
+	}
+
+	converted := make([]*big.Int, len(params))
+	for i, param := range params {
+		if param == nil {
+			return nil, ErrInvalidAssets
+		}
+		v, err := divu(param, wad)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = v
+	}
+
+	return &curveParams{
+		alpha:   converted[0],
+		beta:    converted[1],
+		delta:   converted[2],
+		epsilon: converted[3],
+		lambda:  converted[4],
+		weights: converted[5:],
+	}, nil
+}
+
+func (p *PoolSimulator) CalcAmountOut(
+	tokenAmountIn pool.TokenAmount,
+	tokenOut string,
+) (*pool.CalcAmountOutResult, error) {
+	tokenInIndex, tokenOutIndex := p.GetTokenIndex(tokenAmountIn.Token), p.GetTokenIndex(tokenOut)
+	if tokenInIndex < 0 || tokenOutIndex < 0 {
+		return &pool.CalcAmountOutResult{}, ErrInvalidToken
+	}
+	if tokenAmountIn.Amount == nil || tokenAmountIn.Amount.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, ErrZeroAmountIn
+	}
+
+	amountOut, err := viewOriginSwap(p.curve, p.assimilators, p.Info.Reserves, tokenInIndex, tokenOutIndex, tokenAmountIn.Amount)
+	if err != nil {
+		return &pool.CalcAmountOutResult{}, err
+	}
+	if amountOut.Sign() <= 0 {
+		return &pool.CalcAmountOutResult{}, ErrZeroAmountOut
+	}
+	if amountOut.Cmp(p.Info.Reserves[tokenOutIndex]) >= 0 {
+		return &pool.CalcAmountOutResult{}, ErrInsufficientPoolBalance
+	}
+
+	return &pool.CalcAmountOutResult{
+		TokenAmountOut: &pool.TokenAmount{
+			Token:  tokenOut,
+			Amount: amountOut,
+		},
+		Fee: &pool.TokenAmount{
+			Token:  tokenAmountIn.Token,
+			Amount: nil,
+		},
+		Gas: p.gas.Swap,
+	}, nil
+}
+
+func (p *PoolSimulator) UpdateBalance(params pool.UpdateBalanceParams) {
+	input, output := params.TokenAmountIn, params.TokenAmountOut
+	for i, token := range p.Info.Tokens {
+		if token == input.Token {
+			p.Info.Reserves[i] = new(big.Int).Add(p.Info.Reserves[i], input.Amount)
+		}
+		if token == output.Token {
+			p.Info.Reserves[i] = new(big.Int).Sub(p.Info.Reserves[i], output.Amount)
+		}
+	}
+}
+
+func (p *PoolSimulator) GetMetaInfo(_ string, _ string) interface{} {
+	return nil
+}
@@ -0,0 +1,105 @@
+package xave
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/entity"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/source/pool"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// XSGD/USDC-like curve: alpha 0.8, beta 0.48, fee at halt 0.15, epsilon 0.05%, lambda 0.3
+const testCurve = `{"alpha":800000000000000000,"beta":480000000000000000,"delta":234375000000000001,"epsilon":500000000000000,"lambda":300000000000000000}`
+
+func newTestPool(t *testing.T, reserves entity.PoolReserves, decimals [2]uint8, rates [2]int64) *PoolSimulator {
+	p, err := NewPoolSimulator(entity.Pool{
+		Address:  "0x55bec22f8f6c69137ceaf284d9b441db1b9bfedc",
+		Exchange: "xave",
+		Type:     DexTypeXave,
+		Reserves: reserves,
+		Tokens:   []*entity.PoolToken{{Address: "A", Decimals: decimals[0]}, {Address: "B", Decimals: decimals[1]}},
+		Extra: fmt.Sprintf(`{"curve":%s,"assets":[{"weight":500000000000000000,"rate":%d},{"weight":500000000000000000,"rate":%d}]}`,
+			testCurve, rates[0], rates[1]),
+	})
+	require.Nil(t, err)
+	return p
+}
+
+func TestCalcAmountOut(t *testing.T) {
+	// expected amounts are computed with a port of Swaps.viewOriginSwap, CurveMath and the oracle assimilators
+	balanced := entity.PoolReserves{"1000000000000", "760000000000"}
+	imbalanced := entity.PoolReserves{"2000000000000", "450000000000"}
+
+	testcases := []struct {
+		reserves          entity.PoolReserves
+		decimals          [2]uint8
+		rates             [2]int64
+		in                string
+		inAmount          string
+		out               string
+		expectedOutAmount string
+		expectedErr       error
+	}{
+		// within beta, swaps at the oracle rate minus epsilon
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "1000000", "B", "744627", nil},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "1000000000", "B", "744627500", nil},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "100000000000", "B", "74462750000", nil},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "B", "1000000", "A", "1341610", nil},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "B", "1000000000", "A", "1341610738", nil},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "B", "300000000000", "A", "402483221476", nil},
+		// B is below the beta threshold: fee for selling A, rebate for selling B
+		{imbalanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "1000000", "B", "706969", nil},
+		{imbalanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "1000000000", "B", "706731805", nil},
+		{imbalanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "100000000000", "B", "68436054690", nil},
+		{imbalanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "B", "1000000", "A", "1362853", nil},
+		{imbalanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "B", "1000000000", "A", "1362657969", nil},
+		{imbalanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "B", "300000000000", "A", "403058813975", nil},
+		// different decimals
+		{entity.PoolReserves{"500000000000000000000000", "560000000000"}, [2]uint8{18, 6}, [2]int64{108000000, 100000000}, "A", "12345000000000000000000", "B", "13325933700", nil},
+		{entity.PoolReserves{"500000000000000000000000", "560000000000"}, [2]uint8{18, 6}, [2]int64{108000000, 100000000}, "B", "12345000000", "A", "11424840277777777778277", nil},
+		// reverts
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "1500000000000", "B", "", ErrSwapConvergenceFailed},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "3000000000000", "B", "", ErrUpperHalt},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "0", "B", "", ErrZeroAmountIn},
+		{balanced, [2]uint8{6, 6}, [2]int64{74500000, 100000000}, "A", "1000000", "C", "", ErrInvalidToken},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			p := newTestPool(t, tc.reserves, tc.decimals, tc.rates)
+
+			out, err := p.CalcAmountOut(pool.TokenAmount{Token: tc.in, Amount: bignumber.NewBig10(tc.inAmount)}, tc.out)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, bignumber.NewBig10(tc.expectedOutAmount), out.TokenAmountOut.Amount)
+			assert.Equal(t, tc.out, out.TokenAmountOut.Token)
+		})
+	}
+}
+
+func TestUpdateBalance(t *testing.T) {
+	p := newTestPool(t, entity.PoolReserves{"2000000000000", "450000000000"}, [2]uint8{6, 6}, [2]int64{74500000, 100000000})
+
+	for _, expected := range []string{"68436054690", "64361308256"} {
+		amountIn := pool.TokenAmount{Token: "A", Amount: big.NewInt(100000000000)}
+		out, err := p.CalcAmountOut(amountIn, "B")
+		require.Nil(t, err)
+		assert.Equal(t, bignumber.NewBig10(expected), out.TokenAmountOut.Amount)
+
+		p.UpdateBalance(pool.UpdateBalanceParams{
+			TokenAmountIn:  amountIn,
+			TokenAmountOut: *out.TokenAmountOut,
+			Fee:            *out.Fee,
+		})
+	}
+
+	assert.Equal(t, []*big.Int{big.NewInt(2200000000000), big.NewInt(317202637054)}, p.Info.Reserves)
+}
@@ -0,0 +1,28 @@
+package xave
+
+import "math/big"
+
+type Gas struct {
+	Swap int64
+}
+
+// Extra is the state of an FX pool besides the vault balances, which are the reserves of the pool
+type Extra struct {
+	Curve  Curve   `json:"curve"`
+	Assets []Asset `json:"assets"` // in the order of the pool tokens
+}
+
+// Curve is the result of FXPool.viewParameters, in 1e18
+type Curve struct {
+	Alpha   *big.Int `json:"alpha"`
+	Beta    *big.Int `json:"beta"`
+	Delta   *big.Int `json:"delta"`
+	Epsilon *big.Int `json:"epsilon"`
+	Lambda  *big.Int `json:"lambda"`
+}
+
+// Asset is the state of the assimilator of a token
+type Asset struct {
+	Weight *big.Int `json:"weight"` // in 1e18
+	Rate   *big.Int `json:"rate"`   // USD rate of the oracle, in 1e8
+}
@@ -39,3 +39,9 @@ func NewBig(s string) (res *big.Int) {
 	res, _ = new(big.Int).SetString(s, 0)
 	return res
 }
+
+// ToFloat64 returns the nearest float64 of x
+func ToFloat64(x *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(x).Float64()
+	return f
+}
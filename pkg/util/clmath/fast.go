@@ -0,0 +1,65 @@
+package clmath
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+// FastTickBoundaryMargin is the relative distance to the next initialized tick under which AmountOutWithinTickFast
+// gives up, far bigger than the float64 rounding so the closed form never silently crosses a tick
+const FastTickBoundaryMargin = 1e-9
+
+var q96Float = math.Ldexp(1, 96)
+
+// AmountInLessFee returns amountIn less a fee in hundredths of a bip, rounded down like in ComputeSwapStep
+// (it matters for dust amounts)
+func AmountInLessFee(amountIn *big.Int, feePips int64) *big.Int {
+	return new(big.Int).Div(
+		new(big.Int).Mul(amountIn, big.NewInt(1e6-feePips)),
+		big.NewInt(1e6),
+	)
+}
+
+// AmountOutWithinTickFast quotes an exact input swap of amountInLessFee with the closed form of the single tick range
+// between the current price and boundaryTick, the first initialized tick the swap could cross, in float64.
+// ok is false if the swap would reach boundaryTick, the exact path must be used then.
+func AmountOutWithinTickFast(
+	zeroForOne bool,
+	sqrtPriceX96, liquidity, amountInLessFee *big.Int,
+	boundaryTick int,
+) (amountOut *big.Int, ok bool) {
+	if amountInLessFee.Sign() < 0 || liquidity.Sign() <= 0 {
+		return nil, false
+	}
+
+	sqrtPrice := bignumber.ToFloat64(sqrtPriceX96) / q96Float
+	boundarySqrtPrice := math.Pow(1.0001, float64(boundaryTick)/2)
+	l := bignumber.ToFloat64(liquidity)
+	in := bignumber.ToFloat64(amountInLessFee)
+
+	var out float64
+	if zeroForOne {
+		nextSqrtPrice := l * sqrtPrice / (l + in*sqrtPrice)
+		if nextSqrtPrice <= boundarySqrtPrice*(1+FastTickBoundaryMargin) {
+			return nil, false
+		}
+		// = l * (sqrtPrice - nextSqrtPrice), without the cancellation of two close floats
+		out = in * sqrtPrice * sqrtPrice * l / (l + in*sqrtPrice)
+	} else {
+		nextSqrtPrice := sqrtPrice + in/l
+		if nextSqrtPrice >= boundarySqrtPrice*(1-FastTickBoundaryMargin) {
+			return nil, false
+		}
+		// = l * (nextSqrtPrice - sqrtPrice) / (sqrtPrice * nextSqrtPrice)
+		out = in / (sqrtPrice * nextSqrtPrice)
+	}
+
+	if math.IsNaN(out) || math.IsInf(out, 0) || out < 0 {
+		return nil, false
+	}
+
+	amountOut, _ = big.NewFloat(math.Floor(out)).Int(nil)
+	return amountOut, true
+}
@@ -0,0 +1,53 @@
+package clmath
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/daoleno/uniswapv3-sdk/constants"
+	"github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/bignumber"
+)
+
+func TestAmountOutWithinTickFast(t *testing.T) {
+	liquidity := bignumber.NewBig10("954140562773509808028")
+	sqrtPrice := sqrtRatioAtTick(t, 1199)
+
+	testcases := []struct {
+		zeroForOne   bool
+		amountIn     string
+		boundaryTick int
+		ok           bool
+	}{
+		{true, "1000", 480, true},
+		{true, "10000000000000000000", 480, true},
+		{false, "10000000000000000000", 1200, false}, // reaches the tick 1200
+		{false, "1000000000000", 1200, true},
+		{true, "100000000000000000000000", 480, false},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			amountIn := bignumber.NewBig10(tc.amountIn)
+			amountInLessFee := AmountInLessFee(amountIn, 3000)
+			out, ok := AmountOutWithinTickFast(tc.zeroForOne, sqrtPrice, liquidity, amountInLessFee, tc.boundaryTick)
+			require.Equal(t, tc.ok, ok)
+			if !ok {
+				return
+			}
+
+			// the exact step to the boundary, which isn't reached
+			_, stepIn, stepOut, stepFee, err := utils.ComputeSwapStep(sqrtPrice, sqrtRatioAtTick(t, tc.boundaryTick),
+				liquidity, amountIn, constants.FeeMedium)
+			require.Nil(t, err)
+			require.Equal(t, amountIn, new(big.Int).Add(stepIn, stepFee))
+			assert.InDelta(t, bignumber.ToFloat64(stepOut), bignumber.ToFloat64(out),
+				math.Max(bignumber.ToFloat64(stepOut)*1e-12, 1))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package clmath
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/daoleno/uniswapv3-sdk/utils"
+)
+
+var (
+	ErrInvalidTickLiquidity = errors.New("liquidity of the ticks is negative")
+)
+
+// TickLiquidity is the liquidity delta of an initialized tick, as tracked by every concentrated liquidity source
+type TickLiquidity struct {
+	Index        int
+	LiquidityNet *big.Int
+}
+
+// AmountsForLiquidity returns the amounts of token0 and token1 of liquidity in [sqrtRatioAX96, sqrtRatioBX96]
+// at the current price, rounded down (LiquidityAmounts.getAmountsForLiquidity)
+func AmountsForLiquidity(sqrtPriceX96, sqrtRatioAX96, sqrtRatioBX96, liquidity *big.Int) (*big.Int, *big.Int) {
+	if sqrtRatioAX96.Cmp(sqrtRatioBX96) > 0 {
+		sqrtRatioAX96, sqrtRatioBX96 = sqrtRatioBX96, sqrtRatioAX96
+	}
+
+	switch {
+	case sqrtPriceX96.Cmp(sqrtRatioAX96) <= 0:
+		return utils.GetAmount0Delta(sqrtRatioAX96, sqrtRatioBX96, liquidity, false), new(big.Int)
+	case sqrtPriceX96.Cmp(sqrtRatioBX96) < 0:
+		return utils.GetAmount0Delta(sqrtPriceX96, sqrtRatioBX96, liquidity, false),
+			utils.GetAmount1Delta(sqrtRatioAX96, sqrtPriceX96, liquidity, false)
+	default:
+		return new(big.Int), utils.GetAmount1Delta(sqrtRatioAX96, sqrtRatioBX96, liquidity, false)
+	}
+}
+
+// AmountsInTicks returns the amounts of token0 and token1 locked by the liquidity of the ticks at the current price,
+// summed over the ranges between consecutive initialized ticks
+func AmountsInTicks(ticks []TickLiquidity, sqrtPriceX96 *big.Int) (*big.Int, *big.Int, error) {
+	return AmountsInTickRange(ticks, sqrtPriceX96, math.MinInt, math.MaxInt)
+}
+
+// AmountsInTickRange is AmountsInTicks restricted to the ticks in [lowerTick, upperTick]
+func AmountsInTickRange(ticks []TickLiquidity, sqrtPriceX96 *big.Int, lowerTick, upperTick int) (*big.Int, *big.Int, error) {
+	sorted := make([]TickLiquidity, len(ticks))
+	copy(sorted, ticks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	amount0, amount1 := new(big.Int), new(big.Int)
+	liquidity := new(big.Int)
+	for i := 0; i < len(sorted)-1; i++ {
+		liquidity.Add(liquidity, sorted[i].LiquidityNet)
+		if liquidity.Sign() < 0 {
+			return nil, nil, ErrInvalidTickLiquidity
+		}
+
+		lower, upper := sorted[i].Index, sorted[i+1].Index
+		if lower < lowerTick {
+			lower = lowerTick
+		}
+		if upper > upperTick {
+			upper = upperTick
+		}
+		if liquidity.Sign() == 0 || lower >= upper {
+			continue
+		}
+
+		sqrtRatioAX96, err := utils.GetSqrtRatioAtTick(lower)
+		if err != nil {
+			return nil, nil, err
+		}
+		sqrtRatioBX96, err := utils.GetSqrtRatioAtTick(upper)
+		if err != nil {
+			return nil, nil, err
+		}
+		a0, a1 := AmountsForLiquidity(sqrtPriceX96, sqrtRatioAX96, sqrtRatioBX96, liquidity)
+		amount0.Add(amount0, a0)
+		amount1.Add(amount1, a1)
+	}
+
+	return amount0, amount1, nil
+}
+
+// DriftPercent returns how far balance is from expected, in percent of the largest of them
+func DriftPercent(expected, balance *big.Int) float64 {
+	largest := expected
+	if balance.Cmp(largest) > 0 {
+		largest = balance
+	}
+	if largest.Sign() == 0 {
+		return 0
+	}
+
+	diff := new(big.Int).Abs(new(big.Int).Sub(balance, expected))
+	drift, _ := new(big.Float).Quo(new(big.Float).SetInt(diff), new(big.Float).SetInt(largest)).Float64()
+	return drift * 100
+}
@@ -0,0 +1,106 @@
+package clmath
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/daoleno/uniswapv3-sdk/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sqrtRatioAtTick(t *testing.T, tick int) *big.Int {
+	sqrtRatio, err := utils.GetSqrtRatioAtTick(tick)
+	require.Nil(t, err)
+	return sqrtRatio
+}
+
+func position(lower, upper int, liquidity int64) []TickLiquidity {
+	return []TickLiquidity{
+		{Index: lower, LiquidityNet: big.NewInt(liquidity)},
+		{Index: upper, LiquidityNet: big.NewInt(-liquidity)},
+	}
+}
+
+func TestAmountsForLiquidity(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	sqrtA, sqrtB := sqrtRatioAtTick(t, -600), sqrtRatioAtTick(t, 600)
+
+	testcases := []struct {
+		tick            int
+		expectedAmount0 *big.Int
+		expectedAmount1 *big.Int
+	}{
+		// below the range: only token0
+		{-1200, utils.GetAmount0Delta(sqrtA, sqrtB, liquidity, false), big.NewInt(0)},
+		{0, utils.GetAmount0Delta(sqrtRatioAtTick(t, 0), sqrtB, liquidity, false), utils.GetAmount1Delta(sqrtA, sqrtRatioAtTick(t, 0), liquidity, false)},
+		// above the range: only token1
+		{1200, big.NewInt(0), utils.GetAmount1Delta(sqrtA, sqrtB, liquidity, false)},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			amount0, amount1 := AmountsForLiquidity(sqrtRatioAtTick(t, tc.tick), sqrtA, sqrtB, liquidity)
+			assert.Equal(t, tc.expectedAmount0, amount0)
+			assert.Equal(t, tc.expectedAmount1, amount1)
+
+			// the bounds can be given in any order
+			amount0, amount1 = AmountsForLiquidity(sqrtRatioAtTick(t, tc.tick), sqrtB, sqrtA, liquidity)
+			assert.Equal(t, tc.expectedAmount0, amount0)
+			assert.Equal(t, tc.expectedAmount1, amount1)
+		})
+	}
+}
+
+func TestAmountsInTicks(t *testing.T) {
+	sqrtPrice := sqrtRatioAtTick(t, 30)
+
+	// two overlapping positions, ticks merged and unsorted
+	ticks := []TickLiquidity{
+		{Index: 600, LiquidityNet: big.NewInt(-1e18)},
+		{Index: -600, LiquidityNet: big.NewInt(1e18)},
+		{Index: -60, LiquidityNet: big.NewInt(5e17)},
+		{Index: 120, LiquidityNet: big.NewInt(-5e17)},
+	}
+	amount0, amount1, err := AmountsInTicks(ticks, sqrtPrice)
+	require.Nil(t, err)
+
+	// the ranges are rounded down separately, so the merged ticks may lock a few wei less than the positions
+	expected0, expected1 := new(big.Int), new(big.Int)
+	for _, p := range [][]TickLiquidity{position(-600, 600, 1e18), position(-60, 120, 5e17)} {
+		a0, a1 := AmountsForLiquidity(sqrtPrice, sqrtRatioAtTick(t, p[0].Index), sqrtRatioAtTick(t, p[1].Index), p[0].LiquidityNet)
+		expected0.Add(expected0, a0)
+		expected1.Add(expected1, a1)
+	}
+	assert.InDelta(t, float64(expected0.Int64()), float64(amount0.Int64()), 4)
+	assert.InDelta(t, float64(expected1.Int64()), float64(amount1.Int64()), 4)
+
+	// only the part of the liquidity above the current tick holds token0
+	amount0Above, amount1Above, err := AmountsInTickRange(ticks, sqrtPrice, 30, 887272)
+	require.Nil(t, err)
+	assert.Equal(t, 0, amount1Above.Sign())
+	assert.InDelta(t, float64(amount0.Int64()), float64(amount0Above.Int64()), 4)
+
+	_, _, err = AmountsInTicks([]TickLiquidity{{Index: 0, LiquidityNet: big.NewInt(-1)}, {Index: 60, LiquidityNet: big.NewInt(1)}}, sqrtPrice)
+	assert.ErrorIs(t, err, ErrInvalidTickLiquidity)
+}
+
+func TestDriftPercent(t *testing.T) {
+	testcases := []struct {
+		expected, balance int64
+		drift             float64
+	}{
+		{100, 100, 0},
+		{100, 110, 100.0 / 11},
+		{110, 100, 100.0 / 11},
+		{0, 100, 100},
+		{0, 0, 0},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			assert.InDelta(t, tc.drift, DriftPercent(big.NewInt(tc.expected), big.NewInt(tc.balance)), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package clmath
+
+import (
+	"errors"
+	"math"
+	"math/big"
+
+	cmap "github.com/orcaman/concurrent-map"
+
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
+)
+
+var (
+	ErrNoTickLens = errors.New("no TickLens to read the ticks from")
+)
+
+// ReconcileReserves returns the drift in percent between the balances of the pool and the amounts locked by the
+// liquidity of its ticks, the largest of both tokens. The balances also hold the uncollected fees, so a small drift
+// is expected, a large one means that the ticks or the balances are wrong: the pool is passed to flag, usually to
+// read its ticks from TickLens on the next update, see FlagForceRefresh. A threshold of 0 disables the flag.
+func ReconcileReserves(
+	dexID string,
+	poolAddress string,
+	ticks []TickLiquidity,
+	sqrtPriceX96, reserve0, reserve1 *big.Int,
+	threshold float64,
+	flag func(poolAddress string) error,
+) float64 {
+	if sqrtPriceX96 == nil || reserve0 == nil || reserve1 == nil {
+		return 0
+	}
+
+	amount0, amount1, err := AmountsInTicks(ticks, sqrtPriceX96)
+	if err != nil {
+		logger.WithFields(logger.Fields{
+			"poolAddress": poolAddress,
+			"error":       err,
+		}).Warnf("[%v] failed to compute the amounts locked in the ticks", dexID)
+		flagPool(dexID, poolAddress, flag)
+		return 0
+	}
+
+	drift := math.Max(DriftPercent(amount0, reserve0), DriftPercent(amount1, reserve1))
+	if threshold > 0 && drift > threshold {
+		logger.WithFields(logger.Fields{
+			"poolAddress": poolAddress,
+			"drift":       drift,
+			"amount0":     amount0.String(),
+			"amount1":     amount1.String(),
+			"reserve0":    reserve0.String(),
+			"reserve1":    reserve1.String(),
+		}).Warnf("[%v] reserves drifted from the tracked liquidity, forcing a full refresh", dexID)
+		flagPool(dexID, poolAddress, flag)
+	}
+
+	return drift
+}
+
+func flagPool(dexID, poolAddress string, flag func(poolAddress string) error) {
+	if err := flag(poolAddress); err != nil {
+		logger.WithFields(logger.Fields{
+			"poolAddress": poolAddress,
+			"error":       err,
+		}).Warnf("[%v] failed to flag the pool for a full refresh", dexID)
+	}
+}
+
+// FlagForceRefresh flags the pool of forceRefresh for a full refresh, see TakeForceRefresh.
+// Returns ErrNoTickLens if the ticks can't be read again, the pool isn't flagged then.
+func FlagForceRefresh(forceRefresh cmap.ConcurrentMap, poolAddress, tickLensAddress string) error {
+	if tickLensAddress == "" {
+		return ErrNoTickLens
+	}
+	if forceRefresh != nil {
+		forceRefresh.Set(poolAddress, true)
+	}
+	return nil
+}
+
+// TakeForceRefresh returns true, once, if the pool has been flagged for a full refresh
+func TakeForceRefresh(forceRefresh cmap.ConcurrentMap, poolAddress string) bool {
+	if forceRefresh == nil {
+		return false
+	}
+	_, ok := forceRefresh.Pop(poolAddress)
+	return ok
+}
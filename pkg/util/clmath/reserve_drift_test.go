@@ -0,0 +1,81 @@
+package clmath
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/daoleno/uniswapv3-sdk/utils"
+	cmap "github.com/orcaman/concurrent-map"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileReserves(t *testing.T) {
+	liquidity := big.NewInt(1e18)
+	ticks := []TickLiquidity{
+		{Index: -600, LiquidityNet: liquidity},
+		{Index: 600, LiquidityNet: new(big.Int).Neg(liquidity)},
+	}
+	sqrtPriceX96, err := utils.GetSqrtRatioAtTick(0)
+	require.Nil(t, err)
+	amount0, amount1, err := AmountsInTicks(ticks, sqrtPriceX96)
+	require.Nil(t, err)
+
+	var flagged []string
+	flag := func(poolAddress string) error {
+		flagged = append(flagged, poolAddress)
+		return nil
+	}
+
+	// consistent reserves
+	assert.Equal(t, float64(0), ReconcileReserves("dex", "pool", ticks, sqrtPriceX96, amount0, amount1, 10, flag))
+	assert.Empty(t, flagged)
+
+	// a small drift, e.g. uncollected fees, doesn't flag the pool
+	reserve0 := new(big.Int).Div(new(big.Int).Mul(amount0, big.NewInt(105)), big.NewInt(100))
+	drift := ReconcileReserves("dex", "pool", ticks, sqrtPriceX96, reserve0, amount1, 10, flag)
+	assert.InDelta(t, 100.0*5/105, drift, 1e-6)
+	assert.Empty(t, flagged)
+
+	// token1 balance doubled
+	reserve1 := new(big.Int).Mul(amount1, big.NewInt(2))
+	drift = ReconcileReserves("dex", "pool", ticks, sqrtPriceX96, amount0, reserve1, 10, flag)
+	assert.InDelta(t, 50, drift, 1e-6)
+	assert.Equal(t, []string{"pool"}, flagged)
+
+	// only reported without a threshold, or if the flag fails
+	drift = ReconcileReserves("dex", "pool", ticks, sqrtPriceX96, amount0, reserve1, 0, flag)
+	assert.InDelta(t, 50, drift, 1e-6)
+	assert.Equal(t, []string{"pool"}, flagged)
+	drift = ReconcileReserves("dex", "pool", ticks, sqrtPriceX96, amount0, reserve1, 10, func(string) error {
+		return errors.New("flag failed")
+	})
+	assert.InDelta(t, 50, drift, 1e-6)
+
+	// ticks with a negative liquidity
+	negative := []TickLiquidity{
+		{Index: -600, LiquidityNet: new(big.Int).Neg(liquidity)},
+		{Index: 600, LiquidityNet: liquidity},
+	}
+	assert.Equal(t, float64(0), ReconcileReserves("dex", "other", negative, sqrtPriceX96, amount0, amount1, 10, flag))
+	assert.Equal(t, []string{"pool", "other"}, flagged)
+
+	// unknown balances
+	assert.Equal(t, float64(0), ReconcileReserves("dex", "pool", ticks, sqrtPriceX96, nil, amount1, 10, flag))
+}
+
+func TestFlagForceRefresh(t *testing.T) {
+	forceRefresh := cmap.New()
+
+	assert.Nil(t, FlagForceRefresh(forceRefresh, "pool", "0xticklens"))
+	assert.True(t, TakeForceRefresh(forceRefresh, "pool"))
+	// the flag is consumed by the refresh
+	assert.False(t, TakeForceRefresh(forceRefresh, "pool"))
+
+	// without TickLens, the pool can't be refreshed
+	assert.ErrorIs(t, FlagForceRefresh(forceRefresh, "pool", ""), ErrNoTickLens)
+	assert.False(t, TakeForceRefresh(forceRefresh, "pool"))
+
+	assert.False(t, TakeForceRefresh(nil, "pool"))
+}
@@ -0,0 +1,319 @@
+package clmath
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/daoleno/uniswapv3-sdk/entities"
+)
+
+var (
+	ErrTickNotFound = errors.New("tick not found")
+)
+
+// SparseTickList is a tick data provider storing the initialized ticks by word, the 256 tick spacings of a bitmap word
+// of the contracts, each word holding its ticks sorted. A tick is updated in O(log k), k being the number of ticks of
+// its word, instead of patching a sorted list of all the ticks. The lookups are the ones of entities.TickListDataProvider.
+type SparseTickList struct {
+	tickSpacing int
+	words       map[int][]entities.Tick
+	wordIndexes []int // sorted positions of the non-empty words
+}
+
+// NewSparseTickList validates the ticks like entities.NewTickListDataProvider and stores them by word
+func NewSparseTickList(ticks []entities.Tick, tickSpacing int) (*SparseTickList, error) {
+	if err := entities.ValidateList(ticks, tickSpacing); err != nil {
+		return nil, err
+	}
+
+	l := &SparseTickList{tickSpacing: tickSpacing, words: map[int][]entities.Tick{}}
+	for _, tick := range ticks {
+		wordPos := l.wordPos(tick.Index)
+		if _, ok := l.words[wordPos]; !ok {
+			l.wordIndexes = append(l.wordIndexes, wordPos)
+		}
+		l.words[wordPos] = append(l.words[wordPos], tick)
+	}
+	return l, nil
+}
+
+// wordPos is the position of the bitmap word of the tick, floor(floor(tick / tickSpacing) / 256)
+func (l *SparseTickList) wordPos(tick int) int {
+	compressed := tick / l.tickSpacing
+	if tick < 0 && tick%l.tickSpacing != 0 {
+		compressed--
+	}
+	return compressed >> 8
+}
+
+// Ticks returns all the ticks, sorted
+func (l *SparseTickList) Ticks() []entities.Tick {
+	var ticks []entities.Tick
+	for _, wordPos := range l.wordIndexes {
+		ticks = append(ticks, l.words[wordPos]...)
+	}
+	return ticks
+}
+
+// WithTick returns a copy of the list with SetTick applied, l isn't changed. It's copy on write: the copy shares the
+// words of l but the one of tick, which is copied, so an update only costs the copy of its word and of the positions
+// of the words, instead of the copy of all the ticks.
+func (l *SparseTickList) WithTick(tick entities.Tick) (*SparseTickList, error) {
+	if tick.Index%l.tickSpacing != 0 {
+		return nil, entities.ErrInvalidTickSpacing
+	}
+
+	updated := &SparseTickList{
+		tickSpacing: l.tickSpacing,
+		words:       make(map[int][]entities.Tick, len(l.words)+1),
+		wordIndexes: append(make([]int, 0, len(l.wordIndexes)+1), l.wordIndexes...),
+	}
+	for wordPos, word := range l.words {
+		updated.words[wordPos] = word
+	}
+	wordPos := l.wordPos(tick.Index)
+	if word, ok := l.words[wordPos]; ok {
+		updated.words[wordPos] = append(make([]entities.Tick, 0, len(word)+1), word...)
+	}
+
+	if err := updated.SetTick(tick); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetTick adds or replaces a tick, or removes it if its LiquidityGross is 0. The net liquidity of the ticks isn't
+// checked, a position updates two ticks one after the other. The list is changed in place.
+func (l *SparseTickList) SetTick(tick entities.Tick) error {
+	if tick.Index%l.tickSpacing != 0 {
+		return entities.ErrInvalidTickSpacing
+	}
+
+	wordPos := l.wordPos(tick.Index)
+	word := l.words[wordPos]
+	i := sort.Search(len(word), func(i int) bool { return word[i].Index >= tick.Index })
+	found := i < len(word) && word[i].Index == tick.Index
+
+	switch {
+	case tick.LiquidityGross == nil || tick.LiquidityGross.Sign() == 0:
+		if !found {
+			return nil
+		}
+		word = append(word[:i], word[i+1:]...)
+	case found:
+		word[i] = tick
+	default:
+		word = append(word, entities.Tick{})
+		copy(word[i+1:], word[i:])
+		word[i] = tick
+	}
+
+	j := sort.SearchInts(l.wordIndexes, wordPos)
+	hasWord := j < len(l.wordIndexes) && l.wordIndexes[j] == wordPos
+	switch {
+	case len(word) == 0:
+		delete(l.words, wordPos)
+		if hasWord {
+			l.wordIndexes = append(l.wordIndexes[:j], l.wordIndexes[j+1:]...)
+		}
+	case !hasWord:
+		l.words[wordPos] = word
+		l.wordIndexes = append(l.wordIndexes, 0)
+		copy(l.wordIndexes[j+1:], l.wordIndexes[j:])
+		l.wordIndexes[j] = wordPos
+	default:
+		l.words[wordPos] = word
+	}
+	return nil
+}
+
+// Len returns the number of ticks
+func (l *SparseTickList) Len() int {
+	n := 0
+	for _, word := range l.words {
+		n += len(word)
+	}
+	return n
+}
+
+// Smallest returns the smallest tick, false if the list is empty
+func (l *SparseTickList) Smallest() (entities.Tick, bool) {
+	if len(l.wordIndexes) == 0 {
+		return entities.EmptyTick, false
+	}
+	return l.words[l.wordIndexes[0]][0], true
+}
+
+// Largest returns the largest tick, false if the list is empty
+func (l *SparseTickList) Largest() (entities.Tick, bool) {
+	if len(l.wordIndexes) == 0 {
+		return entities.EmptyTick, false
+	}
+	word := l.words[l.wordIndexes[len(l.wordIndexes)-1]]
+	return word[len(word)-1], true
+}
+
+// LastAtOrBelow returns the largest tick <= tick, false if there is none
+func (l *SparseTickList) LastAtOrBelow(tick int) (entities.Tick, bool) {
+	if smallest, ok := l.Smallest(); !ok || tick < smallest.Index {
+		return entities.EmptyTick, false
+	}
+	return l.lastAtOrBelow(tick), true
+}
+
+// FirstAbove returns the smallest tick > tick, false if there is none
+func (l *SparseTickList) FirstAbove(tick int) (entities.Tick, bool) {
+	if largest, ok := l.Largest(); !ok || tick >= largest.Index {
+		return entities.EmptyTick, false
+	}
+	return l.firstAbove(tick), true
+}
+
+// CountAtOrBelow returns the number of ticks <= tick
+func (l *SparseTickList) CountAtOrBelow(tick int) int {
+	wordPos := l.wordPos(tick)
+	j := sort.SearchInts(l.wordIndexes, wordPos)
+	n := 0
+	for _, pos := range l.wordIndexes[:j] {
+		n += len(l.words[pos])
+	}
+	word := l.words[wordPos]
+	return n + sort.Search(len(word), func(i int) bool { return word[i].Index > tick })
+}
+
+// Ascend calls fn with the ticks >= from in ascending order, until fn returns false
+func (l *SparseTickList) Ascend(from int, fn func(tick entities.Tick) bool) {
+	wordPos := l.wordPos(from)
+	for _, pos := range l.wordIndexes[sort.SearchInts(l.wordIndexes, wordPos):] {
+		word := l.words[pos]
+		i := 0
+		if pos == wordPos {
+			i = sort.Search(len(word), func(i int) bool { return word[i].Index >= from })
+		}
+		for ; i < len(word); i++ {
+			if !fn(word[i]) {
+				return
+			}
+		}
+	}
+}
+
+// GetTick returns the tick at index. Unlike entities.TickListDataProvider, which returns the closest tick below,
+// ErrTickNotFound is returned if it isn't initialized.
+func (l *SparseTickList) GetTick(index int) (entities.Tick, error) {
+	smallest, ok := l.Smallest()
+	if !ok {
+		return entities.EmptyTick, entities.ErrEmptyTickList
+	}
+	if index < smallest.Index {
+		return entities.EmptyTick, entities.ErrBelowSmallest
+	}
+
+	word := l.words[l.wordPos(index)]
+	i := sort.Search(len(word), func(i int) bool { return word[i].Index >= index })
+	if i == len(word) || word[i].Index != index {
+		return entities.EmptyTick, ErrTickNotFound
+	}
+	return word[i], nil
+}
+
+// lastAtOrBelow returns the largest tick <= tick, there must be one
+func (l *SparseTickList) lastAtOrBelow(tick int) entities.Tick {
+	wordPos := l.wordPos(tick)
+	word := l.words[wordPos]
+	if i := sort.Search(len(word), func(i int) bool { return word[i].Index > tick }); i > 0 {
+		return word[i-1]
+	}
+	j := sort.SearchInts(l.wordIndexes, wordPos)
+	word = l.words[l.wordIndexes[j-1]]
+	return word[len(word)-1]
+}
+
+// firstAbove returns the smallest tick > tick, there must be one
+func (l *SparseTickList) firstAbove(tick int) entities.Tick {
+	wordPos := l.wordPos(tick)
+	word := l.words[wordPos]
+	if i := sort.Search(len(word), func(i int) bool { return word[i].Index > tick }); i < len(word) {
+		return word[i]
+	}
+	j := sort.SearchInts(l.wordIndexes, wordPos+1)
+	return l.words[l.wordIndexes[j]][0]
+}
+
+// nextInitializedTick is entities.NextInitializedTick
+func (l *SparseTickList) nextInitializedTick(tick int, lte bool) (entities.Tick, error) {
+	smallest, ok := l.Smallest()
+	if !ok {
+		return entities.EmptyTick, entities.ErrEmptyTickList
+	}
+	largest, _ := l.Largest()
+
+	if lte {
+		if tick < smallest.Index {
+			return entities.EmptyTick, entities.ErrBelowSmallest
+		}
+		if tick >= largest.Index {
+			return largest, nil
+		}
+		return l.lastAtOrBelow(tick), nil
+	}
+
+	if tick >= largest.Index {
+		return entities.EmptyTick, entities.ErrAtOrAboveLargest
+	}
+	if tick < smallest.Index {
+		return smallest, nil
+	}
+	return l.firstAbove(tick), nil
+}
+
+// NextInitializedTickWithinOneWord is the same as the one of entities.TickListDataProvider. The tick spacing must be
+// the one of the list.
+func (l *SparseTickList) NextInitializedTickWithinOneWord(tick int, lte bool, tickSpacing int) (int, bool, error) {
+	if tickSpacing != l.tickSpacing {
+		return entities.ZeroValueTickIndex, entities.ZeroValueTickInitialized, entities.ErrInvalidTickSpacing
+	}
+	smallest, ok := l.Smallest()
+	if !ok {
+		return entities.ZeroValueTickIndex, entities.ZeroValueTickInitialized, entities.ErrEmptyTickList
+	}
+	largest, _ := l.Largest()
+
+	if lte {
+		minimum := (l.wordPos(tick) << 8) * tickSpacing
+		if tick < smallest.Index {
+			return minimum, entities.ZeroValueTickInitialized, entities.ErrBelowSmallest
+		}
+		next, err := l.nextInitializedTick(tick, true)
+		if err != nil {
+			return entities.ZeroValueTickIndex, entities.ZeroValueTickInitialized, err
+		}
+		if next.Index < minimum {
+			return minimum, false, nil
+		}
+		return next.Index, true, nil
+	}
+
+	maximum := ((l.wordPos(tick+tickSpacing)+1)<<8)*tickSpacing - 1
+	if tick >= largest.Index {
+		return maximum, entities.ZeroValueTickInitialized, entities.ErrAtOrAboveLargest
+	}
+	next, err := l.nextInitializedTick(tick, false)
+	if err != nil {
+		return entities.ZeroValueTickIndex, entities.ZeroValueTickInitialized, err
+	}
+	if next.Index > maximum {
+		return maximum, false, nil
+	}
+	return next.Index, true, nil
+}
+
+// NextInitializedTickIndex is the same as the one of entities.TickListDataProvider
+func (l *SparseTickList) NextInitializedTickIndex(tick int, lte bool) (int, bool, error) {
+	next, err := l.nextInitializedTick(tick, lte)
+	if err != nil {
+		return entities.ZeroValueTickIndex, entities.ZeroValueTickInitialized, err
+	}
+	return next.Index, next.LiquidityGross.Cmp(big.NewInt(0)) != 0, nil
+}
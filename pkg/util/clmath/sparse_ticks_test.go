@@ -0,0 +1,242 @@
+package clmath
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/daoleno/uniswapv3-sdk/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomTicks returns n sorted ticks of positions, their liquidityNet summing up to 0
+func randomTicks(rng *rand.Rand, n, tickSpacing int) []entities.Tick {
+	indexes := map[int]struct{}{}
+	for len(indexes) < n {
+		// a few words around 0, so that the words have several ticks
+		indexes[(rng.Intn(4096)-2048)*tickSpacing] = struct{}{}
+	}
+	ticks := make([]entities.Tick, 0, n)
+	for index := range indexes {
+		ticks = append(ticks, entities.Tick{Index: index})
+	}
+	sort.Slice(ticks, func(i, j int) bool { return ticks[i].Index < ticks[j].Index })
+
+	sum := big.NewInt(0)
+	for i := range ticks {
+		liquidityNet := big.NewInt(rng.Int63n(2000) - 1000)
+		if i == len(ticks)-1 {
+			liquidityNet = new(big.Int).Neg(sum)
+		}
+		sum.Add(sum, liquidityNet)
+		ticks[i].LiquidityNet = liquidityNet
+		ticks[i].LiquidityGross = new(big.Int).Abs(liquidityNet)
+		if ticks[i].LiquidityGross.Sign() == 0 {
+			ticks[i].LiquidityGross = big.NewInt(1)
+		}
+	}
+	return ticks
+}
+
+// assertSameLookups compares the lookups of actual with the ones of the sdk on the sorted ticks
+func assertSameLookups(t *testing.T, rng *rand.Rand, ticks []entities.Tick, actual *SparseTickList, tickSpacing int) {
+	for i := 0; i < 200; i++ {
+		tick := rng.Intn(5000*tickSpacing) - 2500*tickSpacing
+		if i%4 == 0 && len(ticks) > 0 {
+			// exactly on an initialized tick, or next to it
+			tick = ticks[rng.Intn(len(ticks))].Index + rng.Intn(3) - 1
+		}
+		for _, lte := range []bool{true, false} {
+			expectedIndex, expectedInitialized, expectedErr := entities.NextInitializedTickWithinOneWord(ticks, tick, lte, tickSpacing)
+			actualIndex, actualInitialized, actualErr := actual.NextInitializedTickWithinOneWord(tick, lte, tickSpacing)
+			require.Equal(t, expectedErr, actualErr, "tick %d lte %v", tick, lte)
+			require.Equal(t, expectedIndex, actualIndex, "tick %d lte %v", tick, lte)
+			require.Equal(t, expectedInitialized, actualInitialized, "tick %d lte %v", tick, lte)
+
+			expectedIndex, expectedInitialized, expectedErr = entities.NextInitializedTickIndex(ticks, tick, lte)
+			actualIndex, actualInitialized, actualErr = actual.NextInitializedTickIndex(tick, lte)
+			require.Equal(t, expectedErr, actualErr, "tick %d lte %v", tick, lte)
+			require.Equal(t, expectedIndex, actualIndex, "tick %d lte %v", tick, lte)
+			require.Equal(t, expectedInitialized, actualInitialized, "tick %d lte %v", tick, lte)
+		}
+	}
+	for i := 0; i < 50; i++ {
+		tick := rng.Intn(5000*tickSpacing) - 2500*tickSpacing
+		if i%4 == 0 && len(ticks) > 0 {
+			tick = ticks[rng.Intn(len(ticks))].Index + rng.Intn(3) - 1
+		}
+		// the position of the first tick > tick
+		j := sort.Search(len(ticks), func(j int) bool { return ticks[j].Index > tick })
+		require.Equal(t, j, actual.CountAtOrBelow(tick), "tick %d", tick)
+
+		below, ok := actual.LastAtOrBelow(tick)
+		require.Equal(t, j > 0, ok, "tick %d", tick)
+		if ok {
+			require.Equal(t, ticks[j-1], below, "tick %d", tick)
+		}
+		above, ok := actual.FirstAbove(tick)
+		require.Equal(t, j < len(ticks), ok, "tick %d", tick)
+		if ok {
+			require.Equal(t, ticks[j], above, "tick %d", tick)
+		}
+
+		var ascended []entities.Tick
+		actual.Ascend(tick+1, func(tick entities.Tick) bool {
+			ascended = append(ascended, tick)
+			return len(ascended) < 3
+		})
+		expected := ticks[j:]
+		if len(expected) > 3 {
+			expected = expected[:3]
+		}
+		require.Equal(t, len(expected), len(ascended), "tick %d", tick)
+		for k := range expected {
+			require.Equal(t, expected[k], ascended[k], "tick %d", tick)
+		}
+	}
+	assert.Equal(t, len(ticks), actual.Len())
+	for _, tick := range ticks {
+		expectedTick, err := entities.GetTick(ticks, tick.Index)
+		require.Nil(t, err)
+		actualTick, err := actual.GetTick(tick.Index)
+		require.Nil(t, err)
+		require.Equal(t, expectedTick, actualTick)
+	}
+	assert.Equal(t, ticks, actual.Ticks())
+}
+
+func TestSparseTickList_Equivalence(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		tickSpacing := []int{1, 10, 60, 200}[rng.Intn(4)]
+		ticks := randomTicks(rng, 2+rng.Intn(100), tickSpacing)
+
+		actual, err := NewSparseTickList(ticks, tickSpacing)
+		require.Nil(t, err)
+		assertSameLookups(t, rng, ticks, actual, tickSpacing)
+	}
+}
+
+func TestSparseTickList_SetTick(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	tickSpacing := 60
+	ticks := randomTicks(rng, 50, tickSpacing)
+	actual, err := NewSparseTickList(ticks, tickSpacing)
+	require.Nil(t, err)
+
+	for i := 0; i < 200; i++ {
+		index := (rng.Intn(4096) - 2048) * tickSpacing
+		if rng.Intn(2) == 0 {
+			index = ticks[rng.Intn(len(ticks))].Index
+		}
+		tick := entities.Tick{Index: index, LiquidityGross: big.NewInt(rng.Int63n(3)), LiquidityNet: big.NewInt(0)}
+		require.Nil(t, actual.SetTick(tick))
+
+		// the same update on the sorted list
+		j := sort.Search(len(ticks), func(j int) bool { return ticks[j].Index >= index })
+		found := j < len(ticks) && ticks[j].Index == index
+		switch {
+		case tick.LiquidityGross.Sign() == 0 && found:
+			ticks = append(ticks[:j:j], ticks[j+1:]...)
+		case tick.LiquidityGross.Sign() == 0:
+		case found:
+			ticks = append(append(ticks[:j:j], tick), ticks[j+1:]...)
+		default:
+			ticks = append(append(ticks[:j:j], tick), ticks[j:]...)
+		}
+		if len(ticks) == 0 {
+			break
+		}
+
+		assertSameLookups(t, rng, ticks, actual, tickSpacing)
+	}
+
+	_, err = NewSparseTickList([]entities.Tick{{Index: 0, LiquidityGross: big.NewInt(1), LiquidityNet: big.NewInt(1)}}, 1)
+	assert.ErrorIs(t, err, entities.ErrZeroNet)
+	_, err = actual.GetTick(7)
+	assert.ErrorIs(t, err, ErrTickNotFound)
+	assert.ErrorIs(t, actual.SetTick(entities.Tick{Index: 7, LiquidityGross: big.NewInt(1)}), entities.ErrInvalidTickSpacing)
+	_, _, err = actual.NextInitializedTickWithinOneWord(0, true, 10)
+	assert.ErrorIs(t, err, entities.ErrInvalidTickSpacing)
+}
+
+func TestSparseTickList_WithTick(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	tickSpacing := 60
+	ticks := randomTicks(rng, 50, tickSpacing)
+	original, err := NewSparseTickList(ticks, tickSpacing)
+	require.Nil(t, err)
+
+	updated := original
+	for _, tick := range ticks[:25] {
+		updated, err = updated.WithTick(entities.Tick{Index: tick.Index, LiquidityGross: big.NewInt(0)})
+		require.Nil(t, err)
+	}
+	updated, err = updated.WithTick(entities.Tick{Index: ticks[25].Index, LiquidityGross: big.NewInt(7), LiquidityNet: big.NewInt(7)})
+	require.Nil(t, err)
+	// a new word
+	updated, err = updated.WithTick(entities.Tick{Index: 3000 * tickSpacing, LiquidityGross: big.NewInt(1), LiquidityNet: big.NewInt(1)})
+	require.Nil(t, err)
+	assert.Equal(t, 26, updated.Len())
+	assert.Equal(t, big.NewInt(7), updated.Ticks()[0].LiquidityGross)
+
+	_, err = updated.WithTick(entities.Tick{Index: 7, LiquidityGross: big.NewInt(1)})
+	assert.ErrorIs(t, err, entities.ErrInvalidTickSpacing)
+
+	assertSameLookups(t, rng, ticks, original, tickSpacing)
+}
+
+func BenchmarkSparseTickList_SetTick(b *testing.B) {
+	rng := rand.New(rand.NewSource(42))
+	tickSpacing := 1
+	ticks := randomTicks(rng, 3000, tickSpacing)
+	update := entities.Tick{Index: ticks[1500].Index, LiquidityGross: big.NewInt(5), LiquidityNet: ticks[1500].LiquidityNet}
+
+	b.Run("TickListDataProvider", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// patched copy of the sorted list, then validated again
+			patched := make([]entities.Tick, len(ticks))
+			copy(patched, ticks)
+			patched[1500] = update
+			_, _ = entities.NewTickListDataProvider(patched, tickSpacing)
+		}
+	})
+	b.Run("SparseTickList", func(b *testing.B) {
+		l, _ := NewSparseTickList(ticks, tickSpacing)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = l.SetTick(update)
+		}
+	})
+	b.Run("SparseTickList.WithTick", func(b *testing.B) {
+		l, _ := NewSparseTickList(ticks, tickSpacing)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _ = l.WithTick(update)
+		}
+	})
+}
+
+func BenchmarkSparseTickList_NextInitializedTickWithinOneWord(b *testing.B) {
+	rng := rand.New(rand.NewSource(42))
+	tickSpacing := 60
+	ticks := randomTicks(rng, 3000, tickSpacing)
+	list, _ := entities.NewTickListDataProvider(ticks, tickSpacing)
+	sparse, _ := NewSparseTickList(ticks, tickSpacing)
+
+	b.Run("TickListDataProvider", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _ = list.NextInitializedTickWithinOneWord(i%100000-50000, i%2 == 0, tickSpacing)
+		}
+	})
+	b.Run("SparseTickList", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _ = sparse.NextInitializedTickWithinOneWord(i%100000-50000, i%2 == 0, tickSpacing)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package jsonscan
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+const (
+	// the chunks double up to the max size, so that small documents don't waste much memory
+	minChunkSize = 4
+	maxChunkSize = 256
+	// the integers up to 256 bits are parsed without allocating, the larger ones use big.Int.SetString
+	maxWords = 256 / bits.UintSize
+
+	// 10^digitsPerWord fits into a word
+	digitsPerWord = 19 * bits.UintSize / 64
+)
+
+var powersOfTen = func() (p [digitsPerWord + 1]big.Word) {
+	p[0] = 1
+	for i := 1; i <= digitsPerWord; i++ {
+		p[i] = p[i-1] * 10
+	}
+	return
+}()
+
+// intAllocator hands out the big.Int of a document from larger chunks, their words too. The words of each integer
+// are capped, so growing one of them reallocates instead of overwriting the next one.
+type intAllocator struct {
+	ints      []big.Int
+	words     []big.Word
+	intChunk  int
+	wordChunk int
+}
+
+func nextChunkSize(size int) int {
+	switch {
+	case size < minChunkSize:
+		return minChunkSize
+	case size < maxChunkSize:
+		return size * 2
+	default:
+		return size
+	}
+}
+
+func (a *intAllocator) newInt() *big.Int {
+	if len(a.ints) == 0 {
+		a.intChunk = nextChunkSize(a.intChunk)
+		a.ints = make([]big.Int, a.intChunk)
+	}
+	z := &a.ints[0]
+	a.ints = a.ints[1:]
+	return z
+}
+
+func (a *intAllocator) newWords(n int) []big.Word {
+	if len(a.words) < n {
+		a.wordChunk = nextChunkSize(a.wordChunk)
+		a.words = make([]big.Word, a.wordChunk+n)
+	}
+	w := a.words[:n:n]
+	a.words = a.words[n:]
+	return w
+}
+
+// parse parses an integer number token like encoding/json does with big.Int.UnmarshalJSON
+func (a *intAllocator) parse(token []byte) *big.Int {
+	neg := token[0] == '-'
+	digits := token
+	if neg {
+		digits = digits[1:]
+	}
+
+	// little-endian words of the integer
+	var acc [maxWords]big.Word
+	n := 0
+	for len(digits) > 0 {
+		k := len(digits)
+		if k > digitsPerWord {
+			k = digitsPerWord
+		}
+		var chunk big.Word
+		for _, c := range digits[:k] {
+			chunk = chunk*10 + big.Word(c-'0')
+		}
+		digits = digits[k:]
+
+		// acc = acc * 10^k + chunk
+		carry := chunk
+		for i := 0; i < n; i++ {
+			hi, lo := bits.Mul(uint(acc[i]), uint(powersOfTen[k]))
+			lo, c := bits.Add(lo, uint(carry), 0)
+			acc[i], carry = big.Word(lo), big.Word(hi+c)
+		}
+		if carry != 0 {
+			if n == maxWords {
+				z, _ := new(big.Int).SetString(string(token), 10)
+				return z
+			}
+			acc[n] = carry
+			n++
+		}
+	}
+
+	z := a.newInt()
+	if n == 0 {
+		return z
+	}
+	words := a.newWords(n)
+	copy(words, acc[:n])
+	z.SetBits(words)
+	if neg {
+		z.Neg(z)
+	}
+	return z
+}
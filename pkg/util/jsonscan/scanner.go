@@ -0,0 +1,296 @@
+// Package jsonscan reads the JSON written by encoding/json for a known schema, without reflection and with few
+// allocations. It only accepts a strict subset of JSON: keys without escapes, integers without exponent... Any input
+// outside of the subset, valid or not, fails the scanner and the caller is expected to decode it with encoding/json
+// instead, so that both accept exactly the same inputs.
+package jsonscan
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Scanner reads the values of a JSON document in order, once it fails all the reads return zero values
+type Scanner struct {
+	data   []byte
+	pos    int
+	failed bool
+	ints   intAllocator
+}
+
+func New(data []byte) *Scanner {
+	return &Scanner{data: data}
+}
+
+// Failed returns true if the document isn't in the subset read by the scanner
+func (s *Scanner) Failed() bool {
+	return s.failed
+}
+
+// Fail marks the document as unsupported, e.g. for an unknown key
+func (s *Scanner) Fail() {
+	s.failed = true
+}
+
+// Seen fails the scanner if the bit is already set in mask, to reject the duplicated keys
+func (s *Scanner) Seen(mask *uint64, bit uint) {
+	if *mask&(1<<bit) != 0 {
+		s.failed = true
+	}
+	*mask |= 1 << bit
+}
+
+// End fails the scanner if there is anything but whitespace after the document
+func (s *Scanner) End() {
+	s.skipSpace()
+	if s.pos != len(s.data) {
+		s.failed = true
+	}
+}
+
+func (s *Scanner) skipSpace() {
+	data, pos := s.data, s.pos
+	for pos < len(data) && (data[pos] == ' ' || data[pos] == '\t' || data[pos] == '\n' || data[pos] == '\r') {
+		pos++
+	}
+	s.pos = pos
+}
+
+// consume skips the whitespace and the byte c, it returns false if the next byte isn't c
+func (s *Scanner) consume(c byte) bool {
+	if s.failed {
+		return false
+	}
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+func (s *Scanner) expect(c byte) {
+	if !s.consume(c) {
+		s.failed = true
+	}
+}
+
+func (s *Scanner) literal(lit string) bool {
+	if s.failed {
+		return false
+	}
+	s.skipSpace()
+	if len(s.data)-s.pos >= len(lit) && string(s.data[s.pos:s.pos+len(lit)]) == lit {
+		s.pos += len(lit)
+		return true
+	}
+	return false
+}
+
+// Null consumes a null literal if it's the next value
+func (s *Scanner) Null() bool {
+	return s.literal("null")
+}
+
+// ObjectStart consumes the opening brace of an object
+func (s *Scanner) ObjectStart() {
+	s.expect('{')
+}
+
+// NextKey returns the key of the n-th member of the object, or nil once the closing brace is consumed.
+// The value of the member must be read before the next call.
+func (s *Scanner) NextKey(n int) []byte {
+	if s.consume('}') {
+		return nil
+	}
+	if n > 0 {
+		s.expect(',')
+	}
+	key := s.key()
+	s.expect(':')
+	if s.failed {
+		return nil
+	}
+	return key
+}
+
+// key reads a string without escapes
+func (s *Scanner) key() []byte {
+	s.expect('"')
+	if s.failed {
+		return nil
+	}
+	data, start := s.data, s.pos
+	for pos := start; pos < len(data); pos++ {
+		switch c := data[pos]; {
+		case c == '"':
+			s.pos = pos + 1
+			return data[start:pos]
+		case c == '\\' || c < 0x20 || c >= 0x80:
+			s.failed = true
+			return nil
+		}
+	}
+	s.failed = true
+	return nil
+}
+
+// ArrayStart consumes the opening bracket of an array
+func (s *Scanner) ArrayStart() {
+	s.expect('[')
+}
+
+// NextElem returns true if there is an n-th element to read in the array, false once the closing bracket is consumed
+func (s *Scanner) NextElem(n int) bool {
+	if s.failed || s.consume(']') {
+		return false
+	}
+	if n > 0 {
+		s.expect(',')
+	}
+	return !s.failed
+}
+
+// number reads a number token, integer reports if it has neither fraction nor exponent
+func (s *Scanner) number() (token []byte, integer bool) {
+	if s.failed {
+		return nil, false
+	}
+	s.skipSpace()
+	start, integer := s.pos, true
+	if s.pos < len(s.data) && s.data[s.pos] == '-' {
+		s.pos++
+	}
+	switch {
+	case s.pos < len(s.data) && s.data[s.pos] == '0':
+		s.pos++
+	case s.pos < len(s.data) && s.data[s.pos] >= '1' && s.data[s.pos] <= '9':
+		s.digits()
+	default:
+		s.failed = true
+		return nil, false
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '.' {
+		s.pos++
+		integer = false
+		if s.digits() == 0 {
+			s.failed = true
+			return nil, false
+		}
+	}
+	if s.pos < len(s.data) && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		integer = false
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		if s.digits() == 0 {
+			s.failed = true
+			return nil, false
+		}
+	}
+	return s.data[start:s.pos], integer
+}
+
+func (s *Scanner) digits() int {
+	data, start := s.data, s.pos
+	pos := start
+	for pos < len(data) && data[pos] >= '0' && data[pos] <= '9' {
+		pos++
+	}
+	s.pos = pos
+	return pos - start
+}
+
+// Int reads an integer fitting into bitSize bits
+func (s *Scanner) Int(bitSize int) int64 {
+	token, integer := s.number()
+	if !integer {
+		s.failed = true
+		return 0
+	}
+	neg := token[0] == '-'
+	if neg {
+		token = token[1:]
+	}
+	n, ok := parseUint(token)
+	limit := uint64(1) << (bitSize - 1)
+	if !ok || !neg && n >= limit || neg && n > limit {
+		s.failed = true
+		return 0
+	}
+	if neg {
+		return -int64(n)
+	}
+	return int64(n)
+}
+
+// Uint reads a non-negative integer fitting into bitSize bits
+func (s *Scanner) Uint(bitSize int) uint64 {
+	token, integer := s.number()
+	if !integer {
+		s.failed = true
+		return 0
+	}
+	n, ok := parseUint(token)
+	if !ok || bitSize < 64 && n >= 1<<bitSize {
+		s.failed = true
+		return 0
+	}
+	return n
+}
+
+// parseUint parses the digits of a number token, false if it's negative or overflows like strconv.ParseUint
+func parseUint(token []byte) (uint64, bool) {
+	if len(token) == 0 || token[0] == '-' {
+		return 0, false
+	}
+	var n uint64
+	for _, c := range token {
+		d := uint64(c - '0')
+		if n > (math.MaxUint64-d)/10 {
+			return 0, false
+		}
+		n = n*10 + d
+	}
+	return n, true
+}
+
+// Float64 reads a number
+func (s *Scanner) Float64() float64 {
+	token, _ := s.number()
+	if s.failed {
+		return 0
+	}
+	f, err := strconv.ParseFloat(string(token), 64)
+	if err != nil {
+		s.failed = true
+		return 0
+	}
+	return f
+}
+
+// Bool reads a boolean
+func (s *Scanner) Bool() bool {
+	switch {
+	case s.literal("true"):
+		return true
+	case s.literal("false"):
+		return false
+	}
+	s.failed = true
+	return false
+}
+
+// BigInt reads an integer of any size, or null which returns nil like encoding/json does for a *big.Int
+func (s *Scanner) BigInt() *big.Int {
+	if s.Null() {
+		return nil
+	}
+	token, integer := s.number()
+	if !integer {
+		s.failed = true
+		return nil
+	}
+	return s.ints.parse(token)
+}
@@ -0,0 +1,105 @@
+package jsonscan
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanner_BigInt(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	testcases := []string{"0", "-0", "1", "-1", "9999999999999999999", "10000000000000000000", "18446744073709551616",
+		"-115792089237316195423570985008687907853269984665640564039457584007913129639935",
+		"115792089237316195423570985008687907853269984665640564039457584007913129639936"}
+	for i := 0; i < 1000; i++ {
+		n := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(rng.Intn(300))))
+		if rng.Intn(2) == 0 {
+			n.Neg(n)
+		}
+		testcases = append(testcases, n.String())
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			expected, _ := new(big.Int).SetString(tc, 10)
+			s := New([]byte(tc))
+			actual := s.BigInt()
+			s.End()
+			assert.False(t, s.Failed())
+			assert.Equal(t, 0, expected.Cmp(actual))
+			assert.Equal(t, expected.String(), actual.String())
+		})
+	}
+}
+
+func TestScanner_Subset(t *testing.T) {
+	testcases := []struct {
+		data   string
+		read   func(s *Scanner)
+		failed bool
+	}{
+		{`{"a":1, "b" : [true,false] }`, readObject, false},
+		{`{}`, readObject, false},
+		{`{"a":1,}`, readObject, true},
+		{`{,"a":1}`, readObject, true},
+		{`{"a":1 "b":[]}`, readObject, true},
+		{`{"a":1,"a":2}`, readObject, true},
+		{`{"A":1}`, readObject, true},
+		{`{"b":[true,]}`, readObject, true},
+		{`{"a":1} x`, readObject, true},
+		{`{"a":1`, readObject, true},
+		{`-32768`, func(s *Scanner) { s.Int(16) }, false},
+		{`-32769`, func(s *Scanner) { s.Int(16) }, true},
+		{`32767`, func(s *Scanner) { s.Int(16) }, false},
+		{`32768`, func(s *Scanner) { s.Int(16) }, true},
+		{`65535`, func(s *Scanner) { s.Uint(16) }, false},
+		{`65536`, func(s *Scanner) { s.Uint(16) }, true},
+		{`-0`, func(s *Scanner) { s.Uint(16) }, true},
+		{`1.0`, func(s *Scanner) { s.Uint(16) }, true},
+		{`1e3`, func(s *Scanner) { s.BigInt() }, true},
+		{`01`, func(s *Scanner) { s.BigInt() }, true},
+		{`"1"`, func(s *Scanner) { s.BigInt() }, true},
+		{`null`, func(s *Scanner) { assert.Nil(t, s.BigInt()) }, false},
+		{`-`, func(s *Scanner) { s.Float64() }, true},
+		{`1.`, func(s *Scanner) { s.Float64() }, true},
+		{`-1.5e-3`, func(s *Scanner) { assert.Equal(t, -1.5e-3, s.Float64()) }, false},
+		{`true`, func(s *Scanner) { s.Float64() }, true},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			s := New([]byte(tc.data))
+			tc.read(s)
+			s.End()
+			assert.Equal(t, tc.failed, s.Failed())
+		})
+	}
+}
+
+// readObject reads {"a": int, "b": [bool]}
+func readObject(s *Scanner) {
+	var seen uint64
+	s.ObjectStart()
+	for i := 0; ; i++ {
+		key := s.NextKey(i)
+		if key == nil {
+			return
+		}
+		switch string(key) {
+		case "a":
+			s.Seen(&seen, 0)
+			s.Int(64)
+		case "b":
+			s.Seen(&seen, 1)
+			s.ArrayStart()
+			for j := 0; s.NextElem(j); j++ {
+				s.Bool()
+			}
+		default:
+			s.Fail()
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	kyberlogger "github.com/KyberNetwork/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// callerSkip is the number of frames between a call to the package and the default logger: the exported function or
+// method, then entry.log or entry.logf
+const callerSkip = 2
+
+// defaultLogger forwards to the global KyberNetwork logger, which may be replaced by its InitLogger at any time.
+// The zap logger is called directly with the caller skip of the package, the KyberNetwork wrappers would report them
+// as the caller.
+type defaultLogger struct{ fields Fields }
+
+type zapDelegate struct {
+	delegate *zap.SugaredLogger
+	logger   *zap.SugaredLogger
+	core     zapcore.Core
+}
+
+var lastZapDelegate atomic.Pointer[zapDelegate]
+
+// currentZapDelegate returns the zap delegate of the global logger, false if it isn't zap
+func currentZapDelegate() (*zapDelegate, bool) {
+	delegate, ok := kyberlogger.GetDelegate().(*zap.SugaredLogger)
+	if !ok {
+		return nil, false
+	}
+	last := lastZapDelegate.Load()
+	if last == nil || last.delegate != delegate {
+		// the delegate already skips the 2 wrappers of the KyberNetwork logger, it's called from defaultLogger instead,
+		// so callerSkip + 1 frames are skipped in total
+		last = &zapDelegate{
+			delegate: delegate,
+			logger:   delegate.WithOptions(zap.AddCallerSkip(callerSkip - 1)),
+			core:     delegate.Desugar().Core(),
+		}
+		lastZapDelegate.Store(last)
+	}
+	return last, true
+}
+
+// Enabled reports the level of the zap logger, the other loggers get all the entries
+func (d defaultLogger) Enabled(lvl Level) bool {
+	last, ok := currentZapDelegate()
+	if !ok {
+		return true
+	}
+	switch lvl {
+	case DebugLevel:
+		return last.core.Enabled(zapcore.DebugLevel)
+	case InfoLevel:
+		return last.core.Enabled(zapcore.InfoLevel)
+	case WarnLevel:
+		return last.core.Enabled(zapcore.WarnLevel)
+	default:
+		return last.core.Enabled(zapcore.ErrorLevel)
+	}
+}
+
+// zapLogger returns the zap logger of the global logger with the caller skip of the package, false if it isn't zap
+func (d defaultLogger) zapLogger() (*zap.SugaredLogger, bool) {
+	last, ok := currentZapDelegate()
+	if !ok {
+		return nil, false
+	}
+	if len(d.fields) == 0 {
+		return last.logger, true
+	}
+	keyValues := make([]interface{}, 0, len(d.fields)*2)
+	for k, v := range d.fields {
+		keyValues = append(keyValues, k, v)
+	}
+	return last.logger.With(keyValues...), true
+}
+
+func (d defaultLogger) kyberLogger() kyberlogger.Logger {
+	return kyberlogger.WithFields(d.fields)
+}
+
+func (d defaultLogger) Debug(msg string) {
+	if l, ok := d.zapLogger(); ok {
+		l.Debug(msg)
+		return
+	}
+	d.kyberLogger().Debug(msg)
+}
+
+func (d defaultLogger) Debugf(format string, args ...interface{}) {
+	if l, ok := d.zapLogger(); ok {
+		l.Debugf(format, args...)
+		return
+	}
+	d.kyberLogger().Debugf(format, args...)
+}
+
+func (d defaultLogger) Info(msg string) {
+	if l, ok := d.zapLogger(); ok {
+		l.Info(msg)
+		return
+	}
+	d.kyberLogger().Info(msg)
+}
+
+func (d defaultLogger) Infof(format string, args ...interface{}) {
+	if l, ok := d.zapLogger(); ok {
+		l.Infof(format, args...)
+		return
+	}
+	d.kyberLogger().Infof(format, args...)
+}
+
+func (d defaultLogger) Infoln(msg string) {
+	if l, ok := d.zapLogger(); ok {
+		l.Infoln(msg)
+		return
+	}
+	d.kyberLogger().Infoln(msg)
+}
+
+func (d defaultLogger) Warn(msg string) {
+	if l, ok := d.zapLogger(); ok {
+		l.Warn(msg)
+		return
+	}
+	d.kyberLogger().Warn(msg)
+}
+
+func (d defaultLogger) Warnf(format string, args ...interface{}) {
+	if l, ok := d.zapLogger(); ok {
+		l.Warnf(format, args...)
+		return
+	}
+	d.kyberLogger().Warnf(format, args...)
+}
+
+func (d defaultLogger) Error(msg string) {
+	if l, ok := d.zapLogger(); ok {
+		l.Error(msg)
+		return
+	}
+	d.kyberLogger().Error(msg)
+}
+
+func (d defaultLogger) Errorf(format string, args ...interface{}) {
+	if l, ok := d.zapLogger(); ok {
+		l.Errorf(format, args...)
+		return
+	}
+	d.kyberLogger().Errorf(format, args...)
+}
+
+func (d defaultLogger) WithFields(keyValues Fields) Logger {
+	return defaultLogger{fields: mergeFields(d.fields, keyValues)}
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLimitedKeys bounds the warnings tracked by the limiter, expired ones are dropped when it is reached
+const maxLimitedKeys = 10000
+
+type limitEntry struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// limiter lets through at most burst identical warnings per window
+type limiter struct {
+	on      atomic.Bool // window > 0 && burst > 0, read without the lock on every warning
+	mu      sync.Mutex
+	window  time.Duration
+	burst   int
+	now     func() time.Time
+	entries map[string]*limitEntry
+}
+
+var warnLimiter = &limiter{now: time.Now}
+
+// SetWarnLimit logs at most burst identical warnings of a pool per window and drops the others, the first warning
+// of the next window carries the number dropped in a "suppressed" field. A burst or a window of 0 logs everything,
+// which is the default.
+func SetWarnLimit(window time.Duration, burst int) {
+	warnLimiter.mu.Lock()
+	defer warnLimiter.mu.Unlock()
+
+	warnLimiter.window, warnLimiter.burst = window, burst
+	warnLimiter.entries = nil
+	warnLimiter.on.Store(window > 0 && burst > 0)
+}
+
+func (l *limiter) enabled() bool {
+	return l.on.Load()
+}
+
+// allow returns whether the warning identified by key can be logged, and how many were dropped before it
+func (l *limiter) allow(key string) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.window <= 0 || l.burst <= 0 {
+		return 0, true
+	}
+
+	now := l.now()
+	if l.entries == nil {
+		l.entries = make(map[string]*limitEntry)
+	}
+	e, ok := l.entries[key]
+	if !ok || now.Sub(e.start) >= l.window {
+		if !ok && len(l.entries) >= maxLimitedKeys {
+			l.dropExpired(now)
+		}
+		var suppressed int
+		if ok {
+			suppressed = e.suppressed
+		}
+		l.entries[key] = &limitEntry{start: now, count: 1}
+		return suppressed, true
+	}
+
+	if e.count >= l.burst {
+		e.suppressed++
+		return 0, false
+	}
+	e.count++
+	return 0, true
+}
+
+func (l *limiter) dropExpired(now time.Time) {
+	for key, e := range l.entries {
+		if now.Sub(e.start) >= l.window {
+			delete(l.entries, key)
+		}
+	}
+	if len(l.entries) >= maxLimitedKeys {
+		l.entries = make(map[string]*limitEntry)
+	}
+}
@@ -0,0 +1,218 @@
+// Package logger is the logger used by the library. It forwards to the global KyberNetwork logger unless an
+// application injects its own with SetLogger, and can cap identical warnings with SetWarnLimit.
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	kyberlogger "github.com/KyberNetwork/logger"
+)
+
+// Fields are the structured fields of a log entry
+type Fields = kyberlogger.Fields
+
+// Logger is the leveled logger an application can inject
+type Logger interface {
+	Debug(msg string)
+	Debugf(format string, args ...interface{})
+
+	Info(msg string)
+	Infof(format string, args ...interface{})
+	Infoln(msg string)
+
+	Warn(msg string)
+	Warnf(format string, args ...interface{})
+
+	Error(msg string)
+	Errorf(format string, args ...interface{})
+
+	WithFields(keyValues Fields) Logger
+}
+
+type holder struct{ logger Logger }
+
+var injected atomic.Pointer[holder]
+
+// SetLogger makes the library log to l, nil restores the global KyberNetwork logger
+func SetLogger(l Logger) {
+	if l == nil {
+		injected.Store(nil)
+		return
+	}
+	injected.Store(&holder{logger: l})
+}
+
+// GetLogger returns the logger the library currently logs to
+func GetLogger() Logger {
+	if h := injected.Load(); h != nil {
+		return h.logger
+	}
+	return defaultLogger{}
+}
+
+// Level is the level of a log entry, see LevelEnabler
+type Level int8
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// LevelEnabler can be implemented by an injected Logger so that the entries of its disabled levels are dropped before
+// their fields are merged, their message formatted or the warning limit checked
+type LevelEnabler interface {
+	Enabled(lvl Level) bool
+}
+
+type level int
+
+const (
+	debugLevel level = iota
+	infoLevel
+	infolnLevel
+	warnLevel
+	errorLevel
+)
+
+func (lvl level) enabledIn(l Logger) bool {
+	enabler, ok := l.(LevelEnabler)
+	if !ok {
+		return true
+	}
+	switch lvl {
+	case debugLevel:
+		return enabler.Enabled(DebugLevel)
+	case infoLevel, infolnLevel:
+		return enabler.Enabled(InfoLevel)
+	case warnLevel:
+		return enabler.Enabled(WarnLevel)
+	default:
+		return enabler.Enabled(ErrorLevel)
+	}
+}
+
+// entry resolves the current logger on every call so that loggers kept in structs follow SetLogger.
+// All the calls go through log or logf, so that the default logger reports the right caller.
+type entry struct{ fields Fields }
+
+// with returns l with the fields of the entry
+func (e entry) with(l Logger) Logger {
+	if e.fields == nil {
+		return l
+	}
+	return l.WithFields(e.fields)
+}
+
+// allowWarn returns the logger of the warning msg, false if the warning is dropped by the limiter
+func (e entry) allowWarn(l Logger, msg string) (Logger, bool) {
+	suppressed, ok := warnLimiter.allow(e.key(msg))
+	if !ok {
+		return nil, false
+	}
+	l = e.with(l)
+	if suppressed > 0 {
+		l = l.WithFields(Fields{"suppressed": suppressed})
+	}
+	return l, true
+}
+
+func (e entry) log(lvl level, msg string) {
+	l := GetLogger()
+	if !lvl.enabledIn(l) {
+		return
+	}
+	if lvl == warnLevel && warnLimiter.enabled() {
+		if l, ok := e.allowWarn(l, msg); ok {
+			l.Warn(msg)
+		}
+		return
+	}
+
+	switch l := e.with(l); lvl {
+	case debugLevel:
+		l.Debug(msg)
+	case infoLevel:
+		l.Info(msg)
+	case infolnLevel:
+		l.Infoln(msg)
+	case warnLevel:
+		l.Warn(msg)
+	default:
+		l.Error(msg)
+	}
+}
+
+func (e entry) logf(lvl level, format string, args []interface{}) {
+	l := GetLogger()
+	if !lvl.enabledIn(l) {
+		return
+	}
+	if lvl == warnLevel && warnLimiter.enabled() {
+		msg := fmt.Sprintf(format, args...)
+		if l, ok := e.allowWarn(l, msg); ok {
+			l.Warn(msg)
+		}
+		return
+	}
+
+	switch l := e.with(l); lvl {
+	case debugLevel:
+		l.Debugf(format, args...)
+	case infoLevel:
+		l.Infof(format, args...)
+	case warnLevel:
+		l.Warnf(format, args...)
+	default:
+		l.Errorf(format, args...)
+	}
+}
+
+func (e entry) Debug(msg string)                          { e.log(debugLevel, msg) }
+func (e entry) Debugf(format string, args ...interface{}) { e.logf(debugLevel, format, args) }
+func (e entry) Info(msg string)                           { e.log(infoLevel, msg) }
+func (e entry) Infof(format string, args ...interface{})  { e.logf(infoLevel, format, args) }
+func (e entry) Infoln(msg string)                         { e.log(infolnLevel, msg) }
+func (e entry) Warn(msg string)                           { e.log(warnLevel, msg) }
+func (e entry) Warnf(format string, args ...interface{})  { e.logf(warnLevel, format, args) }
+func (e entry) Error(msg string)                          { e.log(errorLevel, msg) }
+func (e entry) Errorf(format string, args ...interface{}) { e.logf(errorLevel, format, args) }
+
+func (e entry) WithFields(keyValues Fields) Logger {
+	return entry{fields: mergeFields(e.fields, keyValues)}
+}
+
+// key identifies identical warnings, by pool address when the entry has one
+func (e entry) key(msg string) string {
+	if poolAddress, ok := e.fields["poolAddress"]; ok {
+		return fmt.Sprintf("%v\x00%s", poolAddress, msg)
+	}
+	return msg
+}
+
+func Debug(msg string)                          { entry{}.log(debugLevel, msg) }
+func Debugf(format string, args ...interface{}) { entry{}.logf(debugLevel, format, args) }
+func Info(msg string)                           { entry{}.log(infoLevel, msg) }
+func Infof(format string, args ...interface{})  { entry{}.logf(infoLevel, format, args) }
+func Infoln(msg string)                         { entry{}.log(infolnLevel, msg) }
+func Warn(msg string)                           { entry{}.log(warnLevel, msg) }
+func Warnf(format string, args ...interface{})  { entry{}.logf(warnLevel, format, args) }
+func Error(msg string)                          { entry{}.log(errorLevel, msg) }
+func Errorf(format string, args ...interface{}) { entry{}.logf(errorLevel, format, args) }
+
+func WithFields(keyValues Fields) Logger {
+	return entry{fields: mergeFields(nil, keyValues)}
+}
+
+func mergeFields(fields, keyValues Fields) Fields {
+	merged := make(Fields, len(fields)+len(keyValues))
+	for k, v := range fields {
+		merged[k] = v
+	}
+	for k, v := range keyValues {
+		merged[k] = v
+	}
+	return merged
+}
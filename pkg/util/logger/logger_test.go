@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	level  string
+	msg    string
+	fields Fields
+}
+
+// recorder is a Logger keeping what it receives
+type recorder struct {
+	mu      *sync.Mutex
+	records *[]record
+	fields  Fields
+}
+
+func newRecorder() recorder {
+	return recorder{mu: &sync.Mutex{}, records: &[]record{}}
+}
+
+func (r recorder) log(level, msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.records = append(*r.records, record{level: level, msg: msg, fields: r.fields})
+}
+
+func (r recorder) Debug(msg string) { r.log("debug", msg) }
+func (r recorder) Debugf(format string, args ...interface{}) {
+	r.log("debug", fmt.Sprintf(format, args...))
+}
+func (r recorder) Info(msg string) { r.log("info", msg) }
+func (r recorder) Infof(format string, args ...interface{}) {
+	r.log("info", fmt.Sprintf(format, args...))
+}
+func (r recorder) Infoln(msg string) { r.log("info", msg) }
+func (r recorder) Warn(msg string)   { r.log("warn", msg) }
+func (r recorder) Warnf(format string, args ...interface{}) {
+	r.log("warn", fmt.Sprintf(format, args...))
+}
+func (r recorder) Error(msg string) { r.log("error", msg) }
+func (r recorder) Errorf(format string, args ...interface{}) {
+	r.log("error", fmt.Sprintf(format, args...))
+}
+
+func (r recorder) WithFields(keyValues Fields) Logger {
+	fields := Fields{}
+	for k, v := range r.fields {
+		fields[k] = v
+	}
+	for k, v := range keyValues {
+		fields[k] = v
+	}
+	return recorder{mu: r.mu, records: r.records, fields: fields}
+}
+
+func (r recorder) get() []record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]record(nil), *r.records...)
+}
+
+func TestSetLogger(t *testing.T) {
+	// kept before the injection, as the readers of the sources do
+	kept := WithFields(Fields{"poolAddress": "pool"})
+
+	r := newRecorder()
+	SetLogger(r)
+	defer SetLogger(nil)
+
+	Infof("fetched %d pools", 2)
+	Warn("wrong swapInfo type")
+	kept.Errorf("failed to fetch %s", "reserves")
+	WithFields(Fields{"poolAddress": "pool"}).WithFields(Fields{"error": "reverted"}).Warnf("failed to call %s", "tick")
+
+	assert.Equal(t, []record{
+		{level: "info", msg: "fetched 2 pools"},
+		{level: "warn", msg: "wrong swapInfo type"},
+		{level: "error", msg: "failed to fetch reserves", fields: Fields{"poolAddress": "pool"}},
+		{level: "warn", msg: "failed to call tick", fields: Fields{"poolAddress": "pool", "error": "reverted"}},
+	}, r.get())
+
+	SetLogger(nil)
+	assert.Equal(t, defaultLogger{}, GetLogger())
+	Info("back to the default logger")
+	assert.Len(t, r.get(), 4)
+}
+
+// leveledRecorder is a recorder dropping the levels below min
+type leveledRecorder struct {
+	recorder
+	min Level
+}
+
+func (r leveledRecorder) Enabled(lvl Level) bool { return lvl >= r.min }
+
+func TestLevelEnabler(t *testing.T) {
+	r := leveledRecorder{recorder: newRecorder(), min: WarnLevel}
+	SetLogger(r)
+	defer SetLogger(nil)
+	SetWarnLimit(time.Minute, 1)
+	defer SetWarnLimit(0, 0)
+
+	kept := WithFields(Fields{"poolAddress": "pool"})
+	for i := 0; i < 3; i++ {
+		kept.Debugf("fee %v", i)
+		kept.Info("swapped")
+	}
+	kept.Warn("stale")
+	Error("failed")
+
+	assert.Equal(t, []record{
+		{level: "warn", msg: "stale", fields: Fields{"poolAddress": "pool"}},
+		{level: "error", msg: "failed"},
+	}, r.get())
+
+	// the dropped debug entries don't count towards the warning limit
+	kept.Warn("stale")
+	assert.Len(t, r.get(), 2)
+}
+
+func TestSetWarnLimit(t *testing.T) {
+	r := newRecorder()
+	SetLogger(r)
+	defer SetLogger(nil)
+
+	now := time.Unix(0, 0)
+	warnLimiter.now = func() time.Time { return now }
+	SetWarnLimit(time.Minute, 3)
+	defer func() {
+		SetWarnLimit(0, 0)
+		warnLimiter.now = time.Now
+	}()
+
+	for i := 0; i < 1000; i++ {
+		WithFields(Fields{"poolAddress": "pool0"}).Warnf("failed to UpdateBalance for pool %s", "pool0")
+		WithFields(Fields{"poolAddress": "pool1"}).Warn("wrong swapInfo type")
+		Warnf("failed to UpdateBalance for pool %s", "pool2")
+	}
+	// other levels are never limited
+	for i := 0; i < 5; i++ {
+		WithFields(Fields{"poolAddress": "pool0"}).Error("failed to fetch data")
+	}
+	assert.Len(t, r.get(), 3*3+5)
+
+	now = now.Add(time.Minute)
+	WithFields(Fields{"poolAddress": "pool0"}).Warnf("failed to UpdateBalance for pool %s", "pool0")
+	WithFields(Fields{"poolAddress": "pool0"}).Warn("another warning")
+	records := r.get()
+	assert.Len(t, records, 3*3+5+2)
+	assert.Equal(t, record{
+		level:  "warn",
+		msg:    "failed to UpdateBalance for pool pool0",
+		fields: Fields{"poolAddress": "pool0", "suppressed": 997},
+	}, records[len(records)-2])
+	assert.Equal(t, Fields{"poolAddress": "pool0"}, records[len(records)-1].fields)
+
+	// disabled, every warning goes through
+	SetWarnLimit(0, 0)
+	for i := 0; i < 10; i++ {
+		Warn("wrong swapInfo type")
+	}
+	assert.Len(t, r.get(), 3*3+5+2+10)
+}
+
+func TestLimiter_MaxLimitedKeys(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := &limiter{window: time.Minute, burst: 1, now: func() time.Time { return now }}
+
+	for i := 0; i < maxLimitedKeys; i++ {
+		_, ok := l.allow(fmt.Sprintf("pool%d", i))
+		assert.True(t, ok)
+	}
+	now = now.Add(time.Minute)
+	_, ok := l.allow("another pool")
+	assert.True(t, ok)
+	assert.Len(t, l.entries, 1)
+}
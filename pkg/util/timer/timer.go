@@ -3,7 +3,7 @@ package timer
 import (
 	"time"
 
-	"github.com/KyberNetwork/logger"
+	"github.com/KyberNetwork/kyberswap-dex-lib/pkg/util/logger"
 )
 
 func Start(task any) func() {
@@ -0,0 +1,37 @@
+package valueobject
+
+import "time"
+
+// averageBlockTimes are the average block times of the chains whose blocks are produced at a steady pace
+var averageBlockTimes = map[ChainID]time.Duration{
+	ChainIDEthereum:        12 * time.Second,
+	ChainIDGoerli:          12 * time.Second,
+	ChainIDEthereumW:       12 * time.Second,
+	ChainIDOptimism:        2 * time.Second,
+	ChainIDBSC:             3 * time.Second,
+	ChainIDPolygon:         2 * time.Second,
+	ChainIDArbitrumOne:     250 * time.Millisecond,
+	ChainIDAvalancheCChain: 2 * time.Second,
+	ChainIDFuji:            2 * time.Second,
+	ChainIDFantom:          time.Second,
+	ChainIDCronos:          6 * time.Second,
+	ChainIDBitTorrent:      2 * time.Second,
+	ChainIDAurora:          time.Second,
+}
+
+// AverageBlockTime returns the average block time of the chain, false if it isn't configured: the time-dependent
+// logic must then rely on the block timestamps only
+func AverageBlockTime(chainID ChainID) (time.Duration, bool) {
+	blockTime, ok := averageBlockTimes[chainID]
+	return blockTime, ok
+}
+
+// BlocksToDuration returns the approximate duration of blocks blocks of the chain, false if its block time isn't
+// configured, see AverageBlockTime
+func BlocksToDuration(chainID ChainID, blocks uint64) (time.Duration, bool) {
+	blockTime, ok := AverageBlockTime(chainID)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(blocks) * blockTime, true
+}
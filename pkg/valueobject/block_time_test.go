@@ -0,0 +1,36 @@
+package valueobject
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocksToDuration(t *testing.T) {
+	// 100 blocks behind, stale after 5 minutes
+	maxAge := 5 * time.Minute
+	blocks := uint64(100)
+
+	testcases := []struct {
+		chainID          ChainID
+		expectedDuration time.Duration
+		expectedOk       bool
+		expectedStale    bool
+	}{
+		{ChainIDEthereum, 20 * time.Minute, true, true},
+		{ChainIDPolygon, 200 * time.Second, true, false},
+		{ChainIDArbitrumOne, 25 * time.Second, true, false},
+		{ChainIDSolana, 0, false, false},
+	}
+
+	for idx, tc := range testcases {
+		t.Run(fmt.Sprintf("test %d", idx), func(t *testing.T) {
+			duration, ok := BlocksToDuration(tc.chainID, blocks)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedDuration, duration)
+			assert.Equal(t, tc.expectedStale, duration > maxAge)
+		})
+	}
+}
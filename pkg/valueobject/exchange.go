@@ -63,11 +63,13 @@ var (
 	ExchangeCurve    Exchange = "curve"
 	ExchangeEllipsis Exchange = "ellipsis"
 
-	ExchangeUniSwapV3        Exchange = "uniswapv3"
-	ExchangeKyberswapElastic Exchange = "kyberswap-elastic"
+	ExchangeUniSwapV3         Exchange = "uniswapv3"
+	ExchangeKyberswapElastic  Exchange = "kyberswap-elastic"
+	ExchangePancakeInfinityCL Exchange = "pancake-infinity-cl"
 
 	ExchangeBalancer   Exchange = "balancer"
 	ExchangeBeethovenX Exchange = "beethovenx"
+	ExchangeXave       Exchange = "xave"
 
 	ExchangeDodo Exchange = "dodo"
 
@@ -78,6 +80,7 @@ var (
 	ExchangeSynthetix Exchange = "synthetix"
 
 	ExchangeMakerPSM Exchange = "maker-psm"
+	ExchangeGhoGsm   Exchange = "gho-gsm"
 
 	ExchangeMakerLido Exchange = "lido"
 
@@ -157,14 +160,17 @@ var AMMSourceSet = map[Exchange]struct{}{
 	ExchangeEllipsis:            {},
 	ExchangeUniSwapV3:           {},
 	ExchangeKyberswapElastic:    {},
+	ExchangePancakeInfinityCL:   {},
 	ExchangeBalancer:            {},
 	ExchangeBeethovenX:          {},
+	ExchangeXave:                {},
 	ExchangeDodo:                {},
 	ExchangeGMX:                 {},
 	ExchangeMadMex:              {},
 	ExchangeMetavault:           {},
 	ExchangeSynthetix:           {},
 	ExchangeMakerPSM:            {},
+	ExchangeGhoGsm:              {},
 	ExchangeMakerLido:           {},
 	ExchangeDMM:                 {},
 	ExchangeKyberSwap:           {},